@@ -0,0 +1,214 @@
+// Package sentryamqp provides Sentry integration for RabbitMQ consumers and
+// publishers based on the github.com/rabbitmq/amqp091-go package.
+package sentryamqp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// traceHeader is the message header used to propagate traces from publishers
+// to consumers.
+const traceHeader = "sentry-trace"
+
+// A Publisher publishes messages. It is implemented by *amqp.Channel.
+type Publisher interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+// Publish publishes msg with p. If ctx carries a transaction, the operation is
+// recorded as a child span and msg is published with a sentry-trace header, so
+// that consumers can continue the trace. The headers of msg are not modified.
+//
+//	err := sentryamqp.Publish(ctx, ch, exchange, key, false, false, amqp.Publishing{
+//		Body: body,
+//	})
+func Publish(ctx context.Context, p Publisher, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if sentry.TransactionFromContext(ctx) == nil {
+		return p.Publish(exchange, key, mandatory, immediate, msg)
+	}
+	span := sentry.StartSpan(ctx, "queue.publish", sentry.WithSpanOrigin("auto.queue.amqp"))
+	defer span.Finish()
+	span.Description = key
+	span.SetTag("messaging.system", "rabbitmq")
+	span.SetTag("messaging.destination", exchange)
+
+	headers := make(amqp.Table, len(msg.Headers)+1)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[traceHeader] = span.ToSentryTrace()
+	msg.Headers = headers
+
+	err := p.Publish(exchange, key, mandatory, immediate, msg)
+	if err != nil {
+		span.Status = sentry.SpanStatusInternalError
+	} else {
+		span.Status = sentry.SpanStatusOK
+	}
+	return err
+}
+
+// A HandlerFunc processes a delivery. It is responsible for acknowledging the
+// delivery, unless it was consumed with auto-ack.
+type HandlerFunc func(ctx context.Context, d amqp.Delivery) error
+
+// A Handler is a delivery handler middleware factory that provides integration
+// with Sentry.
+type Handler struct {
+	repanic         bool
+	waitForDelivery bool
+	timeout         time.Duration
+}
+
+// Options configure a Handler.
+type Options struct {
+	// Repanic configures whether to panic again after recovering from a panic
+	// in a delivery handler.
+	Repanic bool
+	// WaitForDelivery indicates, in case of a panic, whether to block the
+	// current goroutine and wait until the panic event has been reported to
+	// Sentry before repanicking or resuming normal execution.
+	WaitForDelivery bool
+	// Timeout for the delivery of panic events. Defaults to 2s. Only relevant
+	// when WaitForDelivery is true.
+	Timeout time.Duration
+}
+
+// New returns a new Handler. Use the Handle method to wrap delivery handlers.
+func New(options Options) *Handler {
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	return &Handler{
+		repanic:         options.Repanic,
+		timeout:         timeout,
+		waitForDelivery: options.WaitForDelivery,
+	}
+}
+
+// Handle wraps a delivery handler. For every delivery, the wrapped handler
+// runs with a delivery-specific hub and in a queue.process transaction, named
+// after the routing key, that continues the trace propagated in the message
+// headers, if any.
+//
+// The outcome of the delivery is recorded as the status of the transaction:
+// ok if the handler acknowledged it, aborted if it was negatively
+// acknowledged or rejected, and internal_error if the handler returned an
+// error or panicked. Panics in the handler are recovered and reported to
+// Sentry; when a panic is not repanicked, the wrapped handler returns an
+// error, such that the caller negatively acknowledges the delivery.
+//
+//	deliveries, err := ch.Consume(queue, "", false, false, false, false, nil)
+//	handle := sentryamqp.New(sentryamqp.Options{}).Handle(process)
+//	for d := range deliveries {
+//		_ = handle(ctx, d)
+//	}
+func (h *Handler) Handle(handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, d amqp.Delivery) (err error) {
+		hub := sentry.GetHubFromContext(ctx)
+		if hub == nil {
+			hub = sentry.CurrentHub()
+		}
+		hub = hub.Clone()
+		ctx = sentry.SetHubOnContext(ctx, hub)
+		hub.Scope().SetTags(map[string]string{
+			"messaging.system":      "rabbitmq",
+			"messaging.destination": d.Exchange,
+			"messaging.routing_key": d.RoutingKey,
+		})
+		if d.MessageId != "" {
+			hub.Scope().SetExtra("messaging.message_id", d.MessageId)
+		}
+
+		var trace string
+		if v, ok := d.Headers[traceHeader].(string); ok {
+			trace = v
+		}
+		span := sentry.StartSpan(ctx, "queue.process",
+			sentry.TransactionName(d.RoutingKey),
+			sentry.WithTransactionSource(sentry.SourceTask),
+			sentry.ContinueFromTrace(trace),
+			sentry.WithSpanOrigin("auto.queue.amqp"),
+		)
+		defer span.Finish()
+
+		ack := &acknowledger{Acknowledger: d.Acknowledger}
+		if d.Acknowledger != nil {
+			d.Acknowledger = ack
+		}
+		defer h.recoverWithSentry(hub, span, &err)
+
+		err = handler(span.Context(), d)
+		outcome := ack.outcome()
+		if outcome != "" {
+			span.SetTag("messaging.outcome", outcome)
+		}
+		switch {
+		case err != nil:
+			span.Status = sentry.SpanStatusInternalError
+		case outcome == "nack" || outcome == "reject":
+			span.Status = sentry.SpanStatusAborted
+		default:
+			span.Status = sentry.SpanStatusOK
+		}
+		return err
+	}
+}
+
+func (h *Handler) recoverWithSentry(hub *sentry.Hub, span *sentry.Span, err *error) {
+	if r := recover(); r != nil {
+		span.Status = sentry.SpanStatusInternalError
+		eventID := hub.RecoverWithContext(span.Context(), r)
+		if eventID != nil && h.waitForDelivery {
+			hub.Flush(h.timeout)
+		}
+		if h.repanic {
+			panic(r)
+		}
+		*err = fmt.Errorf("sentryamqp: panic: %v", r)
+	}
+}
+
+// An acknowledger records how a delivery was acknowledged.
+type acknowledger struct {
+	amqp.Acknowledger
+
+	mu   sync.Mutex
+	last string
+}
+
+func (a *acknowledger) Ack(tag uint64, multiple bool) error {
+	a.record("ack")
+	return a.Acknowledger.Ack(tag, multiple)
+}
+
+func (a *acknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.record("nack")
+	return a.Acknowledger.Nack(tag, multiple, requeue)
+}
+
+func (a *acknowledger) Reject(tag uint64, requeue bool) error {
+	a.record("reject")
+	return a.Acknowledger.Reject(tag, requeue)
+}
+
+func (a *acknowledger) record(outcome string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.last = outcome
+}
+
+// outcome returns the last acknowledgement of the delivery, one of "ack",
+// "nack" or "reject", or "" if it was not acknowledged.
+func (a *acknowledger) outcome() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.last
+}
@@ -0,0 +1,169 @@
+package sentryamqp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	sentryamqp "github.com/getsentry/sentry-go/amqp"
+	"github.com/getsentry/sentry-go/sentrytest"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type publisherMock struct {
+	msgs []amqp.Publishing
+}
+
+func (p *publisherMock) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+type acknowledgerMock struct {
+	acks, nacks, rejects int
+}
+
+func (a *acknowledgerMock) Ack(tag uint64, multiple bool) error {
+	a.acks++
+	return nil
+}
+
+func (a *acknowledgerMock) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.nacks++
+	return nil
+}
+
+func (a *acknowledgerMock) Reject(tag uint64, requeue bool) error {
+	a.rejects++
+	return nil
+}
+
+func TestPublishPropagatesTrace(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	transaction := sentry.StartSpan(ctx, "test", sentry.TransactionName("test"))
+
+	p := &publisherMock{}
+	headers := amqp.Table{"k": "v"}
+	err := sentryamqp.Publish(transaction.Context(), p, "orders", "created", false, false, amqp.Publishing{Headers: headers})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transaction.Finish()
+
+	if len(headers) != 1 {
+		t.Error("Publish modified the headers passed in")
+	}
+	spans := transport.Events()[0].Spans
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if got, want := p.msgs[0].Headers["sentry-trace"], spans[0].ToSentryTrace(); got != want {
+		t.Errorf("got trace header %q, want %q", got, want)
+	}
+	if p.msgs[0].Headers["k"] != "v" {
+		t.Errorf("got headers %v, want existing headers to be kept", p.msgs[0].Headers)
+	}
+}
+
+func TestHandlerRecordsOutcome(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     sentryamqp.HandlerFunc
+		wantStatus  sentry.SpanStatus
+		wantOutcome string
+	}{
+		{
+			name: "Ack",
+			handler: func(ctx context.Context, d amqp.Delivery) error {
+				return d.Ack(false)
+			},
+			wantStatus:  sentry.SpanStatusOK,
+			wantOutcome: "ack",
+		},
+		{
+			name: "Nack",
+			handler: func(ctx context.Context, d amqp.Delivery) error {
+				return d.Nack(false, true)
+			},
+			wantStatus:  sentry.SpanStatusAborted,
+			wantOutcome: "nack",
+		},
+		{
+			name: "Reject",
+			handler: func(ctx context.Context, d amqp.Delivery) error {
+				return d.Reject(false)
+			},
+			wantStatus:  sentry.SpanStatusAborted,
+			wantOutcome: "reject",
+		},
+		{
+			name: "Error",
+			handler: func(ctx context.Context, d amqp.Delivery) error {
+				return errors.New("failed")
+			},
+			wantStatus: sentry.SpanStatusInternalError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+			ack := &acknowledgerMock{}
+			d := amqp.Delivery{
+				Acknowledger: ack,
+				Exchange:     "orders",
+				RoutingKey:   "created",
+				Headers: amqp.Table{
+					"sentry-trace": "bc6d53f15eb88f4320054569b8c553d4-b72fa28504b07285-1",
+				},
+			}
+			_ = sentryamqp.New(sentryamqp.Options{}).Handle(tt.handler)(ctx, d)
+
+			if n := ack.acks + ack.nacks + ack.rejects; tt.wantOutcome != "" && n != 1 {
+				t.Errorf("got %d acknowledgements, want 1", n)
+			}
+			events := transport.Events()
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			event := events[0]
+			if event.Transaction != "created" {
+				t.Errorf("got transaction %q, want %q", event.Transaction, "created")
+			}
+			trace := event.Contexts["trace"].(*sentry.TraceContext)
+			if trace.TraceID.String() != "bc6d53f15eb88f4320054569b8c553d4" {
+				t.Errorf("got trace ID %s, want the trace of the publisher", trace.TraceID)
+			}
+			if trace.Status != tt.wantStatus {
+				t.Errorf("got status %s, want %s", trace.Status, tt.wantStatus)
+			}
+			if got := event.Tags["messaging.outcome"]; got != tt.wantOutcome {
+				t.Errorf("got outcome %q, want %q", got, tt.wantOutcome)
+			}
+		})
+	}
+}
+
+func TestHandlerRecoversPanic(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	handle := sentryamqp.New(sentryamqp.Options{}).Handle(func(ctx context.Context, d amqp.Delivery) error {
+		panic("handler panic")
+	})
+	err := handle(ctx, amqp.Delivery{RoutingKey: "created"})
+	if err == nil || err.Error() != "sentryamqp: panic: handler panic" {
+		t.Errorf("got error %v, want the panic", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0].Message != "handler panic" || events[0].Tags["messaging.routing_key"] != "created" {
+		t.Errorf("unexpected panic event %+v", events[0])
+	}
+	trace := events[1].Contexts["trace"].(*sentry.TraceContext)
+	if trace.Status != sentry.SpanStatusInternalError {
+		t.Errorf("got status %s, want %s", trace.Status, sentry.SpanStatusInternalError)
+	}
+}
@@ -0,0 +1,174 @@
+// Package sentryasynq provides Sentry integration for task queues based on the
+// github.com/hibiken/asynq package.
+package sentryasynq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/hibiken/asynq"
+)
+
+// traceKey is the key of the payload of tasks used to propagate traces from
+// producers to handlers.
+const traceKey = "sentry-trace"
+
+// NewTask returns a new task, like asynq.NewTask. If ctx carries a
+// transaction and payload is a JSON object, the payload gets a sentry-trace
+// field, so that the handler of the task can continue the trace. Handlers that
+// decode payloads with encoding/json ignore it, unless they disallow unknown
+// fields: create their tasks with asynq.NewTask instead. Other payloads are not
+// modified.
+//
+// Since the trace differs for every task, it would make the payloads of
+// otherwise identical tasks differ, and asynq.Unique compares payloads to
+// detect duplicate tasks. The payloads of tasks with the asynq.Unique option
+// are therefore not modified, and their traces are not continued.
+func NewTask(ctx context.Context, typename string, payload []byte, opts ...asynq.Option) *asynq.Task {
+	if transaction := sentry.TransactionFromContext(ctx); transaction != nil && !isUnique(opts) {
+		payload = withTrace(payload, transaction.ToSentryTrace())
+	}
+	return asynq.NewTask(typename, payload, opts...)
+}
+
+// isUnique reports whether opts has the asynq.Unique option.
+func isUnique(opts []asynq.Option) bool {
+	for _, opt := range opts {
+		if opt.Type() == asynq.UniqueOpt {
+			return true
+		}
+	}
+	return false
+}
+
+// withTrace returns payload with the trace in its traceKey field, or payload
+// if it is not a JSON object.
+func withTrace(payload []byte, trace string) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		return payload
+	}
+	fields[traceKey], _ = json.Marshal(trace)
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return payload
+	}
+	return b
+}
+
+// traceOf returns the trace propagated in payload, or "" if there is none.
+func traceOf(payload []byte) string {
+	var fields struct {
+		Trace string `json:"sentry-trace"`
+	}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return ""
+	}
+	return fields.Trace
+}
+
+// A Handler is a task handler middleware factory that provides integration
+// with Sentry.
+type Handler struct {
+	repanic         bool
+	waitForDelivery bool
+	timeout         time.Duration
+}
+
+// Options configure a Handler.
+type Options struct {
+	// Repanic configures whether to panic again after recovering from a panic
+	// in a task handler.
+	Repanic bool
+	// WaitForDelivery indicates, in case of a panic or an error, whether to
+	// block the current goroutine and wait until the event has been reported
+	// to Sentry before returning.
+	WaitForDelivery bool
+	// Timeout for the delivery of events. Defaults to 2s. Only relevant when
+	// WaitForDelivery is true.
+	Timeout time.Duration
+}
+
+// New returns a new Handler. Use the Handle method as a middleware of a
+// server mux.
+func New(options Options) *Handler {
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	return &Handler{
+		repanic:         options.Repanic,
+		timeout:         timeout,
+		waitForDelivery: options.WaitForDelivery,
+	}
+}
+
+// Handle wraps a task handler. For every task, the wrapped handler runs with a
+// task-specific hub, tagged with the type, the ID and the queue of the task,
+// and in a transaction named after the type of the task, which continues the
+// trace propagated by NewTask, if any. The errors returned by the handler and
+// its panics are reported to Sentry. When a panic is not repanicked, the
+// wrapped handler returns an error, so that the task is retried.
+//
+//	mux := asynq.NewServeMux()
+//	mux.Use(sentryasynq.New(sentryasynq.Options{}).Handle)
+//	mux.HandleFunc("email:welcome", sendWelcomeEmail)
+func (h *Handler) Handle(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) (err error) {
+		hub := sentry.GetHubFromContext(ctx)
+		if hub == nil {
+			hub = sentry.CurrentHub().Clone()
+			ctx = sentry.SetHubOnContext(ctx, hub)
+		}
+		retried, _ := asynq.GetRetryCount(ctx)
+		hub.ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetTag("asynq.task_type", task.Type())
+			if id, ok := asynq.GetTaskID(ctx); ok {
+				scope.SetTag("asynq.task_id", id)
+			}
+			if queue, ok := asynq.GetQueueName(ctx); ok {
+				scope.SetTag("asynq.queue", queue)
+			}
+		})
+
+		span := sentry.StartSpan(ctx, "queue.process",
+			sentry.TransactionName(task.Type()),
+			sentry.WithTransactionSource(sentry.SourceTask),
+			sentry.ContinueFromTrace(traceOf(task.Payload())),
+			sentry.WithSpanOrigin("auto.queue.asynq"),
+		)
+		span.SetData("asynq.retry_count", retried)
+		defer span.Finish()
+		defer func() {
+			if r := recover(); r != nil {
+				span.Status = sentry.SpanStatusInternalError
+				eventID := hub.RecoverWithContext(ctx, r)
+				h.wait(hub, eventID)
+				if h.repanic {
+					panic(r)
+				}
+				err = fmt.Errorf("sentryasynq: panic: %v", r)
+			}
+		}()
+
+		err = next.ProcessTask(span.Context(), task)
+		if err != nil {
+			span.Status = sentry.SpanStatusInternalError
+			h.wait(hub, hub.CaptureException(err))
+		} else {
+			span.Status = sentry.SpanStatusOK
+		}
+		return err
+	})
+}
+
+// wait waits for the delivery of the event eventID, if any, when configured
+// to do so.
+func (h *Handler) wait(hub *sentry.Hub, eventID *sentry.EventID) {
+	if eventID != nil && h.waitForDelivery {
+		hub.Flush(h.timeout)
+	}
+}
@@ -0,0 +1,116 @@
+package sentryasynq_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	sentryasynq "github.com/getsentry/sentry-go/asynq"
+	"github.com/getsentry/sentry-go/sentrytest"
+	"github.com/hibiken/asynq"
+)
+
+func TestNewTask(t *testing.T) {
+	ctx, _ := sentrytest.NewContext(t, sentry.ClientOptions{
+		TracesSampleRate: 1.0,
+		Integrations:     func([]sentry.Integration) []sentry.Integration { return nil },
+	})
+
+	// Without a transaction, the payload is not modified.
+	task := sentryasynq.NewTask(ctx, "email:welcome", []byte(`{"user_id":42}`))
+	if string(task.Payload()) != `{"user_id":42}` {
+		t.Errorf("got payload %s", task.Payload())
+	}
+
+	span := sentry.StartSpan(ctx, "signup", sentry.TransactionName("signup"))
+	defer span.Finish()
+	task = sentryasynq.NewTask(span.Context(), "email:welcome", []byte(`{"user_id":42}`))
+	var payload struct {
+		UserID int    `json:"user_id"`
+		Trace  string `json:"sentry-trace"`
+	}
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.UserID != 42 || payload.Trace != span.ToSentryTrace() {
+		t.Errorf("got payload %s", task.Payload())
+	}
+
+	// Payloads other than JSON objects are not modified.
+	task = sentryasynq.NewTask(span.Context(), "ping", []byte("ping"))
+	if string(task.Payload()) != "ping" {
+		t.Errorf("got payload %s", task.Payload())
+	}
+
+	// Neither are the payloads of unique tasks, which asynq compares to
+	// detect duplicates.
+	task = sentryasynq.NewTask(span.Context(), "email:welcome", []byte(`{"user_id":42}`), asynq.Unique(time.Hour))
+	if string(task.Payload()) != `{"user_id":42}` {
+		t.Errorf("got payload %s of unique task", task.Payload())
+	}
+}
+
+func TestHandle(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{
+		TracesSampleRate: 1.0,
+		Integrations:     func([]sentry.Integration) []sentry.Integration { return nil },
+	})
+	producer := sentry.StartSpan(ctx, "signup", sentry.TransactionName("signup"))
+	task := sentryasynq.NewTask(producer.Context(), "email:welcome", []byte(`{"user_id":42}`))
+
+	errSend := errors.New("mail server unavailable")
+	handler := sentryasynq.New(sentryasynq.Options{}).Handle(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		return errSend
+	}))
+	if err := handler.ProcessTask(ctx, task); err != errSend {
+		t.Fatalf("got error %v, want %v", err, errSend)
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	failure, transaction := events[0], events[1]
+	if failure.Exception[0].Value != errSend.Error() || failure.Tags["asynq.task_type"] != "email:welcome" {
+		t.Errorf("unexpected error event %+v", failure)
+	}
+	if transaction.Transaction != "email:welcome" {
+		t.Errorf("got transaction %q, want %q", transaction.Transaction, "email:welcome")
+	}
+	trace := transaction.Contexts["trace"].(*sentry.TraceContext)
+	if trace.TraceID != producer.TraceID || trace.ParentSpanID != producer.SpanID {
+		t.Errorf("got trace %+v, want the trace of the producer", trace)
+	}
+	if trace.Op != "queue.process" || trace.Status != sentry.SpanStatusInternalError {
+		t.Errorf("got trace context %+v", trace)
+	}
+}
+
+func TestHandlePanic(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{
+		TracesSampleRate: 1.0,
+		Integrations:     func([]sentry.Integration) []sentry.Integration { return nil },
+	})
+	handler := sentryasynq.New(sentryasynq.Options{}).Handle(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		panic("test")
+	}))
+	if err := handler.ProcessTask(ctx, asynq.NewTask("panic", nil)); err == nil {
+		t.Error("got no error, want an error for the panic")
+	}
+	events := transport.Events()
+	if len(events) != 2 || events[0].Message != "test" {
+		t.Fatalf("got events %v, want the panic event and the transaction", events)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("got no panic, want a repanic")
+		}
+	}()
+	_ = sentryasynq.New(sentryasynq.Options{Repanic: true}).Handle(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		panic("test")
+	})).ProcessTask(ctx, asynq.NewTask("panic", nil))
+}
@@ -0,0 +1,32 @@
+//go:build go1.18
+// +build go1.18
+
+package sentry
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// buildType describes how the program was built, from the settings recorded
+// by the go command in the build info: "race" with the race detector,
+// "debug" with optimizations or inlining disabled, and "release" otherwise.
+func buildType(info *debug.BuildInfo) string {
+	typ := "release"
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "-race":
+			if setting.Value == "true" {
+				return "race"
+			}
+		case "-gcflags":
+			for _, flag := range strings.Fields(setting.Value) {
+				flag = flag[strings.IndexByte(flag, '=')+1:]
+				if flag == "-N" || flag == "-l" {
+					typ = "debug"
+				}
+			}
+		}
+	}
+	return typ
+}
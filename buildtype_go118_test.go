@@ -0,0 +1,47 @@
+//go:build go1.18
+// +build go1.18
+
+package sentry
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestBuildType(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []debug.BuildSetting
+		want     string
+	}{
+		{
+			name: "default",
+			want: "release",
+		},
+		{
+			name: "race",
+			settings: []debug.BuildSetting{
+				{Key: "-gcflags", Value: "all=-N -l"},
+				{Key: "-race", Value: "true"},
+			},
+			want: "race",
+		},
+		{
+			name:     "optimizations disabled",
+			settings: []debug.BuildSetting{{Key: "-gcflags", Value: "all=-N -l"}},
+			want:     "debug",
+		},
+		{
+			name:     "other flags",
+			settings: []debug.BuildSetting{{Key: "-gcflags", Value: "-m"}},
+			want:     "release",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildType(&debug.BuildInfo{Settings: tt.settings})
+			assertEqual(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !go1.18
+// +build !go1.18
+
+package sentry
+
+import "runtime/debug"
+
+// buildType describes how the program was built. Go versions before 1.18 do
+// not record the build settings in the build info.
+func buildType(info *debug.BuildInfo) string {
+	return ""
+}
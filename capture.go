@@ -0,0 +1,64 @@
+package sentry
+
+import "context"
+
+// A CaptureOption enriches a single captured event, without the need for
+// WithScope. Options are applied, in order, to a copy of the scope used for the
+// event.
+type CaptureOption func(scope *Scope)
+
+// WithTags sets tags on the captured event, in addition to those of the scope.
+func WithTags(tags map[string]string) CaptureOption {
+	return func(scope *Scope) {
+		scope.SetTags(tags)
+	}
+}
+
+// WithExtra sets an extra on the captured event.
+func WithExtra(key string, value interface{}) CaptureOption {
+	return func(scope *Scope) {
+		scope.SetExtra(key, value)
+	}
+}
+
+// WithLevel sets the level of the captured event, overriding the level of the
+// scope and the default level of the event.
+func WithLevel(level Level) CaptureOption {
+	return func(scope *Scope) {
+		scope.SetLevel(level)
+	}
+}
+
+// WithFingerprint sets the fingerprint of the captured event.
+func WithFingerprint(fingerprint []string) CaptureOption {
+	return func(scope *Scope) {
+		scope.SetFingerprint(fingerprint)
+	}
+}
+
+// withSpanFromContext returns options preceded by one that links the event to
+// the span in ctx, if any. The scope is otherwise linked to the span started
+// last on the hub, which may not be the one of ctx when spans run
+// concurrently.
+func withSpanFromContext(ctx context.Context, options []CaptureOption) []CaptureOption {
+	span := spanFromContext(ctx)
+	if span == nil {
+		return options
+	}
+	return append([]CaptureOption{func(scope *Scope) {
+		scope.SetContext("trace", span.traceContext())
+	}}, options...)
+}
+
+// applyCaptureOptions returns scope if there are no options, or a copy of scope
+// with the options applied.
+func applyCaptureOptions(scope *Scope, options []CaptureOption) *Scope {
+	if len(options) == 0 {
+		return scope
+	}
+	scope = scope.Clone()
+	for _, option := range options {
+		option(scope)
+	}
+	return scope
+}
@@ -0,0 +1,78 @@
+package sentry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCaptureOptions(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := NewScope()
+	scope.SetTag("service", "checkout")
+	hub := NewHub(client, scope)
+
+	hub.CaptureException(errors.New("payment failed"),
+		WithTags(map[string]string{"payment.provider": "acme"}),
+		WithExtra("order.id", 42),
+		WithLevel(LevelFatal),
+		WithFingerprint([]string{"payment"}),
+	)
+	hub.CaptureMessage("retrying", WithLevel(LevelWarning))
+	hub.CaptureException(errors.New("payment failed again"))
+
+	events := transport.Events()
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	enriched, message, plain := events[0], events[1], events[2]
+	assertEqual(t, enriched.Tags, map[string]string{"service": "checkout", "payment.provider": "acme"})
+	assertEqual(t, enriched.Extra["order.id"], 42)
+	assertEqual(t, enriched.Level, LevelFatal)
+	assertEqual(t, enriched.Fingerprint, []string{"payment"})
+	assertEqual(t, message.Level, LevelWarning)
+
+	// Options do not leak into the scope or later events.
+	assertEqual(t, plain.Tags, map[string]string{"service": "checkout"})
+	assertEqual(t, plain.Level, LevelError)
+	if _, ok := plain.Extra["order.id"]; ok {
+		t.Error("option leaked into a later event")
+	}
+	assertEqual(t, len(plain.Fingerprint), 0)
+}
+
+func TestCaptureCtx(t *testing.T) {
+	transport := &TransportMock{}
+	ctx := NewTestContext(ClientOptions{
+		Transport:        transport,
+		TracesSampleRate: 1.0,
+	})
+	hub := GetHubFromContext(ctx)
+	hub.Scope().SetTag("request", "1")
+
+	span := StartSpan(ctx, "top", TransactionName("test"))
+	a := span.StartChild("a")
+	b := span.StartChild("b")
+	AddBreadcrumbCtx(a.Context(), &Breadcrumb{Message: "in a"})
+	CaptureExceptionCtx(a.Context(), errors.New("failed in a"), WithLevel(LevelWarning))
+	CaptureMessageCtx(b.Context(), "in b")
+	b.Finish()
+	a.Finish()
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	exception, message := events[0], events[1]
+	// Events land in the scope of the hub of the context.
+	assertEqual(t, exception.Tags["request"], "1")
+	assertEqual(t, exception.Level, LevelWarning)
+	assertEqual(t, exception.Breadcrumbs[0].Message, "in a")
+	// Events are linked to the span of the context, not to the span started
+	// last.
+	assertEqual(t, exception.Contexts["trace"].(*TraceContext).SpanID, a.SpanID)
+	assertEqual(t, message.Contexts["trace"].(*TraceContext).SpanID, b.SpanID)
+}
@@ -0,0 +1,33 @@
+// Package sentrychi provides Sentry integration for servers based on the
+// github.com/go-chi/chi/v5 router.
+package sentrychi
+
+import (
+	"net/http"
+
+	sentryhttp "github.com/getsentry/sentry-go/http"
+	"github.com/go-chi/chi/v5"
+)
+
+// New returns a sentryhttp.Handler that names transactions after the chi
+// route patterns that match requests, for example "GET /users/{id}". Install
+// it as a middleware of the router, so that the route is known once the
+// request has been handled:
+//
+//	r := chi.NewRouter()
+//	r.Use(sentrychi.New(sentryhttp.Options{}).Handle)
+//	r.Get("/users/{id}", getUser)
+func New(options sentryhttp.Options) *sentryhttp.Handler {
+	options.RoutePattern = RoutePattern
+	return sentryhttp.New(options)
+}
+
+// RoutePattern returns the pattern of the chi route that matched r, or "" if
+// r was not routed by chi or no route matched.
+func RoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
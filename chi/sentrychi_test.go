@@ -0,0 +1,56 @@
+package sentrychi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	sentrychi "github.com/getsentry/sentry-go/chi"
+	sentryhttp "github.com/getsentry/sentry-go/http"
+	"github.com/getsentry/sentry-go/sentrytest"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTransactionName(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(sentrychi.New(sentryhttp.Options{}).Handle)
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	router.Route("/orders", func(r chi.Router) {
+		r.Get("/{id}/items", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/42", "GET /users/{id}"},
+		{"/orders/7/items", "GET /orders/{id}/items"},
+		{"/unknown", "GET /unknown"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			transport := sentrytest.NewRecordingTransport()
+			client, err := sentry.NewClient(sentry.ClientOptions{
+				Transport:        transport,
+				TracesSampleRate: 1.0,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hub := sentry.NewHub(client, sentry.NewScope())
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r = r.WithContext(sentry.SetHubOnContext(r.Context(), hub))
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			events := transport.Events()
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			if events[0].Transaction != tt.want {
+				t.Errorf("got transaction %q, want %q", events[0].Transaction, tt.want)
+			}
+		})
+	}
+}
@@ -3,24 +3,29 @@ package sentry
 import (
 	"context"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/getsentry/sentry-go/internal/debug"
 )
 
-// maxErrorDepth is the maximum number of errors reported in a chain of errors.
+// maxErrorDepth is the default maximum number of errors reported in a chain of
+// errors, see ClientOptions.MaxErrorDepth.
 // This protects the SDK from an arbitrarily long chain of wrapped errors.
 //
 // An additional consideration is that arguably reporting a long chain of errors
@@ -87,19 +92,95 @@ type EventModifier interface {
 	ApplyToEvent(event *Event, hint *EventHint) *Event
 }
 
-var globalEventProcessors []EventProcessor
+// processorList is a list of event processors, safe for concurrent use.
+// Events are processed by the processors in the list when their processing
+// starts, such that adding a processor does not affect events in flight.
+type processorList struct {
+	mu         sync.RWMutex
+	processors []EventProcessor
+}
+
+// add appends processor to the list.
+func (l *processorList) add(processor EventProcessor) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.processors = append(l.processors, processor)
+}
+
+// snapshot returns the processors in the list. Processors are only ever
+// appended, so the returned slice is not modified by later calls to add.
+func (l *processorList) snapshot() []EventProcessor {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.processors
+}
+
+// len returns the number of processors in the list.
+func (l *processorList) len() int {
+	return len(l.snapshot())
+}
+
+var globalEventProcessors processorList
 
 // AddGlobalEventProcessor adds processor to the global list of event
-// processors. Global event processors apply to all events.
+// processors. Global event processors apply to all events. It is safe for
+// concurrent use.
 //
 // AddGlobalEventProcessor is deprecated. Most users will prefer to initialize
 // the SDK with Init and provide a ClientOptions.BeforeSend function or use
 // Scope.AddEventProcessor instead.
 func AddGlobalEventProcessor(processor EventProcessor) {
-	globalEventProcessors = append(globalEventProcessors, processor)
+	globalEventProcessors.add(processor)
+}
+
+// A Clock provides the current time. The SDK uses it to timestamp events,
+// breadcrumbs and spans. Implementations must be safe for concurrent use.
+//
+// Custom implementations are mostly useful in tests, for example to freeze
+// time. The default implementation uses time.Now, such that durations are
+// computed from monotonic clock readings and unaffected by changes to the
+// system wall clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default Clock, backed by time.Now.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// clockFor returns the Clock configured in the options of client. It returns
+// the system clock if client is nil or no Clock is configured.
+func clockFor(client *Client) Clock {
+	if client == nil || client.options.Clock == nil {
+		return systemClock{}
+	}
+	return client.options.Clock
+}
+
+// An IDGenerator generates the IDs of events, traces and spans. Implementations
+// must be safe for concurrent use.
+//
+// Custom implementations are mostly useful in tests and replay tooling, to get
+// deterministic IDs. The default implementation generates random IDs with
+// crypto/rand. Event IDs are version 4 UUIDs, encoded as 32 lowercase
+// hexadecimal characters without dashes, as required by the Sentry protocol.
+type IDGenerator interface {
+	EventID() EventID
+	TraceID() TraceID
+	SpanID() SpanID
 }
 
 // Integration allows for registering a functions that modify or discard captured events.
+//
+// Integrations are installed when a Client is created, in the order returned by
+// ClientOptions.Integrations. SetupOnce is called exactly once per client, and
+// is the place to register event processors with Client.AddEventProcessor or
+// to start background work. Integrations are identified by Name: if several
+// integrations have the same name, only the first one is installed. The names
+// of installed integrations are reported with every event.
 type Integration interface {
 	Name() string
 	SetupOnce(client *Client)
@@ -108,23 +189,70 @@ type Integration interface {
 // ClientOptions that configures a SDK Client.
 type ClientOptions struct {
 	// The DSN to use. If the DSN is not set, the client is effectively
-	// disabled.
+	// disabled: unless a custom Transport is set, events are discarded and
+	// capture methods return nil. This allows leaving instrumentation in
+	// place in environments where reporting is not wanted. Defaults to the
+	// SENTRY_DSN environment variable.
 	Dsn string
 	// In debug mode, the debug information is printed to stdout to help you
-	// understand what sentry is doing.
+	// understand what sentry is doing. Can also be enabled with the
+	// SENTRY_DEBUG environment variable.
+	//
+	// For every event, the client logs the decisions taken about it, like
+	// sampling and dropping by BeforeSend, on a line starting with the ID of
+	// the event, and the transports of the SDK log whether it was sent and
+	// the status of the response with the same ID.
 	Debug bool
 	// Configures whether SDK should generate and attach stacktraces to pure
 	// capture message calls.
 	AttachStacktrace bool
+	// Configures whether the SDK should report the current goroutine in the
+	// threads interface of events for captured errors and recovered panics.
+	// For panics, the goroutine is marked as crashed.
+	AttachThreads bool
+	// Configures whether the SDK should report all other goroutines in the
+	// threads interface in addition to the current one. Implies
+	// AttachThreads. Collecting the stacks of all goroutines stops the world
+	// for a short time, use with care in programs that capture errors often.
+	AttachAllThreads bool
+	// Includes personally identifiable information in events. In particular,
+	// the IP address of the client of the request of an event, as set by the
+	// HTTP integrations, is reported as the IP address of the user unless the
+	// user has another one. See also AutoIPAddress.
+	SendDefaultPII bool
 	// The sample rate for event submission in the range [0.0, 1.0]. By default,
 	// all events are sent. Thus, as a historical special case, the sample rate
 	// 0.0 is treated as if it was 1.0. To drop all events, set the DSN to the
 	// empty string.
 	SampleRate float64
-	// The sample rate for sampling traces in the range [0.0, 1.0].
+	// EventSampler returns the sample rate, in the range [0.0, 1.0], of an
+	// error or message event, overriding SampleRate. It is called once the
+	// event has been completed with the data of the scope, so that it can,
+	// for example, keep all fatal events and sample warnings:
+	//
+	//	EventSampler: func(event *sentry.Event, hint *sentry.EventHint) float64 {
+	//		if event.Level == sentry.LevelWarning {
+	//			return 0.01
+	//		}
+	//		return 1.0
+	//	},
+	EventSampler func(event *Event, hint *EventHint) float64
+	// ErrorSampler decides whether to send error and message events, after
+	// SampleRate and EventSampler. Use NewErrorThrottle to limit the rate of
+	// identical events.
+	ErrorSampler ErrorSampler
+	// The sample rate for sampling traces in the range [0.0, 1.0]. Defaults to
+	// the SENTRY_TRACES_SAMPLE_RATE environment variable, unless
+	// TracesSampler is set.
 	TracesSampleRate float64
 	// Used to customize the sampling of traces, overrides TracesSampleRate.
 	TracesSampler TracesSampler
+	// Enables monitoring the health of the transport. While the transport is
+	// dropping events because its buffer is full or Sentry is rate limiting
+	// the project, the effective sample rate of transactions is halved every
+	// 10 seconds, until the transport is healthy again. Only supported by
+	// HTTPTransport and HTTPSyncTransport.
+	EnableBackpressureHandling bool
 	// List of regexp strings that will be used to match against event's message
 	// and if applicable, caught errors type and value.
 	// If the match is found, then a whole event will be dropped.
@@ -136,7 +264,18 @@ type ClientOptions struct {
 	// Before breadcrumb add callback.
 	BeforeBreadcrumb func(breadcrumb *Breadcrumb, hint *BreadcrumbHint) *Breadcrumb
 	// Integrations to be installed on the current Client, receives default
-	// integrations.
+	// integrations. Return a modified slice to add or remove integrations.
+	// For example, to remove the default integration named "Modules":
+	//
+	//	Integrations: func(integrations []sentry.Integration) []sentry.Integration {
+	//		var filtered []sentry.Integration
+	//		for _, integration := range integrations {
+	//			if integration.Name() != "Modules" {
+	//				filtered = append(filtered, integration)
+	//			}
+	//		}
+	//		return filtered
+	//	},
 	Integrations func([]Integration) []Integration
 	// io.Writer implementation that should be used with the Debug mode.
 	DebugWriter io.Writer
@@ -151,8 +290,10 @@ type ClientOptions struct {
 	// https://docs.sentry.io/product/releases/.
 	//
 	// If Release is not set, the SDK will try to derive a default value
-	// from environment variables or the Git repository in the working
-	// directory.
+	// from environment variables, starting with SENTRY_RELEASE, the version
+	// control revision recorded in the build info (Go 1.18+), the Git
+	// repository in the working directory or, as a last resort, a checksum
+	// of the running executable.
 	//
 	// If you distribute a compiled binary, it is recommended to set the
 	// Release value explicitly at build time. As an example, you can use:
@@ -170,10 +311,20 @@ type ClientOptions struct {
 	Release string
 	// The dist to be sent with events.
 	Dist string
-	// The environment to be sent with events.
+	// The environment to be sent with events. Defaults to the
+	// SENTRY_ENVIRONMENT environment variable.
 	Environment string
 	// Maximum number of breadcrumbs.
 	MaxBreadcrumbs int
+	// Size limits of the data held by the scopes of hubs bound to the
+	// client, for long-lived hubs. See ScopeLimits. Unlimited by default.
+	ScopeLimits ScopeLimits
+	// The maximum size of the request bodies attached to events, one of the
+	// RequestBodySize classes or a size in bytes. Defaults to
+	// RequestBodySizeMedium. Request bodies are recorded as the handler reads
+	// them, and not at all with RequestBodySizeNone. Sensitive fields of form
+	// and JSON bodies, like passwords and tokens, are always filtered.
+	MaxRequestBodySize RequestBodySize
 	// An optional pointer to http.Client that will be used with a default
 	// HTTPTransport. Using your own client will make HTTPTransport, HTTPProxy,
 	// HTTPSProxy and CaCerts options ignored.
@@ -191,6 +342,65 @@ type ClientOptions struct {
 	HTTPSProxy string
 	// An optional set of SSL certificates to use.
 	CaCerts *x509.CertPool
+	// An optional function to dial the connections to Sentry, used by the
+	// default HTTPTransport, for example to connect to a relay through a
+	// Unix socket with UnixSocketDialer, or through a SOCKS5 proxy. The
+	// address is the one of the DSN, or of the proxy if one is configured.
+	// Ignored when HTTPClient or HTTPTransport is set.
+	HTTPDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// The clock used to timestamp events, breadcrumbs and spans. Defaults
+	// to the system clock.
+	Clock Clock
+	// The generator of the IDs of events, traces and spans. Defaults to
+	// random IDs.
+	IDGenerator IDGenerator
+	// Mirrors all events to a Spotlight sidecar, in addition to sending them
+	// to Sentry if a DSN is set. Spotlight shows events in the browser during
+	// local development, see https://spotlightjs.com/. Can also be enabled
+	// with the SENTRY_SPOTLIGHT environment variable, set to "true" or to
+	// the URL of the sidecar.
+	EnableSpotlight bool
+	// The URL of the Spotlight sidecar. Defaults to
+	// "http://localhost:8969/stream".
+	SpotlightURL string
+	// The URL of a local Relay, for example "http://relay.internal:3000",
+	// that the default transports send events to instead of the host of the
+	// DSN. Events are sent as envelopes to the envelope endpoint of the
+	// project of the DSN, authenticated with the public key of the DSN in
+	// the query string rather than an X-Sentry-Auth header. Defaults to the
+	// SENTRY_RELAY_URL environment variable.
+	RelayURL string
+	// The version of the Sentry protocol announced in the X-Sentry-Auth
+	// header of requests to the store and envelope endpoints. Defaults to
+	// "7". Set it to an older version, like "6", for legacy on-premise
+	// servers that reject the current one.
+	ProtocolVersion string
+	// The endpoint the HTTP transports send error events to, one of the
+	// EventEndpoint modes. Defaults to EventEndpointAuto, which falls back
+	// to the legacy store endpoint for older self-hosted servers that
+	// reject envelopes.
+	EventEndpoint EventEndpoint
+	// The path of a file used to detect crashes across restarts. When set,
+	// the file is written when the SDK starts handling a fatal condition,
+	// with CrashHandler or a SignalHandler, and the next client created
+	// with the same path reports the previous crash and removes the file.
+	CrashMarkerPath string
+	// FilterFrames is called with the frames of every stack trace of events,
+	// exceptions and threads alike, before event processors run.
+	// Frames are ordered from the outermost call to the innermost one, the top
+	// of the stack being last. Use it to remove frames of helper packages,
+	// like the ones of a logging or error wrapping library, that would
+	// otherwise appear at the top of stack traces and affect grouping.
+	// Stack traces left with no frames are removed.
+	FilterFrames func(frames []Frame) []Frame
+	// The maximum number of errors reported as exceptions for a captured
+	// error, counting the error itself, the errors it wraps, recursively, and
+	// the errors joined with errors.Join. Defaults to 10.
+	MaxErrorDepth int
+	// The number of events most recently handed to the transport that are
+	// kept in memory and returned by Client.LastEvents. Defaults to 0, no
+	// events are kept.
+	MaxLastEvents int
 }
 
 // Client is the underlying processor that is used by the main API and Hub
@@ -198,11 +408,27 @@ type ClientOptions struct {
 type Client struct {
 	options         ClientOptions
 	dsn             *Dsn
-	eventProcessors []EventProcessor
-	integrations    []Integration
+	eventProcessors *processorList
+	// defaultProcessors is the number of eventProcessors added by the
+	// default integrations.
+	defaultProcessors int
+	integrations      []Integration
+	// releaseSource records where options.Release came from, one of the
+	// releaseSource* constants, or empty if no release could be detected.
+	releaseSource string
+	// health is nil unless options.EnableBackpressureHandling is set.
+	health *healthMonitor
 	// Transport is read-only. Replacing the transport of an existing client is
 	// not supported, create a new client instead.
 	Transport Transport
+	// closed is set to 1 by Close.
+	closed int32
+	// lastEvents is nil unless options.MaxLastEvents is positive.
+	lastEvents *eventRing
+	// drops counts the events dropped by the client, see Diagnose.
+	drops *dropCounts
+	// counts are the other counters of the client, see Diagnose.
+	counts *clientCounts
 }
 
 // NewClient creates and returns an instance of Client configured using
@@ -217,6 +443,12 @@ func NewClient(options ClientOptions) (*Client, error) {
 		return nil, errors.New("TracesSampleRate and TracesSampler are mutually exclusive")
 	}
 
+	if !options.Debug {
+		if v, err := strconv.ParseBool(os.Getenv("SENTRY_DEBUG")); err == nil {
+			options.Debug = v
+		}
+	}
+
 	if options.Debug {
 		debugWriter := options.DebugWriter
 		if debugWriter == nil {
@@ -229,14 +461,44 @@ func NewClient(options ClientOptions) (*Client, error) {
 		options.Dsn = os.Getenv("SENTRY_DSN")
 	}
 
+	if options.RelayURL == "" {
+		options.RelayURL = os.Getenv("SENTRY_RELAY_URL")
+	}
+
+	if !options.EnableSpotlight {
+		if v := os.Getenv("SENTRY_SPOTLIGHT"); v != "" {
+			enabled, err := strconv.ParseBool(v)
+			if err != nil {
+				// Any other value is the URL of the sidecar.
+				enabled = true
+				if options.SpotlightURL == "" {
+					options.SpotlightURL = v
+				}
+			}
+			options.EnableSpotlight = enabled
+		}
+	}
+
+	releaseSource := releaseSourceOptions
 	if options.Release == "" {
-		options.Release = defaultRelease()
+		options.Release, releaseSource = defaultRelease()
 	}
 
 	if options.Environment == "" {
 		options.Environment = os.Getenv("SENTRY_ENVIRONMENT")
 	}
 
+	if options.TracesSampleRate == 0.0 && options.TracesSampler == nil {
+		if v := os.Getenv("SENTRY_TRACES_SAMPLE_RATE"); v != "" {
+			rate, err := strconv.ParseFloat(v, 64)
+			if err != nil || rate < 0.0 || rate > 1.0 {
+				Logger.Printf("Ignoring invalid SENTRY_TRACES_SAMPLE_RATE %q: must be a number in the range [0.0, 1.0]", v)
+			} else {
+				options.TracesSampleRate = rate
+			}
+		}
+	}
+
 	// SENTRYGODEBUG is a comma-separated list of key=value pairs (similar
 	// to GODEBUG). It is not a supported feature: recognized debug options
 	// may change any time.
@@ -270,12 +532,22 @@ func NewClient(options ClientOptions) (*Client, error) {
 	}
 
 	client := Client{
-		options: options,
-		dsn:     dsn,
+		options:       options,
+		dsn:           dsn,
+		releaseSource: releaseSource,
+		lastEvents:    newEventRing(options.MaxLastEvents),
+
+		eventProcessors: &processorList{},
+		drops:           &dropCounts{},
+		counts:          &clientCounts{},
 	}
 
 	client.setupTransport()
+	if options.EnableBackpressureHandling {
+		client.health = newHealthMonitor(client.Transport, clockFor(&client))
+	}
 	client.setupIntegrations()
+	client.reportPreviousCrash()
 
 	return &client, nil
 }
@@ -300,6 +572,10 @@ func (client *Client) setupTransport() {
 		}
 	}
 
+	if opts.EnableSpotlight {
+		transport = newSpotlightTransport(transport, opts.SpotlightURL)
+	}
+
 	transport.Configure(opts)
 	client.Transport = transport
 }
@@ -310,6 +586,7 @@ func (client *Client) setupIntegrations() {
 		new(environmentIntegration),
 		new(modulesIntegration),
 		new(ignoreErrorsIntegration),
+		new(dedupeIntegration),
 	}
 
 	if client.options.Integrations != nil {
@@ -317,25 +594,44 @@ func (client *Client) setupIntegrations() {
 	}
 
 	for _, integration := range integrations {
+		if integration == nil {
+			continue
+		}
 		if client.integrationAlreadyInstalled(integration.Name()) {
 			Logger.Printf("Integration %s is already installed\n", integration.Name())
 			continue
 		}
 		client.integrations = append(client.integrations, integration)
+		processors := client.eventProcessors.len()
 		integration.SetupOnce(client)
+		if isDefaultIntegration(integration) {
+			client.defaultProcessors += client.eventProcessors.len() - processors
+		}
 		Logger.Printf("Integration installed: %s\n", integration.Name())
 	}
 }
 
-// AddEventProcessor adds an event processor to the client. It must not be
-// called from concurrent goroutines. Most users will prefer to use
-// ClientOptions.BeforeSend or Scope.AddEventProcessor instead.
+// isDefaultIntegration reports whether integration is one of the integrations
+// installed by default.
+func isDefaultIntegration(integration Integration) bool {
+	switch integration.(type) {
+	case *contextifyFramesIntegration, *environmentIntegration, *modulesIntegration,
+		*ignoreErrorsIntegration, *dedupeIntegration:
+		return true
+	}
+	return false
+}
+
+// AddEventProcessor adds an event processor to the client. It is safe for
+// concurrent use; events already being processed are not affected. Most users
+// will prefer to use ClientOptions.BeforeSend or Scope.AddEventProcessor
+// instead.
 //
 // Note that typical programs have only a single client created by Init and the
 // client is shared among multiple hubs, one per goroutine, such that adding an
 // event processor to the client affects all hubs that share the client.
 func (client *Client) AddEventProcessor(processor EventProcessor) {
-	client.eventProcessors = append(client.eventProcessors, processor)
+	client.eventProcessors.add(processor)
 }
 
 // Options return ClientOptions for the current Client.
@@ -345,14 +641,33 @@ func (client Client) Options() ClientOptions {
 
 // CaptureMessage captures an arbitrary message.
 func (client *Client) CaptureMessage(message string, hint *EventHint, scope EventModifier) *EventID {
+	if client.dropsError(scope) {
+		return nil
+	}
 	event := client.eventFromMessage(message, LevelInfo)
-	return client.CaptureEvent(event, hint, scope)
+	return client.processEvent(event, hint, scope, true)
 }
 
 // CaptureException captures an error.
 func (client *Client) CaptureException(exception error, hint *EventHint, scope EventModifier) *EventID {
+	if client.dropsError(scope) {
+		return nil
+	}
+	return client.captureException(exception, hint, scope)
+}
+
+// captureException captures an error that was already sampled by dropsError.
+func (client *Client) captureException(exception error, hint *EventHint, scope EventModifier) *EventID {
+	if exception == nil {
+		// Both Client.CaptureException and Hub.CaptureException get here.
+		exception = usageError{errors.New("CaptureException called with nil error")}
+	}
 	event := client.eventFromException(exception, LevelError)
-	return client.CaptureEvent(event, hint, scope)
+	client.attachThreads(event, false)
+	mechanism := &Mechanism{Type: "generic"}
+	mechanism.SetHandled(true)
+	setMechanism(event, hint, mechanism)
+	return client.processEvent(event, hint, scope, true)
 }
 
 // CaptureEvent captures an event on the currently active client if any.
@@ -361,7 +676,80 @@ func (client *Client) CaptureException(exception error, hint *EventHint, scope E
 // the utility methods like CaptureException. The return value is the
 // event ID. In case Sentry is disabled or event was dropped, the return value will be nil.
 func (client *Client) CaptureEvent(event *Event, hint *EventHint, scope EventModifier) *EventID {
-	return client.processEvent(event, hint, scope)
+	return client.processEvent(event, hint, scope, false)
+}
+
+// disabled reports whether the client drops all events, which is the case when
+// it was created with an empty DSN and no custom Transport.
+func (client *Client) disabled() bool {
+	_, ok := client.Transport.(*noopTransport)
+	return ok
+}
+
+// discards reports whether the events captured with scope can be dropped
+// before they are even created: the client is closed, in which case the drop
+// is counted, or it is disabled and nothing observes its events. Capture paths
+// check it first so that they allocate nothing when the SDK is disabled.
+func (client *Client) discards(scope EventModifier) bool {
+	if atomic.LoadInt32(&client.closed) != 0 {
+		Logger.Println("Event dropped due to client being closed.")
+		atomic.AddUint64(&client.counts.captured, 1)
+		client.drops.add(DropClientClosed)
+		client.logUncreated("dropped: client closed")
+		return true
+	}
+	return client.unobserved(scope)
+}
+
+// logUncreated logs the trail of an event dropped before it is created, keyed
+// by the ID it would have had. The ID is only generated in debug mode, so that
+// dropped captures allocate nothing otherwise.
+func (client *Client) logUncreated(step string) {
+	if trail := newEventTrail(client.options); trail != nil {
+		trail.add(step)
+		trail.log(idGeneratorFor(client).EventID())
+	}
+}
+
+// unobserved reports whether the client is disabled and nothing observes its
+// events, neither BeforeSend, EventSampler, event processors other than the
+// ones of the default integrations, nor Client.LastEvents.
+func (client *Client) unobserved(scope EventModifier) bool {
+	if !client.disabled() ||
+		client.options.BeforeSend != nil ||
+		client.options.EventSampler != nil ||
+		client.lastEvents != nil ||
+		client.eventProcessors.len() > client.defaultProcessors ||
+		globalEventProcessors.len() > 0 {
+		return false
+	}
+	switch scope := scope.(type) {
+	case nil:
+		return true
+	case *Scope:
+		scope.mu.RLock()
+		defer scope.mu.RUnlock()
+		return len(scope.eventProcessors) == 0
+	}
+	return false
+}
+
+// dropsError reports whether an error event captured with scope is dropped
+// before it is created, because the client discards it or because it is
+// sampled out by SampleRate. Error events that are not dropped must be
+// processed as already sampled, so that they are not sampled twice.
+func (client *Client) dropsError(scope EventModifier) bool {
+	if client.discards(scope) {
+		return true
+	}
+	if client.options.EventSampler == nil && !sample(sampleRate(client.options)) {
+		Logger.Println("Event dropped due to SampleRate hit.")
+		atomic.AddUint64(&client.counts.captured, 1)
+		client.drops.add(DropSampleRate)
+		client.logUncreated("dropped: sampled out by SampleRate")
+		return true
+	}
+	return false
 }
 
 // Recover captures a panic.
@@ -411,10 +799,62 @@ func (client *Client) RecoverWithContext(
 		event = client.eventFromMessage(err, LevelFatal)
 	default:
 		event = client.eventFromMessage(fmt.Sprintf("%#v", err), LevelFatal)
+		event.Extra["panic_value"] = panicValue(err)
 	}
+	client.attachThreads(event, true)
+	setMechanism(event, hint, panicMechanism(ctx))
 	return client.CaptureEvent(event, hint, scope)
 }
 
+// panicValue describes a panic value that is neither an error nor a string,
+// to be sent as an extra. The value is kept as is when it can be represented
+// as JSON, so that its structure is preserved, and formatted with %#v
+// otherwise.
+func panicValue(v interface{}) map[string]interface{} {
+	value := v
+	if _, err := json.Marshal(v); err != nil {
+		value = fmt.Sprintf("%#v", v)
+	}
+	return map[string]interface{}{
+		"type":  fmt.Sprintf("%T", v),
+		"value": value,
+	}
+}
+
+// panicMechanism returns the mechanism of a panic recovered with ctx. If ctx
+// carries the HTTP request being served, the request method and URL are added
+// to the mechanism data.
+func panicMechanism(ctx context.Context) *Mechanism {
+	mechanism := &Mechanism{Type: "panic"}
+	mechanism.SetHandled(false)
+	if ctx == nil {
+		return mechanism
+	}
+	if r, ok := ctx.Value(RequestContextKey).(*http.Request); ok && r != nil {
+		mechanism.Data = map[string]interface{}{
+			"http.method": r.Method,
+		}
+		if r.URL != nil {
+			mechanism.Data["http.url"] = r.URL.Path
+		}
+	}
+	return mechanism
+}
+
+// setMechanism sets the mechanism of the most recent exception of event,
+// unless it already has one. A mechanism in hint takes precedence over m.
+func setMechanism(event *Event, hint *EventHint, m *Mechanism) {
+	if len(event.Exception) == 0 {
+		return
+	}
+	if hint != nil && hint.Mechanism != nil {
+		m = hint.Mechanism
+	}
+	if last := &event.Exception[len(event.Exception)-1]; last.Mechanism == nil {
+		last.Mechanism = m
+	}
+}
+
 // Flush waits until the underlying Transport sends any buffered events to the
 // Sentry server, blocking for at most the given timeout. It returns false if
 // the timeout was reached. In that case, some events may not have been sent.
@@ -427,7 +867,63 @@ func (client *Client) RecoverWithContext(
 // the network synchronously, configure it to use the HTTPSyncTransport in the
 // call to Init.
 func (client *Client) Flush(timeout time.Duration) bool {
-	return client.Transport.Flush(timeout)
+	start := time.Now()
+	ok := client.Transport.Flush(timeout)
+	client.counts.flushed(start, ok)
+	return ok
+}
+
+// FlushWithContext is like Flush, but blocks until ctx is done rather than for
+// a timeout. Transports that do not implement ContextTransport are flushed
+// with a timeout until the deadline of ctx, or of 30 seconds if it has none.
+func (client *Client) FlushWithContext(ctx context.Context) bool {
+	start := time.Now()
+	ok := flushWithContext(ctx, client.Transport)
+	client.counts.flushed(start, ok)
+	return ok
+}
+
+// capturingGoroutines holds the IDs of the goroutines processing an event,
+// across all clients.
+var capturingGoroutines sync.Map
+
+// transportCloser is implemented by transports that hold resources, like
+// HTTPTransport and its worker goroutine.
+type transportCloser interface {
+	Close()
+}
+
+// stopper is implemented by integrations that run in the background, like
+// Watchdog and SignalHandler.
+type stopper interface {
+	Stop()
+}
+
+// Close shuts the client down, for graceful shutdown of servers and in tests.
+// It waits until buffered events are sent, blocking for at most the given
+// timeout like Flush, then stops the integrations that run in the background
+// and the goroutines of the transport. Events captured after Close are
+// dropped. It returns false if the timeout was reached.
+//
+// It is safe to call Close multiple times: once the client is closed, Close
+// returns true immediately.
+func (client *Client) Close(timeout time.Duration) bool {
+	if atomic.LoadInt32(&client.closed) == 1 {
+		return true
+	}
+	ok := client.Flush(timeout)
+	if !atomic.CompareAndSwapInt32(&client.closed, 0, 1) {
+		return ok
+	}
+	for _, integration := range client.integrations {
+		if s, isStopper := integration.(stopper); isStopper {
+			s.Stop()
+		}
+	}
+	if c, isCloser := client.Transport.(transportCloser); isCloser {
+		c.Close()
+	}
+	return ok
 }
 
 func (client *Client) eventFromMessage(message string, level Level) *Event {
@@ -459,27 +955,26 @@ func (client *Client) eventFromException(exception error, level Level) *Event {
 	event := NewEvent()
 	event.Level = level
 
-	for i := 0; i < maxErrorDepth && err != nil; i++ {
+	maxDepth := client.Options().MaxErrorDepth
+	if maxDepth <= 0 {
+		maxDepth = maxErrorDepth
+	}
+	walkErrors(err, maxDepth, func(err error) {
 		event.Exception = append(event.Exception, Exception{
 			Value:      err.Error(),
-			Type:       reflect.TypeOf(err).String(),
+			Type:       errorType(err),
 			Stacktrace: ExtractStacktrace(err),
 		})
-		switch previous := err.(type) {
-		case interface{ Unwrap() error }:
-			err = previous.Unwrap()
-		case interface{ Cause() error }:
-			err = previous.Cause()
-		default:
-			err = nil
-		}
-	}
+	})
 
 	// Add a trace of the current stack to the most recent error in a chain if
-	// it doesn't have a stack trace yet.
+	// no error in the chain carries a stack trace.
 	// We only add to the most recent error to avoid duplication and because the
 	// current stack is most likely unrelated to errors deeper in the chain.
-	if event.Exception[0].Stacktrace == nil {
+	// When an error in the chain records the stack where it was created or
+	// wrapped, that stack tells where the error originated, while the current
+	// stack would only tell where it was captured.
+	if !hasStacktrace(event.Exception) {
 		event.Exception[0].Stacktrace = NewStacktrace()
 	}
 
@@ -489,6 +984,108 @@ func (client *Client) eventFromException(exception error, level Level) *Event {
 	return event
 }
 
+// walkErrors calls f for err and for the errors it wraps, outermost first, for
+// at most maxDepth errors in total. Errors wrapping multiple errors, like the
+// ones returned by errors.Join, are followed into each of their children in
+// turn. An error met again while walking is not followed a second time, which
+// stops self-referential chains early.
+func walkErrors(err error, maxDepth int, f func(err error)) {
+	n := 0
+	seen := make(map[error]bool)
+	var walk func(err error)
+	walk = func(err error) {
+		for n < maxDepth && err != nil {
+			// Chains can only refer back to an error through a pointer.
+			// Pointers are also always safe to use as map keys.
+			if reflect.TypeOf(err).Kind() == reflect.Ptr {
+				if seen[err] {
+					return
+				}
+				seen[err] = true
+			}
+			n++
+			f(err)
+			switch previous := err.(type) {
+			case interface{ Unwrap() []error }:
+				for _, child := range previous.Unwrap() {
+					walk(child)
+				}
+				return
+			case interface{ Unwrap() error }:
+				err = previous.Unwrap()
+			case interface{ Cause() error }:
+				err = previous.Cause()
+			default:
+				err = nil
+			}
+		}
+	}
+	walk(err)
+}
+
+// errorType returns the name of the dynamic type of err, qualified with the
+// import path of its package, for example "*github.com/pkg/errors.fundamental".
+// Unnamed types are returned as formatted by reflect.Type.String.
+func errorType(err error) string {
+	t := reflect.TypeOf(err)
+	var stars string
+	for t.Kind() == reflect.Ptr {
+		stars += "*"
+		t = t.Elem()
+	}
+	if t.Name() == "" || t.PkgPath() == "" {
+		return reflect.TypeOf(err).String()
+	}
+	return stars + t.PkgPath() + "." + t.Name()
+}
+
+// attachThreads populates event.Threads with goroutines, as configured by the
+// AttachThreads and AttachAllThreads options. The current goroutine is marked
+// as crashed if crashed is true.
+func (client *Client) attachThreads(event *Event, crashed bool) {
+	options := client.Options()
+	if !options.AttachThreads && !options.AttachAllThreads {
+		return
+	}
+	event.Threads = goroutineThreads(crashed, options.AttachAllThreads)
+	// Link the most recent exception to the thread where it was captured.
+	if len(event.Exception) > 0 {
+		event.Exception[len(event.Exception)-1].ThreadID = event.Threads[0].ID
+	}
+}
+
+// filterFrames applies the FilterFrames option to the stack traces of the
+// exceptions and threads of event, removing stack traces left with no frames.
+func (client *Client) filterFrames(event *Event) {
+	filter := client.Options().FilterFrames
+	apply := func(st *Stacktrace) *Stacktrace {
+		if st == nil {
+			return nil
+		}
+		st.Frames = filter(st.Frames)
+		if len(st.Frames) == 0 {
+			return nil
+		}
+		return st
+	}
+	for i := range event.Exception {
+		event.Exception[i].Stacktrace = apply(event.Exception[i].Stacktrace)
+	}
+	for i := range event.Threads {
+		event.Threads[i].Stacktrace = apply(event.Threads[i].Stacktrace)
+	}
+}
+
+// hasStacktrace returns true if any of the exceptions has a stack trace.
+func hasStacktrace(exceptions []Exception) bool {
+	for _, exception := range exceptions {
+		if exception.Stacktrace != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // reverse reverses the slice a in place.
 func reverse(a []Exception) {
 	for i := len(a)/2 - 1; i >= 0; i-- {
@@ -497,71 +1094,196 @@ func reverse(a []Exception) {
 	}
 }
 
-func (client *Client) processEvent(event *Event, hint *EventHint, scope EventModifier) *EventID {
+// processEvent prepares the event and hands it to the transport. Error events
+// are sampled by SampleRate unless sampled is set.
+func (client *Client) processEvent(event *Event, hint *EventHint, scope EventModifier, sampled bool) *EventID {
+	// Event processors and BeforeSend always get a non-nil hint.
+	if hint == nil {
+		hint = &EventHint{}
+	}
+
 	if event == nil {
 		err := usageError{fmt.Errorf("%s called with nil event", callerFunctionName())}
 		return client.CaptureException(err, hint, scope)
 	}
 
+	atomic.AddUint64(&client.counts.captured, 1)
 	options := client.Options()
+	// The ID is assigned first, so that the trail of the event is keyed by
+	// it whatever the decisions taken.
+	client.setEventID(event)
+	trail := newEventTrail(options)
+	if atomic.LoadInt32(&client.closed) != 0 {
+		Logger.Println("Event dropped due to client being closed.")
+		client.drops.add(DropClientClosed)
+		trail.add("dropped: client closed")
+		trail.log(event.EventID)
+		return nil
+	}
 
-	// The default error event sample rate for all SDKs is 1.0 (send all).
-	//
-	// In Go, the zero value (default) for float64 is 0.0, which means that
-	// constructing a client with NewClient(ClientOptions{}), or, equivalently,
-	// initializing the SDK with Init(ClientOptions{}) without an explicit
-	// SampleRate would drop all events.
-	//
-	// To retain the desired default behavior, we exceptionally flip SampleRate
-	// from 0.0 to 1.0 here. Setting the sample rate to 0.0 is not very useful
-	// anyway, and the same end result can be achieved in many other ways like
-	// not initializing the SDK, setting the DSN to the empty string or using an
-	// event processor that always returns nil.
-	//
-	// An alternative API could be such that default options don't need to be
-	// the same as Go's zero values, for example using the Functional Options
-	// pattern. That would either require a breaking change if we want to reuse
-	// the obvious NewClient name, or a new function as an alternative
-	// constructor.
-	if options.SampleRate == 0.0 {
-		options.SampleRate = 1.0
+	// Events captured while an event is processed on the same goroutine, by
+	// BeforeSend, an event processor or a synchronous transport for example,
+	// are dropped, so that an error within the SDK's own capture path cannot
+	// recurse infinitely.
+	if id := currentGoroutineID(); id != "" {
+		if _, busy := capturingGoroutines.LoadOrStore(id, struct{}{}); busy {
+			Logger.Println("Event dropped: captured while processing another event on the same goroutine.")
+			client.drops.add(DropRecursion)
+			trail.add("dropped: captured while processing another event")
+			trail.log(event.EventID)
+			return nil
+		}
+		defer capturingGoroutines.Delete(id)
 	}
 
 	// Transactions are sampled by options.TracesSampleRate or
-	// options.TracesSampler when they are started. All other events
-	// (errors, messages) are sampled here.
-	if event.Type != transactionType && !sample(options.SampleRate) {
+	// options.TracesSampler when they are started. Check-ins are never
+	// sampled. All other events (errors, messages) are sampled here, unless
+	// the caller already sampled them.
+	isError := event.Type != transactionType && event.Type != checkInType
+	switch {
+	case !isError || options.EventSampler != nil:
+	case !sampled && !sample(sampleRate(options)):
 		Logger.Println("Event dropped due to SampleRate hit.")
+		client.drops.add(DropSampleRate)
+		trail.add("dropped: sampled out by SampleRate")
+		trail.log(event.EventID)
 		return nil
+	default:
+		trail.add("sampled in by SampleRate")
 	}
 
-	if event = client.prepareEvent(event, hint, scope); event == nil {
+	prepared := client.safelyPrepareEvent(event, hint, scope)
+	if prepared == nil {
+		client.drops.add(DropEventProcessor)
+		trail.add("dropped by an event processor")
+		trail.log(event.EventID)
+		return nil
+	}
+	event = prepared
+	trail.add("kept by event processors")
+
+	if isError && options.EventSampler != nil && !sample(options.EventSampler(event, hint)) {
+		Logger.Println("Event dropped due to EventSampler.")
+		client.drops.add(DropEventSampler)
+		trail.add("dropped: sampled out by EventSampler")
+		trail.log(event.EventID)
 		return nil
 	}
 
-	// As per spec, transactions do not go through BeforeSend.
-	if event.Type != transactionType && options.BeforeSend != nil {
-		if hint == nil {
-			hint = &EventHint{}
-		}
+	if isError && options.ErrorSampler != nil && !options.ErrorSampler.Sample(event) {
+		Logger.Println("Event dropped due to ErrorSampler.")
+		client.drops.add(DropEventSampler)
+		trail.add("dropped: sampled out by ErrorSampler")
+		trail.log(event.EventID)
+		return nil
+	}
+
+	// As per spec, transactions and check-ins do not go through BeforeSend.
+	if isError && options.BeforeSend != nil {
+		id := event.EventID
 		if event = options.BeforeSend(event, hint); event == nil {
 			Logger.Println("Event dropped due to BeforeSend callback.")
+			client.drops.add(DropBeforeSend)
+			trail.add("dropped by BeforeSend")
+			trail.log(id)
 			return nil
 		}
+		trail.add("kept by BeforeSend")
 	}
 
-	client.Transport.SendEvent(event)
+	if client.lastEvents != nil {
+		client.lastEvents.add(event)
+	}
+	trail.add(fmt.Sprintf("handed to %T", client.Transport))
+	trail.log(event.EventID)
+	if hint != nil && hint.Context != nil {
+		sendEventWithContext(hint.Context, client.Transport, event)
+	} else {
+		client.Transport.SendEvent(event)
+	}
+
+	// Events sent by a disabled client are discarded and have no meaningful
+	// ID. Returning nil lets callers tell apart events that were not sent.
+	if client.disabled() {
+		return nil
+	}
 
 	return &event.EventID
 }
 
-func (client *Client) prepareEvent(event *Event, hint *EventHint, scope EventModifier) *Event {
+// sampleRate returns the SampleRate of options, with 0.0 meaning 1.0.
+//
+// The default error event sample rate for all SDKs is 1.0 (send all).
+//
+// In Go, the zero value (default) for float64 is 0.0, which means that
+// constructing a client with NewClient(ClientOptions{}), or, equivalently,
+// initializing the SDK with Init(ClientOptions{}) without an explicit
+// SampleRate would drop all events.
+//
+// To retain the desired default behavior, we exceptionally flip SampleRate
+// from 0.0 to 1.0 here. Setting the sample rate to 0.0 is not very useful
+// anyway, and the same end result can be achieved in many other ways like
+// not initializing the SDK, setting the DSN to the empty string or using an
+// event processor that always returns nil.
+//
+// An alternative API could be such that default options don't need to be
+// the same as Go's zero values, for example using the Functional Options
+// pattern. That would either require a breaking change if we want to reuse
+// the obvious NewClient name, or a new function as an alternative
+// constructor.
+func sampleRate(options ClientOptions) float64 {
+	if options.SampleRate == 0.0 {
+		return 1.0
+	}
+	return options.SampleRate
+}
+
+// safelyPrepareEvent is like prepareEvent, but if the preparation of the event
+// panics, for example in an event processor, the event is reduced to
+// primitive values and returned, so that it is still delivered. Personally
+// identifiable information is removed from the reduced event as usual, since
+// the preparation may have stopped before.
+func (client *Client) safelyPrepareEvent(event *Event, hint *EventHint, scope EventModifier) (prepared *Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			Logger.Printf("Event preparation panicked, sending a reduced event: %v", r)
+			reduceEvent(event, fmt.Sprintf("panic while preparing the event: %v", r))
+			client.sanitizeRequest(event)
+			client.setUserIPAddress(event)
+			prepared = event
+		}
+	}()
+	return client.prepareEvent(event, hint, scope)
+}
+
+// validEventID returns id in the form required by the Sentry protocol, or a
+// new ID if id is not a UUID.
+func (client *Client) validEventID(id EventID) EventID {
+	if valid, err := ParseEventID(string(id)); err == nil {
+		return valid
+	}
+	valid := idGeneratorFor(client).EventID()
+	Logger.Printf("Invalid event ID %q replaced with %s", id, valid)
+	return valid
+}
+
+// setEventID gives event a new ID, or a valid one if its ID is not a UUID.
+func (client *Client) setEventID(event *Event) {
 	if event.EventID == "" {
-		event.EventID = EventID(uuid())
+		event.EventID = idGeneratorFor(client).EventID()
+	} else if !event.EventID.Valid() {
+		event.EventID = client.validEventID(event.EventID)
+	}
+}
+
+func (client *Client) prepareEvent(event *Event, hint *EventHint, scope EventModifier) *Event {
+	if event.CheckIn != nil && event.CheckIn.ID != "" && !event.CheckIn.ID.Valid() {
+		event.CheckIn.ID = client.validEventID(event.CheckIn.ID)
 	}
 
 	if event.Timestamp.IsZero() {
-		event.Timestamp = time.Now()
+		event.Timestamp = clockFor(client).Now()
 	}
 
 	if event.Level == "" {
@@ -599,6 +1321,12 @@ func (client *Client) prepareEvent(event *Event, hint *EventHint, scope EventMod
 		}},
 	}
 
+	if client.Options().FilterFrames != nil {
+		client.filterFrames(event)
+	}
+
+	normalizeMessage(event)
+
 	if scope != nil {
 		event = scope.ApplyToEvent(event, hint)
 		if event == nil {
@@ -606,7 +1334,10 @@ func (client *Client) prepareEvent(event *Event, hint *EventHint, scope EventMod
 		}
 	}
 
-	for _, processor := range client.eventProcessors {
+	client.sanitizeRequest(event)
+	client.setUserIPAddress(event)
+
+	for _, processor := range client.eventProcessors.snapshot() {
 		id := event.EventID
 		event = processor(event, hint)
 		if event == nil {
@@ -615,7 +1346,7 @@ func (client *Client) prepareEvent(event *Event, hint *EventHint, scope EventMod
 		}
 	}
 
-	for _, processor := range globalEventProcessors {
+	for _, processor := range globalEventProcessors.snapshot() {
 		id := event.EventID
 		event = processor(event, hint)
 		if event == nil {
@@ -627,6 +1358,38 @@ func (client *Client) prepareEvent(event *Event, hint *EventHint, scope EventMod
 	return event
 }
 
+// setUserIPAddress resolves AutoIPAddress in the user of event and, with
+// SendDefaultPII, sets the IP address of a user without one to the IP address
+// of the client of the request of event.
+func (client *Client) setUserIPAddress(event *Event) {
+	ip := event.User.IPAddress
+	if ip != "" && ip != AutoIPAddress {
+		return
+	}
+	if client.Options().SendDefaultPII && event.Request != nil {
+		event.User.IPAddress = remoteIPAddress(event.Request)
+	} else if ip == AutoIPAddress {
+		event.User.IPAddress = ""
+	}
+}
+
+// remoteIPAddress returns the IP address of the client that sent r, as
+// reported by proxies in the X-Forwarded-For or X-Real-Ip headers, or the
+// remote address of the connection.
+func remoteIPAddress(r *Request) string {
+	if forwarded := r.Headers["X-Forwarded-For"]; forwarded != "" {
+		// The first address is the one of the original client.
+		if i := strings.IndexByte(forwarded, ','); i >= 0 {
+			forwarded = forwarded[:i]
+		}
+		return strings.TrimSpace(forwarded)
+	}
+	if ip := r.Headers["X-Real-Ip"]; ip != "" {
+		return ip
+	}
+	return r.Env["REMOTE_ADDR"]
+}
+
 func (client Client) listIntegrations() []string {
 	integrations := make([]string, 0, len(client.integrations))
 	for _, integration := range client.integrations {
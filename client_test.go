@@ -1,10 +1,16 @@
 package sentry
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -15,6 +21,112 @@ import (
 	pkgErrors "github.com/pkg/errors"
 )
 
+// setenv sets an environment variable for the duration of a test. It returns a
+// function that restores the previous value.
+func setenv(key, value string) (restore func()) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestNewClientEnvironmentVariables(t *testing.T) {
+	defer setenv("SENTRY_DSN", "https://key@sentry.io/42")()
+	defer setenv("SENTRY_ENVIRONMENT", "staging")()
+	defer setenv("SENTRY_RELEASE", "v1.2.3")()
+	defer setenv("SENTRY_DEBUG", "true")()
+	defer setenv("SENTRY_TRACES_SAMPLE_RATE", "0.25")()
+	defer Logger.SetOutput(ioutil.Discard)
+
+	var debug bytes.Buffer
+	client, err := NewClient(ClientOptions{Transport: &TransportMock{}, DebugWriter: &debug})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := client.Options()
+	assertEqual(t, options.Dsn, "https://key@sentry.io/42")
+	assertEqual(t, options.Environment, "staging")
+	assertEqual(t, options.Release, "v1.2.3")
+	assertEqual(t, options.Debug, true)
+	assertEqual(t, options.TracesSampleRate, 0.25)
+	if debug.Len() == 0 {
+		t.Error("SENTRY_DEBUG should enable debug output")
+	}
+
+	// Explicit options take precedence over environment variables.
+	client, err = NewClient(ClientOptions{
+		Transport:   &TransportMock{},
+		Dsn:         "https://other@sentry.io/1",
+		Environment: "production",
+		Release:     "v2.0.0",
+		TracesSampler: TracesSamplerFunc(func(ctx SamplingContext) Sampled {
+			return SampledFalse
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options = client.Options()
+	assertEqual(t, options.Dsn, "https://other@sentry.io/1")
+	assertEqual(t, options.Environment, "production")
+	assertEqual(t, options.Release, "v2.0.0")
+	assertEqual(t, options.TracesSampleRate, 0.0)
+}
+
+func TestNewClientInvalidTracesSampleRateEnvironmentVariable(t *testing.T) {
+	for _, v := range []string{"abc", "-0.5", "2"} {
+		restore := setenv("SENTRY_TRACES_SAMPLE_RATE", v)
+		client, err := NewClient(ClientOptions{Transport: &TransportMock{}})
+		restore()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, client.Options().TracesSampleRate, 0.0, v)
+	}
+}
+
+func TestDisabledClient(t *testing.T) {
+	defer setenv("SENTRY_DSN", "")()
+
+	var beforeSendCalls int
+	client, err := NewClient(ClientOptions{
+		BeforeSend: func(event *Event, hint *EventHint) *Event {
+			beforeSendCalls++
+			return event
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.Transport.(*noopTransport); !ok {
+		t.Fatalf("got transport %T, want *noopTransport", client.Transport)
+	}
+	hub := NewHub(client, NewScope())
+
+	if id := hub.CaptureMessage("message"); id != nil {
+		t.Errorf("CaptureMessage returned %v, want nil", *id)
+	}
+	if id := hub.CaptureException(errors.New("error")); id != nil {
+		t.Errorf("CaptureException returned %v, want nil", *id)
+	}
+	if id := hub.CaptureEvent(&Event{Message: "event"}); id != nil {
+		t.Errorf("CaptureEvent returned %v, want nil", *id)
+	}
+	if id := hub.Recover("panic"); id != nil {
+		t.Errorf("Recover returned %v, want nil", *id)
+	}
+	assertEqual(t, hub.LastEventID(), EventID(""))
+	assertEqual(t, beforeSendCalls, 4, "events should still be processed")
+	if !hub.Flush(time.Second) {
+		t.Error("Flush should succeed")
+	}
+}
+
 func TestNewClientAllowsEmptyDSN(t *testing.T) {
 	transport := &TransportMock{}
 	client, err := NewClient(ClientOptions{
@@ -71,7 +183,7 @@ func TestCaptureMessageEmptyString(t *testing.T) {
 	want := &Event{
 		Exception: []Exception{
 			{
-				Type:       "sentry.usageError",
+				Type:       "github.com/getsentry/sentry-go.usageError",
 				Value:      "CaptureMessage called with empty message",
 				Stacktrace: &Stacktrace{Frames: []Frame{}},
 			},
@@ -114,6 +226,21 @@ func (e wrappedError) Unwrap() error {
 	return e.original
 }
 
+// joinedError is like the errors returned by errors.Join.
+type joinedError []error
+
+func (e joinedError) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+func (e joinedError) Unwrap() []error {
+	return e
+}
+
 type captureExceptionTestGroup struct {
 	name  string
 	tests []captureExceptionTest
@@ -132,9 +259,10 @@ func TestCaptureException(t *testing.T) {
 			err:  nil,
 			want: []Exception{
 				{
-					Type:       "sentry.usageError",
+					Type:       "github.com/getsentry/sentry-go.usageError",
 					Value:      "CaptureException called with nil error",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  newMechanism("generic", true),
 				},
 			},
 		},
@@ -146,6 +274,7 @@ func TestCaptureException(t *testing.T) {
 					Type:       "*errors.errorString",
 					Value:      "custom error",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  newMechanism("generic", true),
 				},
 			},
 		},
@@ -157,16 +286,35 @@ func TestCaptureException(t *testing.T) {
 			err:  pkgErrors.WithStack(&customErr{}),
 			want: []Exception{
 				{
-					Type:  "*sentry.customErr",
+					Type:  "*github.com/getsentry/sentry-go.customErr",
 					Value: "wat",
 					// No Stacktrace, because we can't tell where the error came
 					// from and because we have a stack trace in the most recent
 					// error in the chain.
 				},
 				{
-					Type:       "*errors.withStack",
+					Type:       "*github.com/pkg/errors.withStack",
 					Value:      "wat",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  newMechanism("generic", true),
+				},
+			},
+		},
+		{
+			name: "CauseHasStack",
+			err:  wrappedError{original: pkgErrors.New("original")},
+			want: []Exception{
+				{
+					Type:       "*github.com/pkg/errors.fundamental",
+					Value:      "original",
+					Stacktrace: &Stacktrace{Frames: []Frame{}},
+				},
+				{
+					Type:      "github.com/getsentry/sentry-go.wrappedError",
+					Value:     "wrapped: original",
+					Mechanism: newMechanism("generic", true),
+					// No Stacktrace, because the cause records where the
+					// error originated.
 				},
 			},
 		},
@@ -175,9 +323,10 @@ func TestCaptureException(t *testing.T) {
 			err:  &customErrWithCause{cause: nil},
 			want: []Exception{
 				{
-					Type:       "*sentry.customErrWithCause",
+					Type:       "*github.com/getsentry/sentry-go.customErrWithCause",
 					Value:      "err",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  newMechanism("generic", true),
 				},
 			},
 		},
@@ -186,13 +335,14 @@ func TestCaptureException(t *testing.T) {
 			err:  &customErrWithCause{cause: &customErr{}},
 			want: []Exception{
 				{
-					Type:  "*sentry.customErr",
+					Type:  "*github.com/getsentry/sentry-go.customErr",
 					Value: "wat",
 				},
 				{
-					Type:       "*sentry.customErrWithCause",
+					Type:       "*github.com/getsentry/sentry-go.customErrWithCause",
 					Value:      "err",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  newMechanism("generic", true),
 				},
 			},
 		},
@@ -205,9 +355,34 @@ func TestCaptureException(t *testing.T) {
 					Value: "original",
 				},
 				{
-					Type:       "sentry.wrappedError",
+					Type:       "github.com/getsentry/sentry-go.wrappedError",
 					Value:      "wrapped: original",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  newMechanism("generic", true),
+				},
+			},
+		},
+		{
+			name: "JoinedErrors",
+			err:  joinedError{wrappedError{original: errors.New("first")}, &customErr{}},
+			want: []Exception{
+				{
+					Type:  "*github.com/getsentry/sentry-go.customErr",
+					Value: "wat",
+				},
+				{
+					Type:  "*errors.errorString",
+					Value: "first",
+				},
+				{
+					Type:  "github.com/getsentry/sentry-go.wrappedError",
+					Value: "wrapped: first",
+				},
+				{
+					Type:       "github.com/getsentry/sentry-go.joinedError",
+					Value:      "wrapped: first\nwat",
+					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  newMechanism("generic", true),
 				},
 			},
 		},
@@ -245,7 +420,7 @@ func TestCaptureException(t *testing.T) {
 func TestCaptureEvent(t *testing.T) {
 	client, _, transport := setupClientTest()
 
-	eventID := EventID("0123456789abcdef")
+	eventID := EventID("0123456789abcdef0123456789abcdef")
 	timestamp := time.Now().UTC()
 	serverName := "testServer"
 
@@ -288,6 +463,18 @@ func TestCaptureEvent(t *testing.T) {
 	}
 }
 
+func TestCaptureEventNormalizesEventID(t *testing.T) {
+	client, scope, transport := setupClientTest()
+
+	client.CaptureEvent(&Event{EventID: "01234567-89AB-CDEF-0123-456789ABCDEF"}, nil, scope)
+	assertEqual(t, transport.lastEvent.EventID, EventID("0123456789abcdef0123456789abcdef"))
+
+	client.CaptureEvent(&Event{EventID: "not-an-id"}, nil, scope)
+	if id := transport.lastEvent.EventID; !id.Valid() {
+		t.Errorf("invalid event ID %q was not replaced", id)
+	}
+}
+
 func TestCaptureEventShouldSendEventWithMessage(t *testing.T) {
 	client, scope, transport := setupClientTest()
 	event := NewEvent()
@@ -302,9 +489,10 @@ func TestCaptureEventNil(t *testing.T) {
 	want := &Event{
 		Exception: []Exception{
 			{
-				Type:       "sentry.usageError",
+				Type:       "github.com/getsentry/sentry-go.usageError",
 				Value:      "CaptureEvent called with nil event",
 				Stacktrace: &Stacktrace{Frames: []Frame{}},
+				Mechanism:  newMechanism("generic", true),
 			},
 		},
 	}
@@ -330,6 +518,63 @@ func TestSampleRateCanDropEvent(t *testing.T) {
 	}
 }
 
+func TestEventSampler(t *testing.T) {
+	client, _, transport := setupClientTest()
+	// The sampler overrides SampleRate.
+	client.options.SampleRate = 0.000000000000001
+	client.options.EventSampler = func(event *Event, hint *EventHint) float64 {
+		if event.Level == LevelWarning {
+			return 0
+		}
+		return 1
+	}
+	scope := NewScope()
+
+	client.CaptureMessage("Foo", nil, scope)
+	if transport.lastEvent == nil || transport.lastEvent.Message != "Foo" {
+		t.Fatalf("got event %v, want the message event", transport.lastEvent)
+	}
+
+	// The sampler sees the level set by the scope.
+	scope.SetLevel(LevelWarning)
+	client.CaptureMessage("Bar", nil, scope)
+	assertEqual(t, transport.lastEvent.Message, "Foo", "expected event to be dropped")
+}
+
+func TestUserIPAddress(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "203.0.113.7:1234"
+	forwarded := httptest.NewRequest("GET", "/", nil)
+	forwarded.Header.Set("X-Forwarded-For", "198.51.100.1, 10.0.0.1")
+
+	tests := []struct {
+		name    string
+		pii     bool
+		request *http.Request
+		user    User
+		want    string
+	}{
+		{"Auto", true, r, User{IPAddress: AutoIPAddress}, "203.0.113.7"},
+		{"Unset", true, r, User{ID: "42"}, "203.0.113.7"},
+		{"Forwarded", true, forwarded, User{}, "198.51.100.1"},
+		{"Explicit", true, r, User{IPAddress: "192.0.2.1"}, "192.0.2.1"},
+		{"AutoWithoutPII", false, r, User{IPAddress: AutoIPAddress}, ""},
+		{"UnsetWithoutPII", false, r, User{}, ""},
+		{"AutoWithoutRequest", true, nil, User{IPAddress: AutoIPAddress}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _, transport := setupClientTest()
+			client.options.SendDefaultPII = tt.pii
+			scope := NewScope()
+			scope.SetUser(tt.user)
+			scope.SetRequest(tt.request)
+			client.CaptureMessage("Foo", nil, scope)
+			assertEqual(t, transport.lastEvent.User.IPAddress, tt.want)
+		})
+	}
+}
+
 func TestApplyToScopeCanDropEvent(t *testing.T) {
 	client, scope, transport := setupClientTest()
 	scope.shouldDropEvent = true
@@ -348,6 +593,88 @@ func TestApplyToScopeCanDropEvent(t *testing.T) {
 	}
 }
 
+func TestEventProcessorPanicSendsReducedEvent(t *testing.T) {
+	client, scope, transport := setupClientTest()
+	client.AddEventProcessor(func(event *Event, hint *EventHint) *Event {
+		event.Extra["user"] = panickingValue{}
+		panic("processor failed")
+	})
+
+	client.CaptureMessage("Foo", nil, scope)
+
+	event := transport.lastEvent
+	if event == nil {
+		t.Fatal("missing event")
+	}
+	assertEqual(t, event.Message, "Foo")
+	assertEqual(t, event.Extra["user"], "%!v(PANIC=String method: String)")
+	assertEqual(t, event.Tags[serializationErrorTag], "panic while preparing the event: processor failed")
+}
+
+func TestEventProcessorPanicRemovesPII(t *testing.T) {
+	client, _, transport := setupClientTest()
+	// Scope processors run before the request is sanitized.
+	scope := NewScope()
+	scope.AddEventProcessor(func(event *Event, hint *EventHint) *Event {
+		panic("processor failed")
+	})
+	event := NewEvent()
+	event.User = User{ID: "1", IPAddress: AutoIPAddress}
+	event.Request = &Request{
+		URL:     "http://example.com/",
+		Method:  "GET",
+		Cookies: "session=secret",
+		Headers: map[string]string{
+			"Authorization":   "Bearer secret",
+			"Cookie":          "session=secret",
+			"X-Forwarded-For": "203.0.113.1",
+			"Accept":          "*/*",
+		},
+	}
+
+	client.CaptureEvent(event, nil, scope)
+
+	event = transport.lastEvent
+	if event == nil {
+		t.Fatal("missing event")
+	}
+	assertEqual(t, event.Request.Cookies, "")
+	assertEqual(t, event.Request.Headers, map[string]string{"Accept": "*/*"})
+	assertEqual(t, event.User.IPAddress, "")
+}
+
+func TestRecursiveCaptureIsDropped(t *testing.T) {
+	client, scope, transport := setupClientTest()
+	hub := NewHub(client, NewScope())
+	client.options.BeforeSend = func(event *Event, hint *EventHint) *Event {
+		if event.Message == "Foo" {
+			// Captures on the same goroutine are dropped, whatever the hub.
+			assertEqual(t, hub.CaptureMessage("nested"), (*EventID)(nil))
+			assertEqual(t, hub.Clone().CaptureException(errors.New("nested")), (*EventID)(nil))
+			// Other goroutines capture as usual.
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				hub.CaptureMessage("concurrent")
+			}()
+			<-done
+		}
+		return event
+	}
+
+	client.CaptureMessage("Foo", nil, scope)
+
+	var messages []string
+	for _, event := range transport.Events() {
+		messages = append(messages, event.Message)
+	}
+	assertEqual(t, messages, []string{"concurrent", "Foo"})
+
+	// Captures on the same goroutine are allowed again after processing.
+	client.CaptureMessage("Bar", nil, scope)
+	assertEqual(t, len(transport.Events()), 3)
+}
+
 func TestBeforeSendCanDropEvent(t *testing.T) {
 	client, scope, transport := setupClientTest()
 	client.options.BeforeSend = func(event *Event, hint *EventHint) *Event {
@@ -439,7 +766,7 @@ func BenchmarkProcessEvent(b *testing.B) {
 		b.Fatal(err)
 	}
 	for i := 0; i < b.N; i++ {
-		c.processEvent(&Event{}, nil, nil)
+		c.processEvent(&Event{}, nil, nil, false)
 	}
 }
 
@@ -456,6 +783,7 @@ func TestRecover(t *testing.T) {
 						Type:       "*errors.errorString",
 						Value:      "panic error",
 						Stacktrace: &Stacktrace{Frames: []Frame{}},
+						Mechanism:  newMechanism("panic", false),
 					},
 				},
 			},
@@ -520,3 +848,364 @@ func TestRecover(t *testing.T) {
 		})
 	}
 }
+
+func newMechanism(typ string, handled bool) *Mechanism {
+	m := &Mechanism{Type: typ}
+	m.SetHandled(handled)
+	return m
+}
+
+func TestRecoverPanicValue(t *testing.T) {
+	type order struct {
+		ID    int
+		Items []string
+	}
+	ch := make(chan int)
+	tests := []struct {
+		v    interface{} // for panic(v)
+		want map[string]interface{}
+	}{
+		{101010, map[string]interface{}{"type": "int", "value": 101010}},
+		{
+			order{ID: 1, Items: []string{"book"}},
+			map[string]interface{}{
+				"type":  "sentry.order",
+				"value": order{ID: 1, Items: []string{"book"}},
+			},
+		},
+		{
+			map[string]interface{}{"id": 1},
+			map[string]interface{}{
+				"type":  "map[string]interface {}",
+				"value": map[string]interface{}{"id": 1},
+			},
+		},
+		// Values that cannot be represented as JSON are formatted.
+		{ch, map[string]interface{}{"type": "chan int", "value": fmt.Sprintf("%#v", ch)}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("%T", tt.v), func(t *testing.T) {
+			client, scope, transport := setupClientTest()
+			func() {
+				defer client.Recover(nil, nil, scope)
+				panic(tt.v)
+			}()
+			got, ok := transport.lastEvent.Extra["panic_value"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("got extras %v, want panic_value", transport.lastEvent.Extra)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("panic_value mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRecoverRuntimeError(t *testing.T) {
+	tests := []struct {
+		name     string
+		f        func()
+		wantType string
+	}{
+		{"NilPointerDereference", func() {
+			var p *struct{ Field int }
+			_ = p.Field
+		}, "runtime.errorString"},
+		{"IndexOutOfRange", func() {
+			var a []int
+			i := 1
+			_ = a[i]
+		}, "runtime.boundsError"},
+		{"TypeAssertion", func() {
+			var v interface{} = "string"
+			_ = v.(int)
+		}, "*runtime.TypeAssertionError"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client, scope, transport := setupClientTest()
+			func() {
+				defer client.Recover(nil, nil, scope)
+				tt.f()
+			}()
+			exceptions := transport.lastEvent.Exception
+			if len(exceptions) != 1 {
+				t.Fatalf("got %d exceptions, want 1", len(exceptions))
+			}
+			if exceptions[0].Type != tt.wantType {
+				t.Errorf("got type %q, want %q", exceptions[0].Type, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestRecoverMechanismHTTPRequest(t *testing.T) {
+	client, scope, transport := setupClientTest()
+	r := httptest.NewRequest("POST", "/checkout?id=1", nil)
+	ctx := context.WithValue(context.Background(), RequestContextKey, r)
+	func() {
+		defer client.RecoverWithContext(ctx, nil, nil, scope)
+		panic(errors.New("panic error"))
+	}()
+
+	want := &Mechanism{
+		Type: "panic",
+		Data: map[string]interface{}{
+			"http.method": "POST",
+			"http.url":    "/checkout",
+		},
+	}
+	want.SetHandled(false)
+	got := transport.lastEvent.Exception[len(transport.lastEvent.Exception)-1].Mechanism
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Mechanism mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCaptureExceptionMechanismFromHint(t *testing.T) {
+	client, scope, transport := setupClientTest()
+	mechanism := &Mechanism{Type: "custom", Data: map[string]interface{}{"key": "value"}}
+	client.CaptureException(pkgErrors.WithStack(errors.New("wrapped")), &EventHint{Mechanism: mechanism}, scope)
+
+	exceptions := transport.lastEvent.Exception
+	if len(exceptions) != 2 {
+		t.Fatalf("got %d exceptions, want 2", len(exceptions))
+	}
+	if exceptions[0].Mechanism != nil {
+		t.Errorf("got mechanism %+v on cause, want nil", exceptions[0].Mechanism)
+	}
+	assertEqual(t, exceptions[1].Mechanism, mechanism)
+}
+
+func TestClockTimestampsEventsAndBreadcrumbs(t *testing.T) {
+	clock := &ClockMock{now: time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)}
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport: transport,
+		Clock:     clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(client, NewScope())
+
+	hub.AddBreadcrumb(&Breadcrumb{Message: "first"}, nil)
+	hub.AddBreadcrumb(NewQueryBreadcrumb("SELECT 1"), nil)
+	req, _ := http.NewRequestWithContext(SetHubOnContext(context.Background(), hub), "GET", "http://example.com/", nil)
+	_, _ = NewBreadcrumbTransport(errRoundTripper{errors.New("failed")}).RoundTrip(req)
+	clock.Advance(time.Second)
+	hub.CaptureMessage("message")
+
+	event := transport.lastEvent
+	assertEqual(t, event.Timestamp, time.Date(2021, 12, 1, 10, 0, 1, 0, time.UTC))
+	assertEqual(t, len(event.Breadcrumbs), 3)
+	for _, b := range event.Breadcrumbs {
+		assertEqual(t, b.Timestamp, time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC))
+	}
+}
+
+func TestClientFilterFrames(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport: transport,
+		FilterFrames: func(frames []Frame) []Frame {
+			var filtered []Frame
+			for _, frame := range frames {
+				if frame.Function != "TestClientFilterFrames" {
+					filtered = append(filtered, frame)
+				}
+			}
+			return filtered
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := NewEvent()
+	event.Exception = []Exception{
+		{Type: "cause", Stacktrace: &Stacktrace{Frames: []Frame{{Function: "TestClientFilterFrames"}}}},
+		{Type: "error", Stacktrace: &Stacktrace{Frames: []Frame{{Function: "main"}, {Function: "TestClientFilterFrames"}}}},
+	}
+	event.Threads = []Thread{{Stacktrace: &Stacktrace{Frames: []Frame{{Function: "main"}, {Function: "TestClientFilterFrames"}}}}}
+
+	client.CaptureEvent(event, nil, nil)
+
+	got := transport.lastEvent
+	if got.Exception[0].Stacktrace != nil {
+		t.Errorf("got stack trace %+v for the cause, want nil", got.Exception[0].Stacktrace)
+	}
+	for _, st := range []*Stacktrace{got.Exception[1].Stacktrace, got.Threads[0].Stacktrace} {
+		if st == nil || len(st.Frames) == 0 {
+			t.Fatalf("got stack trace %+v, want frames", st)
+		}
+		for _, frame := range st.Frames {
+			if frame.Function == "TestClientFilterFrames" {
+				t.Errorf("got frame %+v, want it filtered out", frame)
+			}
+		}
+	}
+}
+
+// selfReferentialError wraps itself.
+type selfReferentialError struct{}
+
+func (e *selfReferentialError) Error() string { return "loop" }
+func (e *selfReferentialError) Unwrap() error { return e }
+
+func TestCaptureExceptionMaxErrorDepth(t *testing.T) {
+	deep := error(&customErr{})
+	for i := 0; i < 20; i++ {
+		deep = wrappedError{original: deep}
+	}
+	tests := []struct {
+		name          string
+		maxErrorDepth int
+		err           error
+		want          int
+	}{
+		{"Default", 0, deep, 10},
+		{"Custom", 3, deep, 3},
+		{"Joined", 3, joinedError{deep, deep}, 3},
+		{"JoinedChildren", 0, joinedError{errors.New("a"), errors.New("b")}, 3},
+		{"SelfReferential", 0, &selfReferentialError{}, 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &TransportMock{}
+			client, err := NewClient(ClientOptions{
+				Transport:     transport,
+				MaxErrorDepth: tt.maxErrorDepth,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.CaptureException(tt.err, nil, nil)
+			if got := len(transport.lastEvent.Exception); got != tt.want {
+				t.Errorf("got %d exceptions, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// contextTransport is a ContextTransport that records the context of the
+// last event, and whose Flush blocks until release is closed.
+type contextTransport struct {
+	TransportMock
+	ctx     context.Context
+	release chan struct{}
+}
+
+func (t *contextTransport) SendEventWithContext(ctx context.Context, event *Event) {
+	t.ctx = ctx
+	t.SendEvent(event)
+}
+
+func (t *contextTransport) Flush(time.Duration) bool {
+	<-t.release
+	return true
+}
+
+func (t *contextTransport) FlushWithContext(ctx context.Context) bool {
+	select {
+	case <-t.release:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func TestClientSendsEventWithHintContext(t *testing.T) {
+	transport := &contextTransport{}
+	client, err := NewClient(ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "request")
+	client.CaptureMessage("with context", &EventHint{Context: ctx}, nil)
+	if transport.ctx != ctx {
+		t.Errorf("transport got context %v, want %v", transport.ctx, ctx)
+	}
+}
+
+func TestClientFlushWithContext(t *testing.T) {
+	for _, onlyTransport := range []bool{false, true} {
+		onlyTransport := onlyTransport
+		t.Run(fmt.Sprintf("onlyTransport=%t", onlyTransport), func(t *testing.T) {
+			release := make(chan struct{})
+			var transport Transport = &contextTransport{release: release}
+			if onlyTransport {
+				// Hide the ContextTransport methods, such that the transport
+				// is flushed with a timeout.
+				transport = struct{ Transport }{transport}
+			}
+			client, err := NewClient(ClientOptions{Transport: transport})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if client.FlushWithContext(ctx) {
+				t.Error("FlushWithContext = true before release, want false")
+			}
+			close(release)
+			if !client.FlushWithContext(context.Background()) {
+				t.Error("FlushWithContext = false after release, want true")
+			}
+		})
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer srv.Close()
+
+	watchdog := NewWatchdog(WatchdogOptions{Threshold: time.Minute})
+	client, err := NewClient(ClientOptions{
+		Dsn: strings.Replace(srv.URL, "//", "//pubkey@", 1) + "/1",
+		Integrations: func(integrations []Integration) []Integration {
+			return append(integrations, watchdog)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := NewScope()
+
+	if client.CaptureMessage("before", nil, scope) == nil {
+		t.Fatal("event not captured before Close")
+	}
+	if !client.Close(time.Second) {
+		t.Fatal("Close timed out")
+	}
+	assertEqual(t, atomic.LoadInt32(&requests), int32(1), "pending events are sent by Close")
+	select {
+	case <-watchdog.stop:
+	default:
+		t.Error("Watchdog not stopped")
+	}
+
+	if id := client.CaptureMessage("after", nil, scope); id != nil {
+		t.Errorf("got event ID %s after Close, want nil", *id)
+	}
+	client.Transport.SendEvent(NewEvent())
+
+	// Closing again and flushing do not wait for the timeout.
+	start := time.Now()
+	if !client.Close(time.Minute) {
+		t.Error("second Close = false, want true")
+	}
+	client.Flush(time.Minute)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Close and Flush after Close took %v", elapsed)
+	}
+	assertEqual(t, atomic.LoadInt32(&requests), int32(1), "events are dropped after Close")
+}
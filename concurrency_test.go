@@ -0,0 +1,196 @@
+package sentry_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/getsentry/sentry-go/sentrytest"
+)
+
+// The tests in this file exercise the API from many goroutines at once. They
+// are meant to be run with the race detector, as in CI:
+//
+//	go test -race -run Concurrent ./...
+
+// stress runs f from n goroutines, each with its own index, and waits for
+// them to return.
+func stress(n int, f func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func newStressClient(t *testing.T, transport sentry.Transport) *sentry.Client {
+	t.Helper()
+	server := sentrytest.NewServer()
+	t.Cleanup(server.Close)
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:           server.DSN(),
+		Transport:     transport,
+		Release:       "stress@1.0.0",
+		MaxLastEvents: 10,
+		ScopeLimits:   sentry.ScopeLimits{MaxExtraBytes: 1024, MaxBreadcrumbBytes: 1024},
+		BeforeSend: func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			return event
+		},
+		BeforeBreadcrumb: func(breadcrumb *sentry.Breadcrumb, hint *sentry.BreadcrumbHint) *sentry.Breadcrumb {
+			return breadcrumb
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close(time.Second) })
+	return client
+}
+
+func TestConcurrentHubUsage(t *testing.T) {
+	client := newStressClient(t, sentry.NewHTTPTransport())
+	hub := sentry.NewHub(client, sentry.NewScope())
+
+	stress(20, func(i int) {
+		for j := 0; j < 20; j++ {
+			// A hub shared by all goroutines.
+			hub.ConfigureScope(func(scope *sentry.Scope) {
+				touchScope(scope, i)
+			})
+			hub.AddBreadcrumb(&sentry.Breadcrumb{Message: fmt.Sprint(i)}, nil)
+			hub.CaptureMessage(fmt.Sprint(i))
+			hub.CaptureException(fmt.Errorf("error %d", i))
+			hub.WithScope(func(scope *sentry.Scope) {
+				scope.SetTag("with_scope", "yes")
+				hub.CaptureEvent(&sentry.Event{Message: "event"})
+			})
+			hub.LastEventID()
+
+			// A hub per goroutine, cloned from the shared hub.
+			local := hub.Clone()
+			local.PushScope()
+			local.Scope().SetUser(sentry.User{ID: fmt.Sprint(i)})
+			local.CaptureMessage("local")
+			local.PopScope()
+			if j%5 == 0 {
+				local.BindClient(client)
+				hub.Flush(time.Second)
+			}
+
+			client.LastEvents()
+			client.Options()
+		}
+	})
+}
+
+func TestConcurrentClientUsage(t *testing.T) {
+	client := newStressClient(t, sentry.NewHTTPSyncTransport())
+	scope := sentry.NewScope()
+
+	stress(20, func(i int) {
+		for j := 0; j < 10; j++ {
+			if j == 0 {
+				client.AddEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+					return event
+				})
+			}
+			client.CaptureMessage(fmt.Sprint(i), nil, scope)
+			client.CaptureException(errors.New("error"), nil, scope)
+			client.CaptureEvent(&sentry.Event{Message: "event"}, nil, nil)
+			client.Recover(fmt.Sprint(i), nil, scope)
+			client.Flush(time.Second)
+		}
+	})
+}
+
+func TestConcurrentScopeSnapshots(t *testing.T) {
+	scope := sentry.NewScope()
+
+	stress(20, func(i int) {
+		for j := 0; j < 20; j++ {
+			switch j % 5 {
+			case 0:
+				scope.SetLimits(sentry.ScopeLimits{MaxExtraBytes: 100 * (j + 1)})
+			case 1:
+				scope.Clear()
+			case 2:
+				scope.AddEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+					return event
+				})
+			}
+			scope.SetExtra(fmt.Sprint(i), j)
+			scope.SetRequest(httptest.NewRequest("GET", "/", nil))
+			scope.AddBreadcrumb(&sentry.Breadcrumb{Message: "foo"}, 10)
+
+			clone := scope.Clone()
+			clone.SetTag("clone", "yes")
+			event := scope.ApplyToEvent(sentry.NewEvent(), nil)
+			if event == nil {
+				t.Error("ApplyToEvent dropped the event")
+				return
+			}
+			scope.Limits()
+		}
+	})
+}
+
+func TestConcurrentTransportUsage(t *testing.T) {
+	tests := map[string]func() sentry.Transport{
+		"HTTPTransport": func() sentry.Transport {
+			transport := sentry.NewHTTPTransport()
+			transport.BufferSize = 1000
+			return transport
+		},
+		"HTTPSyncTransport": func() sentry.Transport {
+			return sentry.NewHTTPSyncTransport()
+		},
+	}
+	for name, newTransport := range tests {
+		newTransport := newTransport
+		t.Run(name, func(t *testing.T) {
+			server := sentrytest.NewServer()
+			defer server.Close()
+			transport := newTransport()
+			transport.Configure(sentry.ClientOptions{Dsn: server.DSN()})
+
+			const n, m = 10, 10
+			stress(n, func(i int) {
+				for j := 0; j < m; j++ {
+					transport.SendEvent(&sentry.Event{
+						EventID: sentry.EventID(fmt.Sprintf("%016x%016x", i, j)),
+						Message: "message",
+					})
+					if j%3 == 0 {
+						transport.Flush(time.Second)
+					}
+				}
+			})
+			if !transport.Flush(5 * time.Second) {
+				t.Fatal("Flush timed out")
+			}
+			if got := len(server.Events()); got != n*m {
+				t.Errorf("server received %d events, want %d", got, n*m)
+			}
+
+			// Events sent while the transport is closed must not panic nor race.
+			stress(n, func(i int) {
+				if i == 0 {
+					if c, ok := transport.(interface{ Close() }); ok {
+						c.Close()
+					}
+					return
+				}
+				transport.SendEvent(&sentry.Event{Message: "late"})
+				transport.Flush(10 * time.Millisecond)
+			})
+		})
+	}
+}
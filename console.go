@@ -0,0 +1,142 @@
+package sentry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ConsoleTransportOptions configure a ConsoleTransport.
+type ConsoleTransportOptions struct {
+	// Output is where events are printed. Defaults to os.Stderr.
+	Output io.Writer
+	// Color highlights the summaries of events with ANSI escape codes,
+	// according to their level.
+	Color bool
+	// Transport, if set, also receives all events, so that events are both
+	// printed and sent, for example by an HTTPTransport.
+	Transport Transport
+}
+
+// A ConsoleTransport is a Transport that prints events, serialized as they
+// would be sent to Sentry, so that developers can see exactly what the SDK
+// sends while iterating locally. Each event is printed as a summary line
+// followed by the indented JSON payload:
+//
+//	sentry.Init(sentry.ClientOptions{
+//		Dsn:       "your-public-dsn",
+//		Transport: sentry.NewConsoleTransport(sentry.ConsoleTransportOptions{
+//			Color:     true,
+//			Transport: sentry.NewHTTPTransport(),
+//		}),
+//	})
+//
+// Without a wrapped transport, events are only printed and no DSN is needed.
+type ConsoleTransport struct {
+	output    io.Writer
+	color     bool
+	transport Transport
+
+	mu sync.Mutex
+}
+
+// NewConsoleTransport returns a new ConsoleTransport.
+func NewConsoleTransport(options ConsoleTransportOptions) *ConsoleTransport {
+	output := options.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	return &ConsoleTransport{
+		output:    output,
+		color:     options.Color,
+		transport: options.Transport,
+	}
+}
+
+// Configure is called by the Client itself, providing it it's own ClientOptions.
+func (t *ConsoleTransport) Configure(options ClientOptions) {
+	if t.transport != nil {
+		t.transport.Configure(options)
+	}
+}
+
+// SendEvent prints the event and sends it to the wrapped transport, if any.
+func (t *ConsoleTransport) SendEvent(event *Event) {
+	// Serialize the event before handing it over to the wrapped transport,
+	// which may modify it asynchronously.
+	body := getRequestBodyFromEvent(event)
+	if t.transport != nil {
+		t.transport.SendEvent(event)
+	}
+	if body == nil {
+		return
+	}
+	var b bytes.Buffer
+	if t.color {
+		fmt.Fprintf(&b, "\x1b[%sm%s\x1b[0m\n", levelColor(event), summarizeEvent(event))
+	} else {
+		fmt.Fprintln(&b, summarizeEvent(event))
+	}
+	if err := json.Indent(&b, body, "", "  "); err != nil {
+		b.Write(body)
+	}
+	b.WriteString("\n\n")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.output.Write(b.Bytes()); err != nil {
+		Logger.Printf("Could not print event: %v", err)
+	}
+}
+
+// Flush waits until the wrapped transport, if any, has sent the events.
+// Printed events are always flushed.
+func (t *ConsoleTransport) Flush(timeout time.Duration) bool {
+	if t.transport == nil {
+		return true
+	}
+	return t.transport.Flush(timeout)
+}
+
+// Close closes the wrapped transport, if it holds resources.
+func (t *ConsoleTransport) Close() {
+	if c, ok := t.transport.(transportCloser); ok {
+		c.Close()
+	}
+}
+
+// summarizeEvent returns a line describing the event, with its kind, ID and
+// message, exception or transaction name.
+func summarizeEvent(event *Event) string {
+	summary := event.Message
+	switch {
+	case event.Type == transactionType:
+		summary = event.Transaction
+	case event.Type == checkInType && event.CheckIn != nil:
+		summary = fmt.Sprintf("%s %s", event.CheckIn.MonitorSlug, event.CheckIn.Status)
+	case len(event.Exception) > 0:
+		e := event.Exception[len(event.Exception)-1]
+		summary = fmt.Sprintf("%s: %s", e.Type, e.Value)
+	}
+	return fmt.Sprintf("[Sentry] %s %s: %s", describeEvent(event), event.EventID, summary)
+}
+
+// levelColor returns the ANSI color code used for the summary of the event.
+func levelColor(event *Event) string {
+	if event.Type != "" {
+		return "36" // cyan
+	}
+	switch event.Level {
+	case LevelFatal, LevelError:
+		return "31" // red
+	case LevelWarning:
+		return "33" // yellow
+	case LevelDebug:
+		return "90" // gray
+	}
+	return "32" // green
+}
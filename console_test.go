@@ -0,0 +1,46 @@
+package sentry
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConsoleTransport(t *testing.T) {
+	var out bytes.Buffer
+	wrapped := &TransportMock{}
+	transport := NewConsoleTransport(ConsoleTransportOptions{Output: &out, Transport: wrapped})
+	client, err := NewClient(ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(client, NewScope())
+
+	hub.CaptureMessage("hello")
+	hub.CaptureException(errors.New("failed"))
+	assertEqual(t, len(wrapped.Events()), 2)
+
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[0], "[Sentry] info event ") || !strings.HasSuffix(lines[0], ": hello") {
+		t.Errorf("got summary %q", lines[0])
+	}
+	assertEqual(t, lines[1], "{")
+	if !strings.Contains(out.String(), `  "message": "hello",`) {
+		t.Errorf("the payload is not indented:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "error event "+string(wrapped.Events()[1].EventID)+": *errors.errorString: failed\n") {
+		t.Errorf("missing exception summary:\n%s", out.String())
+	}
+}
+
+func TestConsoleTransportColor(t *testing.T) {
+	var out bytes.Buffer
+	transport := NewConsoleTransport(ConsoleTransportOptions{Output: &out, Color: true})
+	transport.Configure(ClientOptions{})
+	transport.SendEvent(&Event{EventID: "1", Level: LevelWarning, Message: "careful"})
+	assertEqual(t, transport.Flush(0), true)
+	if !strings.HasPrefix(out.String(), "\x1b[33m[Sentry] warning event 1: careful\x1b[0m\n") {
+		t.Errorf("got output %q", out.String())
+	}
+}
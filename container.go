@@ -0,0 +1,141 @@
+package sentry
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupUnlimited is the smallest memory limit of cgroup v1 that means no
+// limit, which is reported as the largest multiple of the page size.
+const cgroupUnlimited = 1 << 62
+
+// An envTag is a tag whose value is read from the first of the environment
+// variables that is set.
+type envTag struct {
+	tag  string
+	vars []string
+}
+
+// setEnvTags sets the tags whose variables are set in tags.
+func setEnvTags(tags map[string]string, getenv func(string) string, envTags []envTag) {
+	for _, env := range envTags {
+		for _, name := range env.vars {
+			if v := getenv(name); v != "" {
+				tags[env.tag] = v
+				break
+			}
+		}
+	}
+}
+
+// kubernetesEnv lists, for the tags describing the Kubernetes workload, the
+// environment variables commonly set with the downward API:
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom:
+//	    fieldRef:
+//	      fieldPath: metadata.name
+var kubernetesEnv = []envTag{
+	{"k8s.pod.name", []string{"POD_NAME", "K8S_POD_NAME", "KUBERNETES_POD_NAME"}},
+	{"k8s.namespace.name", []string{"POD_NAMESPACE", "K8S_NAMESPACE", "KUBERNETES_NAMESPACE"}},
+	{"k8s.node.name", []string{"NODE_NAME", "K8S_NODE_NAME", "KUBERNETES_NODE_NAME"}},
+}
+
+// containerInfo describes the limits of the cgroup the program runs in, which
+// are those of its container, and the Kubernetes workload it belongs to.
+type containerInfo struct {
+	// memoryLimit is in bytes, and cpuLimit in cores. They are 0 if there is
+	// no limit.
+	memoryLimit uint64
+	cpuLimit    float64
+	// memoryUsage is the file reporting the memory used by the cgroup.
+	memoryUsage string
+	tags        map[string]string
+}
+
+// readContainerInfo reads the limits of the cgroup from the cgroup file system
+// mounted in root, trying cgroup v2 then v1, and the tags of the Kubernetes
+// workload from the environment. The tags are only read in Kubernetes, which
+// sets KUBERNETES_SERVICE_HOST in every container, as variables like NODE_NAME
+// are common elsewhere.
+func readContainerInfo(root string, getenv func(string) string) containerInfo {
+	c := containerInfo{tags: make(map[string]string)}
+	cgroup := filepath.Join(root, "sys", "fs", "cgroup")
+
+	if limit, ok := readCgroupValue(filepath.Join(cgroup, "memory.max")); ok {
+		c.memoryLimit = limit
+		c.memoryUsage = filepath.Join(cgroup, "memory.current")
+	} else if limit, ok := readCgroupValue(filepath.Join(cgroup, "memory", "memory.limit_in_bytes")); ok && limit < cgroupUnlimited {
+		c.memoryLimit = limit
+		c.memoryUsage = filepath.Join(cgroup, "memory", "memory.usage_in_bytes")
+	}
+
+	if b, err := ioutil.ReadFile(filepath.Join(cgroup, "cpu.max")); err == nil {
+		// The quota and the period, or "max" for no quota.
+		if fields := strings.Fields(string(b)); len(fields) == 2 {
+			c.cpuLimit = cpuQuota(fields[0], fields[1])
+		}
+	} else {
+		quota, _ := ioutil.ReadFile(filepath.Join(cgroup, "cpu", "cpu.cfs_quota_us"))
+		period, _ := ioutil.ReadFile(filepath.Join(cgroup, "cpu", "cpu.cfs_period_us"))
+		c.cpuLimit = cpuQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+	}
+
+	if getenv("KUBERNETES_SERVICE_HOST") != "" {
+		setEnvTags(c.tags, getenv, kubernetesEnv)
+	}
+	return c
+}
+
+// readCgroupValue reads a number of bytes from a cgroup file. It returns false
+// if the file cannot be read or there is no limit.
+func readCgroupValue(name string) (uint64, bool) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		// Also "max" for no limit.
+		return 0, false
+	}
+	return v, true
+}
+
+// cpuQuota returns the number of cores of a CFS quota and period in
+// microseconds, or 0 if there is no quota.
+func cpuQuota(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
+
+// setDeviceContext sets the limits of the container in the device context,
+// preserving existing values. The free memory is what the cgroup can still
+// use before being killed for running out of memory.
+func (c containerInfo) setDeviceContext(deviceContext map[string]interface{}) {
+	if c.memoryLimit > 0 {
+		if _, ok := deviceContext["memory_size"]; !ok {
+			deviceContext["memory_size"] = c.memoryLimit
+		}
+		if usage, ok := readCgroupValue(c.memoryUsage); ok && usage <= c.memoryLimit {
+			if _, ok := deviceContext["free_memory"]; !ok {
+				deviceContext["free_memory"] = c.memoryLimit - usage
+			}
+		}
+	}
+	if c.cpuLimit > 0 {
+		if _, ok := deviceContext["cpu_limit"]; !ok {
+			deviceContext["cpu_limit"] = c.cpuLimit
+		}
+	}
+}
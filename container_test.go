@@ -0,0 +1,141 @@
+package sentry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles writes files with the given contents, by path relative to a new
+// temporary directory, which it returns.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "sentry-container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	for name, content := range files {
+		name = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestReadContainerInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		memoryLimit uint64
+		cpuLimit    float64
+		memoryUsage string
+	}{
+		{
+			name: "CgroupV2",
+			files: map[string]string{
+				"sys/fs/cgroup/memory.max":     "536870912\n",
+				"sys/fs/cgroup/memory.current": "134217728\n",
+				"sys/fs/cgroup/cpu.max":        "150000 100000\n",
+			},
+			memoryLimit: 512 << 20,
+			cpuLimit:    1.5,
+			memoryUsage: "sys/fs/cgroup/memory.current",
+		},
+		{
+			name: "CgroupV2Unlimited",
+			files: map[string]string{
+				"sys/fs/cgroup/memory.max": "max\n",
+				"sys/fs/cgroup/cpu.max":    "max 100000\n",
+			},
+		},
+		{
+			name: "CgroupV1",
+			files: map[string]string{
+				"sys/fs/cgroup/memory/memory.limit_in_bytes": "1073741824\n",
+				"sys/fs/cgroup/memory/memory.usage_in_bytes": "1024\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         "200000\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us":        "100000\n",
+			},
+			memoryLimit: 1 << 30,
+			cpuLimit:    2,
+			memoryUsage: "sys/fs/cgroup/memory/memory.usage_in_bytes",
+		},
+		{
+			name: "CgroupV1Unlimited",
+			files: map[string]string{
+				"sys/fs/cgroup/memory/memory.limit_in_bytes": "9223372036854771712\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         "-1\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us":        "100000\n",
+			},
+		},
+		{
+			name: "NoCgroup",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeFiles(t, tt.files)
+			c := readContainerInfo(root, func(string) string { return "" })
+			assertEqual(t, c.memoryLimit, tt.memoryLimit)
+			assertEqual(t, c.cpuLimit, tt.cpuLimit)
+			if tt.memoryUsage != "" {
+				assertEqual(t, c.memoryUsage, filepath.Join(root, tt.memoryUsage))
+			}
+			assertEqual(t, c.tags, map[string]string{})
+		})
+	}
+}
+
+func TestReadContainerInfoKubernetes(t *testing.T) {
+	env := map[string]string{
+		"POD_NAME":             "api-7d4b9c-x2x8q",
+		"K8S_POD_NAME":         "ignored",
+		"KUBERNETES_NAMESPACE": "production",
+		"NODE_NAME":            "node-1",
+	}
+	getenv := func(name string) string { return env[name] }
+
+	// Outside Kubernetes, the variables are not tags.
+	c := readContainerInfo(writeFiles(t, nil), getenv)
+	assertEqual(t, c.tags, map[string]string{})
+
+	env["KUBERNETES_SERVICE_HOST"] = "10.0.0.1"
+	c = readContainerInfo(writeFiles(t, nil), getenv)
+	assertEqual(t, c.tags, map[string]string{
+		"k8s.pod.name":       "api-7d4b9c-x2x8q",
+		"k8s.namespace.name": "production",
+		"k8s.node.name":      "node-1",
+	})
+}
+
+func TestContainerInfoSetDeviceContext(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"sys/fs/cgroup/memory.max":     "1000\n",
+		"sys/fs/cgroup/memory.current": "300\n",
+		"sys/fs/cgroup/cpu.max":        "50000 100000\n",
+	})
+	c := readContainerInfo(root, func(string) string { return "" })
+
+	deviceContext := map[string]interface{}{"memory_size": 2000}
+	c.setDeviceContext(deviceContext)
+	assertEqual(t, deviceContext, map[string]interface{}{
+		"memory_size": 2000,
+		"free_memory": uint64(700),
+		"cpu_limit":   0.5,
+	})
+}
+
+func TestEnvironmentIntegrationContainerTags(t *testing.T) {
+	ei := &environmentIntegration{container: containerInfo{
+		tags: map[string]string{"k8s.pod.name": "pod", "k8s.node.name": "node"},
+	}}
+	event := ei.processor(&Event{Tags: map[string]string{"k8s.node.name": "explicit"}}, nil)
+	assertEqual(t, event.Tags, map[string]string{"k8s.pod.name": "pod", "k8s.node.name": "explicit"})
+}
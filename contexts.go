@@ -0,0 +1,240 @@
+package sentry
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// A TypedContext is a context of events with a known schema, like
+// DeviceContext or OSContext, converted to the map stored in Event.Contexts
+// by ToMap. ToMap returns an error if the context is invalid.
+//
+// Contexts are stored as maps rather than structs such that the SDK and
+// integrations can add to them, like the environment integration adds the
+// architecture to the device context.
+type TypedContext interface {
+	ToMap() (map[string]interface{}, error)
+}
+
+var (
+	_ TypedContext = (*TraceContext)(nil)
+	_ TypedContext = DeviceContext{}
+	_ TypedContext = OSContext{}
+	_ TypedContext = RuntimeContext{}
+	_ TypedContext = AppContext{}
+	_ TypedContext = CultureContext{}
+)
+
+// SetTypedContext sets the context of the given key to the map of c. If c is
+// invalid, it returns the error of ToMap and leaves the scope unchanged:
+//
+//	err := scope.SetTypedContext("os", sentry.OSContext{
+//		Name:    "Alpine Linux",
+//		Version: "3.19",
+//	})
+func (scope *Scope) SetTypedContext(key string, c TypedContext) error {
+	m, err := c.ToMap()
+	if err != nil {
+		return err
+	}
+	scope.SetContext(key, m)
+	return nil
+}
+
+// contextMap builds the map of a context of the given type, leaving out
+// values that are not set.
+type contextMap map[string]interface{}
+
+func newContextMap(kind string) contextMap {
+	return contextMap{"type": kind}
+}
+
+func (m contextMap) setString(key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
+
+func (m contextMap) setInt(key string, value int) {
+	if value != 0 {
+		m[key] = value
+	}
+}
+
+func (m contextMap) setUint(key string, value uint64) {
+	if value != 0 {
+		m[key] = value
+	}
+}
+
+func (m contextMap) setTime(key string, value time.Time) {
+	if !value.IsZero() {
+		m[key] = value
+	}
+}
+
+// ToMap returns the trace context as a map. It returns an error if the trace
+// or span ID is not set. The SDK sets the trace context of events captured
+// within a span, as *TraceContext, so this is only needed to link events to
+// a trace started elsewhere.
+func (tc *TraceContext) ToMap() (map[string]interface{}, error) {
+	if tc.TraceID == zeroTraceID {
+		return nil, errors.New("sentry: trace context without trace ID")
+	}
+	if tc.SpanID == zeroSpanID {
+		return nil, errors.New("sentry: trace context without span ID")
+	}
+	m := newContextMap("trace")
+	m.setString("trace_id", tc.TraceID.String())
+	m.setString("span_id", tc.SpanID.String())
+	if tc.ParentSpanID != zeroSpanID {
+		m.setString("parent_span_id", tc.ParentSpanID.String())
+	}
+	m.setString("op", tc.Op)
+	m.setString("description", tc.Description)
+	m.setString("status", tc.Status.String())
+	m.setString("origin", tc.Origin)
+	return m, nil
+}
+
+// A DeviceContext describes the machine the program runs on. Memory sizes are
+// in bytes.
+type DeviceContext struct {
+	Name           string
+	Family         string
+	Model          string
+	ModelID        string
+	Arch           string
+	NumCPU         int
+	CPUDescription string
+	MemorySize     uint64
+	FreeMemory     uint64
+	UsableMemory   uint64
+	BootTime       time.Time
+	Timezone       string
+}
+
+// ToMap returns the device context as a map. It returns an error if the free
+// or usable memory exceeds the memory size.
+func (c DeviceContext) ToMap() (map[string]interface{}, error) {
+	if c.MemorySize != 0 && (c.FreeMemory > c.MemorySize || c.UsableMemory > c.MemorySize) {
+		return nil, fmt.Errorf("sentry: device context with more free or usable memory than its memory size %d", c.MemorySize)
+	}
+	m := newContextMap("device")
+	m.setString("name", c.Name)
+	m.setString("family", c.Family)
+	m.setString("model", c.Model)
+	m.setString("model_id", c.ModelID)
+	m.setString("arch", c.Arch)
+	m.setInt("num_cpu", c.NumCPU)
+	m.setString("cpu_description", c.CPUDescription)
+	m.setUint("memory_size", c.MemorySize)
+	m.setUint("free_memory", c.FreeMemory)
+	m.setUint("usable_memory", c.UsableMemory)
+	m.setTime("boot_time", c.BootTime)
+	m.setString("timezone", c.Timezone)
+	return m, nil
+}
+
+// An OSContext describes the operating system the program runs on.
+type OSContext struct {
+	Name          string
+	Version       string
+	Build         string
+	KernelVersion string
+	// RawDescription is an unparsed description of the system, like the
+	// output of uname -a, from which Sentry extracts the other fields.
+	RawDescription string
+}
+
+// ToMap returns the OS context as a map. It returns an error if neither the
+// name nor the raw description is set.
+func (c OSContext) ToMap() (map[string]interface{}, error) {
+	if c.Name == "" && c.RawDescription == "" {
+		return nil, errors.New("sentry: OS context without name")
+	}
+	m := newContextMap("os")
+	m.setString("name", c.Name)
+	m.setString("version", c.Version)
+	m.setString("build", c.Build)
+	m.setString("kernel_version", c.KernelVersion)
+	m.setString("raw_description", c.RawDescription)
+	return m, nil
+}
+
+// A RuntimeContext describes the runtime executing the program, like an
+// interpreter embedded in a Go program.
+type RuntimeContext struct {
+	Name    string
+	Version string
+	Build   string
+	// RawDescription is an unparsed description of the runtime, from which
+	// Sentry extracts the other fields.
+	RawDescription string
+}
+
+// ToMap returns the runtime context as a map. It returns an error if neither
+// the name nor the raw description is set.
+func (c RuntimeContext) ToMap() (map[string]interface{}, error) {
+	if c.Name == "" && c.RawDescription == "" {
+		return nil, errors.New("sentry: runtime context without name")
+	}
+	m := newContextMap("runtime")
+	m.setString("name", c.Name)
+	m.setString("version", c.Version)
+	m.setString("build", c.Build)
+	m.setString("raw_description", c.RawDescription)
+	return m, nil
+}
+
+// An AppContext describes the application. Memory is in bytes.
+type AppContext struct {
+	StartTime  time.Time
+	BuildType  string
+	Identifier string
+	Name       string
+	Version    string
+	Build      string
+	Memory     uint64
+}
+
+// ToMap returns the app context as a map. It returns an error if the start
+// time is in the future.
+func (c AppContext) ToMap() (map[string]interface{}, error) {
+	if c.StartTime.After(time.Now()) {
+		return nil, fmt.Errorf("sentry: app context with start time %s in the future", c.StartTime)
+	}
+	m := newContextMap("app")
+	m.setTime("app_start_time", c.StartTime)
+	m.setString("build_type", c.BuildType)
+	m.setString("app_identifier", c.Identifier)
+	m.setString("app_name", c.Name)
+	m.setString("app_version", c.Version)
+	m.setString("app_build", c.Build)
+	m.setUint("app_memory", c.Memory)
+	return m, nil
+}
+
+// A CultureContext describes the locale of the user of the program.
+type CultureContext struct {
+	Locale      string
+	Timezone    string
+	Calendar    string
+	DisplayName string
+	// Is24HourFormat is nil if unknown.
+	Is24HourFormat *bool
+}
+
+// ToMap returns the culture context as a map. It never returns an error.
+func (c CultureContext) ToMap() (map[string]interface{}, error) {
+	m := newContextMap("culture")
+	m.setString("locale", c.Locale)
+	m.setString("timezone", c.Timezone)
+	m.setString("calendar", c.Calendar)
+	m.setString("display_name", c.DisplayName)
+	if c.Is24HourFormat != nil {
+		m["is_24_hour_format"] = *c.Is24HourFormat
+	}
+	return m, nil
+}
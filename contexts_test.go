@@ -0,0 +1,132 @@
+package sentry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTypedContextToMap(t *testing.T) {
+	bootTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	is24HourFormat := true
+	tests := []struct {
+		name    string
+		context TypedContext
+		want    map[string]interface{}
+	}{
+		{
+			name: "Trace",
+			context: &TraceContext{
+				TraceID: TraceIDFromHex("d6c4f03650bd47699ec65c84352b6208"),
+				SpanID:  SpanIDFromHex("1cc4b26ab9094ef0"),
+				Op:      "http.server",
+				Status:  SpanStatusOK,
+			},
+			want: map[string]interface{}{
+				"type":     "trace",
+				"trace_id": "d6c4f03650bd47699ec65c84352b6208",
+				"span_id":  "1cc4b26ab9094ef0",
+				"op":       "http.server",
+				"status":   "ok",
+			},
+		},
+		{
+			name: "Device",
+			context: DeviceContext{
+				Arch:       "amd64",
+				NumCPU:     4,
+				MemorySize: 1 << 30,
+				FreeMemory: 1 << 20,
+				BootTime:   bootTime,
+			},
+			want: map[string]interface{}{
+				"type":        "device",
+				"arch":        "amd64",
+				"num_cpu":     4,
+				"memory_size": uint64(1 << 30),
+				"free_memory": uint64(1 << 20),
+				"boot_time":   bootTime,
+			},
+		},
+		{
+			name:    "OS",
+			context: OSContext{Name: "Linux", KernelVersion: "6.1.0"},
+			want: map[string]interface{}{
+				"type":           "os",
+				"name":           "Linux",
+				"kernel_version": "6.1.0",
+			},
+		},
+		{
+			name:    "Runtime",
+			context: RuntimeContext{RawDescription: "lua 5.4"},
+			want: map[string]interface{}{
+				"type":            "runtime",
+				"raw_description": "lua 5.4",
+			},
+		},
+		{
+			name:    "App",
+			context: AppContext{StartTime: bootTime, BuildType: "release", Memory: 42},
+			want: map[string]interface{}{
+				"type":           "app",
+				"app_start_time": bootTime,
+				"build_type":     "release",
+				"app_memory":     uint64(42),
+			},
+		},
+		{
+			name:    "Culture",
+			context: CultureContext{Locale: "en-US", Is24HourFormat: &is24HourFormat},
+			want: map[string]interface{}{
+				"type":              "culture",
+				"locale":            "en-US",
+				"is_24_hour_format": true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.context.ToMap()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("map mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTypedContextToMapErrors(t *testing.T) {
+	tests := map[string]TypedContext{
+		"TraceWithoutTraceID": &TraceContext{SpanID: SpanIDFromHex("1cc4b26ab9094ef0")},
+		"TraceWithoutSpanID":  &TraceContext{TraceID: TraceIDFromHex("d6c4f03650bd47699ec65c84352b6208")},
+		"DeviceFreeMemory":    DeviceContext{MemorySize: 1, FreeMemory: 2},
+		"OSWithoutName":       OSContext{Version: "1"},
+		"RuntimeWithoutName":  RuntimeContext{Version: "1"},
+		"AppStartInFuture":    AppContext{StartTime: time.Now().Add(time.Hour)},
+	}
+	for name, context := range tests {
+		if m, err := context.ToMap(); err == nil {
+			t.Errorf("%s: ToMap() = %v, want an error", name, m)
+		}
+	}
+}
+
+func TestScopeSetTypedContext(t *testing.T) {
+	scope := NewScope()
+	if err := scope.SetTypedContext("os", OSContext{Name: "Linux"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := scope.SetTypedContext("runtime", RuntimeContext{}); err == nil {
+		t.Error("invalid context should not be set")
+	}
+
+	event := scope.ApplyToEvent(NewEvent(), nil)
+	assertEqual(t, event.Contexts, map[string]interface{}{
+		"os": map[string]interface{}{"type": "os", "name": "Linux"},
+	})
+}
@@ -0,0 +1,86 @@
+package sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// A crashMarker is written to ClientOptions.CrashMarkerPath when the process
+// starts handling a fatal condition. Finding one on startup means that the
+// previous run of the program crashed, even if it died before the crash could
+// be reported.
+type crashMarker struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Reason      string    `json:"reason"`
+	Release     string    `json:"release,omitempty"`
+	Environment string    `json:"environment,omitempty"`
+}
+
+// markCrash writes a crash marker with the given reason, if the client is
+// configured with a CrashMarkerPath. Errors are logged and otherwise ignored,
+// as the process is about to exit anyway.
+func (client *Client) markCrash(reason string) {
+	path := client.options.CrashMarkerPath
+	if path == "" {
+		return
+	}
+	b, err := json.Marshal(crashMarker{
+		Timestamp:   clockFor(client).Now().UTC(),
+		Reason:      reason,
+		Release:     client.options.Release,
+		Environment: client.options.Environment,
+	})
+	if err != nil {
+		Logger.Printf("Cannot encode crash marker: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		Logger.Printf("Cannot write crash marker: %v", err)
+	}
+}
+
+// reportPreviousCrash reports an event if a crash marker left by a previous
+// run of the program exists, and removes the marker.
+func (client *Client) reportPreviousCrash() {
+	path := client.options.CrashMarkerPath
+	if path == "" {
+		return
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		Logger.Printf("Cannot read crash marker: %v", err)
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		// Do not report the same crash on every start.
+		Logger.Printf("Cannot remove crash marker: %v", err)
+		return
+	}
+	var marker crashMarker
+	if err := json.Unmarshal(b, &marker); err != nil {
+		Logger.Printf("Cannot decode crash marker: %v", err)
+		return
+	}
+	Logger.Printf("Previous session crashed at %s: %s", marker.Timestamp, marker.Reason)
+	client.CaptureEvent(eventFromCrashMarker(marker), nil, nil)
+}
+
+func eventFromCrashMarker(marker crashMarker) *Event {
+	event := NewEvent()
+	event.Level = LevelFatal
+	event.Message = fmt.Sprintf("Previous session crashed: %s", marker.Reason)
+	// Attribute the crash to the release that crashed, which may differ
+	// from the current one after a restart with a new version.
+	event.Release = marker.Release
+	event.Environment = marker.Environment
+	event.Tags["crashed"] = "true"
+	event.Extra["crashed_at"] = marker.Timestamp.Format(time.RFC3339)
+	event.Extra["crash_reason"] = marker.Reason
+	return event
+}
@@ -0,0 +1,94 @@
+package sentry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCrashMarker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sentry-crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "crashed")
+
+	clock := &ClockMock{now: time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)}
+	crashed, err := NewClient(ClientOptions{
+		Transport:       &TransportMock{},
+		Clock:           clock,
+		Release:         "v1",
+		Environment:     "production",
+		CrashMarkerPath: path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	crashed.markCrash("panic: crash")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("missing crash marker: %v", err)
+	}
+
+	transport := &TransportMock{}
+	_, err = NewClient(ClientOptions{
+		Transport:       transport,
+		Release:         "v2",
+		CrashMarkerPath: path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	event := events[0]
+	assertEqual(t, event.Level, LevelFatal)
+	assertEqual(t, event.Message, "Previous session crashed: panic: crash")
+	assertEqual(t, event.Release, "v1")
+	assertEqual(t, event.Environment, "production")
+	assertEqual(t, event.Extra["crashed_at"], "2021-12-01T10:00:00Z")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("crash marker was not removed: %v", err)
+	}
+
+	// The crash is reported only once.
+	transport = &TransportMock{}
+	_, err = NewClient(ClientOptions{
+		Transport:       transport,
+		CrashMarkerPath: path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(transport.Events()), 0)
+}
+
+func TestHandleCrashWritesCrashMarker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sentry-crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "crashed")
+
+	client, err := NewClient(ClientOptions{
+		Transport:       &TransportMock{},
+		CrashMarkerPath: path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			_ = recover()
+		}()
+		handleCrash(NewHub(client, NewScope()), "crash", time.Second)
+	}()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("missing crash marker: %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package sentry
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getsentry/sentry-go/internal/ratelimit"
+)
+
+// A DropReason is a reason why the SDK dropped an event, as counted in a
+// DiagnosticReport.
+type DropReason string
+
+// Reasons why events are dropped.
+const (
+	// DropClientClosed counts events captured after Client.Close.
+	DropClientClosed DropReason = "client_closed"
+	// DropRecursion counts events captured while processing another event
+	// on the same goroutine, for example by BeforeSend.
+	DropRecursion DropReason = "recursion"
+	// DropSampleRate counts error events sampled out by SampleRate.
+	DropSampleRate DropReason = "sample_rate"
+	// DropEventSampler counts error events sampled out by EventSampler or
+	// ErrorSampler.
+	DropEventSampler DropReason = "event_sampler"
+	// DropEventProcessor counts events dropped by an event processor, of a
+	// scope, of the client or of an integration.
+	DropEventProcessor DropReason = "event_processor"
+	// DropBeforeSend counts events dropped by BeforeSend.
+	DropBeforeSend DropReason = "before_send"
+	// DropQueueOverflow counts events dropped because the buffer of the
+	// transport is full.
+	DropQueueOverflow DropReason = "queue_overflow"
+	// DropRateLimit counts events dropped because Sentry is rate limiting
+	// their category.
+	DropRateLimit DropReason = "ratelimit_backoff"
+	// DropNetworkError counts events whose request failed, for example
+	// because the server cannot be reached.
+	DropNetworkError DropReason = "network_error"
+	// DropServerRejected counts events that the server rejected, with a
+	// status other than a rate limit.
+	DropServerRejected DropReason = "server_rejected"
+	// DropTransportClosed counts events sent to a closed transport.
+	DropTransportClosed DropReason = "transport_closed"
+)
+
+// dropCounts counts dropped events by reason. It is safe for concurrent use.
+type dropCounts struct {
+	mu     sync.Mutex
+	counts map[DropReason]uint64
+}
+
+// add counts an event dropped for reason.
+func (d *dropCounts) add(reason DropReason) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.counts == nil {
+		d.counts = make(map[DropReason]uint64)
+	}
+	d.counts[reason]++
+}
+
+// addTo adds the counts to m.
+func (d *dropCounts) addTo(m map[DropReason]uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for reason, n := range d.counts {
+		m[reason] += n
+	}
+}
+
+// clientCounts are the counters of a client other than dropped events. The
+// fields are accessed atomically.
+type clientCounts struct {
+	captured      uint64
+	flushes       uint64
+	flushTimeouts uint64
+	flushNanos    uint64
+}
+
+// flushed counts a flush started at start, which timed out unless ok.
+func (c *clientCounts) flushed(start time.Time, ok bool) {
+	atomic.AddUint64(&c.flushes, 1)
+	atomic.AddUint64(&c.flushNanos, uint64(time.Since(start)))
+	if !ok {
+		atomic.AddUint64(&c.flushTimeouts, 1)
+	}
+}
+
+// An eventTrail records the decisions taken about an event by the client,
+// logged as a single line keyed by the ID of the event, to find out where an
+// event went. Transports log what happens next with the same ID. A nil trail,
+// used unless Debug is enabled, records nothing.
+type eventTrail struct {
+	steps []string
+}
+
+// newEventTrail returns a trail if options enable Debug, or nil.
+func newEventTrail(options ClientOptions) *eventTrail {
+	if !options.Debug {
+		return nil
+	}
+	return &eventTrail{}
+}
+
+// add records a decision.
+func (t *eventTrail) add(step string) {
+	if t == nil {
+		return
+	}
+	t.steps = append(t.steps, step)
+}
+
+// log logs the decisions about the event with the given ID.
+func (t *eventTrail) log(id EventID) {
+	if t == nil {
+		return
+	}
+	Logger.Printf("Event %s: %s", id, strings.Join(t.steps, ", "))
+}
+
+// rejection returns the reason why the server did not accept the event of
+// response, if it did not.
+func rejection(response *http.Response) (DropReason, bool) {
+	switch {
+	case response.StatusCode == http.StatusTooManyRequests:
+		return DropRateLimit, true
+	case response.StatusCode >= 400:
+		return DropServerRejected, true
+	}
+	return "", false
+}
+
+// A DiagnosticReport describes the state of a client, to find out why events
+// do not show up in Sentry. Counts are totals since the client was created.
+type DiagnosticReport struct {
+	// SDKVersion is the version of the SDK.
+	SDKVersion string `json:"sdk_version"`
+	// Dsn is the DSN of the client, without its secret key, or empty if
+	// there is no client or it has no DSN, in which case events are
+	// discarded.
+	Dsn string `json:"dsn,omitempty"`
+	// Enabled reports whether the client sends events: it has a DSN and is
+	// not closed.
+	Enabled bool `json:"enabled"`
+	// Release and Environment are those set on events.
+	Release     string `json:"release,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	// ReleaseSource is where the release was found: "options" if set in
+	// ClientOptions, otherwise "env", "buildinfo", "git" or "executable" as
+	// detected, or empty if none was found.
+	ReleaseSource string `json:"release_source,omitempty"`
+	// SampleRate is the effective sample rate of error events.
+	SampleRate float64 `json:"sample_rate"`
+	// Transport is the type of the transport of the client.
+	Transport string `json:"transport,omitempty"`
+	// QueueDepth is the number of events buffered by the transport, waiting
+	// to be sent.
+	QueueDepth int `json:"queue_depth"`
+	// Captured counts the events captured with the client.
+	Captured uint64 `json:"captured"`
+	// Sent counts the events accepted by Sentry.
+	Sent uint64 `json:"sent"`
+	// Dropped counts the dropped events by reason.
+	Dropped map[DropReason]uint64 `json:"dropped"`
+	// Flushes counts the calls to Flush and FlushWithContext, and
+	// FlushTimeouts those that did not complete in time. FlushDuration is
+	// the total time spent flushing.
+	Flushes       uint64        `json:"flushes"`
+	FlushTimeouts uint64        `json:"flush_timeouts"`
+	FlushDuration time.Duration `json:"flush_duration"`
+	// RateLimits maps the categories of events that Sentry is currently
+	// rate limiting, like "error" or "transaction", to when the limit ends.
+	RateLimits map[string]time.Time `json:"rate_limits,omitempty"`
+	// Integrations are the names of the integrations installed on the
+	// client.
+	Integrations []string `json:"integrations"`
+}
+
+// diagnosticsReporter is implemented by transports that report their state
+// in a DiagnosticReport.
+type diagnosticsReporter interface {
+	diagnose(report *DiagnosticReport)
+}
+
+// Diagnose returns a report of the state of the client of the current hub.
+// See Client.Diagnose.
+func Diagnose() DiagnosticReport {
+	client := CurrentHub().Client()
+	if client == nil {
+		return DiagnosticReport{SDKVersion: Version, Dropped: map[DropReason]uint64{}}
+	}
+	return client.Diagnose()
+}
+
+// Diagnose returns a report of the state of the client: its configuration,
+// the state of its transport, and how many events were dropped and why. It
+// helps finding out why events do not show up in Sentry, for example logged
+// at startup or served on a debug endpoint:
+//
+//	report := sentry.Diagnose()
+//	b, _ := json.MarshalIndent(report, "", "  ")
+//	log.Printf("Sentry diagnostics: %s", b)
+//
+// The queue depth, rate limits, sent events and transport drops are only
+// reported by the transports of the SDK.
+func (client *Client) Diagnose() DiagnosticReport {
+	options := client.Options()
+	report := DiagnosticReport{
+		SDKVersion:    Version,
+		Enabled:       !client.disabled() && atomic.LoadInt32(&client.closed) == 0,
+		Release:       options.Release,
+		Environment:   options.Environment,
+		ReleaseSource: client.releaseSource,
+		SampleRate:    sampleRate(options),
+		Dropped:       map[DropReason]uint64{},
+		Integrations:  client.listIntegrations(),
+
+		Captured:      atomic.LoadUint64(&client.counts.captured),
+		Flushes:       atomic.LoadUint64(&client.counts.flushes),
+		FlushTimeouts: atomic.LoadUint64(&client.counts.flushTimeouts),
+		FlushDuration: time.Duration(atomic.LoadUint64(&client.counts.flushNanos)),
+	}
+	if client.dsn != nil {
+		dsn := *client.dsn
+		dsn.secretKey = ""
+		report.Dsn = dsn.String()
+	}
+	if client.Transport != nil {
+		report.Transport = fmt.Sprintf("%T", client.Transport)
+	}
+	client.drops.addTo(report.Dropped)
+	if r, ok := client.Transport.(diagnosticsReporter); ok {
+		r.diagnose(&report)
+	}
+	sort.Strings(report.Integrations)
+	return report
+}
+
+// CaptureDiagnostics captures the report of Diagnose as an info message event,
+// using the current hub. See Hub.CaptureDiagnostics.
+func CaptureDiagnostics() *EventID {
+	return CurrentHub().CaptureDiagnostics()
+}
+
+// CaptureDiagnostics captures the report of the bound client as an info
+// message event, with the report in the "diagnostics" context. It returns the
+// ID of the event, or nil if it is not sent, for example because the SDK is
+// disabled.
+func (hub *Hub) CaptureDiagnostics() *EventID {
+	client := hub.Client()
+	if client == nil {
+		return nil
+	}
+	event := NewEvent()
+	event.Level = LevelInfo
+	event.Message = "Sentry SDK diagnostics"
+	event.Contexts["diagnostics"] = client.Diagnose()
+	return hub.CaptureEvent(event)
+}
+
+// addRateLimits adds the categories of limits that are in effect to the report.
+func (report *DiagnosticReport) addRateLimits(limits ratelimit.Map) {
+	now := time.Now()
+	for category, deadline := range limits {
+		if !time.Time(deadline).After(now) {
+			continue
+		}
+		if report.RateLimits == nil {
+			report.RateLimits = make(map[string]time.Time)
+		}
+		name := string(category)
+		if category == ratelimit.CategoryAll {
+			name = "all"
+		}
+		report.RateLimits[name] = time.Time(deadline)
+	}
+}
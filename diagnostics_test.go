@@ -0,0 +1,228 @@
+package sentry
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiagnose(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "60")
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientOptions{
+		Dsn:         strings.Replace(server.URL, "://", "://public:secret@", 1) + "/1",
+		Transport:   NewHTTPSyncTransport(),
+		Release:     "release",
+		Environment: "environment",
+		BeforeSend: func(event *Event, hint *EventHint) *Event {
+			if event.Message == "drop" {
+				return nil
+			}
+			return event
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := NewScope()
+
+	client.CaptureMessage("drop", nil, scope)
+	client.CaptureMessage("sent", nil, scope)
+	status = http.StatusBadRequest
+	client.CaptureMessage("rejected", nil, scope)
+	status = http.StatusTooManyRequests
+	client.CaptureMessage("limited", nil, scope)
+	client.CaptureMessage("backing off", nil, scope)
+
+	report := client.Diagnose()
+	assertEqual(t, report.SDKVersion, Version)
+	assertEqual(t, report.Dsn, strings.Replace(server.URL, "://", "://public@", 1)+"/1")
+	assertEqual(t, report.Enabled, true)
+	assertEqual(t, report.Release, "release")
+	assertEqual(t, report.Environment, "environment")
+	assertEqual(t, report.SampleRate, 1.0)
+	assertEqual(t, report.Transport, "*sentry.HTTPSyncTransport")
+	assertEqual(t, report.Captured, uint64(5))
+	assertEqual(t, report.Sent, uint64(1))
+	if diff := cmp.Diff(map[DropReason]uint64{
+		DropBeforeSend:     1,
+		DropServerRejected: 1,
+		DropRateLimit:      2,
+	}, report.Dropped); diff != "" {
+		t.Errorf("dropped events mismatch (-want +got):\n%s", diff)
+	}
+	if deadline, ok := report.RateLimits["all"]; !ok || time.Until(deadline) <= 0 {
+		t.Errorf("got rate limits %v, want all categories limited", report.RateLimits)
+	}
+	if len(report.Integrations) == 0 {
+		t.Error("no integrations reported")
+	}
+
+	client.Close(time.Second)
+	assertEqual(t, client.Diagnose().Enabled, false)
+}
+
+func TestDiagnoseClientClosed(t *testing.T) {
+	client, err := NewClient(ClientOptions{Transport: &TransportMock{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(client, NewScope())
+	client.Close(time.Second)
+
+	if id := hub.CaptureMessage("after"); id != nil {
+		t.Errorf("got event ID %s after Close, want nil", *id)
+	}
+	hub.CaptureException(errors.New("after"))
+	hub.AddBreadcrumb(&Breadcrumb{Message: "after"}, nil)
+
+	report := client.Diagnose()
+	assertEqual(t, report.Captured, uint64(2))
+	if diff := cmp.Diff(map[DropReason]uint64{DropClientClosed: 2}, report.Dropped); diff != "" {
+		t.Errorf("dropped events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiagnoseQueueDepth(t *testing.T) {
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer server.Close()
+
+	transport := NewHTTPTransport()
+	transport.BufferSize = 2
+	client, err := NewClient(ClientOptions{
+		Dsn:       strings.Replace(server.URL, "://", "://public@", 1) + "/1",
+		Transport: NewMultiTransport(transport),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close(time.Second)
+
+	// The worker blocks on the first event, the next two fill the buffer.
+	client.CaptureMessage("1", nil, nil)
+	<-received
+	for _, message := range []string{"2", "3", "4"} {
+		client.CaptureMessage(message, nil, nil)
+	}
+
+	report := client.Diagnose()
+	close(release)
+	assertEqual(t, report.QueueDepth, 2)
+	assertEqual(t, report.Dropped, map[DropReason]uint64{DropQueueOverflow: 1})
+}
+
+func TestCaptureDiagnostics(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(client, NewScope())
+
+	if hub.CaptureDiagnostics() == nil {
+		t.Fatal("diagnostics not captured")
+	}
+	event := transport.lastEvent
+	assertEqual(t, event.Level, LevelInfo)
+	report, ok := event.Contexts["diagnostics"].(DiagnosticReport)
+	if !ok {
+		t.Fatalf("got diagnostics context %#v, want a DiagnosticReport", event.Contexts["diagnostics"])
+	}
+	assertEqual(t, report.Transport, "*sentry.TransportMock")
+
+	assertEqual(t, NewHub(nil, NewScope()).CaptureDiagnostics(), (*EventID)(nil))
+}
+
+func TestEventTrail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	defer Logger.SetOutput(ioutil.Discard)
+
+	var debug bytes.Buffer
+	client, err := NewClient(ClientOptions{
+		Dsn:         strings.Replace(server.URL, "://", "://public@", 1) + "/1",
+		Transport:   NewHTTPSyncTransport(),
+		Debug:       true,
+		DebugWriter: &debug,
+		BeforeSend: func(event *Event, hint *EventHint) *Event {
+			if event.Message == "drop" {
+				return nil
+			}
+			return event
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := client.CaptureMessage("sent", nil, nil)
+	dropped := EventID("0123456789abcdef0123456789abcdef")
+	client.CaptureEvent(&Event{EventID: dropped, Message: "drop"}, nil, nil)
+	for _, line := range []string{
+		"Event " + string(*sent) + ": sampled in by SampleRate, kept by event processors, kept by BeforeSend, handed to *sentry.HTTPSyncTransport\n",
+		"Event " + string(*sent) + " sent with status 200 OK\n",
+		"Event " + string(dropped) + ": sampled in by SampleRate, kept by event processors, dropped by BeforeSend\n",
+	} {
+		if !strings.Contains(debug.String(), line) {
+			t.Errorf("debug output does not contain %q:\n%s", line, debug.String())
+		}
+	}
+}
+
+func TestEventTrailEarlyDrops(t *testing.T) {
+	defer Logger.SetOutput(ioutil.Discard)
+
+	var debug bytes.Buffer
+	client, err := NewClient(ClientOptions{
+		Transport:   &TransportMock{},
+		SampleRate:  1e-12,
+		Debug:       true,
+		DebugWriter: &debug,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.CaptureMessage("sampled out", nil, nil)
+	client.Close(time.Second)
+	client.CaptureEvent(&Event{Message: "closed"}, nil, nil)
+	NewHub(client, NewScope()).CaptureMessage("closed")
+
+	for _, pattern := range []string{
+		`Event [0-9a-f]{32}: dropped: sampled out by SampleRate\n`,
+		`Event [0-9a-f]{32}: dropped: client closed\n(.|\n)*Event [0-9a-f]{32}: dropped: client closed\n`,
+	} {
+		if !regexp.MustCompile(pattern).MatchString(debug.String()) {
+			t.Errorf("debug output does not match %q:\n%s", pattern, debug.String())
+		}
+	}
+}
+
+func TestEventTrailWithoutDebug(t *testing.T) {
+	if trail := newEventTrail(ClientOptions{}); trail != nil {
+		t.Errorf("got trail %v without Debug, want nil", trail)
+	}
+}
@@ -31,6 +31,11 @@ The Capture* functions report messages and errors to Sentry.
 Use similarly named functions in the Hub for concurrent programs like web
 servers.
 
+Hubs, scopes, clients and the transports of the SDK are safe for concurrent
+use by multiple goroutines. Still, the scope of a hub is shared by all its
+users, so give each goroutine its own hub with Hub.Clone to keep their data
+apart.
+
 Performance Monitoring
 
 You can use Sentry to monitor your application's performance. More information
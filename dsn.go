@@ -170,10 +170,31 @@ func (dsn Dsn) getAPIURL(s string) *url.URL {
 	return parsedURL
 }
 
+// relayEnvelopeURL returns the URL of the envelope endpoint of the project
+// associated with the DSN on the Relay at relay, authenticated with the public
+// key of the DSN.
+func (dsn Dsn) relayEnvelopeURL(relay *url.URL) *url.URL {
+	u := *relay
+	u.Path = fmt.Sprintf("%s/api/%d/envelope/", strings.TrimSuffix(u.Path, "/"), dsn.projectID)
+	u.RawPath = ""
+	u.RawQuery = url.Values{
+		"sentry_key":     {dsn.publicKey},
+		"sentry_version": {apiVersion},
+		"sentry_client":  {"sentry.go/" + Version},
+	}.Encode()
+	return &u
+}
+
 // RequestHeaders returns all the necessary headers that have to be used in the transport.
 func (dsn Dsn) RequestHeaders() map[string]string {
+	return dsn.requestHeaders(apiVersion, time.Now())
+}
+
+// requestHeaders returns the headers of requests to the Sentry protocol
+// version, authenticated at the time now.
+func (dsn Dsn) requestHeaders(version string, now time.Time) map[string]string {
 	auth := fmt.Sprintf("Sentry sentry_version=%s, sentry_timestamp=%d, "+
-		"sentry_client=sentry.go/%s, sentry_key=%s", apiVersion, time.Now().Unix(), Version, dsn.publicKey)
+		"sentry_client=sentry.go/%s, sentry_key=%s", version, now.Unix(), Version, dsn.publicKey)
 
 	if dsn.secretKey != "" {
 		auth = fmt.Sprintf("%s, sentry_secret=%s", auth, dsn.secretKey)
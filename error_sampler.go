@@ -0,0 +1,143 @@
+package sentry
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// An ErrorSampler decides whether to send an error or message event. It runs
+// after SampleRate and EventSampler, once the event has been completed with
+// the data of the scope, and before BeforeSend. Transactions and check-ins are
+// not sampled by it.
+//
+// Implementations must be safe for concurrent use by multiple goroutines.
+type ErrorSampler interface {
+	Sample(event *Event) bool
+}
+
+// The ErrorSamplerFunc type is an adapter to allow the use of ordinary
+// functions as an ErrorSampler.
+type ErrorSamplerFunc func(event *Event) bool
+
+var _ ErrorSampler = ErrorSamplerFunc(nil)
+
+func (f ErrorSamplerFunc) Sample(event *Event) bool {
+	return f(event)
+}
+
+// maxThrottleKeys bounds the number of token buckets kept by an ErrorThrottle.
+const maxThrottleKeys = 1000
+
+// An ErrorThrottle is an ErrorSampler that limits the rate of identical
+// events, so that an error storm in a hot loop does not send thousands of
+// events per second. Events are identical when they have the same fingerprint
+// or, without a custom fingerprint, the same type and value of the outermost
+// exception, or the same message, or message with placeholders for events
+// with a log entry.
+//
+// Each kind of event has a token bucket: an event is sent if a token is
+// available, and tokens are added at a fixed rate, up to a maximum burst.
+// Time is measured with the timestamps of events.
+type ErrorThrottle struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+var _ ErrorSampler = (*ErrorThrottle)(nil)
+
+// NewErrorThrottle returns an ErrorThrottle that sends, for every kind of
+// event, up to burst events at once and rate events per second on average:
+//
+//	sentry.Init(sentry.ClientOptions{
+//		// At most 10 identical events at once, then 1 per minute.
+//		ErrorSampler: sentry.NewErrorThrottle(1.0/60, 10),
+//	})
+func NewErrorThrottle(rate float64, burst int) *ErrorThrottle {
+	return &ErrorThrottle{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Sample implements ErrorSampler.
+func (t *ErrorThrottle) Sample(event *Event) bool {
+	key := throttleKey(event)
+	now := event.Timestamp
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.buckets[key]
+	if !ok {
+		if len(t.buckets) >= maxThrottleKeys {
+			t.evict(now)
+		}
+		b = &tokenBucket{tokens: t.burst, updated: now}
+		t.buckets[key] = b
+	}
+	b.refill(now, t.rate, t.burst)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// evict forgets the buckets that are full again, which behave like new ones,
+// or all buckets if none is full.
+func (t *ErrorThrottle) evict(now time.Time) {
+	for key, b := range t.buckets {
+		b.refill(now, t.rate, t.burst)
+		if b.tokens >= t.burst {
+			delete(t.buckets, key)
+		}
+	}
+	if len(t.buckets) >= maxThrottleKeys {
+		t.buckets = make(map[string]*tokenBucket)
+	}
+}
+
+// tokenBucket holds the tokens available for a kind of event.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time, rate, burst float64) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * rate
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+		b.updated = now
+	}
+}
+
+// throttleKey returns the key identifying identical events.
+func throttleKey(event *Event) string {
+	var key string
+	switch {
+	case len(event.Exception) > 0:
+		e := event.Exception[len(event.Exception)-1]
+		key = e.Type + "\x00" + e.Value
+	case event.LogEntry != nil:
+		key = event.LogEntry.Message
+	default:
+		key = event.Message
+	}
+	if len(event.Fingerprint) == 0 {
+		return key
+	}
+	fingerprint := strings.Join(event.Fingerprint, "\x00")
+	for _, part := range event.Fingerprint {
+		if part == DefaultFingerprint {
+			// The fingerprint extends the default grouping.
+			return fingerprint + "\x00" + key
+		}
+	}
+	return fingerprint
+}
@@ -0,0 +1,63 @@
+package sentry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestErrorThrottle(t *testing.T) {
+	transport := &TransportMock{}
+	clock := &ClockMock{now: time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)}
+	client, err := NewClient(ClientOptions{
+		Transport:    transport,
+		Clock:        clock,
+		ErrorSampler: NewErrorThrottle(1, 2),
+		// Without Dedupe, which drops consecutive identical events.
+		Integrations: func([]Integration) []Integration { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(client, NewScope())
+
+	for i := 0; i < 5; i++ {
+		hub.CaptureException(errors.New("storm"))
+	}
+	assertEqual(t, len(transport.Events()), 2, "identical events beyond the burst are dropped")
+
+	hub.CaptureException(errors.New("other"))
+	hub.CaptureMessage("storm")
+	assertEqual(t, len(transport.Events()), 4, "different events have their own bucket")
+
+	clock.Advance(time.Second)
+	hub.CaptureException(errors.New("storm"))
+	hub.CaptureException(errors.New("storm"))
+	assertEqual(t, len(transport.Events()), 5, "tokens are added at the rate")
+
+	// Transactions are not throttled.
+	for i := 0; i < 3; i++ {
+		hub.CaptureEvent(&Event{Type: transactionType})
+	}
+	assertEqual(t, len(transport.Events()), 8)
+}
+
+func TestThrottleKey(t *testing.T) {
+	exception := []Exception{{Type: "*errors.errorString", Value: "cause"}, {Type: "*fmt.wrapError", Value: "storm"}}
+	tests := []struct {
+		name  string
+		event *Event
+		want  string
+	}{
+		{"Message", &Event{Message: "hello"}, "hello"},
+		{"Exception", &Event{Message: "ignored", Exception: exception}, "*fmt.wrapError\x00storm"},
+		{"Fingerprint", &Event{Exception: exception, Fingerprint: []string{"payment"}}, "payment"},
+		{"DefaultFingerprint", &Event{Exception: exception, Fingerprint: []string{DefaultFingerprint, "acme"}}, DefaultFingerprint + "\x00acme\x00*fmt.wrapError\x00storm"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assertEqual(t, throttleKey(tt.event), tt.want)
+		})
+	}
+}
@@ -205,7 +205,7 @@ func TestIntegration(t *testing.T) {
 	opts := cmp.Options{
 		cmpopts.IgnoreFields(
 			sentry.Event{},
-			"Contexts", "EventID", "Extra", "Platform",
+			"Contexts", "EventID", "Extra", "Modules", "Platform",
 			"Release", "Sdk", "ServerName", "Tags", "Timestamp",
 		),
 		cmpopts.IgnoreMapEntries(func(k string, v string) bool {
@@ -0,0 +1,318 @@
+package sentry
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// envelopeFileExt is the extension of the files written by a FileTransport.
+const envelopeFileExt = ".envelopes"
+
+// Default limits of the files written by a FileTransport.
+const (
+	defaultMaxEnvelopeFileSize = 1 << 20 // 1 MiB
+	defaultMaxEnvelopeFiles    = 10
+)
+
+// FileTransportOptions configure a FileTransport.
+type FileTransportOptions struct {
+	// Dir is the directory where envelopes are written. It is created if it
+	// does not exist.
+	Dir string
+	// MaxFileSize is the size in bytes after which a new file is started.
+	// Defaults to 1 MiB.
+	MaxFileSize int64
+	// MaxFiles is the number of files kept in Dir. When a new file is
+	// started, the oldest files are removed, and their events lost.
+	// Defaults to 10.
+	MaxFiles int
+}
+
+// A FileTransport is a Transport that writes events as envelopes to files in
+// a directory, instead of sending them to Sentry, for environments that
+// cannot reach Sentry. Files hold envelopes one after the other, each on
+// three lines: the envelope header, the item header and the payload. Use
+// ReplayDirectory to send the events later from a host that can reach
+// Sentry.
+//
+//	sentry.Init(sentry.ClientOptions{
+//		Transport: sentry.NewFileTransport(sentry.FileTransportOptions{
+//			Dir: "/var/spool/sentry",
+//		}),
+//	})
+type FileTransport struct {
+	dir         string
+	maxFileSize int64
+	maxFiles    int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileTransport returns a new FileTransport.
+func NewFileTransport(options FileTransportOptions) *FileTransport {
+	t := &FileTransport{
+		dir:         options.Dir,
+		maxFileSize: options.MaxFileSize,
+		maxFiles:    options.MaxFiles,
+	}
+	if t.maxFileSize <= 0 {
+		t.maxFileSize = defaultMaxEnvelopeFileSize
+	}
+	if t.maxFiles <= 0 {
+		t.maxFiles = defaultMaxEnvelopeFiles
+	}
+	return t
+}
+
+// Configure is called by the Client itself, providing it it's own ClientOptions.
+func (t *FileTransport) Configure(options ClientOptions) {}
+
+// SendEvent writes the event to the current file.
+func (t *FileTransport) SendEvent(event *Event) {
+	body := getRequestBodyFromEvent(event)
+	if body == nil {
+		return
+	}
+	itemType := event.Type
+	if itemType == "" {
+		itemType = "event"
+	}
+	envelope, err := envelopeFromBody(event.EventID, itemType, time.Now(), body)
+	if err != nil {
+		Logger.Printf("Could not create envelope: %v", err)
+		return
+	}
+	defer putBuffer(envelope)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil || t.size+int64(envelope.Len()) > t.maxFileSize {
+		if err := t.rotate(); err != nil {
+			Logger.Printf("Could not write %s to %s: %v", describeEvent(event), t.dir, err)
+			return
+		}
+	}
+	n, err := t.file.Write(envelope.Bytes())
+	t.size += int64(n)
+	if err != nil {
+		Logger.Printf("Could not write %s to %s: %v", describeEvent(event), t.file.Name(), err)
+		return
+	}
+	Logger.Printf("Wrote %s [%s] to %s", describeEvent(event), event.EventID, t.file.Name())
+}
+
+// rotate closes the current file, if any, starts a new one and removes the
+// files beyond the limit.
+func (t *FileTransport) rotate() error {
+	if t.file != nil {
+		if err := t.file.Close(); err != nil {
+			Logger.Printf("Could not close %s: %v", t.file.Name(), err)
+		}
+		t.file = nil
+	}
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return err
+	}
+	// File names sort in the order files were started.
+	name := filepath.Join(t.dir, fmt.Sprintf("%020d%s", time.Now().UnixNano(), envelopeFileExt))
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	t.file, t.size = f, 0
+
+	files, err := envelopeFiles(t.dir)
+	if err != nil {
+		return err
+	}
+	for len(files) > t.maxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			return err
+		}
+		Logger.Printf("Removed %s: too many files in %s", files[0], t.dir)
+		files = files[1:]
+	}
+	return nil
+}
+
+// Flush commits the current file to stable storage. Events are written as
+// they are sent, so Flush does not wait.
+func (t *FileTransport) Flush(timeout time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return true
+	}
+	if err := t.file.Sync(); err != nil {
+		Logger.Printf("Could not sync %s: %v", t.file.Name(), err)
+		return false
+	}
+	return true
+}
+
+// Close closes the current file. Events sent later are written to a new
+// file.
+func (t *FileTransport) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return
+	}
+	if err := t.file.Close(); err != nil {
+		Logger.Printf("Could not close %s: %v", t.file.Name(), err)
+	}
+	t.file = nil
+}
+
+// envelopeFiles returns the paths of the files of envelopes in dir, from the
+// oldest to the newest.
+func envelopeFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), envelopeFileExt) {
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ReplayDirectory sends the events written to dir by a FileTransport with the
+// given transport, typically the transport of a client that can reach Sentry:
+//
+//	client, err := sentry.NewClient(sentry.ClientOptions{Dsn: "your-public-dsn"})
+//	// ...
+//	err = sentry.ReplayDirectory("/var/spool/sentry", client.Transport)
+//
+// Events are sent one at a time, each flushed before the next is sent, so
+// that none overflows the buffer of the transport. A file is removed only
+// once all its events have been accepted. It returns an error if a file
+// cannot be read or removed, if the transport does not flush an event in
+// time or if it reports having dropped one, for example because Sentry is
+// not reachable or rate limits the events, in which case the file and the
+// remaining ones are kept for a later attempt. The events of a file that
+// were sent before are sent again then, and deduplicated by Sentry by their
+// ID. Malformed envelopes are logged and skipped.
+func ReplayDirectory(dir string, transport Transport) error {
+	files, err := envelopeFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		dropped := droppedEvents(transport)
+		if err := replayFile(name, transport); err != nil {
+			return err
+		}
+		if n := droppedEvents(transport) - dropped; n > 0 {
+			return fmt.Errorf("sentry: %d events of %s dropped by the transport", n, name)
+		}
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// droppedEvents returns the number of events dropped by transport, or 0 if
+// it does not report them.
+func droppedEvents(transport Transport) uint64 {
+	r, ok := transport.(diagnosticsReporter)
+	if !ok {
+		return 0
+	}
+	report := DiagnosticReport{Dropped: make(map[DropReason]uint64)}
+	r.diagnose(&report)
+	var n uint64
+	for _, count := range report.Dropped {
+		n += count
+	}
+	return n
+}
+
+// replayFile sends the events of the envelopes of a file with transport,
+// flushing each.
+func replayFile(name string, transport Transport) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<30)
+	for {
+		var lines [3][]byte
+		for i := range lines {
+			if !scanner.Scan() {
+				if i > 0 {
+					Logger.Printf("Skipped a truncated envelope at the end of %s", name)
+				}
+				return scanner.Err()
+			}
+			lines[i] = append([]byte(nil), scanner.Bytes()...)
+		}
+		event, err := eventFromEnvelope(lines[1], lines[2])
+		if err != nil {
+			Logger.Printf("Skipped a malformed envelope of %s: %v", name, err)
+			continue
+		}
+		transport.SendEvent(event)
+		if !transport.Flush(defaultTimeout) {
+			return fmt.Errorf("sentry: events of %s not flushed in time", name)
+		}
+	}
+}
+
+// eventFromEnvelope decodes the event of an envelope item.
+func eventFromEnvelope(itemHeader, payload []byte) (*Event, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(itemHeader, &header); err != nil {
+		return nil, err
+	}
+	if header.Type != checkInType {
+		var event Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+	var checkIn struct {
+		CheckInID   EventID       `json:"check_in_id"`
+		MonitorSlug string        `json:"monitor_slug"`
+		Status      CheckInStatus `json:"status"`
+		Duration    float64       `json:"duration"`
+		Release     string        `json:"release"`
+		Environment string        `json:"environment"`
+	}
+	if err := json.Unmarshal(payload, &checkIn); err != nil {
+		return nil, err
+	}
+	return &Event{
+		Type:        checkInType,
+		EventID:     checkIn.CheckInID,
+		Release:     checkIn.Release,
+		Environment: checkIn.Environment,
+		CheckIn: &CheckIn{
+			ID:          checkIn.CheckInID,
+			MonitorSlug: checkIn.MonitorSlug,
+			Status:      checkIn.Status,
+			Duration:    time.Duration(checkIn.Duration * float64(time.Second)),
+		},
+	}, nil
+}
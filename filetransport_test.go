@@ -0,0 +1,145 @@
+package sentry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileTransportReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sentry-envelopes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	transport := NewFileTransport(FileTransportOptions{Dir: filepath.Join(dir, "spool")})
+	client, err := NewClient(ClientOptions{Transport: transport, TracesSampleRate: 1.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(client, NewScope())
+	hub.CaptureMessage("offline")
+	hub.CaptureCheckIn(&CheckIn{MonitorSlug: "backup", Status: CheckInStatusOK, Duration: 2 * time.Second})
+	assertEqual(t, transport.Flush(time.Second), true)
+	transport.Close()
+
+	files, err := envelopeFiles(filepath.Join(dir, "spool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(files), 1)
+
+	replayed := &TransportMock{}
+	if err := ReplayDirectory(filepath.Join(dir, "spool"), replayed); err != nil {
+		t.Fatal(err)
+	}
+	events := replayed.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	assertEqual(t, events[0].Message, "offline")
+	assertEqual(t, events[0].Level, LevelInfo)
+	assertEqual(t, events[1].Type, checkInType)
+	assertEqual(t, *events[1].CheckIn, CheckIn{
+		ID:          events[1].EventID,
+		MonitorSlug: "backup",
+		Status:      CheckInStatusOK,
+		Duration:    2 * time.Second,
+	})
+
+	// Replayed files are removed.
+	files, err = envelopeFiles(filepath.Join(dir, "spool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(files), 0)
+}
+
+func TestFileTransportRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sentry-envelopes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	transport := NewFileTransport(FileTransportOptions{Dir: dir, MaxFileSize: 1, MaxFiles: 2})
+	defer transport.Close()
+	for _, id := range []EventID{"1", "2", "3"} {
+		transport.SendEvent(&Event{EventID: id, Message: "full"})
+	}
+
+	// Every envelope is larger than the maximum size of a file, so each is in
+	// a file of its own, and only the two newest files are kept.
+	files, err := envelopeFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(files), 2)
+	replayed := &TransportMock{}
+	if err := ReplayDirectory(dir, replayed); err != nil {
+		t.Fatal(err)
+	}
+	events := replayed.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	assertEqual(t, events[0].EventID, EventID("2"))
+	assertEqual(t, events[1].EventID, EventID("3"))
+}
+
+func TestReplayDirectoryHTTPTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sentry-envelopes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	spool := NewFileTransport(FileTransportOptions{Dir: dir})
+	for i := 0; i < 5; i++ {
+		spool.SendEvent(NewEvent())
+	}
+	spool.Close()
+
+	var status int32 = http.StatusInternalServerError
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	// The buffer of the transport is smaller than the number of events.
+	transport := NewHTTPTransport()
+	transport.BufferSize = 1
+	transport.Configure(ClientOptions{Dsn: strings.Replace(server.URL, "://", "://public@", 1) + "/1"})
+	defer transport.Close()
+
+	// Rejected events are kept.
+	if err := ReplayDirectory(dir, transport); err == nil {
+		t.Error("ReplayDirectory succeeded, want an error")
+	}
+	files, err := envelopeFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(files), 1)
+
+	atomic.StoreInt32(&status, http.StatusOK)
+	atomic.StoreInt32(&received, 0)
+	if err := ReplayDirectory(dir, transport); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, atomic.LoadInt32(&received), int32(5))
+	files, err = envelopeFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(files), 0)
+}
@@ -0,0 +1,36 @@
+package sentry
+
+// DefaultFingerprint is the fingerprint part that stands for the default
+// grouping of Sentry. Include it in a fingerprint to split the default groups
+// further instead of replacing them:
+//
+//	// Group by the default grouping, and then by tenant.
+//	event.SetFingerprint([]string{sentry.DefaultFingerprint, tenant})
+const DefaultFingerprint = "{{ default }}"
+
+// SetFingerprint sets the fingerprint of the event, which controls how Sentry
+// groups it with other events. Events with the same fingerprint are grouped
+// together. A fingerprint set on the event takes precedence over the one of
+// the scope.
+func (e *Event) SetFingerprint(fingerprint []string) {
+	e.Fingerprint = append([]string(nil), fingerprint...)
+}
+
+// FingerprintFromError returns a fingerprint made of the types of err and of
+// the errors it wraps, outermost first, as they are reported in exceptions.
+// Use it to group errors by type, regardless of their messages and of where
+// they were captured:
+//
+//	hub.WithScope(func(scope *sentry.Scope) {
+//		scope.SetFingerprint(sentry.FingerprintFromError(err))
+//		hub.CaptureException(err)
+//	})
+//
+// It returns nil if err is nil.
+func FingerprintFromError(err error) []string {
+	var fingerprint []string
+	walkErrors(err, maxErrorDepth, func(err error) {
+		fingerprint = append(fingerprint, errorType(err))
+	})
+	return fingerprint
+}
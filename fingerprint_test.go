@@ -0,0 +1,36 @@
+package sentry
+
+import (
+	"testing"
+)
+
+func TestEventSetFingerprint(t *testing.T) {
+	fingerprint := []string{DefaultFingerprint, "tenant-1"}
+	event := NewEvent()
+	event.SetFingerprint(fingerprint)
+	fingerprint[1] = "modified"
+
+	assertEqual(t, event.Fingerprint, []string{"{{ default }}", "tenant-1"})
+}
+
+func TestEventFingerprintTakesPrecedenceOverScope(t *testing.T) {
+	client, _, transport := setupClientTest()
+	scope := NewScope()
+	scope.SetFingerprint([]string{"scope"})
+	event := NewEvent()
+	event.Message = "message"
+	event.SetFingerprint([]string{DefaultFingerprint, "event"})
+
+	client.CaptureEvent(event, nil, scope)
+
+	assertEqual(t, transport.lastEvent.Fingerprint, []string{"{{ default }}", "event"})
+}
+
+func TestFingerprintFromError(t *testing.T) {
+	err := wrappedError{&customErrWithCause{cause: &customErr{}}}
+
+	got := FingerprintFromError(err)
+	want := []string{"github.com/getsentry/sentry-go.wrappedError", "*github.com/getsentry/sentry-go.customErrWithCause", "*github.com/getsentry/sentry-go.customErr"}
+	assertEqual(t, got, want)
+	assertEqual(t, FingerprintFromError(nil), []string(nil))
+}
@@ -0,0 +1,77 @@
+package sentry
+
+import (
+	"container/list"
+	"runtime"
+	"sync"
+)
+
+// frameCacheSize is the maximum number of program counters whose frames are
+// cached.
+const frameCacheSize = 4096
+
+// frameCache caches the frames of program counters, so that the stack traces
+// of errors captured repeatedly from the same code do not resolve and classify
+// the same frames again.
+var frameCache = newFrameLRU(frameCacheSize)
+
+// A frameLRU is a bounded cache of the frames of program counters, evicting
+// the least recently used ones. It is safe for concurrent use.
+type frameLRU struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[uintptr]*list.Element
+	// order holds *frameEntry values, the most recently used first.
+	order *list.List
+}
+
+type frameEntry struct {
+	pc     uintptr
+	frames []Frame
+}
+
+func newFrameLRU(capacity int) *frameLRU {
+	return &frameLRU{
+		capacity: capacity,
+		entries:  make(map[uintptr]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// frames returns the frames of pc, a return program counter as returned by
+// runtime.Callers, the innermost first. There are several frames when
+// functions were inlined. The frames are shared: callers must copy them.
+func (c *frameLRU) frames(pc uintptr) []Frame {
+	c.mu.Lock()
+	if e, ok := c.entries[pc]; ok {
+		c.order.MoveToFront(e)
+		frames := e.Value.(*frameEntry).frames
+		c.mu.Unlock()
+		return frames
+	}
+	c.mu.Unlock()
+
+	// Frames are resolved without holding the lock. Concurrent misses for
+	// the same pc resolve the same frames.
+	var frames []Frame
+	callersFrames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		callerFrame, more := callersFrames.Next()
+		frames = append(frames, NewFrame(callerFrame))
+		if !more {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[pc]; !ok {
+		c.entries[pc] = c.order.PushFront(&frameEntry{pc: pc, frames: frames})
+		if c.order.Len() > c.capacity {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*frameEntry).pc)
+		}
+	}
+	return frames
+}
@@ -0,0 +1,57 @@
+package sentry
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFrameLRUResolvesFrames(t *testing.T) {
+	pcs := make([]uintptr, 10)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+
+	var want []Frame
+	callersFrames := runtime.CallersFrames(pcs)
+	for {
+		callerFrame, more := callersFrames.Next()
+		want = append(want, NewFrame(callerFrame))
+		if !more {
+			break
+		}
+	}
+
+	c := newFrameLRU(100)
+	for i := 0; i < 2; i++ {
+		var got []Frame
+		for _, pc := range pcs {
+			got = append(got, c.frames(pc)...)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("frames mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestFrameLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	pcs := make([]uintptr, 3)
+	if n := runtime.Callers(1, pcs); n < 3 {
+		t.Fatalf("got %d program counters, want 3", n)
+	}
+
+	c := newFrameLRU(2)
+	c.frames(pcs[0])
+	c.frames(pcs[1])
+	c.frames(pcs[0])
+	c.frames(pcs[2])
+
+	assertEqual(t, c.order.Len(), 2)
+	if _, ok := c.entries[pcs[1]]; ok {
+		t.Error("least recently used pc was not evicted")
+	}
+	for _, pc := range []uintptr{pcs[0], pcs[2]} {
+		if _, ok := c.entries[pc]; !ok {
+			t.Errorf("pc %#x was evicted", pc)
+		}
+	}
+}
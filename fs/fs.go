@@ -0,0 +1,164 @@
+//go:build go1.16
+// +build go1.16
+
+package sentryfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// tracedFS is an fs.FS whose files are recorded in spans.
+type tracedFS struct {
+	ctx  context.Context
+	fsys fs.FS
+}
+
+// New returns a file system that records the files opened from fsys in
+// "file.read" spans, children of the span in ctx, from the time they are
+// opened until they are closed. Since the methods of fs.FS take no context,
+// wrap fsys for every unit of work, for example for every request:
+//
+//	tmpl, err := template.ParseFS(sentryfs.New(r.Context(), templates), "*.html")
+//
+// Directory listings and file information are not recorded.
+func New(ctx context.Context, fsys fs.FS) fs.FS {
+	return tracedFS{ctx: ctx, fsys: fsys}
+}
+
+// Open implements fs.FS.
+func (t tracedFS) Open(name string) (fs.File, error) {
+	span := startSpan(t.ctx, opRead, name)
+	f, err := t.fsys.Open(name)
+	if err != nil {
+		var c counter
+		c.finish(span, err)
+		return nil, err
+	}
+	if span == nil {
+		return f, nil
+	}
+	file := &tracedFile{File: f, span: span}
+	if d, ok := f.(fs.ReadDirFile); ok {
+		return &tracedDir{tracedFile: file, dir: d}, nil
+	}
+	// Keep the optional methods of the file, that http.FileServer uses to
+	// serve ranges and detect content types.
+	_, isSeeker := f.(io.Seeker)
+	_, isReaderAt := f.(io.ReaderAt)
+	switch {
+	case isSeeker && isReaderAt:
+		return seekerReaderAtFile{file}, nil
+	case isSeeker:
+		return seekerFile{file}, nil
+	case isReaderAt:
+		return readerAtFile{file}, nil
+	}
+	return file, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (t tracedFS) ReadFile(name string) ([]byte, error) {
+	span := startSpan(t.ctx, opRead, name)
+	b, err := fs.ReadFile(t.fsys, name)
+	var c counter
+	c.addRead(len(b), nil)
+	c.finish(span, err)
+	return b, err
+}
+
+// Stat implements fs.StatFS.
+func (t tracedFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(t.fsys, name)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (t tracedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(t.fsys, name)
+}
+
+// tracedFile is an fs.File whose reads are recorded in a span.
+type tracedFile struct {
+	fs.File
+	span  *sentry.Span
+	count counter
+}
+
+func (f *tracedFile) Read(b []byte) (int, error) {
+	n, err := f.File.Read(b)
+	f.count.addRead(n, err)
+	return n, err
+}
+
+func (f *tracedFile) Close() error {
+	err := f.File.Close()
+	f.count.finish(f.span, err)
+	f.span = nil
+	return err
+}
+
+func (f *tracedFile) seek(offset int64, whence int) (int64, error) {
+	return f.File.(io.Seeker).Seek(offset, whence)
+}
+
+func (f *tracedFile) readAt(b []byte, off int64) (int, error) {
+	n, err := f.File.(io.ReaderAt).ReadAt(b, off)
+	f.count.addRead(n, err)
+	return n, err
+}
+
+// seekerFile is a tracedFile for a file that implements io.Seeker.
+type seekerFile struct {
+	*tracedFile
+}
+
+func (f seekerFile) Seek(offset int64, whence int) (int64, error) {
+	return f.seek(offset, whence)
+}
+
+// readerAtFile is a tracedFile for a file that implements io.ReaderAt.
+type readerAtFile struct {
+	*tracedFile
+}
+
+func (f readerAtFile) ReadAt(b []byte, off int64) (int, error) {
+	return f.readAt(b, off)
+}
+
+// seekerReaderAtFile is a tracedFile for a file that implements io.Seeker and
+// io.ReaderAt, like os.File and the files of embed.FS and fstest.MapFS.
+type seekerReaderAtFile struct {
+	*tracedFile
+}
+
+func (f seekerReaderAtFile) Seek(offset int64, whence int) (int64, error) {
+	return f.seek(offset, whence)
+}
+
+func (f seekerReaderAtFile) ReadAt(b []byte, off int64) (int, error) {
+	return f.readAt(b, off)
+}
+
+// tracedDir is a tracedFile for a directory.
+type tracedDir struct {
+	*tracedFile
+	dir fs.ReadDirFile
+}
+
+func (d *tracedDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	return d.dir.ReadDir(n)
+}
+
+var (
+	_ fs.ReadFileFS  = tracedFS{}
+	_ fs.StatFS      = tracedFS{}
+	_ fs.ReadDirFS   = tracedFS{}
+	_ fs.ReadDirFile = (*tracedDir)(nil)
+	_ io.ReadSeeker  = seekerFile{}
+	_ io.ReaderAt    = readerAtFile{}
+	_ io.ReadSeeker  = seekerReaderAtFile{}
+	_ io.ReaderAt    = seekerReaderAtFile{}
+)
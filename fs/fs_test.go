@@ -0,0 +1,78 @@
+//go:build go1.16
+// +build go1.16
+
+package sentryfs_test
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	sentryfs "github.com/getsentry/sentry-go/fs"
+)
+
+func TestFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/index.html": {Data: []byte("<h1>hello</h1>")},
+	}
+	spans := traced(t, func(ctx context.Context) {
+		tfs := sentryfs.New(ctx, fsys)
+		if err := fstest.TestFS(tfs, "templates/index.html"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if len(spans) == 0 {
+		t.Fatal("got no spans")
+	}
+
+	spans = traced(t, func(ctx context.Context) {
+		b, err := fs.ReadFile(sentryfs.New(ctx, fsys), "templates/index.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "<h1>hello</h1>" {
+			t.Errorf("got %q", b)
+		}
+	})
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Op != "file.read" || spans[0].Description != "templates/index.html" || spans[0].Data["file.bytes_read"] != int64(14) {
+		t.Errorf("got span %+v", spans[0])
+	}
+}
+
+func TestFSFileServer(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.html": {Data: []byte("<h1>hello</h1>")},
+	}
+	spans := traced(t, func(ctx context.Context) {
+		f, err := sentryfs.New(ctx, fsys).Open("page.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, ok := f.(io.ReadSeeker); !ok {
+			t.Error("file does not implement io.Seeker")
+		}
+		if _, ok := f.(io.ReaderAt); !ok {
+			t.Error("file does not implement io.ReaderAt")
+		}
+
+		// http.FileServer serves ranges of files that implement io.Seeker.
+		r := httptest.NewRequest("GET", "/page.html", nil)
+		r.Header.Set("Range", "bytes=4-8")
+		w := httptest.NewRecorder()
+		http.FileServer(http.FS(sentryfs.New(ctx, fsys))).ServeHTTP(w, r)
+		if w.Code != http.StatusPartialContent || w.Body.String() != "hello" {
+			t.Errorf("got %d %q, want %d %q", w.Code, w.Body, http.StatusPartialContent, "hello")
+		}
+	})
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+}
@@ -0,0 +1,222 @@
+// Package sentryfs provides Sentry integration for file I/O, recording the
+// reads and writes of files opened within a transaction as spans, to diagnose
+// slow disk operations.
+package sentryfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Span operations of files opened for reading and for writing.
+const (
+	opRead  = "file.read"
+	opWrite = "file.write"
+)
+
+// counter counts the bytes read from and written to a file, and the first
+// error other than io.EOF.
+type counter struct {
+	// read and written are accessed atomically. They come first to be
+	// 64-bit aligned on 32-bit platforms.
+	read    int64
+	written int64
+	failed  int32
+}
+
+func (c *counter) addRead(n int, err error) {
+	atomic.AddInt64(&c.read, int64(n))
+	if err != nil && !errors.Is(err, io.EOF) {
+		atomic.StoreInt32(&c.failed, 1)
+	}
+}
+
+func (c *counter) addWritten(n int, err error) {
+	atomic.AddInt64(&c.written, int64(n))
+	if err != nil {
+		atomic.StoreInt32(&c.failed, 1)
+	}
+}
+
+// finish records the byte counts and the outcome of I/O on span, and finishes
+// it. It is a no-op if span is nil.
+func (c *counter) finish(span *sentry.Span, err error) {
+	if span == nil {
+		return
+	}
+	if n := atomic.LoadInt64(&c.read); n > 0 {
+		span.SetData("file.bytes_read", n)
+	}
+	if n := atomic.LoadInt64(&c.written); n > 0 {
+		span.SetData("file.bytes_written", n)
+	}
+	switch {
+	case err != nil:
+		span.Status = status(err)
+	case atomic.LoadInt32(&c.failed) != 0:
+		span.Status = sentry.SpanStatusInternalError
+	default:
+		span.Status = sentry.SpanStatusOK
+	}
+	span.Finish()
+}
+
+// startSpan starts a span for the I/O on the file with the given path, or
+// returns nil if ctx has no transaction.
+func startSpan(ctx context.Context, op, path string) *sentry.Span {
+	if ctx == nil || sentry.TransactionFromContext(ctx) == nil {
+		return nil
+	}
+	span := sentry.StartSpan(ctx, op, sentry.WithSpanOrigin("auto.file.sentryfs"))
+	span.Description = path
+	span.SetData("file.path", path)
+	return span
+}
+
+// status returns the span status corresponding to a file system error.
+func status(err error) sentry.SpanStatus {
+	switch {
+	case os.IsNotExist(err):
+		return sentry.SpanStatusNotFound
+	case os.IsExist(err):
+		return sentry.SpanStatusAlreadyExists
+	case os.IsPermission(err):
+		return sentry.SpanStatusPermissionDenied
+	}
+	return sentry.SpanStatusInternalError
+}
+
+// File is an *os.File whose I/O is recorded in a span, from the time it is
+// opened until it is closed. The span has the operation "file.read", or
+// "file.write" if the file was opened for writing, the path of the file as its
+// description, and the number of bytes read and written as data.
+//
+// Files opened with a context that has no transaction are not recorded.
+type File struct {
+	*os.File
+	span  *sentry.Span
+	count counter
+}
+
+// Open opens the named file for reading, like os.Open.
+func Open(ctx context.Context, name string) (*File, error) {
+	return OpenFile(ctx, name, os.O_RDONLY, 0)
+}
+
+// Create creates or truncates the named file, like os.Create.
+func Create(ctx context.Context, name string) (*File, error) {
+	return OpenFile(ctx, name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// OpenFile opens the named file with the given flag and permissions, like
+// os.OpenFile. If the file cannot be opened, the span is finished right away
+// with a status describing the error.
+func OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (*File, error) {
+	op := opRead
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		op = opWrite
+	}
+	span := startSpan(ctx, op, name)
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		var c counter
+		c.finish(span, err)
+		return nil, err
+	}
+	return &File{File: f, span: span}, nil
+}
+
+// ReadFile reads the named file in a "file.read" span, like ioutil.ReadFile.
+func ReadFile(ctx context.Context, name string) ([]byte, error) {
+	f, err := Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return b, err
+}
+
+// WriteFile writes data to the named file in a "file.write" span, creating it
+// if necessary, like ioutil.WriteFile.
+func WriteFile(ctx context.Context, name string, data []byte, perm os.FileMode) error {
+	f, err := OpenFile(ctx, name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Read implements io.Reader.
+func (f *File) Read(b []byte) (int, error) {
+	n, err := f.File.Read(b)
+	f.count.addRead(n, err)
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *File) ReadAt(b []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(b, off)
+	f.count.addRead(n, err)
+	return n, err
+}
+
+// Write implements io.Writer.
+func (f *File) Write(b []byte) (int, error) {
+	n, err := f.File.Write(b)
+	f.count.addWritten(n, err)
+	return n, err
+}
+
+// WriteAt implements io.WriterAt.
+func (f *File) WriteAt(b []byte, off int64) (int, error) {
+	n, err := f.File.WriteAt(b, off)
+	f.count.addWritten(n, err)
+	return n, err
+}
+
+// WriteString is like Write, but writes the contents of string s.
+func (f *File) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+// ReadFrom implements io.ReaderFrom.
+func (f *File) ReadFrom(r io.Reader) (int64, error) {
+	n, err := f.File.ReadFrom(r)
+	atomic.AddInt64(&f.count.written, n)
+	if err != nil {
+		atomic.StoreInt32(&f.count.failed, 1)
+	}
+	return n, err
+}
+
+// WriteTo implements io.WriterTo, counting the bytes read from the file.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, readerOnly{f})
+}
+
+// Close closes the file and finishes its span.
+func (f *File) Close() error {
+	err := f.File.Close()
+	f.count.finish(f.span, err)
+	f.span = nil
+	return err
+}
+
+// readerOnly hides the methods of a reader other than Read, so that io.Copy
+// reads through it rather than calling WriteTo again.
+type readerOnly struct {
+	io.Reader
+}
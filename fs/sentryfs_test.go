@@ -0,0 +1,100 @@
+package sentryfs_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	sentryfs "github.com/getsentry/sentry-go/fs"
+	"github.com/getsentry/sentry-go/sentrytest"
+)
+
+// traced runs f in a transaction and returns the spans it recorded.
+func traced(t *testing.T, f func(ctx context.Context)) []*sentry.Span {
+	t.Helper()
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	span := sentry.StartSpan(ctx, "test", sentry.TransactionName("test"))
+	f(span.Context())
+	span.Finish()
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	return events[0].Spans
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "sentryfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestWriteAndReadFile(t *testing.T) {
+	name := filepath.Join(tempDir(t), "data.txt")
+	spans := traced(t, func(ctx context.Context) {
+		if err := sentryfs.WriteFile(ctx, name, []byte("hello"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		b, err := sentryfs.ReadFile(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "hello" {
+			t.Errorf("got %q, want %q", b, "hello")
+		}
+	})
+
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	write, read := spans[0], spans[1]
+	if write.Op != "file.write" || write.Description != name || write.Data["file.bytes_written"] != int64(5) {
+		t.Errorf("got write span %+v", write)
+	}
+	if read.Op != "file.read" || read.Description != name || read.Data["file.bytes_read"] != int64(5) {
+		t.Errorf("got read span %+v", read)
+	}
+	for _, span := range spans {
+		if span.Status != sentry.SpanStatusOK {
+			t.Errorf("got status %s for %s, want %s", span.Status, span.Op, sentry.SpanStatusOK)
+		}
+	}
+}
+
+func TestOpenMissingFile(t *testing.T) {
+	name := filepath.Join(tempDir(t), "missing.txt")
+	spans := traced(t, func(ctx context.Context) {
+		if _, err := sentryfs.Open(ctx, name); !os.IsNotExist(err) {
+			t.Errorf("got error %v, want a not exist error", err)
+		}
+	})
+
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Status != sentry.SpanStatusNotFound {
+		t.Errorf("got status %s, want %s", spans[0].Status, sentry.SpanStatusNotFound)
+	}
+}
+
+func TestCreateWithoutTransaction(t *testing.T) {
+	name := filepath.Join(tempDir(t), "data.txt")
+	f, err := sentryfs.Create(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
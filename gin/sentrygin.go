@@ -2,6 +2,7 @@ package sentrygin
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
@@ -47,18 +48,41 @@ func New(options Options) gin.HandlerFunc {
 }
 
 func (h *handler) handle(ctx *gin.Context) {
-	hub := sentry.GetHubFromContext(ctx.Request.Context())
+	r := ctx.Request
+	hub := sentry.GetHubFromContext(r.Context())
 	if hub == nil {
 		hub = sentry.CurrentHub().Clone()
 	}
+
+	// Gin matches the route before running middlewares, the pattern of the
+	// route is known already.
+	name, source := r.URL.Path, sentry.SourceURL
+	if route := ctx.FullPath(); route != "" {
+		name, source = route, sentry.SourceRoute
+	}
+	span := sentry.StartSpan(sentry.SetHubOnContext(r.Context(), hub), "http.server",
+		sentry.TransactionName(fmt.Sprintf("%s %s", r.Method, name)),
+		sentry.WithTransactionSource(source),
+		sentry.ContinueFromRequest(r),
+		sentry.WithSpanOrigin("auto.http.gin"),
+	)
+	span.SetData("http.method", r.Method)
+	if source == sentry.SourceRoute {
+		span.SetTag("url", name)
+	}
+	defer span.Finish()
+	ctx.Request = r.WithContext(span.Context())
+
 	hub.Scope().SetRequest(ctx.Request)
 	ctx.Set(valuesKey, hub)
-	defer h.recoverWithSentry(hub, ctx.Request)
+	defer h.recoverWithSentry(hub, span, ctx.Request)
 	ctx.Next()
+	span.Status = sentry.HTTPtoSpanStatus(ctx.Writer.Status())
 }
 
-func (h *handler) recoverWithSentry(hub *sentry.Hub, r *http.Request) {
+func (h *handler) recoverWithSentry(hub *sentry.Hub, span *sentry.Span, r *http.Request) {
 	if err := recover(); err != nil {
+		span.Status = sentry.SpanStatusInternalError
 		if !isBrokenPipeError(err) {
 			eventID := hub.RecoverWithContext(
 				context.WithValue(r.Context(), sentry.RequestContextKey, r),
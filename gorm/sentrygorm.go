@@ -0,0 +1,134 @@
+// Package sentrygorm provides Sentry integration for the gorm.io/gorm ORM.
+package sentrygorm
+
+import (
+	"errors"
+
+	"github.com/getsentry/sentry-go"
+	"gorm.io/gorm"
+)
+
+// spanKey is the key under which the span of a statement is stored in the
+// instance settings of a *gorm.DB.
+const spanKey = "sentry:span"
+
+// Options configure a Plugin.
+type Options struct {
+	// CaptureRecordNotFound configures whether gorm.ErrRecordNotFound is
+	// reported to Sentry. By default, it is considered an expected outcome
+	// and not reported.
+	CaptureRecordNotFound bool
+}
+
+// A Plugin is a GORM plugin that records database operations as spans and
+// reports errors to Sentry.
+//
+// For every create, query, update, delete, row and raw operation run with a
+// context that carries a transaction, the Plugin creates a db.sql child span
+// described by the SQL statement, without the values of its parameters, and
+// tagged with the table name. The number of affected rows is recorded in the
+// span data. Errors returned by operations are captured on the hub of the
+// context, or the current hub.
+//
+//	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+//	if err != nil {
+//		panic(err)
+//	}
+//	if err := db.Use(sentrygorm.New(sentrygorm.Options{})); err != nil {
+//		panic(err)
+//	}
+//	db.WithContext(span.Context()).First(&user)
+type Plugin struct {
+	captureRecordNotFound bool
+}
+
+var _ gorm.Plugin = (*Plugin)(nil)
+
+// New returns a new Plugin. Install it with gorm.DB.Use.
+func New(options Options) *Plugin {
+	return &Plugin{
+		captureRecordNotFound: options.CaptureRecordNotFound,
+	}
+}
+
+// Name implements gorm.Plugin.
+func (p *Plugin) Name() string {
+	return "sentrygorm"
+}
+
+// Initialize implements gorm.Plugin. It registers callbacks around the
+// default callbacks of every operation.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("sentry:before_create", p.before),
+		cb.Create().After("gorm:create").Register("sentry:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("sentry:before_query", p.before),
+		cb.Query().After("gorm:query").Register("sentry:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("sentry:before_update", p.before),
+		cb.Update().After("gorm:update").Register("sentry:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("sentry:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("sentry:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("sentry:before_row", p.before),
+		cb.Row().After("gorm:row").Register("sentry:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("sentry:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("sentry:after_raw", p.after),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Plugin) before(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if ctx == nil || sentry.TransactionFromContext(ctx) == nil {
+		return
+	}
+	span := sentry.StartSpan(ctx, "db.sql", sentry.WithSpanOrigin("auto.db.gorm"))
+	span.SetTag("db.system", db.Dialector.Name())
+	db.InstanceSet(spanKey, span)
+}
+
+func (p *Plugin) after(db *gorm.DB) {
+	err := db.Error
+	ignored := errors.Is(err, gorm.ErrRecordNotFound) && !p.captureRecordNotFound
+
+	if v, ok := db.InstanceGet(spanKey); ok {
+		span := v.(*sentry.Span)
+		span.Description = db.Statement.SQL.String()
+		if db.Statement.Table != "" {
+			span.SetTag("db.table", db.Statement.Table)
+		}
+		span.SetData("db.rows_affected", db.Statement.RowsAffected)
+		switch {
+		case err == nil:
+			span.Status = sentry.SpanStatusOK
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			span.Status = sentry.SpanStatusNotFound
+		default:
+			span.Status = sentry.SpanStatusInternalError
+		}
+		// The span is finished last, so that the error is linked to it.
+		defer span.Finish()
+	}
+
+	if err == nil || ignored {
+		return
+	}
+	var hub *sentry.Hub
+	if ctx := db.Statement.Context; ctx != nil {
+		hub = sentry.GetHubFromContext(ctx)
+	}
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	hub.WithScope(func(scope *sentry.Scope) {
+		if db.Statement.Table != "" {
+			scope.SetTag("db.table", db.Statement.Table)
+		}
+		scope.SetExtra("db.statement", db.Statement.SQL.String())
+		hub.CaptureException(err)
+	})
+}
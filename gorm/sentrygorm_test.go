@@ -0,0 +1,115 @@
+package sentrygorm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	sentrygorm "github.com/getsentry/sentry-go/gorm"
+	"github.com/getsentry/sentry-go/sentrytest"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/utils/tests"
+)
+
+type Product struct {
+	ID   uint
+	Code string
+}
+
+// newTestDB returns a database that builds statements without running them.
+// Queries fail with the error returned by queryErr.
+func newTestDB(t *testing.T, options sentrygorm.Options, queryErr func() error) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	if err := db.Use(sentrygorm.New(options)); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Callback().Query().Before("sentry:after_query").Register("test:error", func(db *gorm.DB) {
+		if err := queryErr(); err != nil {
+			_ = db.AddError(err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestPluginSpans(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	db := newTestDB(t, sentrygorm.Options{}, func() error { return nil })
+	transaction := sentry.StartSpan(ctx, "test", sentry.TransactionName("test"))
+
+	tx := db.WithContext(transaction.Context())
+	tx.Create(&Product{Code: "secret"})
+	var product Product
+	tx.Where("code = ?", "secret").Find(&product)
+	transaction.Finish()
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	spans := events[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	for _, span := range spans {
+		if span.Op != "db.sql" || span.Status != sentry.SpanStatusOK {
+			t.Errorf("unexpected span %+v", span)
+		}
+		if span.Tags["db.table"] != "products" {
+			t.Errorf("got tags %v, want the table", span.Tags)
+		}
+		if _, ok := span.Data["db.rows_affected"]; !ok {
+			t.Errorf("got data %v, want the affected rows", span.Data)
+		}
+	}
+	if want := "SELECT * FROM `products` WHERE code = ?"; spans[1].Description != want {
+		t.Errorf("got description %q, want %q", spans[1].Description, want)
+	}
+}
+
+func TestPluginCapturesErrors(t *testing.T) {
+	errQuery := errors.New("connection refused")
+	testCases := []struct {
+		name       string
+		options    sentrygorm.Options
+		err        error
+		wantEvents int
+	}{
+		{"Error", sentrygorm.Options{}, errQuery, 1},
+		{"RecordNotFound", sentrygorm.Options{}, gorm.ErrRecordNotFound, 0},
+		{"CaptureRecordNotFound", sentrygorm.Options{CaptureRecordNotFound: true}, gorm.ErrRecordNotFound, 1},
+	}
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+			db := newTestDB(t, tt.options, func() error { return tt.err })
+
+			var product Product
+			db.WithContext(ctx).First(&product)
+
+			events := transport.Events()
+			if len(events) != tt.wantEvents {
+				t.Fatalf("got %d events, want %d", len(events), tt.wantEvents)
+			}
+			if tt.wantEvents == 0 {
+				return
+			}
+			exception := events[0].Exception[len(events[0].Exception)-1]
+			if exception.Value != tt.err.Error() {
+				t.Errorf("got exception %q, want %q", exception.Value, tt.err)
+			}
+			if events[0].Tags["db.table"] != "products" {
+				t.Errorf("got tags %v, want the table", events[0].Tags)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package sentry
+
+import (
+	"context"
+	"fmt"
+)
+
+// Go runs f in a new goroutine and captures a panic in f on a clone of hub,
+// instead of crashing the program. Cloning the hub when the goroutine starts
+// means that later changes to the scope of hub, for example by the request
+// that started the goroutine, do not affect the reported panic. If hub is nil,
+// the current hub is cloned.
+//
+// Use GoContext to make the cloned hub available to f.
+func Go(hub *Hub, f func()) {
+	if hub == nil {
+		hub = CurrentHub()
+	}
+	hub = hub.Clone()
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				hub.Recover(err)
+			}
+		}()
+		f()
+	}()
+}
+
+// GoContext is like Go, but takes the hub from ctx, falling back to the
+// current hub, and passes f a context derived from ctx that carries the clone.
+// Changes f makes to the scope of that hub do not affect other goroutines:
+//
+//	sentry.GoContext(ctx, func(ctx context.Context) {
+//		sentry.GetHubFromContext(ctx).Scope().SetTag("worker", "1")
+//		// ...
+//	})
+func GoContext(ctx context.Context, f func(ctx context.Context)) {
+	ctx = SetHubOnContext(ctx, hubFromContext(ctx).Clone())
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				GetHubFromContext(ctx).RecoverWithContext(ctx, err)
+			}
+		}()
+		f(ctx)
+	}()
+}
+
+// WrapFunc returns a function, suitable for errgroup.Group.Go, that calls f
+// with a context derived from ctx that carries a clone of the hub of ctx. A
+// panic in f is captured and returned as an error, so that the group is
+// canceled instead of the program crashing:
+//
+//	g, ctx := errgroup.WithContext(ctx)
+//	for _, url := range urls {
+//		url := url
+//		g.Go(sentry.WrapFunc(ctx, func(ctx context.Context) error {
+//			return fetch(ctx, url)
+//		}))
+//	}
+//	err := g.Wait()
+//
+// Errors returned by f are not captured.
+func WrapFunc(ctx context.Context, f func(ctx context.Context) error) func() error {
+	return func() (err error) {
+		ctx := SetHubOnContext(ctx, hubFromContext(ctx).Clone())
+		defer func() {
+			if r := recover(); r != nil {
+				GetHubFromContext(ctx).RecoverWithContext(ctx, r)
+				if e, ok := r.(error); ok {
+					err = fmt.Errorf("panic: %w", e)
+				} else {
+					err = fmt.Errorf("panic: %v", r)
+				}
+			}
+		}()
+		return f(ctx)
+	}
+}
@@ -0,0 +1,102 @@
+package sentry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupGoroutineTest() (*Hub, *TransportMock) {
+	transport := &TransportMock{}
+	client, _ := NewClient(ClientOptions{Transport: transport})
+	return NewHub(client, NewScope()), transport
+}
+
+// waitForEvents waits for the events of a panic that is captured after the
+// goroutine signaled it was done.
+func waitForEvents(transport *TransportMock, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for len(transport.Events()) < n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGo(t *testing.T) {
+	hub, transport := setupGoroutineTest()
+	hub.Scope().SetTag("parent", "yes")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	Go(hub, func() {
+		defer wg.Done()
+		panic("goroutine panic")
+	})
+	wg.Wait()
+	waitForEvents(transport, 1)
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	assertEqual(t, events[0].Message, "goroutine panic")
+	assertEqual(t, events[0].Tags["parent"], "yes")
+}
+
+func TestGoContextIsolatesScope(t *testing.T) {
+	hub, transport := setupGoroutineTest()
+	ctx := SetHubOnContext(context.Background(), hub)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	GoContext(ctx, func(ctx context.Context) {
+		defer wg.Done()
+		goroutineHub := GetHubFromContext(ctx)
+		if goroutineHub == hub {
+			t.Error("goroutine shares the hub of the parent")
+		}
+		goroutineHub.Scope().SetTag("worker", "1")
+		panic(errors.New("goroutine panic"))
+	})
+	wg.Wait()
+	waitForEvents(transport, 1)
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	assertEqual(t, events[0].Tags["worker"], "1")
+	if _, ok := hub.Scope().tags["worker"]; ok {
+		t.Error("goroutine modified the scope of the parent")
+	}
+}
+
+func TestWrapFunc(t *testing.T) {
+	hub, transport := setupGoroutineTest()
+	ctx := SetHubOnContext(context.Background(), hub)
+
+	errWant := errors.New("failed")
+	err := WrapFunc(ctx, func(ctx context.Context) error {
+		if GetHubFromContext(ctx) == hub {
+			t.Error("function shares the hub of the parent")
+		}
+		return errWant
+	})()
+	assertEqual(t, err, errWant)
+	assertEqual(t, len(transport.Events()), 0)
+
+	panicErr := errors.New("panic error")
+	err = WrapFunc(ctx, func(ctx context.Context) error {
+		panic(panicErr)
+	})()
+	if !errors.Is(err, panicErr) {
+		t.Errorf("got error %v, want it to wrap %v", err, panicErr)
+	}
+	assertEqual(t, len(transport.Events()), 1)
+
+	err = WrapFunc(ctx, func(ctx context.Context) error {
+		panic("panic string")
+	})()
+	assertEqual(t, err.Error(), "panic: panic string")
+}
@@ -0,0 +1,225 @@
+// Package sentrygqlgen provides Sentry integration for GraphQL servers based on
+// the github.com/99designs/gqlgen package.
+package sentrygqlgen
+
+import (
+	"context"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/getsentry/sentry-go"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Options configure an Extension.
+type Options struct {
+	// TraceAllFields configures whether to record a span for every field,
+	// including the fields without a resolver. By default, only the fields
+	// with a resolver are recorded, as the other fields are many and fast.
+	TraceAllFields bool
+}
+
+// An Extension is a gqlgen server extension that provides integration with
+// Sentry. Add it to a server with the Use method:
+//
+//	srv := handler.NewDefaultServer(generated.NewExecutableSchema(config))
+//	srv.Use(sentrygqlgen.New(sentrygqlgen.Options{}))
+//
+// Operations are recorded in spans, as a child of the transaction of the
+// request, started for example by the sentryhttp package, which is named after
+// the operation, or as new transactions. Fields with a resolver are recorded
+// in child spans. The errors returned by resolvers and their panics are
+// reported to Sentry, with the path of the field and the variables of the
+// operation, once per distinct error message and operation. The values of
+// variables are only reported with ClientOptions.SendDefaultPII. Panics are
+// then handled by the server, which reports an internal error.
+type Extension struct {
+	traceAllFields bool
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.FieldInterceptor
+} = Extension{}
+
+// New returns a new Extension.
+func New(options Options) Extension {
+	return Extension{traceAllFields: options.TraceAllFields}
+}
+
+// ExtensionName implements graphql.HandlerExtension.
+func (Extension) ExtensionName() string {
+	return "Sentry"
+}
+
+// Validate implements graphql.HandlerExtension.
+func (Extension) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation implements graphql.OperationInterceptor.
+func (e Extension) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	responses := next(ctx)
+	return func(ctx context.Context) *graphql.Response {
+		oc := graphql.GetOperationContext(ctx)
+		hub := sentry.GetHubFromContext(ctx)
+		if hub == nil {
+			hub = sentry.CurrentHub().Clone()
+			ctx = sentry.SetHubOnContext(ctx, hub)
+		}
+		name := operationName(oc)
+		span := sentry.StartSpan(ctx, "graphql."+operationType(oc),
+			sentry.TransactionName(name),
+			sentry.WithTransactionSource(sentry.SourceComponent),
+			sentry.WithDescription(name),
+			sentry.WithSpanOrigin("auto.graphql.gqlgen"),
+		)
+		defer span.Finish()
+		hub.Scope().SetContext("graphql", operationData(hub, oc))
+
+		ctx = context.WithValue(span.Context(), reportedKey{}, &reportedErrors{})
+		response := responses(ctx)
+		if response != nil && len(response.Errors) > 0 {
+			span.Status = sentry.SpanStatusInternalError
+		} else {
+			span.Status = sentry.SpanStatusOK
+		}
+		return response
+	}
+}
+
+// InterceptField implements graphql.FieldInterceptor.
+func (e Extension) InterceptField(ctx context.Context, next graphql.Resolver) (res interface{}, err error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil {
+		return next(ctx)
+	}
+	var span *sentry.Span
+	if sentry.TransactionFromContext(ctx) != nil && (fc.IsResolver || e.traceAllFields) {
+		span = sentry.StartSpan(ctx, "graphql.resolve",
+			sentry.WithDescription(fc.Object+"."+fc.Field.Name),
+			sentry.WithSpanOrigin("auto.graphql.gqlgen"),
+		)
+		defer span.Finish()
+		span.SetData("graphql.path", fc.Path().String())
+		// Until next returns, assume that it panics.
+		span.Status = sentry.SpanStatusInternalError
+		ctx = span.Context()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			withFieldScope(ctx, fc, func(hub *sentry.Hub) {
+				hub.RecoverWithContext(ctx, r)
+			})
+			panic(r)
+		}
+	}()
+	res, err = next(ctx)
+	if span != nil && err == nil {
+		span.Status = sentry.SpanStatusOK
+	}
+	if err != nil && firstReport(ctx, err) {
+		withFieldScope(ctx, fc, func(hub *sentry.Hub) {
+			hub.CaptureException(err)
+		})
+	}
+	return res, err
+}
+
+// withFieldScope calls f with the hub of ctx, in a scope holding the path and
+// the name of the field.
+func withFieldScope(ctx context.Context, fc *graphql.FieldContext, f func(hub *sentry.Hub)) {
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetTag("graphql.field", fc.Object+"."+fc.Field.Name)
+		scope.SetExtra("graphql.path", fc.Path().String())
+		f(hub)
+	})
+}
+
+// reportedKey is the context key of the errors reported for an operation.
+type reportedKey struct{}
+
+// reportedErrors holds the messages of the errors reported for an operation.
+// Fields may be resolved concurrently.
+type reportedErrors struct {
+	mu       sync.Mutex
+	messages map[string]bool
+}
+
+// firstReport reports whether err is the first error with its message
+// reported for the operation of ctx, and records it.
+func firstReport(ctx context.Context, err error) bool {
+	reported, ok := ctx.Value(reportedKey{}).(*reportedErrors)
+	if !ok {
+		return true
+	}
+	reported.mu.Lock()
+	defer reported.mu.Unlock()
+	if reported.messages == nil {
+		reported.messages = make(map[string]bool)
+	}
+	msg := err.Error()
+	if reported.messages[msg] {
+		return false
+	}
+	reported.messages[msg] = true
+	return true
+}
+
+// operationType returns the type of the operation, "query", "mutation" or
+// "subscription".
+func operationType(oc *graphql.OperationContext) string {
+	if oc.Operation == nil {
+		return string(ast.Query)
+	}
+	return string(oc.Operation.Operation)
+}
+
+// declaredName returns the name of the operation, or "" if it is anonymous.
+func declaredName(oc *graphql.OperationContext) string {
+	if oc.OperationName == "" && oc.Operation != nil {
+		return oc.Operation.Name
+	}
+	return oc.OperationName
+}
+
+// operationName returns the name of the transaction of an operation, made of
+// its type and its name, if any, for example "query GetUser".
+func operationName(oc *graphql.OperationContext) string {
+	if name := declaredName(oc); name != "" {
+		return operationType(oc) + " " + name
+	}
+	return operationType(oc)
+}
+
+// operationData returns the data of the graphql context of the events of an
+// operation. Variable values are filtered unless SendDefaultPII is set.
+func operationData(hub *sentry.Hub, oc *graphql.OperationContext) map[string]interface{} {
+	data := map[string]interface{}{
+		"operation_name": declaredName(oc),
+		"operation_type": operationType(oc),
+	}
+	if len(oc.Variables) == 0 {
+		return data
+	}
+	pii := false
+	if client := hub.Client(); client != nil {
+		pii = client.Options().SendDefaultPII
+	}
+	variables := make(map[string]interface{}, len(oc.Variables))
+	for k, v := range oc.Variables {
+		if pii {
+			variables[k] = v
+		} else {
+			variables[k] = "[Filtered]"
+		}
+	}
+	data["variables"] = variables
+	return data
+}
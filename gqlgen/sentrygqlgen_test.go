@@ -0,0 +1,141 @@
+package sentrygqlgen_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/getsentry/sentry-go"
+	sentrygqlgen "github.com/getsentry/sentry-go/gqlgen"
+	"github.com/getsentry/sentry-go/sentrytest"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var schema = gqlparser.MustLoadSchema(&ast.Source{Input: `
+	type Query {
+		user(id: Int!): String!
+		boom: String!
+	}
+`})
+
+// resolve resolves a field of the root query like generated code does.
+func resolve(ctx context.Context, alias, name string, f func() (interface{}, error)) {
+	ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+		Object: "Query",
+		Field: graphql.CollectedField{Field: &ast.Field{
+			Name:       name,
+			Alias:      alias,
+			Definition: schema.Types["Query"].Fields.ForName(name),
+		}},
+		IsResolver: true,
+	})
+	defer func() {
+		if r := recover(); r != nil {
+			graphql.AddError(ctx, fmt.Errorf("internal system error"))
+		}
+	}()
+	_, err := graphql.GetOperationContext(ctx).ResolverMiddleware(ctx, func(ctx context.Context) (interface{}, error) {
+		return f()
+	})
+	if err != nil {
+		graphql.AddError(ctx, err)
+	}
+}
+
+func newServer() *handler.Server {
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			ran := false
+			return func(ctx context.Context) *graphql.Response {
+				if ran {
+					return nil
+				}
+				ran = true
+				errNotFound := errors.New("user not found")
+				resolve(ctx, "a", "user", func() (interface{}, error) { return nil, errNotFound })
+				resolve(ctx, "b", "user", func() (interface{}, error) { return nil, errNotFound })
+				resolve(ctx, "boom", "boom", func() (interface{}, error) { panic("boom") })
+				return &graphql.Response{Data: []byte(`null`)}
+			}
+		},
+		SchemaFunc: func() *ast.Schema { return schema },
+	})
+	srv.AddTransport(transport.POST{})
+	srv.Use(sentrygqlgen.New(sentrygqlgen.Options{}))
+	return srv
+}
+
+func TestExtension(t *testing.T) {
+	sentryTransport := sentrytest.NewRecordingTransport()
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Transport:        sentryTransport,
+		TracesSampleRate: 1.0,
+		Integrations:     func([]sentry.Integration) []sentry.Integration { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := sentry.NewHub(client, sentry.NewScope())
+
+	body := `{"query": "query GetUsers($id: Int!) { a: user(id: $id) b: user(id: $id) boom }", "variables": {"id": 42}}`
+	r := httptest.NewRequest("POST", "/graphql", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r = r.WithContext(sentry.SetHubOnContext(r.Context(), hub))
+	w := httptest.NewRecorder()
+	newServer().ServeHTTP(w, r)
+	if !strings.Contains(w.Body.String(), "user not found") {
+		t.Fatalf("unexpected response %s", w.Body)
+	}
+
+	var errorEvents []*sentry.Event
+	var transaction *sentry.Event
+	for _, event := range sentryTransport.Events() {
+		if event.Type == "transaction" {
+			transaction = event
+		} else {
+			errorEvents = append(errorEvents, event)
+		}
+	}
+
+	// The second "user not found" error is not reported again.
+	if len(errorEvents) != 2 {
+		t.Fatalf("got %d error events, want 2", len(errorEvents))
+	}
+	notFound, panicked := errorEvents[0], errorEvents[1]
+	if notFound.Exception[0].Value != "user not found" || notFound.Extra["graphql.path"] != "a" ||
+		notFound.Tags["graphql.field"] != "Query.user" {
+		t.Errorf("unexpected error event %+v", notFound)
+	}
+	if panicked.Message != "boom" || panicked.Extra["graphql.path"] != "boom" {
+		t.Errorf("unexpected panic event %+v", panicked)
+	}
+	data, _ := notFound.Contexts["graphql"].(map[string]interface{})
+	variables, _ := data["variables"].(map[string]interface{})
+	if data["operation_name"] != "GetUsers" || variables["id"] != "[Filtered]" {
+		t.Errorf("unexpected graphql context %v", notFound.Contexts["graphql"])
+	}
+
+	if transaction == nil {
+		t.Fatal("missing transaction")
+	}
+	if transaction.Transaction != "query GetUsers" {
+		t.Errorf("got transaction %q, want %q", transaction.Transaction, "query GetUsers")
+	}
+	var spans []string
+	for _, span := range transaction.Spans {
+		spans = append(spans, fmt.Sprintf("%s %s %s", span.Op, span.Description, span.Status))
+	}
+	want := "graphql.resolve Query.user internal_error," +
+		"graphql.resolve Query.user internal_error," +
+		"graphql.resolve Query.boom internal_error"
+	if strings.Join(spans, ",") != want {
+		t.Errorf("got spans %v", spans)
+	}
+}
@@ -0,0 +1,91 @@
+package sentry
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// healthCheckInterval is how often the health monitor checks the transport.
+const healthCheckInterval = 10 * time.Second
+
+// maxDownsampleFactor bounds how much transactions are downsampled. The
+// effective sample rate is never divided by more than 2^maxDownsampleFactor.
+const maxDownsampleFactor = 10
+
+// healthReporter is implemented by transports that can report whether they
+// keep up with the events submitted to them.
+type healthReporter interface {
+	// isHealthy reports whether the transport accepted all events submitted
+	// since the previous call and is not currently rate limited.
+	isHealthy() bool
+}
+
+// A healthMonitor tracks the health of a transport and computes how much
+// transactions should be downsampled to reduce pressure on the transport, the
+// application and Sentry.
+//
+// Every healthCheckInterval, or rather on the first sampling decision after
+// that, the transport is checked. Each unhealthy check halves the effective
+// sample rate of transactions, and a healthy check restores it.
+type healthMonitor struct {
+	reporter healthReporter
+	clock    Clock
+
+	mu               sync.Mutex
+	lastCheck        time.Time
+	downsampleFactor int
+}
+
+// newHealthMonitor returns a health monitor for transport, or nil if transport
+// cannot report its health.
+func newHealthMonitor(transport Transport, clock Clock) *healthMonitor {
+	if t, ok := transport.(*spotlightTransport); ok {
+		transport = t.Transport
+	}
+	reporter, ok := transport.(healthReporter)
+	if !ok {
+		Logger.Printf("Backpressure handling is not supported by transport %T.", transport)
+		return nil
+	}
+	return &healthMonitor{
+		reporter:  reporter,
+		clock:     clock,
+		lastCheck: clock.Now(),
+	}
+}
+
+// sampleRateMultiplier returns the factor by which the sample rate of
+// transactions should be multiplied, in the range (0.0, 1.0].
+func (m *healthMonitor) sampleRateMultiplier() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now := m.clock.Now(); now.Sub(m.lastCheck) >= healthCheckInterval {
+		m.lastCheck = now
+		factor := 0
+		if !m.reporter.isHealthy() {
+			factor = m.downsampleFactor + 1
+			if factor > maxDownsampleFactor {
+				factor = maxDownsampleFactor
+			}
+		}
+		if factor != m.downsampleFactor {
+			Logger.Printf("Transaction downsample factor changed from %d to %d.", m.downsampleFactor, factor)
+			m.downsampleFactor = factor
+		}
+	}
+	return math.Pow(0.5, float64(m.downsampleFactor))
+}
+
+// downsample applies the downsampling computed by the health monitor of client,
+// if any, to a positive sampling decision.
+func downsample(client *Client, sampled Sampled) Sampled {
+	if client == nil || client.health == nil || sampled != SampledTrue {
+		return sampled
+	}
+	if multiplier := client.health.sampleRateMultiplier(); multiplier < 1 && !sample(multiplier) {
+		return SampledFalse
+	}
+	return sampled
+}
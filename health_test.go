@@ -0,0 +1,102 @@
+package sentry
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go/internal/ratelimit"
+)
+
+// healthReporterMock is a healthReporter with a configurable health.
+type healthReporterMock struct {
+	TransportMock
+	healthy bool
+	checks  int
+}
+
+func (r *healthReporterMock) isHealthy() bool {
+	r.checks++
+	return r.healthy
+}
+
+func TestHealthMonitorDownsampling(t *testing.T) {
+	reporter := &healthReporterMock{}
+	clock := &ClockMock{now: goReleaseDate}
+	m := newHealthMonitor(reporter, clock)
+
+	assertEqual(t, m.sampleRateMultiplier(), 1.0)
+	assertEqual(t, reporter.checks, 0, "health should not be checked before the interval")
+
+	for _, want := range []float64{0.5, 0.25, 0.125} {
+		clock.Advance(healthCheckInterval)
+		assertEqual(t, m.sampleRateMultiplier(), want)
+		assertEqual(t, m.sampleRateMultiplier(), want, "factor should only change once per interval")
+	}
+
+	for i := 0; i < 2*maxDownsampleFactor; i++ {
+		clock.Advance(healthCheckInterval)
+		m.sampleRateMultiplier()
+	}
+	assertEqual(t, m.downsampleFactor, maxDownsampleFactor)
+
+	reporter.healthy = true
+	clock.Advance(healthCheckInterval)
+	assertEqual(t, m.sampleRateMultiplier(), 1.0)
+}
+
+func TestHealthMonitorUnsupportedTransport(t *testing.T) {
+	if m := newHealthMonitor(&TransportMock{}, systemClock{}); m != nil {
+		t.Errorf("got %v, want nil", m)
+	}
+}
+
+func TestBackpressureHandlingDownsamplesTransactions(t *testing.T) {
+	transport := &healthReporterMock{}
+	clock := &ClockMock{now: goReleaseDate}
+	client, err := NewClient(ClientOptions{
+		Transport:                  transport,
+		Clock:                      clock,
+		TracesSampleRate:           1.0,
+		EnableBackpressureHandling: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := SetHubOnContext(context.Background(), NewHub(client, NewScope()))
+
+	assertEqual(t, StartSpan(ctx, "op").Sampled, SampledTrue)
+
+	// Drive the effective sample rate down to 1/1024.
+	for i := 0; i < maxDownsampleFactor; i++ {
+		clock.Advance(healthCheckInterval)
+		client.health.sampleRateMultiplier()
+	}
+	var sampled int
+	for i := 0; i < 100; i++ {
+		if StartSpan(ctx, "op").Sampled == SampledTrue {
+			sampled++
+		}
+	}
+	if sampled > 10 {
+		t.Errorf("got %d sampled transactions out of 100, want nearly none", sampled)
+	}
+
+	// Child spans inherit the decision of their parent.
+	parent := StartSpan(ctx, "op", func(s *Span) { s.Sampled = SampledTrue })
+	assertEqual(t, parent.StartChild("child").Sampled, SampledTrue)
+
+}
+
+func TestHTTPTransportIsHealthy(t *testing.T) {
+	transport := NewHTTPTransport()
+	assertEqual(t, transport.isHealthy(), true)
+
+	atomic.StoreInt32(&transport.saturated, 1)
+	assertEqual(t, transport.isHealthy(), false, "dropped events should make the transport unhealthy")
+	assertEqual(t, transport.isHealthy(), true, "saturation should be reset after reporting")
+
+	transport.limits[ratelimit.CategoryTransaction] = ratelimit.Deadline(time.Now().Add(time.Minute))
+	assertEqual(t, transport.isHealthy(), false, "rate limits should make the transport unhealthy")
+}
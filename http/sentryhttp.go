@@ -3,9 +3,13 @@
 package sentryhttp
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/getsentry/sentry-go"
@@ -17,6 +21,9 @@ type Handler struct {
 	repanic         bool
 	waitForDelivery bool
 	timeout         time.Duration
+	routePattern    func(r *http.Request) string
+	captureResponse bool
+	responseHeaders []string
 }
 
 // Options configure a Handler.
@@ -44,6 +51,24 @@ type Options struct {
 	// If the timeout is reached, the current goroutine is no longer blocked
 	// waiting, but the delivery is not canceled.
 	Timeout time.Duration
+	// RoutePattern returns the pattern of the route that matched the request,
+	// for example "/users/{id}", or "" if no route matched. It is called after
+	// the wrapped handler returns, when routers have matched the request. The
+	// pattern is used for the transaction name and the url tag of the
+	// transaction instead of the request path, so that requests to the same
+	// route are grouped together. See the sentrychi and sentrymux packages for
+	// the patterns of the chi and gorilla/mux routers.
+	RoutePattern func(r *http.Request) string
+	// CaptureResponse configures whether to record the response in a
+	// "response" context of the events captured during a request, with the
+	// status code, the number of bytes of the body written so far and the
+	// headers listed in ResponseHeaders. Events captured before the handler
+	// writes the status code, for example while recovering from a panic, have
+	// no status code.
+	CaptureResponse bool
+	// ResponseHeaders lists the response headers recorded when
+	// CaptureResponse is true. Defaults to Content-Type.
+	ResponseHeaders []string
 }
 
 // New returns a new Handler. Use the Handle and HandleFunc methods to wrap
@@ -53,10 +78,17 @@ func New(options Options) *Handler {
 	if timeout == 0 {
 		timeout = 2 * time.Second
 	}
+	responseHeaders := options.ResponseHeaders
+	if responseHeaders == nil {
+		responseHeaders = []string{"Content-Type"}
+	}
 	return &Handler{
 		repanic:         options.Repanic,
 		timeout:         timeout,
 		waitForDelivery: options.WaitForDelivery,
+		routePattern:    options.RoutePattern,
+		captureResponse: options.CaptureResponse,
+		responseHeaders: responseHeaders,
 	}
 }
 
@@ -88,24 +120,66 @@ func (h *Handler) handle(handler http.Handler) http.HandlerFunc {
 		}
 		span := sentry.StartSpan(ctx, "http.server",
 			sentry.TransactionName(fmt.Sprintf("%s %s", r.Method, r.URL.Path)),
+			sentry.WithTransactionSource(sentry.SourceURL),
 			sentry.ContinueFromRequest(r),
+			sentry.WithSpanOrigin("auto.http.stdlib"),
 		)
+		span.SetData("http.method", r.Method)
 		defer span.Finish()
-		// TODO(tracing): if the next handler.ServeHTTP panics, store
-		// information on the transaction accordingly (status, tag,
-		// level?, ...).
 		r = r.WithContext(span.Context())
 		hub.Scope().SetRequest(r)
-		defer h.recoverWithSentry(hub, r)
-		// TODO(tracing): use custom response writer to intercept
-		// response. Use HTTP status to add tag to transaction; set span
-		// status.
-		handler.ServeHTTP(w, r)
+		defer h.recoverWithSentry(hub, span, r)
+		if h.routePattern != nil {
+			defer h.setRoute(hub, span, r)
+		}
+		rw := &responseWriter{ResponseWriter: w}
+		if h.captureResponse {
+			rw.headerKeys = h.responseHeaders
+			hub.Scope().AddEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+				if event.Contexts == nil {
+					event.Contexts = make(map[string]interface{})
+				}
+				event.Contexts["response"] = h.responseContext(rw)
+				return event
+			})
+		}
+		handler.ServeHTTP(rw, r)
+		span.Status = sentry.HTTPtoSpanStatus(rw.statusCode())
+	}
+}
+
+// setRoute names the transaction of a request after the pattern of the route
+// that matched it, if any.
+func (h *Handler) setRoute(hub *sentry.Hub, span *sentry.Span, r *http.Request) {
+	pattern := h.routePattern(r)
+	if pattern == "" {
+		return
+	}
+	hub.Scope().SetTransaction(fmt.Sprintf("%s %s", r.Method, pattern))
+	hub.Scope().SetTransactionSource(sentry.SourceRoute)
+	span.SetTag("url", pattern)
+}
+
+// responseContext returns the response context of the response written to w.
+func (h *Handler) responseContext(w *responseWriter) map[string]interface{} {
+	response := map[string]interface{}{
+		"body_size": atomic.LoadInt64(&w.size),
+	}
+	if status := atomic.LoadInt32(&w.status); status != 0 {
+		response["status_code"] = int(status)
 	}
+	w.mu.Lock()
+	headers := w.headers
+	w.mu.Unlock()
+	if len(headers) > 0 {
+		response["headers"] = headers
+	}
+	return response
 }
 
-func (h *Handler) recoverWithSentry(hub *sentry.Hub, r *http.Request) {
+func (h *Handler) recoverWithSentry(hub *sentry.Hub, span *sentry.Span, r *http.Request) {
 	if err := recover(); err != nil {
+		span.Status = sentry.SpanStatusInternalError
 		eventID := hub.RecoverWithContext(
 			context.WithValue(r.Context(), sentry.RequestContextKey, r),
 			err,
@@ -118,3 +192,94 @@ func (h *Handler) recoverWithSentry(hub *sentry.Hub, r *http.Request) {
 		}
 	}
 }
+
+// responseWriter records the status code and the size of the response written
+// through it. It implements the optional http.Flusher, http.Hijacker and
+// http.Pusher interfaces by delegating to the wrapped http.ResponseWriter.
+//
+// The status code and the size are accessed atomically, as events may be
+// captured from other goroutines than the one of the handler. For the same
+// reason, the headers listed in headerKeys are copied when the status code is
+// written, rather than read from the header map the handler may still modify.
+type responseWriter struct {
+	http.ResponseWriter
+	status     int32
+	size       int64
+	headerKeys []string
+
+	mu      sync.Mutex
+	headers map[string]string
+}
+
+// setStatus records the status code of the response and its headers, unless
+// already written. It is only called from the goroutine of the handler.
+func (w *responseWriter) setStatus(code int) {
+	if atomic.LoadInt32(&w.status) != 0 {
+		return
+	}
+	if len(w.headerKeys) > 0 {
+		headers := make(map[string]string)
+		for _, key := range w.headerKeys {
+			if value := w.ResponseWriter.Header().Get(key); value != "" {
+				headers[http.CanonicalHeaderKey(key)] = value
+			}
+		}
+		w.mu.Lock()
+		w.headers = headers
+		w.mu.Unlock()
+	}
+	atomic.StoreInt32(&w.status, int32(code))
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	// Informational responses, like 103 Early Hints, precede the response.
+	if code >= 200 || code == http.StatusSwitchingProtocols {
+		w.setStatus(code)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.setStatus(http.StatusOK)
+	n, err := w.ResponseWriter.Write(b)
+	atomic.AddInt64(&w.size, int64(n))
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.setStatus(http.StatusOK)
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("sentryhttp: %T does not implement http.Hijacker", w.ResponseWriter)
+	}
+	w.setStatus(http.StatusSwitchingProtocols)
+	return h.Hijack()
+}
+
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for use with
+// http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusCode returns the status code of the response. Handlers that write
+// nothing respond with 200 OK.
+func (w *responseWriter) statusCode() int {
+	if status := atomic.LoadInt32(&w.status); status != 0 {
+		return int(status)
+	}
+	return http.StatusOK
+}
@@ -6,11 +6,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/getsentry/sentry-go"
 	sentryhttp "github.com/getsentry/sentry-go/http"
+	"github.com/getsentry/sentry-go/sentrytest"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
@@ -213,7 +215,7 @@ func TestIntegration(t *testing.T) {
 	opts := cmp.Options{
 		cmpopts.IgnoreFields(
 			sentry.Event{},
-			"Contexts", "EventID", "Extra", "Platform",
+			"Contexts", "EventID", "Extra", "Modules", "Platform",
 			"Release", "Sdk", "ServerName", "Tags", "Timestamp",
 		),
 		cmpopts.IgnoreFields(
@@ -225,3 +227,216 @@ func TestIntegration(t *testing.T) {
 		t.Fatalf("Events mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestRoutePattern(t *testing.T) {
+	tests := []struct {
+		name            string
+		pattern         string
+		wantTransaction string
+		wantSource      sentry.TransactionSource
+	}{
+		{"Route", "/users/{id}", "GET /users/{id}", sentry.SourceRoute},
+		{"NoRoute", "", "GET /users/42", sentry.SourceURL},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			transport := sentrytest.NewRecordingTransport()
+			client, err := sentry.NewClient(sentry.ClientOptions{
+				Transport:        transport,
+				TracesSampleRate: 1.0,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hub := sentry.NewHub(client, sentry.NewScope())
+			handler := sentryhttp.New(sentryhttp.Options{
+				RoutePattern: func(r *http.Request) string { return tt.pattern },
+			}).HandleFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			r := httptest.NewRequest("GET", "/users/42", nil)
+			r = r.WithContext(sentry.SetHubOnContext(r.Context(), hub))
+			handler(httptest.NewRecorder(), r)
+
+			events := transport.Events()
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			if events[0].Transaction != tt.wantTransaction {
+				t.Errorf("got transaction %q, want %q", events[0].Transaction, tt.wantTransaction)
+			}
+			if events[0].TransactionInfo == nil || events[0].TransactionInfo.Source != tt.wantSource {
+				t.Errorf("got transaction info %+v, want source %q", events[0].TransactionInfo, tt.wantSource)
+			}
+			if got := events[0].Tags["url"]; got != tt.pattern {
+				t.Errorf("got url tag %q, want %q", got, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestSpanStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    sentry.SpanStatus
+	}{
+		{"Empty", func(w http.ResponseWriter, r *http.Request) {}, sentry.SpanStatusOK},
+		{"Write", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) }, sentry.SpanStatusOK},
+		{"NotFound", http.NotFound, sentry.SpanStatusNotFound},
+		{"Unavailable", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.WriteHeader(http.StatusOK)
+		}, sentry.SpanStatusUnavailable},
+		{"Panic", func(w http.ResponseWriter, r *http.Request) { panic("test") }, sentry.SpanStatusInternalError},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			transport := sentrytest.NewRecordingTransport()
+			client, err := sentry.NewClient(sentry.ClientOptions{
+				Transport:        transport,
+				TracesSampleRate: 1.0,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hub := sentry.NewHub(client, sentry.NewScope())
+			handler := sentryhttp.New(sentryhttp.Options{}).HandleFunc(tt.handler)
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r = r.WithContext(sentry.SetHubOnContext(r.Context(), hub))
+			handler(httptest.NewRecorder(), r)
+
+			var transaction *sentry.Event
+			for _, event := range transport.Events() {
+				if event.Type == "transaction" {
+					transaction = event
+				}
+			}
+			if transaction == nil {
+				t.Fatal("missing transaction")
+			}
+			if got := transaction.Contexts["trace"].(*sentry.TraceContext).Status; got != tt.want {
+				t.Errorf("got status %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaptureResponse(t *testing.T) {
+	transport := sentrytest.NewRecordingTransport()
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Transport: transport,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := sentry.NewHub(client, sentry.NewScope())
+	handler := sentryhttp.New(sentryhttp.Options{
+		CaptureResponse: true,
+		ResponseHeaders: []string{"content-type", "Retry-After"},
+	}).HandleFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Del("Retry-After")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Set-Cookie", "session=1")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failure"))
+		// Headers set once the response is written are not sent.
+		w.Header().Set("Retry-After", "20")
+		sentry.GetHubFromContext(r.Context()).CaptureMessage("failed")
+	})
+
+	// The server, unlike httptest.ResponseRecorder, sends informational
+	// responses.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r.WithContext(sentry.SetHubOnContext(r.Context(), hub)))
+	}))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	want := map[string]interface{}{
+		"status_code": http.StatusInternalServerError,
+		"body_size":   int64(7),
+		"headers":     map[string]string{"Content-Type": "text/plain"},
+	}
+	if diff := cmp.Diff(want, events[0].Contexts["response"]); diff != "" {
+		t.Errorf("response context mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// asyncTransport delivers events in the background, after a delay.
+type asyncTransport struct {
+	sentrytest.RecordingTransport
+	wg sync.WaitGroup
+}
+
+func (t *asyncTransport) SendEvent(event *sentry.Event) {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		time.Sleep(10 * time.Millisecond)
+		t.RecordingTransport.SendEvent(event)
+	}()
+}
+
+func (t *asyncTransport) Flush(timeout time.Duration) bool {
+	t.wg.Wait()
+	return true
+}
+
+func TestRepanicAndWaitForDelivery(t *testing.T) {
+	tests := []struct {
+		name    string
+		options sentryhttp.Options
+	}{
+		{"Repanic", sentryhttp.Options{Repanic: true}},
+		{"WaitForDelivery", sentryhttp.Options{WaitForDelivery: true, Timeout: time.Second}},
+		{"Both", sentryhttp.Options{Repanic: true, WaitForDelivery: true}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &asyncTransport{}
+			client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hub := sentry.NewHub(client, sentry.NewScope())
+			handler := sentryhttp.New(tt.options).HandleFunc(func(http.ResponseWriter, *http.Request) {
+				panic("test")
+			})
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r = r.WithContext(sentry.SetHubOnContext(r.Context(), hub))
+			var recovered interface{}
+			func() {
+				// Stands for an outer recovery middleware.
+				defer func() { recovered = recover() }()
+				handler(httptest.NewRecorder(), r)
+			}()
+			if got := recovered != nil; got != tt.options.Repanic {
+				t.Errorf("got repanic %v, want %v", got, tt.options.Repanic)
+			}
+
+			delivered := len(transport.Events())
+			if tt.options.WaitForDelivery && delivered != 1 {
+				t.Errorf("got %d events delivered before the handler returned, want 1", delivered)
+			}
+			transport.Flush(time.Second)
+			if events := transport.Events(); len(events) != 1 || events[0].Message != "test" {
+				t.Errorf("got events %v, want the panic event", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package sentry
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// NewBreadcrumbTransport returns an http.RoundTripper that records every
+// request made with rt as an "http" breadcrumb, with the method, the URL
+// without its query string, the status code of the response and the duration
+// of the request. Breadcrumbs are recorded on the hub of the context of the
+// request, or on the current hub. If rt is nil, http.DefaultTransport is used.
+//
+// Unlike spans, breadcrumbs are recorded whether tracing is enabled or not:
+//
+//	client := &http.Client{Transport: sentry.NewBreadcrumbTransport(nil)}
+//
+// Failed requests are recorded with the level error and the error as reason.
+// Requests sent by the SDK, for example to deliver events to Sentry or to a
+// Relay, are not recorded.
+func NewBreadcrumbTransport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &breadcrumbTransport{rt: rt}
+}
+
+// breadcrumbTransport is the http.RoundTripper of NewBreadcrumbTransport.
+type breadcrumbTransport struct {
+	rt http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *breadcrumbTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context().Value(sdkRequestContextKey) != nil {
+		return t.rt.RoundTrip(req)
+	}
+	start := time.Now()
+	resp, err := t.rt.RoundTrip(req)
+	duration := time.Since(start)
+
+	var b *Breadcrumb
+	if err != nil {
+		b = NewHTTPBreadcrumb(req.Method, strippedURL(req.URL), 0)
+		delete(b.Data, "status_code")
+		b.Data["reason"] = err.Error()
+		b.Level = LevelError
+	} else {
+		b = NewHTTPBreadcrumb(req.Method, strippedURL(req.URL), resp.StatusCode)
+	}
+	b.Data["duration_ms"] = float64(duration) / float64(time.Millisecond)
+
+	hub := GetHubFromContext(req.Context())
+	if hub == nil {
+		hub = CurrentHub()
+	}
+	hub.AddBreadcrumb(b, &BreadcrumbHint{"request": req, "response": resp})
+	return resp, err
+}
+
+// strippedURL returns u without its user information, query string and
+// fragment, which may hold secrets.
+func strippedURL(u *url.URL) string {
+	stripped := *u
+	stripped.User = nil
+	stripped.RawQuery = ""
+	stripped.ForceQuery = false
+	stripped.Fragment = ""
+	return stripped.String()
+}
+
+// HTTPClientBreadcrumbs is an integration that records the requests made with
+// httpClient as breadcrumbs, by wrapping its transport with
+// NewBreadcrumbTransport. If the transport of httpClient is nil,
+// http.DefaultTransport is wrapped, without replacing it.
+//
+// The integration is opt-in. To record the requests made with the functions of
+// the net/http package like http.Get, pass http.DefaultClient:
+//
+//	sentry.Init(sentry.ClientOptions{
+//		Integrations: func(integrations []sentry.Integration) []sentry.Integration {
+//			return append(integrations, sentry.HTTPClientBreadcrumbs(http.DefaultClient))
+//		},
+//	})
+func HTTPClientBreadcrumbs(httpClient *http.Client) Integration {
+	return &httpClientBreadcrumbsIntegration{httpClient: httpClient}
+}
+
+type httpClientBreadcrumbsIntegration struct {
+	httpClient *http.Client
+	once       sync.Once
+}
+
+func (hi *httpClientBreadcrumbsIntegration) Name() string {
+	return "HTTPClientBreadcrumbs"
+}
+
+func (hi *httpClientBreadcrumbsIntegration) SetupOnce(client *Client) {
+	hi.once.Do(func() {
+		if _, ok := hi.httpClient.Transport.(*breadcrumbTransport); !ok {
+			hi.httpClient.Transport = NewBreadcrumbTransport(hi.httpClient.Transport)
+		}
+	})
+}
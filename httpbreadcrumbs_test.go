@@ -0,0 +1,91 @@
+package sentry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// errRoundTripper is an http.RoundTripper that always fails.
+type errRoundTripper struct {
+	err error
+}
+
+func (rt errRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, rt.err
+}
+
+func TestBreadcrumbTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	hub, _, scope := setupHubTest()
+	ctx := SetHubOnContext(context.Background(), hub)
+	client := &http.Client{Transport: NewBreadcrumbTransport(nil)}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://user:secret@"+srv.Listener.Addr().String()+"/users?token=secret#top", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// Requests sent by the SDK, that need not carry an X-Sentry-Auth header
+	// when sent to a Relay, are not recorded.
+	req, _ = http.NewRequestWithContext(ctx, "POST", srv.URL+"/api/1/envelope/", nil)
+	resp, err = doRequest(client, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	errFailed := errors.New("connection refused")
+	req, _ = http.NewRequestWithContext(ctx, "POST", "http://example.com/", nil)
+	if _, err := NewBreadcrumbTransport(errRoundTripper{errFailed}).RoundTrip(req); err != errFailed {
+		t.Fatalf("got error %v, want %v", err, errFailed)
+	}
+
+	if len(scope.breadcrumbs) != 2 {
+		t.Fatalf("got %d breadcrumbs, want 2", len(scope.breadcrumbs))
+	}
+	b := scope.breadcrumbs[0]
+	assertEqual(t, b.Category, "http")
+	assertEqual(t, b.Level, LevelWarning)
+	assertEqual(t, b.Data["method"], "GET")
+	assertEqual(t, b.Data["url"], srv.URL+"/users")
+	assertEqual(t, b.Data["status_code"], http.StatusNotFound)
+	if _, ok := b.Data["duration_ms"].(float64); !ok {
+		t.Errorf("got duration %v, want milliseconds", b.Data["duration_ms"])
+	}
+
+	b = scope.breadcrumbs[1]
+	assertEqual(t, b.Level, LevelError)
+	assertEqual(t, b.Data["reason"], "connection refused")
+	if _, ok := b.Data["status_code"]; ok {
+		t.Error("failed request has a status code")
+	}
+}
+
+func TestHTTPClientBreadcrumbs(t *testing.T) {
+	defaultTransport := http.DefaultTransport
+	httpClient := &http.Client{}
+
+	integration := HTTPClientBreadcrumbs(httpClient)
+	integration.SetupOnce(nil)
+	integration.SetupOnce(nil)
+	HTTPClientBreadcrumbs(httpClient).SetupOnce(nil)
+
+	rt, ok := httpClient.Transport.(*breadcrumbTransport)
+	if !ok {
+		t.Fatalf("transport is %T, want a breadcrumb transport", httpClient.Transport)
+	}
+	assertEqual(t, rt.rt, defaultTransport)
+	// http.DefaultTransport is left untouched, as callers clone it.
+	if _, ok := http.DefaultTransport.(*http.Transport); !ok {
+		t.Fatalf("http.DefaultTransport is %T, want an *http.Transport", http.DefaultTransport)
+	}
+}
@@ -2,7 +2,9 @@ package sentry
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -70,6 +72,7 @@ type stack []*layer
 
 // NewHub returns an instance of a Hub with provided Client and Scope bound.
 func NewHub(client *Client, scope *Scope) *Hub {
+	applyScopeLimits(scope, client)
 	hub := Hub{
 		stack: &stack{{
 			client: client,
@@ -116,6 +119,10 @@ func (hub *Hub) stackTop() *layer {
 }
 
 // Clone returns a copy of the current Hub with top-most scope and client copied over.
+//
+// The scope of the new hub is a deep copy of the top-most scope, see
+// Scope.Clone, so that it can be modified without affecting the original hub.
+// Use Clone to create a hub for each request or goroutine.
 func (hub *Hub) Clone() *Hub {
 	top := hub.stackTop()
 	scope := top.scope
@@ -183,6 +190,21 @@ func (hub *Hub) PopScope() {
 func (hub *Hub) BindClient(client *Client) {
 	top := hub.stackTop()
 	top.SetClient(client)
+	applyScopeLimits(top.scope, client)
+}
+
+// applyScopeLimits sets the ScopeLimits of the options of client, if any, and
+// its MaxRequestBodySize on scope.
+func applyScopeLimits(scope *Scope, client *Client) {
+	if scope == nil || client == nil {
+		return
+	}
+	scope.mu.Lock()
+	scope.requestBodySize = client.options.MaxRequestBodySize
+	scope.mu.Unlock()
+	if limits := client.options.ScopeLimits; limits != (ScopeLimits{}) {
+		scope.SetLimits(limits)
+	}
 }
 
 // WithScope runs f in an isolated temporary scope.
@@ -217,13 +239,21 @@ func (hub *Hub) ConfigureScope(f func(scope *Scope)) {
 // passing it a top-level Scope.
 // Returns EventID if successfully, or nil if there's no Scope or Client available.
 func (hub *Hub) CaptureEvent(event *Event) *EventID {
+	return hub.CaptureEventWithHint(event, nil)
+}
+
+// CaptureEventWithHint is like CaptureEvent, but also passes a hint to the
+// client. The hint is made available to event processors and to BeforeSend,
+// which can use it to access, for example, the original error or the HTTP
+// request associated with the event.
+func (hub *Hub) CaptureEventWithHint(event *Event, hint *EventHint) *EventID {
 	client, scope := hub.Client(), hub.Scope()
-	if client == nil || scope == nil {
+	if client == nil || scope == nil || client.discards(scope) {
 		return nil
 	}
-	eventID := client.CaptureEvent(event, nil, scope)
+	eventID := client.CaptureEvent(event, hint, scope)
 
-	if event.Type != transactionType && eventID != nil {
+	if event != nil && event.Type != transactionType && event.Type != checkInType && eventID != nil {
 		hub.mu.Lock()
 		hub.lastEventID = *eventID
 		hub.mu.Unlock()
@@ -232,14 +262,14 @@ func (hub *Hub) CaptureEvent(event *Event) *EventID {
 }
 
 // CaptureMessage calls the method of a same name on currently bound Client instance
-// passing it a top-level Scope.
+// passing it a top-level Scope. Options, if any, only apply to this event.
 // Returns EventID if successfully, or nil if there's no Scope or Client available.
-func (hub *Hub) CaptureMessage(message string) *EventID {
+func (hub *Hub) CaptureMessage(message string, options ...CaptureOption) *EventID {
 	client, scope := hub.Client(), hub.Scope()
-	if client == nil || scope == nil {
+	if client == nil || scope == nil || client.discards(scope) {
 		return nil
 	}
-	eventID := client.CaptureMessage(message, nil, scope)
+	eventID := client.CaptureMessage(message, nil, applyCaptureOptions(scope, options))
 
 	if eventID != nil {
 		hub.mu.Lock()
@@ -249,15 +279,69 @@ func (hub *Hub) CaptureMessage(message string) *EventID {
 	return eventID
 }
 
+// CaptureMessagef is like CaptureMessage, with the message formatted according
+// to a format specifier, as in fmt.Sprintf. The event has a log entry with the
+// format and the arguments, so that messages that only differ by their
+// arguments are grouped together. See NewLogEntry.
+func (hub *Hub) CaptureMessagef(format string, args ...interface{}) *EventID {
+	client := hub.Client()
+	if client == nil || client.discards(hub.Scope()) {
+		return nil
+	}
+	event := client.eventFromMessage(fmt.Sprintf(format, args...), LevelInfo)
+	if event.Message != "" {
+		event.LogEntry = NewLogEntry(format, args...)
+	}
+	return hub.CaptureEvent(event)
+}
+
+// CaptureWarning is like CaptureMessage, but the event has level warning.
+func (hub *Hub) CaptureWarning(message string) *EventID {
+	return hub.captureMessageWithLevel(message, LevelWarning)
+}
+
+// CaptureInfo is like CaptureMessage, but the event has level info.
+func (hub *Hub) CaptureInfo(message string) *EventID {
+	return hub.captureMessageWithLevel(message, LevelInfo)
+}
+
+// captureMessageWithLevel captures a message event with the given level on the
+// currently bound Client, passing it the top-level Scope.
+func (hub *Hub) captureMessageWithLevel(message string, level Level) *EventID {
+	client := hub.Client()
+	if client == nil || client.discards(hub.Scope()) {
+		return nil
+	}
+	return hub.CaptureEvent(client.eventFromMessage(message, level))
+}
+
+// CaptureCheckIn sends a check-in of a monitored job using the currently bound
+// Client, passing it the top-level Scope. Returns the ID of the check-in, which
+// can be used to update a check-in in progress, or nil if there's no Scope or
+// Client available.
+func (hub *Hub) CaptureCheckIn(checkIn *CheckIn) *EventID {
+	event := &Event{Type: checkInType, CheckIn: checkIn}
+	if checkIn != nil && checkIn.ID != "" {
+		event.EventID = checkIn.ID
+	}
+	return hub.CaptureEvent(event)
+}
+
 // CaptureException calls the method of a same name on currently bound Client instance
-// passing it a top-level Scope.
+// passing it a top-level Scope. Options, if any, only apply to this event:
+//
+//	hub.CaptureException(err,
+//		sentry.WithTags(map[string]string{"payment.provider": "acme"}),
+//		sentry.WithLevel(sentry.LevelFatal),
+//	)
+//
 // Returns EventID if successfully, or nil if there's no Scope or Client available.
-func (hub *Hub) CaptureException(exception error) *EventID {
+func (hub *Hub) CaptureException(exception error, options ...CaptureOption) *EventID {
 	client, scope := hub.Client(), hub.Scope()
-	if client == nil || scope == nil {
+	if client == nil || scope == nil || client.dropsError(scope) {
 		return nil
 	}
-	eventID := client.CaptureException(exception, &EventHint{OriginalException: exception}, scope)
+	eventID := client.captureException(exception, &EventHint{OriginalException: exception}, applyCaptureOptions(scope, options))
 
 	if eventID != nil {
 		hub.mu.Lock()
@@ -280,6 +364,11 @@ func (hub *Hub) AddBreadcrumb(breadcrumb *Breadcrumb, hint *BreadcrumbHint) {
 		return
 	}
 
+	// Breadcrumbs are only useful to events that are not discarded.
+	if atomic.LoadInt32(&client.closed) != 0 || client.unobserved(hub.Scope()) {
+		return
+	}
+
 	options := client.Options()
 	max := defaultMaxBreadcrumbs
 
@@ -291,6 +380,10 @@ func (hub *Hub) AddBreadcrumb(breadcrumb *Breadcrumb, hint *BreadcrumbHint) {
 		return
 	}
 
+	if breadcrumb.Timestamp.IsZero() {
+		breadcrumb.Timestamp = clockFor(client).Now()
+	}
+
 	if options.BeforeBreadcrumb != nil {
 		h := &BreadcrumbHint{}
 		if hint != nil {
@@ -340,6 +433,10 @@ func (hub *Hub) RecoverWithContext(ctx context.Context, err interface{}) *EventI
 // Sentry server, blocking for at most the given timeout. It returns false if
 // the timeout was reached. In that case, some events may not have been sent.
 //
+// All the clients bound on the stack of the hub are flushed, concurrently, so
+// that events captured with a client bound in a scope that has been popped
+// since are delivered too. Flush returns false if there is no client.
+//
 // Flush should be called before terminating the program to avoid
 // unintentionally dropping events.
 //
@@ -348,13 +445,86 @@ func (hub *Hub) RecoverWithContext(ctx context.Context, err interface{}) *EventI
 // the network synchronously, configure it to use the HTTPSyncTransport in the
 // call to Init.
 func (hub *Hub) Flush(timeout time.Duration) bool {
+	return hub.flush(func(client *Client) bool {
+		return client.Flush(timeout)
+	})
+}
+
+// FlushWithContext is like Flush, but blocks until ctx is done rather than for
+// a timeout. See Client.FlushWithContext.
+func (hub *Hub) FlushWithContext(ctx context.Context) bool {
+	return hub.flush(func(client *Client) bool {
+		return client.FlushWithContext(ctx)
+	})
+}
+
+// flush flushes the clients bound on the stack of the hub with f.
+func (hub *Hub) flush(f func(client *Client) bool) bool {
+	clients := hub.clients()
+
+	switch len(clients) {
+	case 0:
+		return false
+	case 1:
+		return f(clients[0])
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ok := true
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *Client) {
+			defer wg.Done()
+			if !f(client) {
+				mu.Lock()
+				ok = false
+				mu.Unlock()
+			}
+		}(client)
+	}
+	wg.Wait()
+	return ok
+}
+
+// clients returns the distinct clients bound on the stack of the hub, from
+// the top.
+func (hub *Hub) clients() []*Client {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	var clients []*Client
+	stack := *hub.stack
+	for i := len(stack) - 1; i >= 0; i-- {
+		client := stack[i].Client()
+		if client == nil {
+			continue
+		}
+		seen := false
+		for _, c := range clients {
+			if c == client {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// Close shuts down the bound client, waiting for at most the given timeout for
+// buffered events to be sent. See Client.Close. It returns false if there is
+// no client or the timeout was reached.
+func (hub *Hub) Close(timeout time.Duration) bool {
 	client := hub.Client()
 
 	if client == nil {
 		return false
 	}
 
-	return client.Flush(timeout)
+	return client.Close(timeout)
 }
 
 // HasHubOnContext checks whether Hub instance is bound to a given Context struct.
@@ -2,9 +2,13 @@ package sentry
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http/httptest"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -46,6 +50,39 @@ func TestCloneHubInheritsClientAndScope(t *testing.T) {
 	assertEqual(t, clone.Scope(), scope)
 }
 
+func TestCloneHubDoesNotAliasScopeData(t *testing.T) {
+	hub, _, scope := setupHubTest()
+	scope.SetTag("tag", "original")
+	scope.SetExtra("extra", "original")
+	scope.SetContext("context", map[string]interface{}{"key": "original"})
+	scope.SetFingerprint([]string{"original"})
+	scope.AddBreadcrumb(&Breadcrumb{Message: "original", Data: map[string]interface{}{"key": "original"}}, maxBreadcrumbs)
+	scope.eventProcessors = make([]EventProcessor, 0, 10)
+	scope.AddEventProcessor(func(event *Event, hint *EventHint) *Event { return event })
+
+	clone := hub.Clone()
+	clone.ConfigureScope(func(s *Scope) {
+		s.SetTag("tag", "modified")
+		s.SetExtra("extra", "modified")
+		s.contexts["context"].(map[string]interface{})["key"] = "modified"
+		s.fingerprint[0] = "modified"
+		s.breadcrumbs[0].Message = "modified"
+		s.breadcrumbs[0].Data["key"] = "modified"
+		s.AddEventProcessor(func(event *Event, hint *EventHint) *Event { return nil })
+	})
+
+	assertEqual(t, scope.tags["tag"], "original")
+	assertEqual(t, scope.extra["extra"], "original")
+	assertEqual(t, scope.contexts["context"], map[string]interface{}{"key": "original"})
+	assertEqual(t, scope.fingerprint, []string{"original"})
+	assertEqual(t, scope.breadcrumbs[0].Message, "original")
+	assertEqual(t, scope.breadcrumbs[0].Data, map[string]interface{}{"key": "original"})
+	assertEqual(t, len(scope.eventProcessors), 1)
+	if got := scope.eventProcessors[:cap(scope.eventProcessors)][1]; got != nil {
+		t.Error("event processor added to the clone leaked into the original scope")
+	}
+}
+
 func TestPushScopeAddsScopeOnTopOfStack(t *testing.T) {
 	hub, _, _ := setupHubTest()
 	hub.PushScope()
@@ -190,6 +227,84 @@ func TestLastEventIDUpdatesAfterCaptures(t *testing.T) {
 	assertEqual(t, *eventID, hub.LastEventID())
 }
 
+func TestCaptureMessageHelpers(t *testing.T) {
+	hub, client, _ := setupHubTest()
+	transport := &TransportMock{}
+	client.Transport = transport
+
+	tests := []struct {
+		capture func() *EventID
+		message string
+		level   Level
+	}{
+		{func() *EventID { return hub.CaptureMessagef("%d %s", 42, "wat") }, "42 wat", LevelInfo},
+		{func() *EventID { return hub.CaptureWarning("careful") }, "careful", LevelWarning},
+		{func() *EventID { return hub.CaptureInfo("fyi") }, "fyi", LevelInfo},
+	}
+	for _, tt := range tests {
+		eventID := tt.capture()
+		if eventID == nil {
+			t.Fatalf("event %q was not captured", tt.message)
+		}
+		assertEqual(t, *eventID, hub.LastEventID())
+		event := transport.lastEvent
+		assertEqual(t, event.Message, tt.message)
+		assertEqual(t, event.Level, tt.level)
+	}
+}
+
+func TestCaptureEventWithHint(t *testing.T) {
+	var seen []string
+	check := func(stage string, hint *EventHint) {
+		if hint == nil || hint.Data != "data" || hint.Request == nil {
+			t.Errorf("%s: hint not propagated: %+v", stage, hint)
+		}
+		seen = append(seen, stage)
+	}
+	client, err := NewClient(ClientOptions{
+		Transport: &TransportMock{},
+		BeforeSend: func(event *Event, hint *EventHint) *Event {
+			check("BeforeSend", hint)
+			return event
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.AddEventProcessor(func(event *Event, hint *EventHint) *Event {
+		check("client processor", hint)
+		return event
+	})
+	scope := NewScope()
+	scope.AddEventProcessor(func(event *Event, hint *EventHint) *Event {
+		check("scope processor", hint)
+		return event
+	})
+	hub := NewHub(client, scope)
+
+	hint := &EventHint{Data: "data", Request: httptest.NewRequest("GET", "/", nil)}
+	if hub.CaptureEventWithHint(&Event{Message: "msg"}, hint) == nil {
+		t.Fatal("event was not captured")
+	}
+	assertEqual(t, seen, []string{"scope processor", "client processor", "BeforeSend"})
+}
+
+func TestCaptureEventNilHint(t *testing.T) {
+	hub, client, scope := setupHubTest()
+	client.Transport = &TransportMock{}
+	scope.AddEventProcessor(func(event *Event, hint *EventHint) *Event {
+		if hint == nil {
+			t.Error("event processors should get a non-nil hint")
+		}
+		return event
+	})
+	hub.CaptureMessage("msg")
+	// A nil event is reported as a usage error instead of panicking.
+	if hub.CaptureEvent(nil) == nil {
+		t.Error("nil event should be reported")
+	}
+}
+
 func TestLastEventIDNotChangedForTransactions(t *testing.T) {
 	hub, _, _ := setupHubTest()
 
@@ -200,6 +315,26 @@ func TestLastEventIDNotChangedForTransactions(t *testing.T) {
 	assertEqual(t, *errorID, hub.LastEventID())
 }
 
+func TestCaptureCheckIn(t *testing.T) {
+	hub, client, _ := setupHubTest()
+	transport := &TransportMock{}
+	client.Transport = transport
+
+	errorID := hub.CaptureException(fmt.Errorf("wat"))
+	checkInID := hub.CaptureCheckIn(&CheckIn{MonitorSlug: "job", Status: CheckInStatusInProgress})
+	if checkInID == nil {
+		t.Fatal("check-in was not captured")
+	}
+	assertEqual(t, *errorID, hub.LastEventID())
+
+	updatedID := hub.CaptureCheckIn(&CheckIn{ID: *checkInID, MonitorSlug: "job", Status: CheckInStatusOK})
+	assertEqual(t, *updatedID, *checkInID)
+
+	event := transport.lastEvent
+	assertEqual(t, event.Type, checkInType)
+	assertEqual(t, event.CheckIn.Status, CheckInStatusOK)
+}
+
 func TestLastEventIDDoesNotReset(t *testing.T) {
 	hub, client, _ := setupHubTest()
 
@@ -408,3 +543,126 @@ func TestConcurrentHubClone(t *testing.T) {
 		t.Errorf("Events mismatch (-want +got):\n%s", diff)
 	}
 }
+
+// flushCountingTransport is a TransportMock that counts calls to Flush.
+type flushCountingTransport struct {
+	TransportMock
+	flushed int32
+	ok      bool
+}
+
+func (t *flushCountingTransport) Flush(timeout time.Duration) bool {
+	atomic.AddInt32(&t.flushed, 1)
+	return t.ok
+}
+
+func TestFlushFlushesAllClients(t *testing.T) {
+	newClient := func(transport Transport) *Client {
+		client, err := NewClient(ClientOptions{Transport: transport})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+	bottom := &flushCountingTransport{ok: true}
+	top := &flushCountingTransport{ok: true}
+	hub := NewHub(newClient(bottom), NewScope())
+	hub.PushScope()
+	hub.PushScope()
+	hub.BindClient(newClient(top))
+
+	assertEqual(t, hub.Flush(time.Second), true)
+	assertEqual(t, atomic.LoadInt32(&bottom.flushed), int32(1), "each client is flushed once")
+	assertEqual(t, atomic.LoadInt32(&top.flushed), int32(1))
+
+	bottom.ok = false
+	assertEqual(t, hub.Flush(time.Second), false, "Flush fails if any client times out")
+
+	assertEqual(t, NewHub(nil, NewScope()).Flush(time.Second), false)
+}
+
+// newDisabledHub returns a hub with a client created with an empty DSN.
+func newDisabledHub(tb testing.TB) *Hub {
+	client, err := NewClient(ClientOptions{})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return NewHub(client, NewScope())
+}
+
+// newSampledOutHub returns a hub whose client samples out all error events.
+func newSampledOutHub(tb testing.TB) *Hub {
+	client, err := NewClient(ClientOptions{
+		Transport:  &TransportMock{},
+		SampleRate: 1e-12,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return NewHub(client, NewScope())
+}
+
+func TestDroppedCapturesDoNotAllocate(t *testing.T) {
+	err := errors.New("error")
+	breadcrumb := &Breadcrumb{Message: "breadcrumb", Timestamp: testNow}
+	tests := []struct {
+		name string
+		hub  *Hub
+		f    func(hub *Hub)
+	}{
+		{"Disabled/CaptureException", newDisabledHub(t), func(hub *Hub) { hub.CaptureException(err) }},
+		{"Disabled/CaptureMessage", newDisabledHub(t), func(hub *Hub) { hub.CaptureMessage("message") }},
+		{"Disabled/AddBreadcrumb", newDisabledHub(t), func(hub *Hub) { hub.AddBreadcrumb(breadcrumb, nil) }},
+		{"SampledOut/CaptureException", newSampledOutHub(t), func(hub *Hub) { hub.CaptureException(err) }},
+		{"SampledOut/CaptureMessage", newSampledOutHub(t), func(hub *Hub) { hub.CaptureMessage("message") }},
+	}
+	for _, tt := range tests {
+		if allocs := testing.AllocsPerRun(100, func() { tt.f(tt.hub) }); allocs != 0 {
+			t.Errorf("%s: got %v allocations, want 0", tt.name, allocs)
+		}
+	}
+}
+
+func TestDisabledClientWithBeforeSendProcessesEvents(t *testing.T) {
+	var events int
+	client, err := NewClient(ClientOptions{
+		BeforeSend: func(event *Event, hint *EventHint) *Event {
+			events++
+			return event
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(client, NewScope())
+	hub.CaptureException(errors.New("error"))
+	hub.CaptureMessage("message")
+	assertEqual(t, events, 2)
+}
+
+func BenchmarkCaptureExceptionDisabled(b *testing.B) {
+	hub := newDisabledHub(b)
+	err := errors.New("error")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		hub.CaptureException(err)
+	}
+}
+
+func BenchmarkCaptureExceptionSampledOut(b *testing.B) {
+	hub := newSampledOutHub(b)
+	err := errors.New("error")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		hub.CaptureException(err)
+	}
+}
+
+func BenchmarkAddBreadcrumbDisabled(b *testing.B) {
+	hub := newDisabledHub(b)
+	breadcrumb := &Breadcrumb{Message: "breadcrumb", Timestamp: testNow}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		hub.AddBreadcrumb(breadcrumb, nil)
+	}
+}
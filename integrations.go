@@ -2,11 +2,13 @@ package sentry
 
 import (
 	"fmt"
+	"os"
 	"regexp"
 	"runtime"
 	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 )
 
 // ================================
@@ -27,17 +29,27 @@ func (mi *modulesIntegration) SetupOnce(client *Client) {
 }
 
 func (mi *modulesIntegration) processor(event *Event, hint *EventHint) *Event {
-	if len(event.Modules) == 0 {
-		mi.once.Do(func() {
-			info, ok := debug.ReadBuildInfo()
-			if !ok {
-				Logger.Print("The Modules integration is not available in binaries built without module support.")
-				return
-			}
-			mi.modules = extractModules(info)
-		})
+	// Preserve modules set explicitly on the event.
+	if len(event.Modules) > 0 {
+		return event
+	}
+	mi.once.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			Logger.Print("The Modules integration is not available in binaries built without module support.")
+			return
+		}
+		mi.modules = extractModules(info)
+	})
+	if mi.modules == nil {
+		return event
+	}
+	// Each event gets its own copy, so that changes made to one event, for
+	// example in BeforeSend, don't affect others.
+	event.Modules = make(map[string]string, len(mi.modules))
+	for path, version := range mi.modules {
+		event.Modules[path] = version
 	}
-	event.Modules = mi.modules
 	return event
 }
 
@@ -59,13 +71,24 @@ func extractModules(info *debug.BuildInfo) map[string]string {
 // Environment Integration
 // ================================
 
-type environmentIntegration struct{}
+// appStartTime approximates when the program started, as the time the package
+// was initialized.
+var appStartTime = time.Now()
+
+type environmentIntegration struct {
+	buildType string
+	container containerInfo
+}
 
 func (ei *environmentIntegration) Name() string {
 	return "Environment"
 }
 
 func (ei *environmentIntegration) SetupOnce(client *Client) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		ei.buildType = buildType(info)
+	}
+	ei.container = readContainerInfo("/", os.Getenv)
 	client.AddEventProcessor(ei.processor)
 }
 
@@ -74,7 +97,7 @@ func (ei *environmentIntegration) processor(event *Event, hint *EventHint) *Even
 	if event.Contexts == nil {
 		event.Contexts = make(map[string]interface{})
 	}
-	for _, name := range []string{"device", "os", "runtime"} {
+	for _, name := range []string{"device", "os", "runtime", "app"} {
 		if event.Contexts[name] == nil {
 			event.Contexts[name] = make(map[string]interface{})
 		}
@@ -90,6 +113,7 @@ func (ei *environmentIntegration) processor(event *Event, hint *EventHint) *Even
 		if _, ok := deviceContext["num_cpu"]; !ok {
 			deviceContext["num_cpu"] = runtime.NumCPU()
 		}
+		ei.container.setDeviceContext(deviceContext)
 	}
 	if osContext, ok := event.Contexts["os"].(map[string]interface{}); ok {
 		if _, ok := osContext["name"]; !ok {
@@ -113,9 +137,57 @@ func (ei *environmentIntegration) processor(event *Event, hint *EventHint) *Even
 			runtimeContext["go_numcgocalls"] = runtime.NumCgoCall()
 		}
 	}
+	for key, value := range ei.container.tags {
+		if event.Tags == nil {
+			event.Tags = make(map[string]string)
+		}
+		if _, ok := event.Tags[key]; !ok {
+			event.Tags[key] = value
+		}
+	}
+	if appContext, ok := event.Contexts["app"].(map[string]interface{}); ok {
+		if _, ok := appContext["app_start_time"]; !ok {
+			appContext["app_start_time"] = appStartTime
+		}
+		if _, ok := appContext["build_type"]; !ok && ei.buildType != "" {
+			appContext["build_type"] = ei.buildType
+		}
+		// Reading memory statistics stops the world briefly, which is
+		// acceptable for errors but not for every transaction.
+		if event.Type != transactionType {
+			setMemoryStats(appContext)
+		}
+	}
 	return event
 }
 
+// setMemoryStats sets the memory statistics of the runtime that show memory
+// pressure in the app context, preserving existing values. Memory is in
+// bytes and pauses in nanoseconds.
+func setMemoryStats(appContext map[string]interface{}) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	stats := map[string]interface{}{
+		"app_memory":         m.Sys,
+		"go_heap_alloc":      m.HeapAlloc,
+		"go_heap_sys":        m.HeapSys,
+		"go_heap_objects":    m.HeapObjects,
+		"go_num_gc":          m.NumGC,
+		"go_gc_pause_total":  m.PauseTotalNs,
+		"go_gc_cpu_fraction": m.GCCPUFraction,
+		"go_next_gc":         m.NextGC,
+	}
+	if m.NumGC > 0 {
+		stats["go_last_gc"] = time.Unix(0, int64(m.LastGC))
+		stats["go_last_gc_pause"] = m.PauseNs[(m.NumGC+255)%256]
+	}
+	for key, value := range stats {
+		if _, ok := appContext[key]; !ok {
+			appContext[key] = value
+		}
+	}
+}
+
 // ================================
 // Ignore Errors Integration
 // ================================
@@ -176,6 +248,163 @@ func getIgnoreErrorsSuspects(event *Event) []string {
 	return suspects
 }
 
+// ================================
+// Dedupe Integration
+// ================================
+
+// dedupeWindow is how long the dedupe integration aggregates duplicates of an
+// event after sending it.
+const dedupeWindow = 5 * time.Second
+
+// dedupeMaxEntries bounds the number of distinct errors the dedupe integration
+// keeps track of.
+const dedupeMaxEntries = 100
+
+// dedupeIntegration aggregates an error event with the duplicates captured
+// immediately after it, which protects against flooding a project with the
+// same error reported from a retry loop without hiding how often it happens.
+//
+// Duplicates captured within dedupeWindow after an event is sent are dropped
+// and counted. The counts are sent with the next occurrence of the same error
+// captured after the window, in the extra data: "occurrences" is the number of
+// events it stands for, those dropped and itself included, and "first_seen"
+// and "last_seen" are when the first and last dropped duplicates were
+// captured. Other events, like messages, are never deduplicated.
+type dedupeIntegration struct {
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[string]*dedupeEntry // by signature
+	last    string                  // the signature of the last event sent
+}
+
+// dedupeEntry tracks the duplicates of an error event sent.
+type dedupeEntry struct {
+	exception interface{}
+	sent      time.Time // when the event was sent
+	firstSeen time.Time // when its first duplicate was captured
+	lastSeen  time.Time // when its last duplicate was captured
+	dropped   int       // its duplicates dropped
+}
+
+func (di *dedupeIntegration) Name() string {
+	return "Dedupe"
+}
+
+func (di *dedupeIntegration) SetupOnce(client *Client) {
+	di.clock = clockFor(client)
+	di.entries = make(map[string]*dedupeEntry)
+	client.AddEventProcessor(di.processor)
+}
+
+func (di *dedupeIntegration) processor(event *Event, hint *EventHint) *Event {
+	// Only errors are deduplicated: messages, transactions and check-ins
+	// legitimately repeat.
+	if event.Type == transactionType || event.Type == checkInType || len(event.Exception) == 0 {
+		return event
+	}
+
+	var exception interface{}
+	if hint != nil {
+		if hint.OriginalException != nil {
+			exception = hint.OriginalException
+		} else {
+			exception = hint.RecoveredException
+		}
+	}
+	signature := dedupeSignature(event)
+	now := di.clock.Now()
+
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	// The same error value captured again is a duplicate of the last event,
+	// even if captured from elsewhere.
+	if last, ok := di.entries[di.last]; ok && exception != nil && sameException(exception, last.exception) {
+		signature = di.last
+	}
+	entry, ok := di.entries[signature]
+	if ok && now.Sub(entry.sent) < dedupeWindow {
+		if entry.dropped == 0 {
+			entry.firstSeen = now
+		}
+		entry.dropped++
+		entry.lastSeen = now
+		Logger.Println("Event dropped due to being a duplicate of previously captured event.")
+		return nil
+	}
+
+	if ok && entry.dropped > 0 {
+		if event.Extra == nil {
+			event.Extra = make(map[string]interface{})
+		}
+		event.Extra["occurrences"] = entry.dropped + 1
+		event.Extra["first_seen"] = entry.firstSeen
+		event.Extra["last_seen"] = entry.lastSeen
+	}
+	if !ok && len(di.entries) >= dedupeMaxEntries {
+		di.evict(now)
+	}
+	di.entries[signature] = &dedupeEntry{exception: exception, sent: now}
+	di.last = signature
+	return event
+}
+
+// evict forgets the errors whose window ended without duplicates or, if there
+// are none, the error sent the longest ago. The caller must hold di.mu.
+func (di *dedupeIntegration) evict(now time.Time) {
+	oldest := ""
+	for signature, entry := range di.entries {
+		if entry.dropped == 0 && now.Sub(entry.sent) >= dedupeWindow {
+			delete(di.entries, signature)
+			continue
+		}
+		if oldest == "" || entry.sent.Before(di.entries[oldest].sent) {
+			oldest = signature
+		}
+	}
+	if len(di.entries) >= dedupeMaxEntries {
+		delete(di.entries, oldest)
+	}
+}
+
+// sameException reports whether a and b are the same value. Values that
+// cannot be compared, like slices or structs holding them in an interface
+// field, are never the same: comparing them with == panics.
+func sameException(a, b interface{}) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+// dedupeSignature returns a string that identifies the error reported in
+// event, made of its message, fingerprint and exceptions, including stack
+// traces.
+func dedupeSignature(event *Event) string {
+	var b strings.Builder
+	writeFrames := func(st *Stacktrace) {
+		if st == nil {
+			return
+		}
+		for _, f := range st.Frames {
+			fmt.Fprintf(&b, "\t%s.%s %s:%d\n", f.Module, f.Function, f.AbsPath, f.Lineno)
+		}
+	}
+	fmt.Fprintf(&b, "%q %q\n", event.Message, event.Fingerprint)
+	for _, ex := range event.Exception {
+		fmt.Fprintf(&b, "%q %q %q\n", ex.Type, ex.Value, ex.Module)
+		writeFrames(ex.Stacktrace)
+	}
+	for _, thread := range event.Threads {
+		if thread.Current {
+			writeFrames(thread.Stacktrace)
+		}
+	}
+	return b.String()
+}
+
 // ================================
 // Contextify Frames Integration
 // ================================
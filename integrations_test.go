@@ -2,14 +2,209 @@ package sentry
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"path/filepath"
 	"regexp"
 	"runtime/debug"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
 
+// countingIntegration is an integration that counts calls to SetupOnce.
+type countingIntegration struct {
+	name  string
+	setup int
+}
+
+func (ci *countingIntegration) Name() string { return ci.name }
+
+func (ci *countingIntegration) SetupOnce(client *Client) { ci.setup++ }
+
+func TestIntegrationsLifecycle(t *testing.T) {
+	custom := &countingIntegration{name: "Custom"}
+	duplicate := &countingIntegration{name: "Custom"}
+	client, err := NewClient(ClientOptions{
+		Integrations: func(integrations []Integration) []Integration {
+			var filtered []Integration
+			for _, integration := range integrations {
+				if integration.Name() != "Modules" {
+					filtered = append(filtered, integration)
+				}
+			}
+			return append(filtered, custom, nil, duplicate)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, custom.setup, 1)
+	assertEqual(t, duplicate.setup, 0, "duplicate integration should not be installed")
+	assertEqual(t, client.listIntegrations(), []string{"ContextifyFrames", "Custom", "Dedupe", "Environment", "IgnoreErrors"})
+}
+
+func TestDedupeIntegration(t *testing.T) {
+	transport := &TransportMock{}
+	clock := &ClockMock{now: goReleaseDate}
+	client, err := NewClient(ClientOptions{
+		Transport: transport,
+		Clock:     clock,
+		Integrations: func([]Integration) []Integration {
+			return []Integration{new(dedupeIntegration)}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	captureError := func(err error) {
+		client.CaptureException(err, &EventHint{OriginalException: err}, nil)
+	}
+
+	errA := errors.New("a")
+	captureError(errA)
+	captureError(errA)
+	assertEqual(t, len(transport.Events()), 1, "same error instance should be dropped")
+
+	captureError(errors.New("b"))
+	assertEqual(t, len(transport.Events()), 2, "different error should be sent")
+
+	for i := 0; i < 2; i++ {
+		captureError(errors.New("retry"))
+	}
+	assertEqual(t, len(transport.Events()), 3, "identical error should be dropped")
+
+	clock.Advance(dedupeWindow)
+	captureError(errors.New("retry"))
+	assertEqual(t, len(transport.Events()), 4, "duplicate should be sent after the window")
+
+	for i := 0; i < 2; i++ {
+		client.CaptureMessage("retry", nil, nil)
+	}
+	assertEqual(t, len(transport.Events()), 6, "messages should not be deduplicated")
+
+	for i := 0; i < 2; i++ {
+		client.CaptureEvent(&Event{Type: transactionType, Transaction: "tx"}, nil, nil)
+	}
+	assertEqual(t, len(transport.Events()), 8, "transactions should not be deduplicated")
+}
+
+func TestDedupeIntegrationOccurrences(t *testing.T) {
+	transport := &TransportMock{}
+	clock := &ClockMock{now: goReleaseDate}
+	client, err := NewClient(ClientOptions{
+		Transport: transport,
+		Clock:     clock,
+		Integrations: func([]Integration) []Integration {
+			return []Integration{new(dedupeIntegration)}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.CaptureException(errors.New("retry"), nil, nil)
+	clock.Advance(time.Second)
+	firstSeen := clock.Now()
+	for i := 0; i < 3; i++ {
+		client.CaptureException(errors.New("retry"), nil, nil)
+		clock.Advance(time.Second)
+	}
+	lastSeen := clock.Now().Add(-time.Second)
+	events := transport.Events()
+	assertEqual(t, len(events), 1, "duplicates should be dropped")
+	assertEqual(t, len(events[0].Extra), 0, "first event should not report duplicates")
+
+	// Unrelated errors do not carry the counts of others.
+	client.CaptureException(errors.New("other"), nil, nil)
+	events = transport.Events()
+	assertEqual(t, len(events), 2)
+	assertEqual(t, len(events[1].Extra), 0, "unrelated event should not report duplicates")
+
+	// The counts are sent with the next occurrence after the window.
+	clock.Advance(dedupeWindow)
+	client.CaptureException(errors.New("retry"), nil, nil)
+	events = transport.Events()
+	assertEqual(t, len(events), 3)
+	if diff := cmp.Diff(map[string]interface{}{
+		"occurrences": 4,
+		"first_seen":  firstSeen,
+		"last_seen":   lastSeen,
+	}, events[2].Extra); diff != "" {
+		t.Errorf("extra mismatch (-want +got):\n%s", diff)
+	}
+
+	// The other error had no duplicates.
+	client.CaptureException(errors.New("other"), nil, nil)
+	events = transport.Events()
+	assertEqual(t, len(events), 4)
+	assertEqual(t, len(events[3].Extra), 0, "event without duplicates should not report any")
+
+	// Counts are reset once sent.
+	clock.Advance(dedupeWindow)
+	client.CaptureException(errors.New("retry"), nil, nil)
+	events = transport.Events()
+	assertEqual(t, len(events), 5)
+	assertEqual(t, len(events[4].Extra), 0, "counts should be sent once")
+}
+
+func TestDedupeIntegrationMaxEntries(t *testing.T) {
+	clock := &ClockMock{now: goReleaseDate}
+	di := &dedupeIntegration{clock: clock, entries: make(map[string]*dedupeEntry)}
+	capture := func(message string) *Event {
+		return di.processor(&Event{Exception: []Exception{{Value: message}}}, nil)
+	}
+
+	capture("storm")
+	capture("storm")
+	for i := 0; i < 2*dedupeMaxEntries; i++ {
+		capture(fmt.Sprint(i))
+		clock.Advance(time.Millisecond)
+	}
+	assertEqual(t, len(di.entries), dedupeMaxEntries)
+}
+
+func TestDedupeIntegrationNonComparableError(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport: transport,
+		Integrations: func([]Integration) []Integration {
+			return []Integration{new(dedupeIntegration)}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{sliceError{"x"}, sliceError{"y"}} {
+		client.CaptureException(err, &EventHint{OriginalException: err}, nil)
+	}
+	assertEqual(t, len(transport.Events()), 2)
+
+	// The type of causeError is comparable, but comparing its values
+	// panics.
+	for _, cause := range []interface{}{[]string{"x"}, []string{"y"}} {
+		err := causeError{cause}
+		client.CaptureException(err, &EventHint{OriginalException: err}, nil)
+	}
+	assertEqual(t, len(transport.Events()), 3, "identical error should be dropped")
+}
+
+// sliceError is an error whose values cannot be compared with ==.
+type sliceError []string
+
+func (e sliceError) Error() string { return strings.Join(e, ", ") }
+
+// causeError is an error that holds a value of any type.
+type causeError struct {
+	cause interface{}
+}
+
+func (e causeError) Error() string { return "failed" }
+
 func TestTransformStringsIntoRegexps(t *testing.T) {
 	got := transformStringsIntoRegexps([]string{
 		"+",
@@ -344,6 +539,44 @@ func TestExtractModules(t *testing.T) {
 	}
 }
 
+func TestModulesIntegration(t *testing.T) {
+	mi := &modulesIntegration{}
+	mi.once.Do(func() {})
+	mi.modules = map[string]string{"my/module": "(devel)"}
+
+	event := mi.processor(NewEvent(), nil)
+	assertEqual(t, event.Modules, map[string]string{"my/module": "(devel)"})
+	event.Modules["other/module"] = "v1.0.0"
+	assertEqual(t, mi.modules, map[string]string{"my/module": "(devel)"}, "modules should be copied per event")
+
+	event = &Event{Modules: map[string]string{"explicit/module": "v2.0.0"}}
+	event = mi.processor(event, nil)
+	assertEqual(t, event.Modules, map[string]string{"explicit/module": "v2.0.0"}, "explicit modules should be preserved")
+}
+
+func TestEnvironmentIntegrationAppContext(t *testing.T) {
+	ei := &environmentIntegration{buildType: "release"}
+
+	event := ei.processor(&Event{
+		Contexts: map[string]interface{}{
+			"app": map[string]interface{}{"app_memory": 42},
+		},
+	}, nil)
+	app := event.Contexts["app"].(map[string]interface{})
+	assertEqual(t, app["app_start_time"], appStartTime)
+	assertEqual(t, app["build_type"], "release")
+	assertEqual(t, app["app_memory"], 42, "existing values should be preserved")
+	if heap, ok := app["go_heap_alloc"].(uint64); !ok || heap == 0 {
+		t.Errorf("got heap alloc %#v, want the allocated heap", app["go_heap_alloc"])
+	}
+
+	event = ei.processor(&Event{Type: transactionType}, nil)
+	app = event.Contexts["app"].(map[string]interface{})
+	if _, ok := app["go_heap_alloc"]; ok {
+		t.Error("transactions should not report memory statistics")
+	}
+}
+
 func TestEnvironmentIntegrationDoesNotOverrideExistingContexts(t *testing.T) {
 	transport := &TransportMock{}
 	client, err := NewClient(ClientOptions{
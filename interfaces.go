@@ -16,6 +16,9 @@ import (
 // transactionType is the type of a transaction event.
 const transactionType = "transaction"
 
+// checkInType is the type of a check-in event.
+const checkInType = "check_in"
+
 // Level marks the severity of the event.
 type Level string
 
@@ -50,7 +53,8 @@ type SdkPackage struct {
 type BreadcrumbHint map[string]interface{}
 
 // Breadcrumb specifies an application event that occurred before a Sentry event.
-// An event may contain one or more breadcrumbs.
+// An event may contain one or more breadcrumbs. A zero Timestamp is set when
+// the breadcrumb is added to a hub, with the Clock of its client.
 type Breadcrumb struct {
 	Type      string                 `json:"type,omitempty"`
 	Category  string                 `json:"category,omitempty"`
@@ -60,8 +64,80 @@ type Breadcrumb struct {
 	Timestamp time.Time              `json:"timestamp"`
 }
 
-// TODO: provide constants for known breadcrumb types.
-// See https://develop.sentry.dev/sdk/event-payloads/breadcrumbs/#breadcrumb-types.
+// Known breadcrumb types, used as Breadcrumb.Type. Sentry renders breadcrumbs
+// of each type differently. See
+// https://develop.sentry.dev/sdk/event-payloads/breadcrumbs/#breadcrumb-types.
+const (
+	BreadcrumbTypeDefault     = "default"
+	BreadcrumbTypeDebug       = "debug"
+	BreadcrumbTypeError       = "error"
+	BreadcrumbTypeNavigation  = "navigation"
+	BreadcrumbTypeHTTP        = "http"
+	BreadcrumbTypeInfo        = "info"
+	BreadcrumbTypeQuery       = "query"
+	BreadcrumbTypeTransaction = "transaction"
+	BreadcrumbTypeUI          = "ui"
+	BreadcrumbTypeUser        = "user"
+)
+
+// NewHTTPBreadcrumb returns a breadcrumb describing an HTTP request and the
+// status code of its response. The level is set to warning for client errors
+// (4xx) and to error for server errors (5xx).
+func NewHTTPBreadcrumb(method, url string, statusCode int) *Breadcrumb {
+	level := LevelInfo
+	switch {
+	case statusCode >= 500:
+		level = LevelError
+	case statusCode >= 400:
+		level = LevelWarning
+	}
+	return &Breadcrumb{
+		Type:     BreadcrumbTypeHTTP,
+		Category: "http",
+		Data: map[string]interface{}{
+			"method":      method,
+			"url":         url,
+			"status_code": statusCode,
+		},
+		Level: level,
+	}
+}
+
+// NewQueryBreadcrumb returns a breadcrumb describing a query, for example to
+// a database. The query is used as the breadcrumb message.
+func NewQueryBreadcrumb(query string) *Breadcrumb {
+	return &Breadcrumb{
+		Type:     BreadcrumbTypeQuery,
+		Category: "query",
+		Message:  query,
+		Level:    LevelInfo,
+	}
+}
+
+// NewNavigationBreadcrumb returns a breadcrumb describing a navigation from
+// one location to another, for example between pages or screens.
+func NewNavigationBreadcrumb(from, to string) *Breadcrumb {
+	return &Breadcrumb{
+		Type:     BreadcrumbTypeNavigation,
+		Category: "navigation",
+		Data: map[string]interface{}{
+			"from": from,
+			"to":   to,
+		},
+		Level: LevelInfo,
+	}
+}
+
+// NewUserBreadcrumb returns a breadcrumb describing an action performed by a
+// user, for example "ui.click" or "auth.login".
+func NewUserBreadcrumb(category, message string) *Breadcrumb {
+	return &Breadcrumb{
+		Type:     BreadcrumbTypeUser,
+		Category: category,
+		Message:  message,
+		Level:    LevelInfo,
+	}
+}
 
 // MarshalJSON converts the Breadcrumb struct to JSON.
 func (b *Breadcrumb) MarshalJSON() ([]byte, error) {
@@ -90,6 +166,11 @@ func (b *Breadcrumb) MarshalJSON() ([]byte, error) {
 	return json.Marshal((*breadcrumb)(b))
 }
 
+// AutoIPAddress is a placeholder for User.IPAddress, replaced by the IP address
+// of the client of the request of the event when ClientOptions.SendDefaultPII
+// is set, and removed otherwise.
+const AutoIPAddress = "{{auto}}"
+
 // User describes the user associated with an Event. If this is used, at least
 // an ID or an IP address should be provided.
 type User struct {
@@ -97,6 +178,18 @@ type User struct {
 	ID        string `json:"id,omitempty"`
 	IPAddress string `json:"ip_address,omitempty"`
 	Username  string `json:"username,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Segment is the segment of users the user belongs to, for example a
+	// subscription plan.
+	Segment string `json:"segment,omitempty"`
+	// Data holds additional information about the user.
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// IsEmpty reports whether u holds no information.
+func (u User) IsEmpty() bool {
+	return u.ID == "" && u.Email == "" && u.IPAddress == "" && u.Username == "" &&
+		u.Name == "" && u.Segment == "" && len(u.Data) == 0
 }
 
 // Request contains information on a HTTP request related to the event.
@@ -112,14 +205,24 @@ type Request struct {
 
 // NewRequest returns a new Sentry Request from the given http.Request.
 //
+// The URL is reconstructed as seen by the client: behind proxies, the scheme
+// and the host are taken from the Forwarded, X-Forwarded-Proto and
+// X-Forwarded-Host headers. Sensitive headers, cookies and the remote address
+// are removed from events by the client unless ClientOptions.SendDefaultPII is
+// set, and the body is attached by the scope, see Scope.SetRequest.
+//
 // NewRequest avoids operations that depend on network access. In particular, it
 // does not read r.Body.
 func NewRequest(r *http.Request) *Request {
+	proto, host := forwardedProtoHost(r.Header)
 	protocol := schemeHTTP
-	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+	if r.TLS != nil || proto == "https" {
 		protocol = schemeHTTPS
 	}
-	url := fmt.Sprintf("%s://%s%s", protocol, r.Host, r.URL.Path)
+	if host == "" {
+		host = r.Host
+	}
+	url := fmt.Sprintf("%s://%s%s", protocol, host, r.URL.Path)
 
 	// We read only the first Cookie header because of the specification:
 	// https://tools.ietf.org/html/rfc6265#section-5.4
@@ -148,6 +251,33 @@ func NewRequest(r *http.Request) *Request {
 	}
 }
 
+// forwardedProtoHost returns the scheme and the host requested by the client,
+// as reported by the first proxy in the Forwarded header or, without it, in the
+// X-Forwarded-Proto and X-Forwarded-Host headers.
+func forwardedProtoHost(h http.Header) (proto, host string) {
+	if forwarded := h.Get("Forwarded"); forwarded != "" {
+		first := strings.SplitN(forwarded, ",", 2)[0]
+		for _, pair := range strings.Split(first, ";") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			value := strings.Trim(kv[1], `"`)
+			switch strings.ToLower(kv[0]) {
+			case "proto":
+				proto = strings.ToLower(value)
+			case "host":
+				host = value
+			}
+		}
+		return proto, host
+	}
+	firstValue := func(key string) string {
+		return strings.TrimSpace(strings.SplitN(h.Get(key), ",", 2)[0])
+	}
+	return strings.ToLower(firstValue("X-Forwarded-Proto")), firstValue("X-Forwarded-Host")
+}
+
 // Exception specifies an error that occurred.
 type Exception struct {
 	Type       string      `json:"type,omitempty"`  // used as the main issue title
@@ -155,12 +285,63 @@ type Exception struct {
 	Module     string      `json:"module,omitempty"`
 	ThreadID   string      `json:"thread_id,omitempty"`
 	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+	Mechanism  *Mechanism  `json:"mechanism,omitempty"`
+}
+
+// Mechanism describes how an exception was captured, for example whether it
+// was reported by the application or caught while recovering from a panic.
+// Sentry uses it to tell crashes apart from handled errors.
+type Mechanism struct {
+	// Type identifies the mechanism, for example "generic" for errors
+	// captured with CaptureException or "panic" for recovered panics.
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	HelpLink    string `json:"help_link,omitempty"`
+	// Handled reports whether the application handled the exception. A nil
+	// value leaves it unspecified.
+	Handled *bool `json:"handled,omitempty"`
+	// Data holds arbitrary, mechanism-specific information, such as the
+	// request that caused a panic or the signal that terminated a process.
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// SetHandled sets whether the exception was handled by the application.
+func (m *Mechanism) SetHandled(handled bool) {
+	m.Handled = &handled
 }
 
 // EventID is a hexadecimal string representing a unique uuid4 for an Event.
 // An EventID must be 32 characters long, lowercase and not have any dashes.
 type EventID string
 
+// ParseEventID returns the EventID for s, a UUID in its canonical form with
+// dashes, or as 32 hexadecimal characters, in any case. The result is directly
+// usable in Sentry URLs and user feedback.
+func ParseEventID(s string) (EventID, error) {
+	id := strings.ToLower(s)
+	if len(id) == 36 && id[8] == '-' && id[13] == '-' && id[18] == '-' && id[23] == '-' {
+		id = strings.Replace(id, "-", "", -1)
+	}
+	if !EventID(id).Valid() {
+		return "", fmt.Errorf("sentry: invalid event ID %q", s)
+	}
+	return EventID(id), nil
+}
+
+// Valid reports whether the ID has the form required by the Sentry protocol:
+// 32 lowercase hexadecimal characters.
+func (id EventID) Valid() bool {
+	if len(id) != 32 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
 // Event is the fundamental data structure that is sent to Sentry.
 type Event struct {
 	Breadcrumbs []*Breadcrumb          `json:"breadcrumbs,omitempty"`
@@ -172,6 +353,7 @@ type Event struct {
 	Fingerprint []string               `json:"fingerprint,omitempty"`
 	Level       Level                  `json:"level,omitempty"`
 	Message     string                 `json:"message,omitempty"`
+	LogEntry    *LogEntry              `json:"logentry,omitempty"`
 	Platform    string                 `json:"platform,omitempty"`
 	Release     string                 `json:"release,omitempty"`
 	Sdk         SdkInfo                `json:"sdk,omitempty"`
@@ -188,9 +370,32 @@ type Event struct {
 
 	// The fields below are only relevant for transactions.
 
-	Type      string    `json:"type,omitempty"`
-	StartTime time.Time `json:"start_timestamp"`
-	Spans     []*Span   `json:"spans,omitempty"`
+	Type            string           `json:"type,omitempty"`
+	StartTime       time.Time        `json:"start_timestamp"`
+	Spans           []*Span          `json:"spans,omitempty"`
+	TransactionInfo *TransactionInfo `json:"transaction_info,omitempty"`
+
+	// Measurements holds custom numeric values recorded for a transaction,
+	// keyed by name.
+	Measurements map[string]Measurement `json:"measurements,omitempty"`
+
+	// The fields below are only relevant for check-ins.
+
+	CheckIn *CheckIn `json:"-"`
+}
+
+// TransactionInfo holds information about the name of a transaction.
+type TransactionInfo struct {
+	Source TransactionSource `json:"source,omitempty"`
+}
+
+// Measurement is a numeric value recorded for a transaction, like the time a
+// job waited in a queue or the size of a payload.
+type Measurement struct {
+	Value float64 `json:"value"`
+	// Unit is the unit of Value, for example "millisecond", "byte" or
+	// "percent". An empty unit means a unitless value.
+	Unit string `json:"unit,omitempty"`
 }
 
 // TODO: Event.Contexts map[string]interface{} => map[string]EventContext,
@@ -208,8 +413,11 @@ func (e *Event) MarshalJSON() ([]byte, error) {
 	//
 	// We overcome the limitation and achieve what we want by shadowing fields
 	// and a few type tricks.
-	if e.Type == transactionType {
+	switch e.Type {
+	case transactionType:
 		return e.transactionMarshalJSON()
+	case checkInType:
+		return e.checkInMarshalJSON()
 	}
 	return e.defaultMarshalJSON()
 }
@@ -228,16 +436,30 @@ func (e *Event) defaultMarshalJSON() ([]byte, error) {
 		// include the timestamp when non-zero and omit it otherwise.
 		Timestamp json.RawMessage `json:"timestamp,omitempty"`
 
+		// User and Sdk shadow the original fields. They allow us to include
+		// them when non-empty and omit them otherwise.
+		User *User    `json:"user,omitempty"`
+		Sdk  *SdkInfo `json:"sdk,omitempty"`
+
 		// The fields below are not part of error events and only make sense to
 		// be sent for transactions. They shadow the respective fields in Event
 		// and are meant to remain nil, triggering the omitempty behavior.
 
-		Type      json.RawMessage `json:"type,omitempty"`
-		StartTime json.RawMessage `json:"start_timestamp,omitempty"`
-		Spans     json.RawMessage `json:"spans,omitempty"`
+		Type            json.RawMessage `json:"type,omitempty"`
+		StartTime       json.RawMessage `json:"start_timestamp,omitempty"`
+		Spans           json.RawMessage `json:"spans,omitempty"`
+		TransactionInfo json.RawMessage `json:"transaction_info,omitempty"`
+		Measurements    json.RawMessage `json:"measurements,omitempty"`
 	}
 
-	x := errorEvent{event: (*event)(e)}
+	x := errorEvent{event: (*event)(e), User: e.nonEmptyUser(), Sdk: e.nonEmptySdk()}
+	if e.LogEntry != nil {
+		// The log entry supersedes the message, so marshal a copy of the
+		// event without it.
+		c := *e
+		c.Message = ""
+		x.event = (*event)(&c)
+	}
 	if !e.Timestamp.IsZero() {
 		b, err := e.Timestamp.MarshalJSON()
 		if err != nil {
@@ -263,9 +485,14 @@ func (e *Event) transactionMarshalJSON() ([]byte, error) {
 
 		StartTime json.RawMessage `json:"start_timestamp,omitempty"`
 		Timestamp json.RawMessage `json:"timestamp,omitempty"`
+
+		// User and Sdk shadow the original fields. They allow us to include
+		// them when non-empty and omit them otherwise.
+		User *User    `json:"user,omitempty"`
+		Sdk  *SdkInfo `json:"sdk,omitempty"`
 	}
 
-	x := transactionEvent{event: (*event)(e)}
+	x := transactionEvent{event: (*event)(e), User: e.nonEmptyUser(), Sdk: e.nonEmptySdk()}
 	if !e.Timestamp.IsZero() {
 		b, err := e.Timestamp.MarshalJSON()
 		if err != nil {
@@ -283,6 +510,55 @@ func (e *Event) transactionMarshalJSON() ([]byte, error) {
 	return json.Marshal(x)
 }
 
+func (e *Event) checkInMarshalJSON() ([]byte, error) {
+	// Check-ins have a payload of their own, unrelated to the one of other
+	// events. Only the check-in and a few top-level fields are sent.
+	checkIn := e.CheckIn
+	if checkIn == nil {
+		checkIn = &CheckIn{}
+	}
+	id := checkIn.ID
+	if id == "" {
+		id = e.EventID
+	}
+	var duration float64
+	if checkIn.Duration > 0 {
+		duration = checkIn.Duration.Seconds()
+	}
+	return json.Marshal(struct {
+		CheckInID   EventID       `json:"check_in_id"`
+		MonitorSlug string        `json:"monitor_slug"`
+		Status      CheckInStatus `json:"status"`
+		Duration    float64       `json:"duration,omitempty"`
+		Release     string        `json:"release,omitempty"`
+		Environment string        `json:"environment,omitempty"`
+	}{
+		CheckInID:   id,
+		MonitorSlug: checkIn.MonitorSlug,
+		Status:      checkIn.Status,
+		Duration:    duration,
+		Release:     e.Release,
+		Environment: e.Environment,
+	})
+}
+
+// nonEmptyUser returns a pointer to e.User, or nil if it is the zero value.
+func (e *Event) nonEmptyUser() *User {
+	if e.User.IsEmpty() {
+		return nil
+	}
+	return &e.User
+}
+
+// nonEmptySdk returns a pointer to e.Sdk, or nil if it has no data.
+func (e *Event) nonEmptySdk() *SdkInfo {
+	sdk := e.Sdk
+	if sdk.Name == "" && sdk.Version == "" && len(sdk.Integrations) == 0 && len(sdk.Packages) == 0 {
+		return nil
+	}
+	return &e.Sdk
+}
+
 // NewEvent creates a new Event.
 func NewEvent() *Event {
 	event := Event{
@@ -294,6 +570,29 @@ func NewEvent() *Event {
 	return &event
 }
 
+// CheckInStatus is the status of a check-in.
+type CheckInStatus string
+
+// Check-in statuses.
+const (
+	CheckInStatusInProgress CheckInStatus = "in_progress"
+	CheckInStatusOK         CheckInStatus = "ok"
+	CheckInStatusError      CheckInStatus = "error"
+)
+
+// CheckIn reports the status of a run of a monitored job, for example a
+// cron job, to Sentry Crons.
+type CheckIn struct {
+	// ID identifies the check-in. Set it to the ID of a previous check-in with
+	// status in progress to update it. If empty, the event ID is used.
+	ID EventID
+	// MonitorSlug is the slug of the monitor the check-in belongs to.
+	MonitorSlug string
+	Status      CheckInStatus
+	// Duration is how long the job ran for. It is sent in seconds.
+	Duration time.Duration
+}
+
 // Thread specifies threads that were running at the time of an event.
 type Thread struct {
 	ID         string      `json:"id,omitempty"`
@@ -312,4 +611,8 @@ type EventHint struct {
 	Context            context.Context
 	Request            *http.Request
 	Response           *http.Response
+	// Mechanism, if set, overrides the mechanism the SDK attaches to the
+	// most recent exception of the event. Integrations use it to describe
+	// how they captured an error.
+	Mechanism *Mechanism
 }
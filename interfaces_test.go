@@ -41,6 +41,33 @@ func TestNewRequest(t *testing.T) {
 	}
 }
 
+func TestNewRequestBehindProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"None", nil, "http://example.com/path"},
+		{"XForwarded", map[string]string{
+			"X-Forwarded-Proto": "https",
+			"X-Forwarded-Host":  "www.example.org, proxy.internal",
+		}, "https://www.example.org/path"},
+		{"Forwarded", map[string]string{
+			"Forwarded":         `proto=HTTPS;host="shop.example.org", proto=http`,
+			"X-Forwarded-Proto": "http",
+		}, "https://shop.example.org/path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/path?q=1", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			assertEqual(t, NewRequest(r).URL, tt.want)
+		})
+	}
+}
+
 func TestEventMarshalJSON(t *testing.T) {
 	event := NewEvent()
 	event.Spans = []*Span{{
@@ -60,7 +87,7 @@ func TestEventMarshalJSON(t *testing.T) {
 	}
 
 	// Non-transaction event should not have fields Spans and StartTime
-	want := `{"sdk":{},"user":{},"timestamp":"1970-01-01T00:00:14Z"}`
+	want := `{"timestamp":"1970-01-01T00:00:14Z"}`
 
 	if diff := cmp.Diff(want, string(got)); diff != "" {
 		t.Errorf("Event mismatch (-want +got):\n%s", diff)
@@ -129,6 +156,17 @@ func TestStructSnapshots(t *testing.T) {
 				Contexts: map[string]interface{}{
 					"context_key": "context_val",
 				},
+				Exception: []Exception{{
+					Type:  "*errors.errorString",
+					Value: "panic error",
+					Mechanism: &Mechanism{
+						Type:    "panic",
+						Handled: new(bool),
+						Data: map[string]interface{}{
+							"http.method": "GET",
+						},
+					},
+				}},
 			},
 		},
 		{
@@ -178,3 +216,96 @@ func TestStructSnapshots(t *testing.T) {
 		})
 	}
 }
+
+func TestParseEventID(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    EventID
+		wantErr bool
+	}{
+		{in: "0123456789abcdef0123456789abcdef", want: "0123456789abcdef0123456789abcdef"},
+		{in: "0123456789ABCDEF0123456789ABCDEF", want: "0123456789abcdef0123456789abcdef"},
+		{in: "01234567-89ab-cdef-0123-456789abcdef", want: "0123456789abcdef0123456789abcdef"},
+		{in: "", wantErr: true},
+		{in: "0123456789abcdef", wantErr: true},
+		{in: "0123456789abcdef0123456789abcdeg", wantErr: true},
+		{in: "0123-4567-89ab-cdef-0123456789abcdef", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseEventID(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEventID(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		assertEqual(t, got, tt.want)
+	}
+}
+
+func TestBreadcrumbConstructors(t *testing.T) {
+	tests := []struct {
+		name string
+		got  *Breadcrumb
+		want *Breadcrumb
+	}{
+		{
+			name: "HTTP",
+			got:  NewHTTPBreadcrumb("GET", "https://example.com/", 200),
+			want: &Breadcrumb{
+				Type:     "http",
+				Category: "http",
+				Data:     map[string]interface{}{"method": "GET", "url": "https://example.com/", "status_code": 200},
+				Level:    LevelInfo,
+			},
+		},
+		{
+			name: "HTTPClientError",
+			got:  NewHTTPBreadcrumb("POST", "https://example.com/", 404),
+			want: &Breadcrumb{
+				Type:     "http",
+				Category: "http",
+				Data:     map[string]interface{}{"method": "POST", "url": "https://example.com/", "status_code": 404},
+				Level:    LevelWarning,
+			},
+		},
+		{
+			name: "HTTPServerError",
+			got:  NewHTTPBreadcrumb("GET", "https://example.com/", 503),
+			want: &Breadcrumb{
+				Type:     "http",
+				Category: "http",
+				Data:     map[string]interface{}{"method": "GET", "url": "https://example.com/", "status_code": 503},
+				Level:    LevelError,
+			},
+		},
+		{
+			name: "Query",
+			got:  NewQueryBreadcrumb("SELECT 1"),
+			want: &Breadcrumb{Type: "query", Category: "query", Message: "SELECT 1", Level: LevelInfo},
+		},
+		{
+			name: "Navigation",
+			got:  NewNavigationBreadcrumb("/a", "/b"),
+			want: &Breadcrumb{
+				Type:     "navigation",
+				Category: "navigation",
+				Data:     map[string]interface{}{"from": "/a", "to": "/b"},
+				Level:    LevelInfo,
+			},
+		},
+		{
+			name: "User",
+			got:  NewUserBreadcrumb("ui.click", "button#submit"),
+			want: &Breadcrumb{Type: "user", Category: "ui.click", Message: "button#submit", Level: LevelInfo},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// Timestamps are set by the hub, with the clock of the
+			// client.
+			if diff := cmp.Diff(tt.want, tt.got); diff != "" {
+				t.Errorf("Breadcrumb mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
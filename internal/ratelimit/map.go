@@ -22,6 +22,20 @@ func (m Map) isRateLimited(c Category, now time.Time) bool {
 	return m.Deadline(c).After(Deadline(now))
 }
 
+// IsAnyRateLimited returns true if any category is currently rate limited.
+func (m Map) IsAnyRateLimited() bool {
+	return m.isAnyRateLimited(time.Now())
+}
+
+func (m Map) isAnyRateLimited(now time.Time) bool {
+	for _, deadline := range m {
+		if deadline.After(Deadline(now)) {
+			return true
+		}
+	}
+	return false
+}
+
 // Deadline returns the deadline when the rate limit for the given category or
 // the special CategoryAll expire, whichever is furthest into the future.
 func (m Map) Deadline(c Category) Deadline {
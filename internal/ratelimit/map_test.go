@@ -211,6 +211,30 @@ func TestMapDeadlineIsRateLimited(t *testing.T) {
 	}
 }
 
+func TestMapIsAnyRateLimited(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Map
+		want bool
+	}{
+		{"empty", Map{}, false},
+		{"expired", Map{CategoryError: Deadline(now.Add(-time.Second))}, false},
+		{"one category", Map{
+			CategoryError:       Deadline(now.Add(-time.Second)),
+			CategoryTransaction: Deadline(now.Add(time.Second)),
+		}, true},
+		{"all", Map{CategoryAll: Deadline(now.Add(time.Second))}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.isAnyRateLimited(now); got != tt.want {
+				t.Errorf("isAnyRateLimited() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestMapMerge(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,200 @@
+// Package sentrykafka provides Sentry integration for Kafka producers and
+// consumers based on the github.com/segmentio/kafka-go package.
+package sentrykafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/segmentio/kafka-go"
+)
+
+// traceHeader is the message header used to propagate traces from producers
+// to consumers.
+const traceHeader = "sentry-trace"
+
+// A MessageWriter writes messages to Kafka. It is implemented by
+// *kafka.Writer.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// A Writer wraps a MessageWriter to create a span for every produce operation
+// and propagate the trace to consumers in the message headers.
+type Writer struct {
+	writer MessageWriter
+}
+
+// NewWriter returns a new Writer that writes messages with w.
+func NewWriter(w MessageWriter) *Writer {
+	return &Writer{writer: w}
+}
+
+// WriteMessages writes msgs with the wrapped MessageWriter. If ctx carries a
+// transaction, the operation is recorded as a child span and every message is
+// written with a sentry-trace header, so that consumers can continue the trace.
+// The messages passed in are not modified.
+func (w *Writer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if sentry.TransactionFromContext(ctx) == nil {
+		return w.writer.WriteMessages(ctx, msgs...)
+	}
+	span := sentry.StartSpan(ctx, "queue.publish", sentry.WithSpanOrigin("auto.queue.kafka"))
+	defer span.Finish()
+	span.Description = strings.Join(topics(msgs), ",")
+	span.SetTag("messaging.system", "kafka")
+
+	trace := span.ToSentryTrace()
+	traced := make([]kafka.Message, len(msgs))
+	for i, msg := range msgs {
+		msg.Headers = withHeader(msg.Headers, traceHeader, trace)
+		traced[i] = msg
+	}
+	err := w.writer.WriteMessages(span.Context(), traced...)
+	if err != nil {
+		span.Status = sentry.SpanStatusInternalError
+	} else {
+		span.Status = sentry.SpanStatusOK
+	}
+	return err
+}
+
+// topics returns the distinct topics of msgs, in order.
+func topics(msgs []kafka.Message) []string {
+	var topics []string
+	seen := make(map[string]bool)
+	for _, msg := range msgs {
+		if msg.Topic != "" && !seen[msg.Topic] {
+			seen[msg.Topic] = true
+			topics = append(topics, msg.Topic)
+		}
+	}
+	return topics
+}
+
+// withHeader returns a copy of headers with the header key set to value.
+func withHeader(headers []kafka.Header, key, value string) []kafka.Header {
+	result := make([]kafka.Header, 0, len(headers)+1)
+	for _, h := range headers {
+		if h.Key != key {
+			result = append(result, h)
+		}
+	}
+	return append(result, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// header returns the value of the header key in msg, or "" if msg does not
+// have the header.
+func header(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// A HandlerFunc processes a message consumed from Kafka.
+type HandlerFunc func(ctx context.Context, msg kafka.Message) error
+
+// A Handler is a message handler middleware factory that provides integration
+// with Sentry.
+type Handler struct {
+	repanic         bool
+	waitForDelivery bool
+	timeout         time.Duration
+}
+
+// Options configure a Handler.
+type Options struct {
+	// Repanic configures whether to panic again after recovering from a panic
+	// in a message handler.
+	Repanic bool
+	// WaitForDelivery indicates, in case of a panic, whether to block the
+	// current goroutine and wait until the panic event has been reported to
+	// Sentry before repanicking or resuming normal execution.
+	WaitForDelivery bool
+	// Timeout for the delivery of panic events. Defaults to 2s. Only relevant
+	// when WaitForDelivery is true.
+	Timeout time.Duration
+}
+
+// New returns a new Handler. Use the Handle method to wrap message handlers.
+func New(options Options) *Handler {
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	return &Handler{
+		repanic:         options.Repanic,
+		timeout:         timeout,
+		waitForDelivery: options.WaitForDelivery,
+	}
+}
+
+// Handle wraps a message handler. For every message, the wrapped handler runs
+// with a message-specific hub, tagged with the topic and partition of the
+// message, and in a transaction that continues the trace propagated by the
+// producer, if any. Panics in the handler are recovered and reported to Sentry.
+// When a panic is not repanicked, the wrapped handler returns an error, such
+// that the message is not committed.
+//
+//	reader := kafka.NewReader(config)
+//	handle := sentrykafka.New(sentrykafka.Options{}).Handle(process)
+//	for {
+//		msg, err := reader.ReadMessage(ctx)
+//		if err != nil {
+//			break
+//		}
+//		_ = handle(ctx, msg)
+//	}
+func (h *Handler) Handle(handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, msg kafka.Message) (err error) {
+		hub := sentry.GetHubFromContext(ctx)
+		if hub == nil {
+			hub = sentry.CurrentHub()
+		}
+		hub = hub.Clone()
+		ctx = sentry.SetHubOnContext(ctx, hub)
+		hub.Scope().SetTags(map[string]string{
+			"messaging.system":      "kafka",
+			"messaging.destination": msg.Topic,
+			"messaging.partition":   strconv.Itoa(msg.Partition),
+		})
+		hub.Scope().SetExtra("messaging.offset", msg.Offset)
+
+		span := sentry.StartSpan(ctx, "queue.process",
+			sentry.TransactionName(msg.Topic),
+			sentry.WithTransactionSource(sentry.SourceTask),
+			sentry.ContinueFromTrace(header(msg, traceHeader)),
+			sentry.WithSpanOrigin("auto.queue.kafka"),
+		)
+		defer span.Finish()
+		defer h.recoverWithSentry(hub, span, &err)
+
+		err = handler(span.Context(), msg)
+		if err != nil {
+			span.Status = sentry.SpanStatusInternalError
+		} else {
+			span.Status = sentry.SpanStatusOK
+		}
+		return err
+	}
+}
+
+func (h *Handler) recoverWithSentry(hub *sentry.Hub, span *sentry.Span, err *error) {
+	if r := recover(); r != nil {
+		span.Status = sentry.SpanStatusInternalError
+		eventID := hub.RecoverWithContext(span.Context(), r)
+		if eventID != nil && h.waitForDelivery {
+			hub.Flush(h.timeout)
+		}
+		if h.repanic {
+			panic(r)
+		}
+		*err = fmt.Errorf("sentrykafka: panic: %v", r)
+	}
+}
@@ -0,0 +1,155 @@
+package sentrykafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	sentrykafka "github.com/getsentry/sentry-go/kafka"
+	"github.com/getsentry/sentry-go/sentrytest"
+	"github.com/segmentio/kafka-go"
+)
+
+type writerMock struct {
+	msgs []kafka.Message
+	err  error
+}
+
+func (w *writerMock) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.msgs = append(w.msgs, msgs...)
+	return w.err
+}
+
+func traceHeader(msg kafka.Message) string {
+	for _, h := range msg.Headers {
+		if h.Key == "sentry-trace" {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestWriterPropagatesTrace(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	transaction := sentry.StartSpan(ctx, "test", sentry.TransactionName("test"))
+
+	mock := &writerMock{}
+	msgs := []kafka.Message{
+		{Topic: "orders", Value: []byte("1"), Headers: []kafka.Header{{Key: "k", Value: []byte("v")}}},
+		{Topic: "orders", Value: []byte("2")},
+	}
+	if err := sentrykafka.NewWriter(mock).WriteMessages(transaction.Context(), msgs...); err != nil {
+		t.Fatal(err)
+	}
+	transaction.Finish()
+
+	if len(msgs[0].Headers) != 1 || msgs[1].Headers != nil {
+		t.Error("WriteMessages modified the messages passed in")
+	}
+	if len(mock.msgs) != 2 {
+		t.Fatalf("wrote %d messages, want 2", len(mock.msgs))
+	}
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	spans := events[0].Spans
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Op != "queue.publish" || span.Description != "orders" || span.Status != sentry.SpanStatusOK {
+		t.Errorf("unexpected span %+v", span)
+	}
+	for _, msg := range mock.msgs {
+		if got, want := traceHeader(msg), span.ToSentryTrace(); got != want {
+			t.Errorf("got trace header %q, want %q", got, want)
+		}
+	}
+	if len(mock.msgs[0].Headers) != 2 {
+		t.Errorf("got headers %v, want existing headers to be kept", mock.msgs[0].Headers)
+	}
+}
+
+func TestWriterWithoutTransaction(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	mock := &writerMock{err: errors.New("write failed")}
+	err := sentrykafka.NewWriter(mock).WriteMessages(ctx, kafka.Message{Topic: "orders"})
+	if err != mock.err {
+		t.Errorf("got error %v, want %v", err, mock.err)
+	}
+	if traceHeader(mock.msgs[0]) != "" {
+		t.Error("unexpected trace header without a transaction")
+	}
+	if n := len(transport.Events()); n != 0 {
+		t.Errorf("got %d events, want 0", n)
+	}
+}
+
+func TestHandlerContinuesTrace(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	msg := kafka.Message{
+		Topic:     "orders",
+		Partition: 3,
+		Offset:    42,
+		Headers: []kafka.Header{{
+			Key:   "sentry-trace",
+			Value: []byte("bc6d53f15eb88f4320054569b8c553d4-b72fa28504b07285-1"),
+		}},
+	}
+	errHandler := errors.New("handler failed")
+	handle := sentrykafka.New(sentrykafka.Options{}).Handle(func(ctx context.Context, msg kafka.Message) error {
+		return errHandler
+	})
+	if err := handle(ctx, msg); err != errHandler {
+		t.Errorf("got error %v, want %v", err, errHandler)
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.Transaction != "orders" {
+		t.Errorf("got transaction %q, want %q", event.Transaction, "orders")
+	}
+	trace := event.Contexts["trace"].(*sentry.TraceContext)
+	if trace.TraceID.String() != "bc6d53f15eb88f4320054569b8c553d4" {
+		t.Errorf("got trace ID %s, want the trace of the producer", trace.TraceID)
+	}
+	if trace.Op != "queue.process" || trace.Status != sentry.SpanStatusInternalError {
+		t.Errorf("unexpected trace context %+v", trace)
+	}
+	if event.Tags["messaging.partition"] != "3" {
+		t.Errorf("got tags %v, want the partition", event.Tags)
+	}
+}
+
+func TestHandlerRecoversPanic(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	handle := sentrykafka.New(sentrykafka.Options{}).Handle(func(ctx context.Context, msg kafka.Message) error {
+		panic("handler panic")
+	})
+	err := handle(ctx, kafka.Message{Topic: "orders", Partition: 1})
+	if err == nil || err.Error() != "sentrykafka: panic: handler panic" {
+		t.Errorf("got error %v, want the panic", err)
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	panicEvent := events[0]
+	if panicEvent.Message != "handler panic" || panicEvent.Level != sentry.LevelFatal {
+		t.Errorf("unexpected panic event %+v", panicEvent)
+	}
+	if panicEvent.Tags["messaging.destination"] != "orders" || panicEvent.Tags["messaging.partition"] != "1" {
+		t.Errorf("got tags %v, want topic and partition", panicEvent.Tags)
+	}
+	// The scope of the hub in ctx is not modified by the handler.
+	sentry.GetHubFromContext(ctx).CaptureMessage("after")
+	if tags := transport.Events()[2].Tags; len(tags) != 0 {
+		t.Errorf("got tags %v, want none", tags)
+	}
+}
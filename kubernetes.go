@@ -0,0 +1,143 @@
+package sentry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// kubernetesWorkloadEnv lists the environment variables of the tags that the
+// downward API cannot provide, which must be set in the manifest:
+//
+//	env:
+//	- name: CLUSTER_NAME
+//	  value: eu-west-1
+var kubernetesWorkloadEnv = []envTag{
+	{"k8s.cluster.name", []string{"CLUSTER_NAME", "K8S_CLUSTER_NAME", "KUBERNETES_CLUSTER_NAME"}},
+	{"k8s.container.name", []string{"CONTAINER_NAME", "K8S_CONTAINER_NAME", "KUBERNETES_CONTAINER_NAME"}},
+	{"k8s.deployment.name", []string{"DEPLOYMENT_NAME", "K8S_DEPLOYMENT_NAME", "KUBERNETES_DEPLOYMENT_NAME"}},
+}
+
+// serviceAccountNamespace is the file holding the namespace of the pod, in
+// the service account volume mounted in pods by default.
+const serviceAccountNamespace = "var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Kubernetes is an integration that tags events with the Kubernetes workload
+// that captured them, such that issues can be routed by workload in
+// multi-tenant clusters:
+//
+//	k8s.cluster.name      CLUSTER_NAME
+//	k8s.namespace.name    POD_NAMESPACE, or the namespace of the service account
+//	k8s.node.name         NODE_NAME
+//	k8s.pod.name          POD_NAME, or the host name
+//	k8s.container.name    CONTAINER_NAME
+//	k8s.deployment.name   DEPLOYMENT_NAME, or derived from the pod name
+//
+// The values are read once, from the environment variables listed, with the
+// prefixes K8S_ and KUBERNETES_ also accepted, like K8S_CLUSTER_NAME. The
+// namespace, pod and node can be set with the downward API. Tags set on
+// events are preserved.
+//
+// The integration is opt-in:
+//
+//	sentry.Init(sentry.ClientOptions{
+//		Integrations: func(integrations []sentry.Integration) []sentry.Integration {
+//			return append(integrations, sentry.Kubernetes())
+//		},
+//	})
+func Kubernetes() Integration {
+	return &kubernetesIntegration{
+		root:     "/",
+		getenv:   os.Getenv,
+		hostname: os.Hostname,
+	}
+}
+
+type kubernetesIntegration struct {
+	root     string
+	getenv   func(string) string
+	hostname func() (string, error)
+
+	tags map[string]string
+}
+
+func (ki *kubernetesIntegration) Name() string {
+	return "Kubernetes"
+}
+
+func (ki *kubernetesIntegration) SetupOnce(client *Client) {
+	ki.tags = ki.readTags()
+	if len(ki.tags) == 0 {
+		Logger.Println("Kubernetes integration found no metadata: the program is not running in Kubernetes.")
+		return
+	}
+	client.AddEventProcessor(ki.processor)
+}
+
+// readTags reads the tags of the workload.
+func (ki *kubernetesIntegration) readTags() map[string]string {
+	tags := make(map[string]string)
+	setEnvTags(tags, ki.getenv, kubernetesEnv)
+	setEnvTags(tags, ki.getenv, kubernetesWorkloadEnv)
+
+	if _, ok := tags["k8s.namespace.name"]; !ok {
+		if b, err := ioutil.ReadFile(filepath.Join(ki.root, serviceAccountNamespace)); err == nil {
+			if namespace := strings.TrimSpace(string(b)); namespace != "" {
+				tags["k8s.namespace.name"] = namespace
+			}
+		}
+	}
+	// The host name of a pod is its name, unless the pod sets another one.
+	// It is only used if the program runs in Kubernetes.
+	if _, ok := tags["k8s.pod.name"]; !ok && ki.getenv("KUBERNETES_SERVICE_HOST") != "" {
+		if hostname, err := ki.hostname(); err == nil && hostname != "" {
+			tags["k8s.pod.name"] = hostname
+		}
+	}
+	if _, ok := tags["k8s.deployment.name"]; !ok {
+		if deployment := deploymentFromPod(tags["k8s.pod.name"]); deployment != "" {
+			tags["k8s.deployment.name"] = deployment
+		}
+	}
+	return tags
+}
+
+func (ki *kubernetesIntegration) processor(event *Event, hint *EventHint) *Event {
+	if event.Tags == nil {
+		event.Tags = make(map[string]string, len(ki.tags))
+	}
+	for key, value := range ki.tags {
+		if _, ok := event.Tags[key]; !ok {
+			event.Tags[key] = value
+		}
+	}
+	return event
+}
+
+// deploymentFromPod returns the name of the deployment of a pod created by
+// the ReplicaSet of a deployment, named after the deployment, the hash of the
+// pod template and a random suffix, like api-7d4b9c8f6-x2x8q. It returns an
+// empty string for other names.
+func deploymentFromPod(pod string) string {
+	parts := strings.Split(pod, "-")
+	if len(parts) < 3 {
+		return ""
+	}
+	suffix, hash := parts[len(parts)-1], parts[len(parts)-2]
+	if len(suffix) != 5 || !isSafeEncoded(suffix) || len(hash) < 6 || len(hash) > 10 || !isSafeEncoded(hash) {
+		return ""
+	}
+	return strings.Join(parts[:len(parts)-2], "-")
+}
+
+// isSafeEncoded reports whether s only has the characters Kubernetes uses in
+// generated names and hashes, which exclude vowels and look-alike digits.
+func isSafeEncoded(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("bcdfghjklmnpqrstvwxz2456789", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package sentry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKubernetesIntegration(t *testing.T) {
+	env := map[string]string{
+		"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+		"K8S_CLUSTER_NAME":        "eu-west-1",
+		"NODE_NAME":               "node-1",
+		"CONTAINER_NAME":          "api",
+	}
+	root := writeFiles(t, map[string]string{
+		serviceAccountNamespace: "production\n",
+	})
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport: transport,
+		Integrations: func([]Integration) []Integration {
+			return []Integration{&kubernetesIntegration{
+				root:     root,
+				getenv:   func(name string) string { return env[name] },
+				hostname: func() (string, error) { return "api-7d4b9c8f6-x2x8q", nil },
+			}}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope := NewScope()
+	scope.SetTag("k8s.node.name", "explicit")
+	client.CaptureMessage("message", nil, scope)
+	assertEqual(t, transport.lastEvent.Tags, map[string]string{
+		"k8s.cluster.name":    "eu-west-1",
+		"k8s.namespace.name":  "production",
+		"k8s.node.name":       "explicit",
+		"k8s.pod.name":        "api-7d4b9c8f6-x2x8q",
+		"k8s.container.name":  "api",
+		"k8s.deployment.name": "api",
+	})
+}
+
+func TestKubernetesIntegrationOutsideKubernetes(t *testing.T) {
+	ki := &kubernetesIntegration{
+		root:     writeFiles(t, nil),
+		getenv:   func(string) string { return "" },
+		hostname: func() (string, error) { return "", errors.New("hostname should not be used") },
+	}
+	client, err := NewClient(ClientOptions{
+		Transport: &TransportMock{},
+		Integrations: func([]Integration) []Integration {
+			return []Integration{ki}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, ki.tags, map[string]string{})
+	assertEqual(t, client.eventProcessors.len(), 0)
+}
+
+func TestDeploymentFromPod(t *testing.T) {
+	tests := map[string]string{
+		"api-7d4b9c8f6-x2x8q":          "api",
+		"payment-api-5c9f8d7b46-lq2zt": "payment-api",
+		"api-0":                        "",
+		"worker-x2x8q":                 "",
+		"api-7d4b9c8f6-aaaaa":          "",
+		"api-abcdefgh-x2x8q":           "",
+		"":                             "",
+	}
+	for pod, want := range tests {
+		if got := deploymentFromPod(pod); got != want {
+			t.Errorf("deploymentFromPod(%q) = %q, want %q", pod, got, want)
+		}
+	}
+}
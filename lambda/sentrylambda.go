@@ -0,0 +1,135 @@
+// Package sentrylambda provides Sentry integration for AWS Lambda functions
+// based on the github.com/aws/aws-lambda-go package.
+package sentrylambda
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/getsentry/sentry-go"
+)
+
+// defaultFlushTimeout is the maximum time to wait for events to be delivered
+// at the end of an invocation.
+const defaultFlushTimeout = 2 * time.Second
+
+// flushMargin is the time left to the runtime between the end of the flush
+// and the deadline of an invocation.
+const flushMargin = 100 * time.Millisecond
+
+// Wrap wraps a Lambda function handler, of any of the signatures accepted by
+// lambda.Start, to report it to Sentry. Start the returned handler with
+// lambda.StartHandler:
+//
+//	func main() {
+//		sentry.Init(sentry.ClientOptions{
+//			// ...
+//		})
+//		lambda.StartHandler(sentrylambda.Wrap(handler))
+//	}
+//
+// Every invocation runs with its own hub, available in the handler with
+// sentry.GetHubFromContext, and in a transaction named after the function. If
+// the triggering event has HTTP headers, like API Gateway and Application Load
+// Balancer requests do, the transaction continues the trace of the sentry-trace
+// header. Panics and returned errors are captured, and pending events are
+// flushed before the invocation completes, since the execution environment may
+// be frozen afterwards. Panics are repanicked, so that the Lambda runtime
+// reports them as usual.
+func Wrap(handler interface{}) lambda.Handler {
+	return &wrappedHandler{handler: lambda.NewHandler(handler)}
+}
+
+type wrappedHandler struct {
+	handler lambda.Handler
+}
+
+// Invoke implements lambda.Handler.
+func (h *wrappedHandler) Invoke(ctx context.Context, payload []byte) (response []byte, err error) {
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	hub = hub.Clone()
+	ctx = sentry.SetHubOnContext(ctx, hub)
+	hub.Scope().SetContext("aws.lambda", lambdaContext(ctx))
+
+	span := sentry.StartSpan(ctx, "serverless.function",
+		sentry.TransactionName(lambdacontext.FunctionName),
+		sentry.WithTransactionSource(sentry.SourceComponent),
+		sentry.ContinueFromTrace(traceHeader(payload)),
+		sentry.WithSpanOrigin("auto.function.lambda"),
+	)
+	defer func() {
+		if r := recover(); r != nil {
+			span.Status = sentry.SpanStatusInternalError
+			span.Finish()
+			hub.RecoverWithContext(span.Context(), r)
+			flush(ctx, hub)
+			panic(r)
+		}
+	}()
+
+	response, err = h.handler.Invoke(span.Context(), payload)
+	if err != nil {
+		span.Status = sentry.SpanStatusInternalError
+		hub.CaptureException(err)
+	} else {
+		span.Status = sentry.SpanStatusOK
+	}
+	span.Finish()
+	flush(ctx, hub)
+	return response, err
+}
+
+// lambdaContext returns information about the function and the invocation
+// in ctx, to be sent as a context of events.
+func lambdaContext(ctx context.Context) map[string]interface{} {
+	c := map[string]interface{}{
+		"function_name":    lambdacontext.FunctionName,
+		"function_version": lambdacontext.FunctionVersion,
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		c["aws_request_id"] = lc.AwsRequestID
+		c["invoked_function_arn"] = lc.InvokedFunctionArn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		c["remaining_time_ms"] = time.Until(deadline).Milliseconds()
+	}
+	return c
+}
+
+// traceHeader returns the sentry-trace header of an event with HTTP headers,
+// or "" if the event has no such header.
+func traceHeader(payload []byte) string {
+	var event struct {
+		Headers map[string]string `json:"headers"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return ""
+	}
+	for k, v := range event.Headers {
+		if strings.EqualFold(k, "sentry-trace") {
+			return v
+		}
+	}
+	return ""
+}
+
+// flush waits for the delivery of pending events, for at most
+// defaultFlushTimeout and without exceeding the deadline of ctx.
+func flush(ctx context.Context, hub *sentry.Hub) {
+	timeout := defaultFlushTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline) - flushMargin; remaining < timeout {
+			timeout = remaining
+		}
+	}
+	if timeout > 0 {
+		hub.Flush(timeout)
+	}
+}
@@ -0,0 +1,95 @@
+package sentrylambda_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/getsentry/sentry-go"
+	sentrylambda "github.com/getsentry/sentry-go/lambda"
+	"github.com/getsentry/sentry-go/sentrytest"
+)
+
+func newTestContext(t *testing.T) (context.Context, *sentrytest.RecordingTransport) {
+	t.Helper()
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	ctx = lambdacontext.NewContext(ctx, &lambdacontext.LambdaContext{AwsRequestID: "request-1"})
+	return ctx, transport
+}
+
+func TestWrapCapturesErrors(t *testing.T) {
+	lambdacontext.FunctionName = "checkout"
+	ctx, transport := newTestContext(t)
+	errHandler := errors.New("handler failed")
+	handler := sentrylambda.Wrap(func(ctx context.Context, event map[string]interface{}) (string, error) {
+		if sentry.GetHubFromContext(ctx) == nil {
+			t.Error("missing hub in handler context")
+		}
+		return "", errHandler
+	})
+
+	payload := []byte(`{"headers": {"Sentry-Trace": "bc6d53f15eb88f4320054569b8c553d4-b72fa28504b07285-1"}}`)
+	if _, err := handler.Invoke(ctx, payload); err != errHandler {
+		t.Errorf("got error %v, want %v", err, errHandler)
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	errorEvent, transaction := events[0], events[1]
+	if errorEvent.Exception[0].Value != "handler failed" {
+		t.Errorf("got exception %+v", errorEvent.Exception)
+	}
+	lc, ok := errorEvent.Contexts["aws.lambda"].(map[string]interface{})
+	if !ok || lc["aws_request_id"] != "request-1" || lc["function_name"] != "checkout" {
+		t.Errorf("got context %v, want the invocation", errorEvent.Contexts["aws.lambda"])
+	}
+	if transaction.Transaction != "checkout" {
+		t.Errorf("got transaction %q, want %q", transaction.Transaction, "checkout")
+	}
+	trace := transaction.Contexts["trace"].(*sentry.TraceContext)
+	if trace.TraceID.String() != "bc6d53f15eb88f4320054569b8c553d4" {
+		t.Errorf("got trace ID %s, want the trace of the request", trace.TraceID)
+	}
+	if trace.Status != sentry.SpanStatusInternalError {
+		t.Errorf("got status %s, want %s", trace.Status, sentry.SpanStatusInternalError)
+	}
+	if transport.Flushes() == 0 {
+		t.Error("events were not flushed")
+	}
+}
+
+func TestWrapRepanics(t *testing.T) {
+	ctx, transport := newTestContext(t)
+	handler := sentrylambda.Wrap(func() error {
+		panic("handler panic")
+	})
+
+	func() {
+		defer func() {
+			if r := recover(); r != "handler panic" {
+				t.Errorf("got panic %v, want %q", r, "handler panic")
+			}
+		}()
+		_, _ = handler.Invoke(ctx, []byte("{}"))
+	}()
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	var panicEvent *sentry.Event
+	for _, event := range events {
+		if event.Type == "" {
+			panicEvent = event
+		}
+	}
+	if panicEvent == nil || panicEvent.Message != "handler panic" || panicEvent.Level != sentry.LevelFatal {
+		t.Errorf("got events %+v, want a panic event", events)
+	}
+	if transport.Flushes() == 0 {
+		t.Error("events were not flushed")
+	}
+}
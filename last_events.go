@@ -0,0 +1,57 @@
+package sentry
+
+import "sync"
+
+// eventRing keeps the last events handed to the transport, up to a fixed
+// capacity, overwriting the oldest ones.
+type eventRing struct {
+	mu     sync.Mutex
+	events []*Event
+	next   int
+	full   bool
+}
+
+// newEventRing returns a ring of the given capacity, or nil if size is not
+// positive.
+func newEventRing(size int) *eventRing {
+	if size <= 0 {
+		return nil
+	}
+	return &eventRing{events: make([]*Event, size)}
+}
+
+func (r *eventRing) add(event *Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events[r.next] = event
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the events of the ring, from the oldest to the newest.
+func (r *eventRing) list() []*Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]*Event(nil), r.events[:r.next]...)
+	}
+	events := make([]*Event, 0, len(r.events))
+	events = append(events, r.events[r.next:]...)
+	return append(events, r.events[:r.next]...)
+}
+
+// LastEvents returns the last events the client sent or tried to send, up to
+// ClientOptions.MaxLastEvents, from the oldest to the newest. The events are
+// complete, as they were handed to the transport, including transactions and
+// check-ins. It lets health endpoints and debugging tools inspect what the SDK
+// recently reported without a round trip to Sentry.
+//
+// The returned events are shared with the client and must not be modified.
+func (client *Client) LastEvents() []*Event {
+	if client.lastEvents == nil {
+		return nil
+	}
+	return client.lastEvents.list()
+}
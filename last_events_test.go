@@ -0,0 +1,56 @@
+package sentry
+
+import "testing"
+
+func TestLastEvents(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		Transport:     &TransportMock{},
+		MaxLastEvents: 2,
+		Integrations:  func([]Integration) []Integration { return nil },
+		BeforeSend: func(event *Event, hint *EventHint) *Event {
+			if event.Message == "dropped" {
+				return nil
+			}
+			return event
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := NewScope()
+
+	if events := client.LastEvents(); len(events) != 0 {
+		t.Fatalf("got %d events, want none", len(events))
+	}
+
+	client.CaptureMessage("first", nil, scope)
+	client.CaptureMessage("dropped", nil, scope)
+	events := client.LastEvents()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	assertEqual(t, events[0].Message, "first")
+
+	client.CaptureMessage("second", nil, scope)
+	client.CaptureMessage("third", nil, scope)
+	var messages []string
+	for _, event := range client.LastEvents() {
+		messages = append(messages, event.Message)
+	}
+	assertEqual(t, messages, []string{"second", "third"})
+
+	client.CaptureMessage("fourth", nil, scope)
+	messages = nil
+	for _, event := range client.LastEvents() {
+		messages = append(messages, event.Message)
+	}
+	assertEqual(t, messages, []string{"third", "fourth"})
+}
+
+func TestLastEventsDisabled(t *testing.T) {
+	client, _, _ := setupClientTest()
+	client.CaptureMessage("Foo", nil, NewScope())
+	if events := client.LastEvents(); events != nil {
+		t.Errorf("got %v, want nil", events)
+	}
+}
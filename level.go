@@ -0,0 +1,78 @@
+package sentry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// levelSeverity orders levels from the least to the most severe.
+var levelSeverity = map[Level]int{
+	LevelDebug:   1,
+	LevelInfo:    2,
+	LevelWarning: 3,
+	LevelError:   4,
+	LevelFatal:   5,
+}
+
+// levelNames maps the names of levels used by logging libraries to levels.
+var levelNames = map[string]Level{
+	"trace":    LevelDebug,
+	"debug":    LevelDebug,
+	"info":     LevelInfo,
+	"notice":   LevelInfo,
+	"warn":     LevelWarning,
+	"warning":  LevelWarning,
+	"err":      LevelError,
+	"error":    LevelError,
+	"fatal":    LevelFatal,
+	"critical": LevelFatal,
+	"panic":    LevelFatal,
+}
+
+// ParseLevel returns the level with the given name. Names are case-insensitive
+// and include the names used by common logging libraries, such as "warn" for
+// LevelWarning or "panic" for LevelFatal.
+func ParseLevel(s string) (Level, error) {
+	if level, ok := levelNames[strings.ToLower(s)]; ok {
+		return level, nil
+	}
+	return "", fmt.Errorf("sentry: unknown level %q", s)
+}
+
+// Severity returns the rank of the level, from 1 for LevelDebug to 5 for
+// LevelFatal, or 0 if the level is unknown.
+func (l Level) Severity() int {
+	return levelSeverity[l]
+}
+
+// AtLeast reports whether the level is at least as severe as min. Unknown
+// levels are less severe than all known levels.
+func (l Level) AtLeast(min Level) bool {
+	return l.Severity() >= min.Severity()
+}
+
+// lineLevel returns the level of a line written by a logger, found in a
+// field like "ERROR", "[warn]", "WARNING:" or "level=debug", or LevelInfo if
+// the line has none. Lowercase level names are only recognized within
+// brackets or after "level=", so that messages like "no error" are not
+// mistaken for errors.
+func lineLevel(line string) Level {
+	for _, field := range strings.Fields(line) {
+		if value := strings.TrimPrefix(field, "level="); value != field {
+			if level, err := ParseLevel(strings.Trim(value, `"`)); err == nil {
+				return level
+			}
+			continue
+		}
+		name := strings.TrimRight(field, ":")
+		if bracketed := strings.Trim(name, "[]"); bracketed != name {
+			name = bracketed
+		} else if name != strings.ToUpper(name) {
+			continue
+		}
+		if level, err := ParseLevel(name); err == nil {
+			return level
+		}
+	}
+	return LevelInfo
+}
@@ -0,0 +1,22 @@
+//go:build go1.21
+// +build go1.21
+
+package sentry
+
+import "log/slog"
+
+// LevelFromSlog returns the level corresponding to a level of the log/slog
+// package. Levels between the levels defined by slog are rounded down, so
+// that slog.LevelWarn+2 is LevelWarning.
+func LevelFromSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarning
+	default:
+		return LevelError
+	}
+}
@@ -0,0 +1,23 @@
+//go:build go1.21
+// +build go1.21
+
+package sentry
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestLevelFromSlog(t *testing.T) {
+	tests := map[slog.Level]Level{
+		slog.LevelDebug:     LevelDebug,
+		slog.LevelInfo:      LevelInfo,
+		slog.LevelInfo + 1:  LevelInfo,
+		slog.LevelWarn:      LevelWarning,
+		slog.LevelError:     LevelError,
+		slog.LevelError + 4: LevelError,
+	}
+	for level, want := range tests {
+		assertEqual(t, LevelFromSlog(level), want, level)
+	}
+}
@@ -0,0 +1,49 @@
+package sentry
+
+import "testing"
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{
+		"debug":    LevelDebug,
+		"TRACE":    LevelDebug,
+		"Info":     LevelInfo,
+		"warn":     LevelWarning,
+		"WARNING":  LevelWarning,
+		"error":    LevelError,
+		"critical": LevelFatal,
+		"panic":    LevelFatal,
+	}
+	for s, want := range tests {
+		got, err := ParseLevel(s)
+		if err != nil {
+			t.Errorf("ParseLevel(%q): %v", s, err)
+		}
+		assertEqual(t, got, want, s)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("got no error for an unknown level")
+	}
+}
+
+func TestLevelOrdering(t *testing.T) {
+	assertEqual(t, LevelError.AtLeast(LevelWarning), true)
+	assertEqual(t, LevelWarning.AtLeast(LevelWarning), true)
+	assertEqual(t, LevelDebug.AtLeast(LevelInfo), false)
+	assertEqual(t, Level("loud").AtLeast(LevelDebug), false)
+	assertEqual(t, LevelFatal.Severity(), 5)
+}
+
+func TestLineLevel(t *testing.T) {
+	tests := map[string]Level{
+		"2021/05/04 10:00:00 ERROR: connection refused": LevelError,
+		"[warn] disk almost full":                       LevelWarning,
+		`time=now level="debug" msg=ready`:              LevelDebug,
+		"WARNING retrying":                              LevelWarning,
+		"no error occurred":                             LevelInfo,
+		"level=loud message":                            LevelInfo,
+		"listening on :8080":                            LevelInfo,
+	}
+	for line, want := range tests {
+		assertEqual(t, lineLevel(line), want, line)
+	}
+}
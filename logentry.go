@@ -0,0 +1,119 @@
+package sentry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LogEntry is a message with placeholders and the parameters that replace
+// them. Sentry groups the events that have a log entry by the message with
+// placeholders, so that messages like "failed to load user 123" and "failed to
+// load user 456" are grouped in a single issue.
+//
+// Placeholders are written %s, and a literal percent sign %%. An event with a
+// log entry is sent without its Message, which holds the formatted message
+// for the processing of the event by the SDK.
+type LogEntry struct {
+	Message string        `json:"message"`
+	Params  []interface{} `json:"params,omitempty"`
+	// Formatted is the message with the placeholders replaced by the
+	// parameters. It is computed when the event is captured if left empty.
+	Formatted string `json:"formatted,omitempty"`
+}
+
+// NewLogEntry returns a LogEntry for a message formatted according to a
+// format specifier, as in fmt.Sprintf. Every verb of format becomes a %s
+// placeholder for the formatted argument:
+//
+//	NewLogEntry("failed to load user %d", 123)
+//	// &LogEntry{Message: "failed to load user %s", Params: []interface{}{"123"}, Formatted: "failed to load user 123"}
+//
+// Formats with explicit argument indexes or star widths, and formats whose
+// verbs do not match the arguments, are not turned into placeholders: the log
+// entry then only has the formatted message.
+func NewLogEntry(format string, args ...interface{}) *LogEntry {
+	formatted := fmt.Sprintf(format, args...)
+	message, params, ok := placeholders(format, args)
+	if !ok {
+		return &LogEntry{Message: formatted, Formatted: formatted}
+	}
+	return &LogEntry{Message: message, Params: params, Formatted: formatted}
+}
+
+// placeholders returns format with its verbs replaced by %s placeholders, and
+// args formatted by the verbs. It reports false if format cannot be converted.
+func placeholders(format string, args []interface{}) (string, []interface{}, bool) {
+	var b strings.Builder
+	var params []interface{}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0.123456789", format[i]) >= 0 {
+			i++
+		}
+		if i == len(format) {
+			return "", nil, false
+		}
+		switch verb := format[i]; {
+		case verb == '%':
+			b.WriteString("%%")
+		case verb == '*' || verb == '[' || len(params) == len(args):
+			return "", nil, false
+		default:
+			params = append(params, fmt.Sprintf(format[start:i+1], args[len(params)]))
+			b.WriteString("%s")
+		}
+	}
+	if len(params) != len(args) {
+		return "", nil, false
+	}
+	return b.String(), params, true
+}
+
+// format returns the message of the log entry with the placeholders replaced
+// by the parameters.
+func (e *LogEntry) format() string {
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(e.Message); i++ {
+		if e.Message[i] != '%' || i+1 == len(e.Message) {
+			b.WriteByte(e.Message[i])
+			continue
+		}
+		switch e.Message[i+1] {
+		case '%':
+			b.WriteByte('%')
+			i++
+		case 's':
+			if n < len(e.Params) {
+				fmt.Fprint(&b, e.Params[n])
+				n++
+			} else {
+				b.WriteString("%s")
+			}
+			i++
+		default:
+			b.WriteByte('%')
+		}
+	}
+	return b.String()
+}
+
+// normalizeMessage completes the log entry of event, if any, with its
+// formatted message, which also becomes the message of the event if it has
+// none.
+func normalizeMessage(event *Event) {
+	if event.LogEntry == nil {
+		return
+	}
+	if event.LogEntry.Formatted == "" {
+		event.LogEntry.Formatted = event.LogEntry.format()
+	}
+	if event.Message == "" {
+		event.Message = event.LogEntry.Formatted
+	}
+}
@@ -0,0 +1,92 @@
+package sentry
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewLogEntry(t *testing.T) {
+	tests := []struct {
+		format string
+		args   []interface{}
+		want   LogEntry
+	}{
+		{
+			"failed to load user %d", []interface{}{123},
+			LogEntry{Message: "failed to load user %s", Params: []interface{}{"123"}, Formatted: "failed to load user 123"},
+		},
+		{
+			"%q took %.2fs (100%%)", []interface{}{"job", 1.5},
+			LogEntry{Message: "%s took %ss (100%%)", Params: []interface{}{`"job"`, "1.50"}, Formatted: `"job" took 1.50s (100%)`},
+		},
+		{
+			"no verbs", nil,
+			LogEntry{Message: "no verbs", Formatted: "no verbs"},
+		},
+		// Formats that cannot be converted keep the formatted message only.
+		{
+			"%[1]d %[1]d", []interface{}{7},
+			LogEntry{Message: "7 7", Formatted: "7 7"},
+		},
+		{
+			"%*d", []interface{}{4, 2},
+			LogEntry{Message: "   2", Formatted: "   2"},
+		},
+		{
+			"%d %d", []interface{}{1},
+			LogEntry{Message: "1 %!d(MISSING)", Formatted: "1 %!d(MISSING)"},
+		},
+		{
+			"%d", []interface{}{1, 2},
+			LogEntry{Message: "1%!(EXTRA int=2)", Formatted: "1%!(EXTRA int=2)"},
+		},
+	}
+	for _, tt := range tests {
+		got := NewLogEntry(tt.format, tt.args...)
+		assertEqual(t, *got, tt.want, tt.format)
+		if len(got.Params) > 0 {
+			got.Formatted = ""
+			assertEqual(t, got.format(), tt.want.Formatted, tt.format)
+		}
+	}
+}
+
+func TestCaptureMessagefLogEntry(t *testing.T) {
+	hub, client, _ := setupHubTest()
+	transport := &TransportMock{}
+	client.Transport = transport
+
+	hub.CaptureMessagef("failed to load user %d", 123)
+	hub.CaptureEvent(&Event{LogEntry: &LogEntry{Message: "retry %s of %s", Params: []interface{}{2, 3}}})
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	assertEqual(t, events[0].Message, "failed to load user 123")
+	assertEqual(t, events[0].LogEntry.Message, "failed to load user %s")
+	// Log entries without a formatted message are completed.
+	assertEqual(t, events[1].Message, "retry 2 of 3")
+	assertEqual(t, events[1].LogEntry.Formatted, "retry 2 of 3")
+
+	b, err := json.Marshal(events[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), `"message":"failed to load user 123"`) {
+		t.Errorf("event with a log entry has a message: %s", b)
+	}
+	if !strings.Contains(string(b), `"logentry":{"message":"failed to load user %s","params":["123"],"formatted":"failed to load user 123"}`) {
+		t.Errorf("event has no log entry: %s", b)
+	}
+}
+
+func TestErrorThrottleLogEntry(t *testing.T) {
+	throttle := NewErrorThrottle(0, 1)
+	event := func(id int) *Event {
+		return &Event{Message: "formatted", LogEntry: NewLogEntry("user %d", id)}
+	}
+	assertEqual(t, throttle.Sample(event(1)), true)
+	assertEqual(t, throttle.Sample(event(2)), false)
+}
@@ -0,0 +1,65 @@
+package sentry
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// logBreadcrumbWriter is an io.Writer that records lines as breadcrumbs.
+type logBreadcrumbWriter struct {
+	// hub is the hub where breadcrumbs are recorded. If nil, breadcrumbs are
+	// recorded on the current hub.
+	hub *Hub
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewLogBreadcrumbWriter returns an io.Writer that records every line written
+// to it as a breadcrumb on the current hub. It is safe for concurrent use.
+//
+// Use it as the output of a log.Logger, so that messages logged with the
+// standard library are attached to subsequent events:
+//
+//	log.SetOutput(io.MultiWriter(os.Stderr, sentry.NewLogBreadcrumbWriter()))
+//
+// Each line becomes the message of a breadcrumb with category "log", including
+// any prefix and timestamp added by the logger. The level of the breadcrumb is
+// the level found in the line, as in "ERROR: connection refused" or
+// "level=warn", and info otherwise. Incomplete lines are buffered until a
+// newline is written.
+func NewLogBreadcrumbWriter() io.Writer {
+	return &logBreadcrumbWriter{}
+}
+
+// Write implements io.Writer.
+func (w *logBreadcrumbWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	w.buf = append(w.buf, p...)
+	var lines []string
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimRight(w.buf[:i], "\r"); len(line) > 0 {
+			lines = append(lines, string(line))
+		}
+		w.buf = w.buf[i+1:]
+	}
+	w.mu.Unlock()
+
+	hub := w.hub
+	if hub == nil {
+		hub = CurrentHub()
+	}
+	for _, line := range lines {
+		hub.AddBreadcrumb(&Breadcrumb{
+			Category: "log",
+			Message:  line,
+			Level:    lineLevel(line),
+		}, nil)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,41 @@
+package sentry
+
+import (
+	"log"
+	"testing"
+)
+
+func TestLogBreadcrumbWriter(t *testing.T) {
+	hub, _, scope := setupHubTest()
+	w := &logBreadcrumbWriter{hub: hub}
+	logger := log.New(w, "app: ", 0)
+
+	logger.Print("first")
+	logger.Printf("second %d", 2)
+	// Partial lines are buffered until the newline is written.
+	if _, err := w.Write([]byte("thi")); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(scope.breadcrumbs), 2)
+	if _, err := w.Write([]byte("rd\r\n\nfourth\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+	for _, b := range scope.breadcrumbs {
+		assertEqual(t, b.Category, "log")
+		assertEqual(t, b.Level, LevelInfo)
+		messages = append(messages, b.Message)
+	}
+	assertEqual(t, messages, []string{"app: first", "app: second 2", "third", "fourth"})
+}
+
+func TestLogBreadcrumbWriterLevel(t *testing.T) {
+	hub, _, scope := setupHubTest()
+	logger := log.New(&logBreadcrumbWriter{hub: hub}, "", log.LstdFlags)
+
+	logger.Print("ERROR: connection refused")
+	logger.Print("[warn] disk almost full")
+	assertEqual(t, scope.breadcrumbs[0].Level, LevelError)
+	assertEqual(t, scope.breadcrumbs[1].Level, LevelWarning)
+}
@@ -0,0 +1,173 @@
+// Package sentrymachinery provides Sentry integration for task queues based on
+// the github.com/RichardKnop/machinery package.
+package sentrymachinery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/getsentry/sentry-go"
+)
+
+// traceHeader is the header of signatures used to propagate traces from
+// producers to tasks.
+const traceHeader = "sentry-trace"
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Inject sets the sentry-trace header of sig if ctx carries a transaction, so
+// that the task can continue the trace. Call it before sending the task:
+//
+//	sig := &tasks.Signature{Name: "send_email", Args: args}
+//	sentrymachinery.Inject(ctx, sig)
+//	_, err := server.SendTaskWithContext(ctx, sig)
+func Inject(ctx context.Context, sig *tasks.Signature) {
+	transaction := sentry.TransactionFromContext(ctx)
+	if transaction == nil {
+		return
+	}
+	if sig.Headers == nil {
+		sig.Headers = make(tasks.Headers)
+	}
+	sig.Headers[traceHeader] = transaction.ToSentryTrace()
+}
+
+// A Handler is a task middleware factory that provides integration with
+// Sentry.
+type Handler struct {
+	repanic         bool
+	waitForDelivery bool
+	timeout         time.Duration
+}
+
+// Options configure a Handler.
+type Options struct {
+	// Repanic configures whether to panic again after recovering from a panic
+	// in a task.
+	Repanic bool
+	// WaitForDelivery indicates, in case of a panic or an error, whether to
+	// block the current goroutine and wait until the event has been reported
+	// to Sentry before returning.
+	WaitForDelivery bool
+	// Timeout for the delivery of events. Defaults to 2s. Only relevant when
+	// WaitForDelivery is true.
+	Timeout time.Duration
+}
+
+// New returns a new Handler. Use the Wrap method to wrap task functions.
+func New(options Options) *Handler {
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	return &Handler{
+		repanic:         options.Repanic,
+		timeout:         timeout,
+		waitForDelivery: options.WaitForDelivery,
+	}
+}
+
+// Wrap returns a task function of the same type as task, which must be a
+// function returning an error last, like every machinery task. The wrapped
+// task runs with a task-specific hub and in a transaction named name. The
+// errors returned by the task and its panics are reported to Sentry. When a
+// panic is not repanicked, the wrapped task returns an error.
+//
+// Tasks that take a context.Context as first argument get the hub and the
+// transaction in their context, and continue the trace propagated with Inject.
+// Their events are tagged with the UUID of the task.
+//
+//	h := sentrymachinery.New(sentrymachinery.Options{})
+//	err := server.RegisterTask("send_email", h.Wrap("send_email", SendEmail))
+//
+// Other values are returned unchanged, for machinery to reject them.
+func (h *Handler) Wrap(name string, task interface{}) interface{} {
+	fn := reflect.ValueOf(task)
+	typ := fn.Type()
+	if typ.Kind() != reflect.Func || typ.NumOut() == 0 || typ.Out(typ.NumOut()-1) != errorType {
+		return task
+	}
+	usesContext := typ.NumIn() > 0 && typ.In(0) == contextType
+
+	return reflect.MakeFunc(typ, func(args []reflect.Value) (results []reflect.Value) {
+		ctx := context.Background()
+		if usesContext {
+			if c, ok := args[0].Interface().(context.Context); ok && c != nil {
+				ctx = c
+			}
+		}
+		hub := sentry.GetHubFromContext(ctx)
+		if hub == nil {
+			hub = sentry.CurrentHub().Clone()
+			ctx = sentry.SetHubOnContext(ctx, hub)
+		}
+		var trace string
+		sig := tasks.SignatureFromContext(ctx)
+		hub.ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetTag("machinery.task_name", name)
+			if sig != nil {
+				scope.SetTag("machinery.task_uuid", sig.UUID)
+				trace, _ = sig.Headers[traceHeader].(string)
+			}
+		})
+
+		span := sentry.StartSpan(ctx, "queue.process",
+			sentry.TransactionName(name),
+			sentry.WithTransactionSource(sentry.SourceTask),
+			sentry.ContinueFromTrace(trace),
+			sentry.WithSpanOrigin("auto.queue.machinery"),
+		)
+		if sig != nil {
+			span.SetData("machinery.retry_count", sig.RetryCount)
+		}
+		defer span.Finish()
+		defer func() {
+			if r := recover(); r != nil {
+				span.Status = sentry.SpanStatusInternalError
+				eventID := hub.RecoverWithContext(ctx, r)
+				h.wait(hub, eventID)
+				if h.repanic {
+					panic(r)
+				}
+				results = errorResults(typ, fmt.Errorf("sentrymachinery: panic: %v", r))
+			}
+		}()
+
+		if usesContext {
+			args[0] = reflect.ValueOf(span.Context())
+		}
+		results = fn.Call(args)
+		if err, _ := results[len(results)-1].Interface().(error); err != nil {
+			span.Status = sentry.SpanStatusInternalError
+			h.wait(hub, hub.CaptureException(err))
+		} else {
+			span.Status = sentry.SpanStatusOK
+		}
+		return results
+	}).Interface()
+}
+
+// errorResults returns the results of a call to a function of type typ that
+// returns err and zero values.
+func errorResults(typ reflect.Type, err error) []reflect.Value {
+	results := make([]reflect.Value, typ.NumOut())
+	for i := range results {
+		results[i] = reflect.Zero(typ.Out(i))
+	}
+	results[len(results)-1] = reflect.ValueOf(&err).Elem()
+	return results
+}
+
+// wait waits for the delivery of the event eventID, if any, when configured
+// to do so.
+func (h *Handler) wait(hub *sentry.Hub, eventID *sentry.EventID) {
+	if eventID != nil && h.waitForDelivery {
+		hub.Flush(h.timeout)
+	}
+}
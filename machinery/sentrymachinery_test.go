@@ -0,0 +1,103 @@
+package sentrymachinery_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/getsentry/sentry-go"
+	sentrymachinery "github.com/getsentry/sentry-go/machinery"
+	"github.com/getsentry/sentry-go/sentrytest"
+)
+
+// bindClient binds a new client to the current hub for the duration of the
+// test, and returns its transport. Machinery runs tasks with a background
+// context, so tasks report to a clone of the current hub.
+func bindClient(t *testing.T) *sentrytest.RecordingTransport {
+	t.Helper()
+	transport := sentrytest.NewRecordingTransport()
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Transport:        transport,
+		TracesSampleRate: 1.0,
+		Integrations:     func([]sentry.Integration) []sentry.Integration { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := sentry.CurrentHub().Client()
+	sentry.CurrentHub().BindClient(client)
+	t.Cleanup(func() { sentry.CurrentHub().BindClient(previous) })
+	return transport
+}
+
+var errSend = errors.New("mail server unavailable")
+
+func SendEmail(ctx context.Context, to string) (string, error) {
+	if sentry.TransactionFromContext(ctx) == nil {
+		return "", errors.New("missing transaction")
+	}
+	if tasks.SignatureFromContext(ctx) == nil {
+		return "", errors.New("missing signature")
+	}
+	return "", errSend
+}
+
+func TestWrap(t *testing.T) {
+	transport := bindClient(t)
+	producer := sentry.StartSpan(context.Background(), "signup", sentry.TransactionName("signup"))
+	sig := &tasks.Signature{
+		UUID: "task_1",
+		Name: "send_email",
+		Args: []tasks.Arg{{Type: "string", Value: "jane@example.com"}},
+	}
+	sentrymachinery.Inject(producer.Context(), sig)
+	producer.Finish()
+
+	h := sentrymachinery.New(sentrymachinery.Options{})
+	task, err := tasks.NewWithSignature(h.Wrap("send_email", SendEmail), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := task.Call(); err != errSend {
+		t.Fatalf("got error %v, want %v", err, errSend)
+	}
+
+	var failure, transaction *sentry.Event
+	for _, event := range transport.Events() {
+		switch {
+		case event.Type == "" && failure == nil:
+			failure = event
+		case event.Type == "transaction" && event.Transaction == "send_email":
+			transaction = event
+		}
+	}
+	if failure == nil || failure.Exception[0].Value != errSend.Error() || failure.Tags["machinery.task_uuid"] != "task_1" {
+		t.Errorf("unexpected error event %+v", failure)
+	}
+	if transaction == nil {
+		t.Fatal("missing transaction")
+	}
+	trace := transaction.Contexts["trace"].(*sentry.TraceContext)
+	if trace.TraceID != producer.TraceID || trace.ParentSpanID != producer.SpanID {
+		t.Errorf("got trace %+v, want the trace of the producer", trace)
+	}
+}
+
+func TestWrapPanic(t *testing.T) {
+	transport := bindClient(t)
+	h := sentrymachinery.New(sentrymachinery.Options{})
+	wrapped := h.Wrap("panic", func() error { panic("test") }).(func() error)
+	if err := wrapped(); err == nil {
+		t.Error("got no error, want an error for the panic")
+	}
+	events := transport.Events()
+	if len(events) != 2 || events[0].Message != "test" {
+		t.Fatalf("got events %v, want the panic event and the transaction", events)
+	}
+
+	// Values other than task functions are returned unchanged.
+	if got := h.Wrap("invalid", 42); got != 42 {
+		t.Errorf("got %v, want 42", got)
+	}
+}
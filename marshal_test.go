@@ -24,10 +24,25 @@ func TestMarshalJSON(t *testing.T) {
 		in  interface{}
 		out string
 	}{
-		// TODO: eliminate empty struct fields from serialization of empty event.
 		// Only *Event implements json.Marshaler.
 		// {Event{}, `{"sdk":{},"user":{}}`},
-		{&Event{}, `{"sdk":{},"user":{}}`},
+		{&Event{}, `{}`},
+		{&Event{Type: transactionType}, `{"type":"transaction"}`},
+		{&Event{User: User{ID: "1"}, Sdk: SdkInfo{Name: "sentry.go"}}, `{"user":{"id":"1"},"sdk":{"name":"sentry.go"}}`},
+		{&Event{
+			Type:        checkInType,
+			EventID:     "c4b5b9d1a1e84ab1a6c3bde9c0a8e6f0",
+			Release:     "1.0",
+			Environment: "production",
+			Message:     "omitted",
+			Timestamp:   goReleaseDate,
+			CheckIn:     &CheckIn{MonitorSlug: "job", Status: CheckInStatusOK, Duration: 1500 * time.Millisecond},
+		}, `{"check_in_id":"c4b5b9d1a1e84ab1a6c3bde9c0a8e6f0","monitor_slug":"job","status":"ok","duration":1.5,"release":"1.0","environment":"production"}`},
+		{&Event{
+			Type:    checkInType,
+			EventID: "c4b5b9d1a1e84ab1a6c3bde9c0a8e6f0",
+			CheckIn: &CheckIn{ID: "0a1b2c3d4e5f60718293a4b5c6d7e8f9", MonitorSlug: "job", Status: CheckInStatusInProgress},
+		}, `{"check_in_id":"0a1b2c3d4e5f60718293a4b5c6d7e8f9","monitor_slug":"job","status":"in_progress"}`},
 		// Only *Breadcrumb implements json.Marshaler.
 		// {Breadcrumb{}, `{}`},
 		{&Breadcrumb{}, `{}`},
@@ -101,6 +116,18 @@ func TestTransactionEventMarshalJSON(t *testing.T) {
 		{
 			Type: transactionType,
 		},
+		{
+			Type:            transactionType,
+			Transaction:     "GET /users/{id}",
+			TransactionInfo: &TransactionInfo{Source: SourceRoute},
+		},
+		{
+			Type: transactionType,
+			Measurements: map[string]Measurement{
+				"queue_wait": {Value: 12.3, Unit: "millisecond"},
+				"items":      {Value: 3},
+			},
+		},
 	}
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
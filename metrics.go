@@ -0,0 +1,128 @@
+package sentry
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// dropReasons are all the reasons of dropped events, reported as metrics
+// even when no event was dropped, such that alerts on their rate work.
+var dropReasons = []DropReason{
+	DropClientClosed,
+	DropRecursion,
+	DropSampleRate,
+	DropEventSampler,
+	DropEventProcessor,
+	DropBeforeSend,
+	DropQueueOverflow,
+	DropRateLimit,
+	DropNetworkError,
+	DropServerRejected,
+	DropTransportClosed,
+}
+
+// PublishExpvar publishes the report of Diagnose as the expvar variable with
+// the given name, served by the handler of the expvar package on
+// /debug/vars. The report is of the client of the current hub when the
+// variable is read:
+//
+//	sentry.PublishExpvar("sentry")
+//
+// Like expvar.Publish, it panics if the name is already in use.
+func PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return Diagnose()
+	}))
+}
+
+// MetricsHandler returns an HTTP handler serving metrics about the client of
+// the current hub in the text format of Prometheus, such that operators can
+// alert when events are not delivered:
+//
+//	http.Handle("/metrics/sentry", sentry.MetricsHandler())
+//
+// The metrics are those of the report of Diagnose:
+//
+//	sentry_enabled                          1 if the client sends events
+//	sentry_events_captured_total            events captured
+//	sentry_events_sent_total                events accepted by Sentry
+//	sentry_events_dropped_total{reason}     events dropped, by DropReason
+//	sentry_transport_queue_depth            events waiting to be sent
+//	sentry_flush_duration_seconds           summary of the time spent flushing
+//	sentry_flush_timeouts_total             flushes that did not complete in time
+//	sentry_rate_limited                     1 if Sentry rate limits any category
+//	sentry_rate_limit_seconds{category}     time left of the rate limits in effect
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveMetrics(w, Diagnose())
+	})
+}
+
+// MetricsHandler returns an HTTP handler serving metrics about the client in
+// the text format of Prometheus. See the MetricsHandler function.
+func (client *Client) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveMetrics(w, client.Diagnose())
+	})
+}
+
+func serveMetrics(w http.ResponseWriter, report DiagnosticReport) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, report, time.Now())
+}
+
+// writeMetrics writes the metrics of report in the text format of
+// Prometheus. Rate limits are measured from now.
+func writeMetrics(w io.Writer, report DiagnosticReport, now time.Time) {
+	header := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	boolean := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	header("sentry_enabled", "gauge", "Whether the client sends events.")
+	fmt.Fprintf(w, "sentry_enabled %d\n", boolean(report.Enabled))
+
+	header("sentry_events_captured_total", "counter", "Events captured.")
+	fmt.Fprintf(w, "sentry_events_captured_total %d\n", report.Captured)
+
+	header("sentry_events_sent_total", "counter", "Events accepted by Sentry.")
+	fmt.Fprintf(w, "sentry_events_sent_total %d\n", report.Sent)
+
+	header("sentry_events_dropped_total", "counter", "Events dropped, by reason.")
+	for _, reason := range dropReasons {
+		fmt.Fprintf(w, "sentry_events_dropped_total{reason=%s} %d\n", strconv.Quote(string(reason)), report.Dropped[reason])
+	}
+
+	header("sentry_transport_queue_depth", "gauge", "Events waiting to be sent.")
+	fmt.Fprintf(w, "sentry_transport_queue_depth %d\n", report.QueueDepth)
+
+	header("sentry_flush_duration_seconds", "summary", "Time spent flushing.")
+	fmt.Fprintf(w, "sentry_flush_duration_seconds_sum %g\n", report.FlushDuration.Seconds())
+	fmt.Fprintf(w, "sentry_flush_duration_seconds_count %d\n", report.Flushes)
+
+	header("sentry_flush_timeouts_total", "counter", "Flushes that did not complete in time.")
+	fmt.Fprintf(w, "sentry_flush_timeouts_total %d\n", report.FlushTimeouts)
+
+	header("sentry_rate_limited", "gauge", "Whether Sentry rate limits any category of events.")
+	fmt.Fprintf(w, "sentry_rate_limited %d\n", boolean(len(report.RateLimits) > 0))
+
+	categories := make([]string, 0, len(report.RateLimits))
+	for category := range report.RateLimits {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	header("sentry_rate_limit_seconds", "gauge", "Time left of the rate limits in effect, by category.")
+	for _, category := range categories {
+		fmt.Fprintf(w, "sentry_rate_limit_seconds{category=%s} %g\n", strconv.Quote(category), report.RateLimits[category].Sub(now).Seconds())
+	}
+}
@@ -0,0 +1,96 @@
+package sentry
+
+import (
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	report := DiagnosticReport{
+		Enabled:       true,
+		Captured:      5,
+		Sent:          3,
+		Dropped:       map[DropReason]uint64{DropBeforeSend: 1, DropRateLimit: 1},
+		QueueDepth:    1,
+		Flushes:       2,
+		FlushTimeouts: 1,
+		FlushDuration: 1500 * time.Millisecond,
+		RateLimits:    map[string]time.Time{"error": now.Add(time.Minute)},
+	}
+
+	var b strings.Builder
+	writeMetrics(&b, report, now)
+	var got []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") && !strings.HasSuffix(line, "} 0") {
+			got = append(got, line)
+		}
+	}
+	want := []string{
+		"sentry_enabled 1",
+		"sentry_events_captured_total 5",
+		"sentry_events_sent_total 3",
+		`sentry_events_dropped_total{reason="before_send"} 1`,
+		`sentry_events_dropped_total{reason="ratelimit_backoff"} 1`,
+		"sentry_transport_queue_depth 1",
+		"sentry_flush_duration_seconds_sum 1.5",
+		"sentry_flush_duration_seconds_count 2",
+		"sentry_flush_timeouts_total 1",
+		"sentry_rate_limited 1",
+		`sentry_rate_limit_seconds{category="error"} 60`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(b.String(), `sentry_events_dropped_total{reason="network_error"} 0`) {
+		t.Error("metrics do not report reasons without dropped events")
+	}
+}
+
+func TestClientMetricsHandler(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		Transport: &TransportMock{},
+		BeforeSend: func(event *Event, hint *EventHint) *Event {
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.CaptureMessage("dropped", nil, nil)
+	client.Flush(time.Second)
+
+	recorder := httptest.NewRecorder()
+	client.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assertEqual(t, recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8")
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"sentry_events_captured_total 1\n",
+		`sentry_events_dropped_total{reason="before_send"} 1` + "\n",
+		"sentry_flush_duration_seconds_count 1\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("metrics do not contain %q:\n%s", line, body)
+		}
+	}
+}
+
+func TestPublishExpvar(t *testing.T) {
+	// Variables cannot be published twice, when the test is run repeatedly.
+	if expvar.Get("sentry_test") == nil {
+		PublishExpvar("sentry_test")
+	}
+	v := expvar.Get("sentry_test")
+	if v == nil {
+		t.Fatal("variable not published")
+	}
+	if !strings.Contains(v.String(), `"sdk_version":"`+Version+`"`) {
+		t.Errorf("got %s, want a DiagnosticReport", v)
+	}
+}
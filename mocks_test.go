@@ -42,3 +42,21 @@ func (t *TransportMock) Events() []*Event {
 	defer t.mu.Unlock()
 	return t.events
 }
+
+// ClockMock is a Clock that returns a fixed time, advanced manually.
+type ClockMock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *ClockMock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *ClockMock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
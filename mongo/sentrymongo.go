@@ -0,0 +1,176 @@
+// Package sentrymongo provides Sentry integration for the official MongoDB
+// driver, go.mongodb.org/mongo-driver.
+package sentrymongo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/getsentry/sentry-go"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// operation is the operation of the spans created by a Monitor.
+const operation = "db.mongodb"
+
+// Options configure a Monitor.
+type Options struct {
+	// CaptureFailures configures whether failed commands are reported as
+	// events. By default, failures are only recorded as breadcrumbs.
+	CaptureFailures bool
+}
+
+// A Monitor records MongoDB commands as spans, and command failures as
+// breadcrumbs or events. It provides a CommandMonitor and a PoolMonitor to
+// install in the client options:
+//
+//	monitor := sentrymongo.New(sentrymongo.Options{})
+//	client, err := mongo.Connect(ctx, options.Client().
+//		ApplyURI("mongodb://localhost:27017").
+//		SetMonitor(monitor.CommandMonitor()).
+//		SetPoolMonitor(monitor.PoolMonitor()))
+//
+// Spans are only created for commands run with a context that carries a
+// transaction. Command documents are never sent to Sentry, only the name of
+// the command, the database and the collection.
+type Monitor struct {
+	captureFailures bool
+
+	mu    sync.Mutex
+	spans map[commandKey]*sentry.Span
+}
+
+// commandKey identifies a command across the events of a CommandMonitor.
+type commandKey struct {
+	connectionID string
+	requestID    int64
+}
+
+// New returns a new Monitor.
+func New(options Options) *Monitor {
+	return &Monitor{
+		captureFailures: options.CaptureFailures,
+		spans:           make(map[commandKey]*sentry.Span),
+	}
+}
+
+// CommandMonitor returns a command monitor that reports to m.
+func (m *Monitor) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started:   m.started,
+		Succeeded: m.succeeded,
+		Failed:    m.failed,
+	}
+}
+
+// PoolMonitor returns a connection pool monitor that records the clearing of
+// pools, closed connections and failures to check out a connection as
+// breadcrumbs on the current hub.
+func (m *Monitor) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: m.poolEvent,
+	}
+}
+
+func (m *Monitor) started(ctx context.Context, e *event.CommandStartedEvent) {
+	if sentry.TransactionFromContext(ctx) == nil {
+		return
+	}
+	span := sentry.StartSpan(ctx, operation, sentry.WithSpanOrigin("auto.db.mongo"))
+	span.Description = e.CommandName
+	span.SetTag("db.system", "mongodb")
+	span.SetTag("db.name", e.DatabaseName)
+	span.SetTag("db.operation", e.CommandName)
+	if collection, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+		span.Description += " " + collection
+		span.SetTag("db.mongodb.collection", collection)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spans[commandKey{e.ConnectionID, e.RequestID}] = span
+}
+
+func (m *Monitor) succeeded(ctx context.Context, e *event.CommandSucceededEvent) {
+	m.finish(e.CommandFinishedEvent, sentry.SpanStatusOK)
+}
+
+func (m *Monitor) failed(ctx context.Context, e *event.CommandFailedEvent) {
+	// The span is finished last, so that the failure is linked to it.
+	defer m.finish(e.CommandFinishedEvent, sentry.SpanStatusInternalError)
+
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	hub.AddBreadcrumb(&sentry.Breadcrumb{
+		Type:     "query",
+		Category: "mongodb",
+		Message:  e.CommandName + " failed: " + e.Failure,
+		Level:    sentry.LevelError,
+		Data: map[string]interface{}{
+			"connection_id": e.ConnectionID,
+			"duration_ms":   e.DurationNanos / 1e6,
+		},
+	}, nil)
+	if m.captureFailures {
+		hub.WithScope(func(scope *sentry.Scope) {
+			scope.SetTag("db.operation", e.CommandName)
+			hub.CaptureException(&CommandError{Command: e.CommandName, Failure: e.Failure})
+		})
+	}
+}
+
+// finish finishes the span of the command e, if any.
+func (m *Monitor) finish(e event.CommandFinishedEvent, status sentry.SpanStatus) {
+	key := commandKey{e.ConnectionID, e.RequestID}
+	m.mu.Lock()
+	span, ok := m.spans[key]
+	delete(m.spans, key)
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	span.Status = status
+	span.Finish()
+}
+
+func (m *Monitor) poolEvent(e *event.PoolEvent) {
+	var message string
+	level := sentry.LevelWarning
+	switch e.Type {
+	case event.PoolCleared:
+		message = "Connection pool cleared"
+	case event.ConnectionClosed:
+		message = "Connection closed"
+		level = sentry.LevelInfo
+	case event.GetFailed:
+		message = "Connection check out failed"
+		level = sentry.LevelError
+	default:
+		return
+	}
+	data := map[string]interface{}{
+		"address": e.Address,
+	}
+	if e.Reason != "" {
+		data["reason"] = e.Reason
+	}
+	sentry.CurrentHub().AddBreadcrumb(&sentry.Breadcrumb{
+		Category: "mongodb.pool",
+		Message:  message,
+		Level:    level,
+		Data:     data,
+	}, nil)
+}
+
+// A CommandError is reported when a command fails and the Monitor is
+// configured to capture failures.
+type CommandError struct {
+	Command string
+	Failure string
+}
+
+func (e *CommandError) Error() string {
+	return "mongodb: " + e.Command + " failed: " + e.Failure
+}
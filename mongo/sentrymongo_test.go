@@ -0,0 +1,94 @@
+package sentrymongo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	sentrymongo "github.com/getsentry/sentry-go/mongo"
+	"github.com/getsentry/sentry-go/sentrytest"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func started(t *testing.T, requestID int64, command bson.D) *event.CommandStartedEvent {
+	t.Helper()
+	raw, err := bson.Marshal(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &event.CommandStartedEvent{
+		Command:      raw,
+		DatabaseName: "shop",
+		CommandName:  command[0].Key,
+		RequestID:    requestID,
+		ConnectionID: "localhost:27017[-1]",
+	}
+}
+
+func finished(e *event.CommandStartedEvent) event.CommandFinishedEvent {
+	return event.CommandFinishedEvent{
+		DurationNanos: int64(3 * time.Millisecond),
+		CommandName:   e.CommandName,
+		RequestID:     e.RequestID,
+		ConnectionID:  e.ConnectionID,
+	}
+}
+
+func TestCommandMonitorSpans(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	transaction := sentry.StartSpan(ctx, "test", sentry.TransactionName("test"))
+	ctx = transaction.Context()
+	monitor := sentrymongo.New(sentrymongo.Options{}).CommandMonitor()
+
+	find := started(t, 1, bson.D{{Key: "find", Value: "users"}, {Key: "filter", Value: bson.D{{Key: "email", Value: "secret"}}}})
+	insert := started(t, 2, bson.D{{Key: "insert", Value: "orders"}})
+	monitor.Started(ctx, find)
+	monitor.Started(ctx, insert)
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished(find)})
+	monitor.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: finished(insert), Failure: "duplicate key"})
+	transaction.Finish()
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	spans := events[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Description != "find users" || spans[0].Status != sentry.SpanStatusOK {
+		t.Errorf("unexpected span %+v", spans[0])
+	}
+	if spans[0].Tags["db.name"] != "shop" || spans[0].Tags["db.mongodb.collection"] != "users" {
+		t.Errorf("got tags %v, want database and collection", spans[0].Tags)
+	}
+	if spans[1].Description != "insert orders" || spans[1].Status != sentry.SpanStatusInternalError {
+		t.Errorf("unexpected span %+v", spans[1])
+	}
+	breadcrumbs := events[0].Breadcrumbs
+	if len(breadcrumbs) != 1 || breadcrumbs[0].Message != "insert failed: duplicate key" {
+		t.Errorf("got breadcrumbs %+v, want the failed command", breadcrumbs)
+	}
+}
+
+func TestCommandMonitorCaptureFailures(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	monitor := sentrymongo.New(sentrymongo.Options{CaptureFailures: true}).CommandMonitor()
+
+	insert := started(t, 1, bson.D{{Key: "insert", Value: "orders"}})
+	monitor.Started(ctx, insert)
+	monitor.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: finished(insert), Failure: "duplicate key"})
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	exception := events[0].Exception[len(events[0].Exception)-1]
+	if exception.Value != "mongodb: insert failed: duplicate key" {
+		t.Errorf("got exception %q", exception.Value)
+	}
+	if events[0].Tags["db.operation"] != "insert" {
+		t.Errorf("got tags %v, want the operation", events[0].Tags)
+	}
+}
@@ -0,0 +1,115 @@
+package sentry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// multiTransport is the Transport of NewMultiTransport.
+type multiTransport struct {
+	transports []Transport
+}
+
+// NewMultiTransport returns a Transport that sends every event to all the
+// given transports, for example to Sentry and to a local file while migrating
+// or auditing:
+//
+//	sentry.Init(sentry.ClientOptions{
+//		Dsn:       "your-public-dsn",
+//		Transport: sentry.NewMultiTransport(sentry.NewHTTPTransport(), auditTransport),
+//	})
+//
+// Transports fail independently: a transport that panics is logged and does
+// not prevent the others from receiving the event. The same event is passed
+// to all transports, which must not modify it.
+func NewMultiTransport(transports ...Transport) Transport {
+	return &multiTransport{transports: transports}
+}
+
+// Configure configures all transports.
+func (t *multiTransport) Configure(options ClientOptions) {
+	for _, transport := range t.transports {
+		callTransport(transport, "configure", func() { transport.Configure(options) })
+	}
+}
+
+// SendEvent sends the event to all transports.
+func (t *multiTransport) SendEvent(event *Event) {
+	for _, transport := range t.transports {
+		callTransport(transport, "send an event with", func() { transport.SendEvent(event) })
+	}
+}
+
+// SendEventWithContext sends the event to all transports, with ctx for those
+// that are a ContextTransport.
+func (t *multiTransport) SendEventWithContext(ctx context.Context, event *Event) {
+	for _, transport := range t.transports {
+		callTransport(transport, "send an event with", func() { sendEventWithContext(ctx, transport, event) })
+	}
+}
+
+// Flush flushes all transports concurrently, within the same timeout. It
+// reports whether all transports flushed their events.
+func (t *multiTransport) Flush(timeout time.Duration) bool {
+	return t.flush(func(transport Transport) bool {
+		return transport.Flush(timeout)
+	})
+}
+
+// FlushWithContext flushes all transports concurrently, until ctx is done. It
+// reports whether all transports flushed their events.
+func (t *multiTransport) FlushWithContext(ctx context.Context) bool {
+	return t.flush(func(transport Transport) bool {
+		return flushWithContext(ctx, transport)
+	})
+}
+
+// flush flushes all transports concurrently with f.
+func (t *multiTransport) flush(f func(transport Transport) bool) bool {
+	var wg sync.WaitGroup
+	results := make([]bool, len(t.transports))
+	for i, transport := range t.transports {
+		i, transport := i, transport
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			callTransport(transport, "flush", func() { results[i] = f(transport) })
+		}()
+	}
+	wg.Wait()
+	for _, ok := range results {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// diagnose reports the state of all transports.
+func (t *multiTransport) diagnose(report *DiagnosticReport) {
+	for _, transport := range t.transports {
+		if r, ok := transport.(diagnosticsReporter); ok {
+			r.diagnose(report)
+		}
+	}
+}
+
+// Close closes the transports that hold resources.
+func (t *multiTransport) Close() {
+	for _, transport := range t.transports {
+		if c, ok := transport.(transportCloser); ok {
+			callTransport(transport, "close", c.Close)
+		}
+	}
+}
+
+// callTransport calls f, logging and recovering from a panic of the transport.
+func callTransport(transport Transport, action string, f func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			Logger.Printf("Could not %s transport %T: %v", action, transport, err)
+		}
+	}()
+	f()
+}
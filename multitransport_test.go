@@ -0,0 +1,40 @@
+package sentry
+
+import (
+	"testing"
+	"time"
+)
+
+// panicTransport is a Transport that panics on every call.
+type panicTransport struct{}
+
+func (panicTransport) Configure(ClientOptions)  { panic("configure") }
+func (panicTransport) SendEvent(*Event)         { panic("send") }
+func (panicTransport) Flush(time.Duration) bool { panic("flush") }
+
+// slowTransport is a Transport that fails to flush in time.
+type slowTransport struct {
+	TransportMock
+}
+
+func (*slowTransport) Flush(time.Duration) bool { return false }
+
+func TestMultiTransport(t *testing.T) {
+	first, second := &TransportMock{}, &TransportMock{}
+	transport := NewMultiTransport(first, panicTransport{}, second)
+	client, err := NewClient(ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(client, NewScope())
+
+	hub.CaptureMessage("hello")
+	assertEqual(t, len(first.Events()), 1)
+	assertEqual(t, len(second.Events()), 1)
+	assertEqual(t, first.Events()[0], second.Events()[0])
+	// A panicking transport does not flush.
+	assertEqual(t, hub.Flush(time.Second), false)
+
+	assertEqual(t, NewMultiTransport(first, second).Flush(time.Second), true)
+	assertEqual(t, NewMultiTransport(first, &slowTransport{}).Flush(time.Second), false)
+}
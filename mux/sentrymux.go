@@ -0,0 +1,37 @@
+// Package sentrymux provides Sentry integration for servers based on the
+// github.com/gorilla/mux router.
+package sentrymux
+
+import (
+	"net/http"
+
+	sentryhttp "github.com/getsentry/sentry-go/http"
+	"github.com/gorilla/mux"
+)
+
+// New returns a sentryhttp.Handler that names transactions after the path
+// templates of the gorilla/mux routes that match requests, for example
+// "GET /users/{id}". Install it as a middleware of the router, which runs
+// once a route has matched:
+//
+//	r := mux.NewRouter()
+//	r.Use(sentrymux.New(sentryhttp.Options{}).Handle)
+//	r.HandleFunc("/users/{id}", getUser).Methods("GET")
+func New(options sentryhttp.Options) *sentryhttp.Handler {
+	options.RoutePattern = RoutePattern
+	return sentryhttp.New(options)
+}
+
+// RoutePattern returns the path template of the gorilla/mux route that matched
+// r, or "" if no route matched.
+func RoutePattern(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return template
+}
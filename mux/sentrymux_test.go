@@ -0,0 +1,54 @@
+package sentrymux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	sentryhttp "github.com/getsentry/sentry-go/http"
+	sentrymux "github.com/getsentry/sentry-go/mux"
+	"github.com/getsentry/sentry-go/sentrytest"
+	"github.com/gorilla/mux"
+)
+
+func TestTransactionName(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(sentrymux.New(sentryhttp.Options{}).Handle)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	orders := router.PathPrefix("/orders").Subrouter()
+	orders.HandleFunc("/{id}/items", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/42", "GET /users/{id}"},
+		{"/orders/7/items", "GET /orders/{id}/items"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			transport := sentrytest.NewRecordingTransport()
+			client, err := sentry.NewClient(sentry.ClientOptions{
+				Transport:        transport,
+				TracesSampleRate: 1.0,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hub := sentry.NewHub(client, sentry.NewScope())
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r = r.WithContext(sentry.SetHubOnContext(r.Context(), hub))
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			events := transport.Events()
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			if events[0].Transaction != tt.want {
+				t.Errorf("got transaction %q, want %q", events[0].Transaction, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package sentryotel
+
+import (
+	"context"
+	"encoding/hex"
+	"regexp"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sentryTraceHeader is the header used to propagate traces between
+// applications instrumented with Sentry SDKs.
+const sentryTraceHeader = "sentry-trace"
+
+// sentryTracePattern matches the value of a sentry-trace header:
+//
+//	TRACE_ID - SPAN_ID [- SAMPLED]
+var sentryTracePattern = regexp.MustCompile(`^([[:xdigit:]]{32})-([[:xdigit:]]{16})(?:-([01]))?$`)
+
+// propagator is a propagation.TextMapPropagator for the sentry-trace and
+// baggage headers.
+type propagator struct {
+	baggage propagation.Baggage
+}
+
+// NewSentryPropagator returns an OpenTelemetry propagator that translates
+// between OpenTelemetry span contexts and the sentry-trace header, so that
+// traces continue across services instrumented with OpenTelemetry and with
+// Sentry SDKs. It also propagates the W3C baggage header.
+//
+// To accept and send both the W3C traceparent header and the sentry-trace
+// header, compose it with propagation.TraceContext:
+//
+//	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+//		propagation.TraceContext{},
+//		sentryotel.NewSentryPropagator(),
+//	))
+func NewSentryPropagator() propagation.TextMapPropagator {
+	return propagator{}
+}
+
+// Inject implements propagation.TextMapPropagator.
+func (p propagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if sc.IsValid() {
+		sampled := "0"
+		if sc.IsSampled() {
+			sampled = "1"
+		}
+		carrier.Set(sentryTraceHeader, sc.TraceID().String()+"-"+sc.SpanID().String()+"-"+sampled)
+	}
+	p.baggage.Inject(ctx, carrier)
+}
+
+// Extract implements propagation.TextMapPropagator. If the carrier has a
+// valid sentry-trace header, the returned context has a remote span context
+// with the trace ID, parent span ID and sampling decision of the header. A
+// sentry-trace header without a sampling decision is extracted as sampled,
+// leaving the decision to the sampler of the tracer provider.
+func (p propagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	ctx = p.baggage.Extract(ctx, carrier)
+	m := sentryTracePattern.FindStringSubmatch(carrier.Get(sentryTraceHeader))
+	if m == nil {
+		return ctx
+	}
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	_, _ = hex.Decode(traceID[:], []byte(m[1]))
+	_, _ = hex.Decode(spanID[:], []byte(m[2]))
+	config := trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Remote:  true,
+	}
+	if m[3] != "0" {
+		config.TraceFlags = trace.FlagsSampled
+	}
+	sc := trace.NewSpanContext(config)
+	if !sc.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Fields implements propagation.TextMapPropagator.
+func (p propagator) Fields() []string {
+	return append([]string{sentryTraceHeader}, p.baggage.Fields()...)
+}
@@ -0,0 +1,203 @@
+// Package sentryotel provides Sentry integration for OpenTelemetry, sending
+// spans recorded with the go.opentelemetry.io/otel SDK to Sentry as
+// transactions and propagating traces between both.
+package sentryotel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultFlushTimeout is the maximum time to wait for events to be delivered
+// by ForceFlush and Shutdown when their context has no deadline.
+const defaultFlushTimeout = 2 * time.Second
+
+// spanProcessor converts OpenTelemetry spans into Sentry spans. The root span
+// of every local span tree becomes a Sentry transaction.
+type spanProcessor struct {
+	mu    sync.Mutex
+	spans map[trace.SpanID]*sentry.Span
+}
+
+// NewSentrySpanProcessor returns an OpenTelemetry span processor that sends
+// the spans recorded by a tracer provider to Sentry. Register it with the
+// tracer provider, along with NewSentryPropagator:
+//
+//	sentry.Init(sentry.ClientOptions{
+//		// ...
+//	})
+//	tp := sdktrace.NewTracerProvider(
+//		sdktrace.WithSpanProcessor(sentryotel.NewSentrySpanProcessor()),
+//	)
+//	otel.SetTracerProvider(tp)
+//	otel.SetTextMapPropagator(sentryotel.NewSentryPropagator())
+//
+// The local root span of a trace is sent as a transaction named after the span
+// and its descendants as child spans. Span IDs, trace IDs and sampling
+// decisions are those of OpenTelemetry, so that the tracer provider's sampler
+// applies and TracesSampleRate and TracesSampler do not. Attributes are
+// recorded as span data, and the operation and description of Sentry spans
+// are derived from the semantic conventions for HTTP, database and messaging
+// spans. Transactions are reported on the hub of the context of the root span,
+// or a clone of the current hub.
+func NewSentrySpanProcessor() sdktrace.SpanProcessor {
+	return &spanProcessor{
+		spans: make(map[trace.SpanID]*sentry.Span),
+	}
+}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (p *spanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	sc := s.SpanContext()
+	sampled := sentry.SampledFalse
+	if sc.IsSampled() {
+		sampled = sentry.SampledTrue
+	}
+	setIDs := func(span *sentry.Span) {
+		span.TraceID = sentry.TraceID(sc.TraceID())
+		span.SpanID = sentry.SpanID(sc.SpanID())
+		span.Sampled = sampled
+	}
+
+	p.mu.Lock()
+	parentSpan := p.spans[s.Parent().SpanID()]
+	p.mu.Unlock()
+
+	origin := sentry.WithSpanOrigin("auto.otel")
+	var span *sentry.Span
+	if parentSpan != nil {
+		span = parentSpan.StartChild(s.Name(), setIDs, origin)
+	} else {
+		hub := sentry.GetHubFromContext(parent)
+		if hub == nil {
+			hub = sentry.CurrentHub()
+		}
+		ctx := sentry.SetHubOnContext(parent, hub.Clone())
+		span = sentry.StartSpan(ctx, s.Name(),
+			sentry.TransactionName(s.Name()),
+			setIDs,
+			origin,
+			func(span *sentry.Span) {
+				if s.Parent().IsValid() {
+					span.ParentSpanID = sentry.SpanID(s.Parent().SpanID())
+				}
+			},
+		)
+	}
+	span.StartTime = s.StartTime()
+
+	p.mu.Lock()
+	p.spans[sc.SpanID()] = span
+	p.mu.Unlock()
+}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (p *spanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.mu.Lock()
+	span, ok := p.spans[s.SpanContext().SpanID()]
+	delete(p.spans, s.SpanContext().SpanID())
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	attrs := make(map[attribute.Key]attribute.Value, len(s.Attributes()))
+	span.Data = make(map[string]interface{}, len(s.Attributes()))
+	for _, kv := range s.Attributes() {
+		attrs[kv.Key] = kv.Value
+		span.Data[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	span.Op, span.Description = describe(s, attrs)
+	span.Status = status(s, attrs)
+	span.EndTime = s.EndTime()
+	if sentry.TransactionFromContext(span.Context()) == span {
+		sentry.GetHubFromContext(span.Context()).Scope().SetTransaction(s.Name())
+	}
+	span.Finish()
+}
+
+// Shutdown implements sdktrace.SpanProcessor. It waits for the delivery of
+// pending events.
+func (p *spanProcessor) Shutdown(ctx context.Context) error {
+	return p.ForceFlush(ctx)
+}
+
+// ForceFlush implements sdktrace.SpanProcessor. It waits for the delivery of
+// pending events, for at most the time until the deadline of ctx.
+func (p *spanProcessor) ForceFlush(ctx context.Context) error {
+	timeout := defaultFlushTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	if !sentry.Flush(timeout) {
+		return fmt.Errorf("sentryotel: events not delivered within %s", timeout)
+	}
+	return nil
+}
+
+// describe returns the operation and the description of the Sentry span
+// corresponding to s.
+func describe(s sdktrace.ReadOnlySpan, attrs map[attribute.Key]attribute.Value) (op, description string) {
+	if method, ok := attrs["http.method"]; ok {
+		op = "http"
+		switch s.SpanKind() {
+		case trace.SpanKindServer:
+			op = "http.server"
+		case trace.SpanKindClient:
+			op = "http.client"
+		}
+		for _, key := range []attribute.Key{"http.route", "http.target", "http.url"} {
+			if v, ok := attrs[key]; ok {
+				return op, method.AsString() + " " + v.AsString()
+			}
+		}
+		return op, s.Name()
+	}
+	if system, ok := attrs["db.system"]; ok {
+		op = "db"
+		if system.AsString() == "redis" || system.AsString() == "mongodb" {
+			op = "db." + system.AsString()
+		}
+		if statement, ok := attrs["db.statement"]; ok {
+			return op, statement.AsString()
+		}
+		return op, s.Name()
+	}
+	if _, ok := attrs["messaging.system"]; ok {
+		switch s.SpanKind() {
+		case trace.SpanKindProducer:
+			return "queue.publish", s.Name()
+		case trace.SpanKindConsumer:
+			return "queue.process", s.Name()
+		}
+		return "queue", s.Name()
+	}
+	return s.Name(), s.Name()
+}
+
+// status returns the status of the Sentry span corresponding to s.
+func status(s sdktrace.ReadOnlySpan, attrs map[attribute.Key]attribute.Value) sentry.SpanStatus {
+	if code, ok := attrs["http.status_code"]; ok {
+		if st := sentry.HTTPtoSpanStatus(int(code.AsInt64())); st != sentry.SpanStatusUnknown {
+			return st
+		}
+	}
+	if code, ok := attrs["rpc.grpc.status_code"]; ok {
+		return sentry.GRPCtoSpanStatus(uint32(code.AsInt64()))
+	}
+	switch s.Status().Code {
+	case codes.Ok:
+		return sentry.SpanStatusOK
+	case codes.Error:
+		return sentry.SpanStatusInternalError
+	}
+	return sentry.SpanStatusUndefined
+}
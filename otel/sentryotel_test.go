@@ -0,0 +1,118 @@
+package sentryotel_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	sentryotel "github.com/getsentry/sentry-go/otel"
+	"github.com/getsentry/sentry-go/sentrytest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTracer() trace.Tracer {
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSpanProcessor(sentryotel.NewSentrySpanProcessor()),
+	)
+	return tp.Tracer("test")
+}
+
+func TestSpanProcessor(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{})
+	tracer := newTracer()
+
+	ctx, root := tracer.Start(ctx, "GET /users/:id", trace.WithSpanKind(trace.SpanKindServer))
+	root.SetAttributes(
+		attribute.String("http.method", "GET"),
+		attribute.String("http.route", "/users/:id"),
+		attribute.Int("http.status_code", 404),
+	)
+	_, child := tracer.Start(ctx, "SELECT users")
+	child.SetAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", "SELECT * FROM users WHERE id = $1"),
+	)
+	child.SetStatus(codes.Error, "connection reset")
+	child.End()
+	root.End()
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	transaction := events[0]
+	if transaction.Transaction != "GET /users/:id" {
+		t.Errorf("got transaction %q, want %q", transaction.Transaction, "GET /users/:id")
+	}
+	tc := transaction.Contexts["trace"].(*sentry.TraceContext)
+	if tc.TraceID != sentry.TraceID(root.SpanContext().TraceID()) || tc.SpanID != sentry.SpanID(root.SpanContext().SpanID()) {
+		t.Errorf("got trace context %+v, want the IDs of the root span", tc)
+	}
+	if tc.Op != "http.server" || tc.Description != "GET /users/:id" || tc.Status != sentry.SpanStatusNotFound {
+		t.Errorf("got trace context %+v", tc)
+	}
+	if len(transaction.Spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(transaction.Spans))
+	}
+	span := transaction.Spans[0]
+	if span.Op != "db" || span.Description != "SELECT * FROM users WHERE id = $1" || span.Status != sentry.SpanStatusInternalError {
+		t.Errorf("got span %+v", span)
+	}
+	if span.ParentSpanID != tc.SpanID {
+		t.Errorf("got parent span ID %s, want %s", span.ParentSpanID, tc.SpanID)
+	}
+	if span.Data["db.system"] != "postgresql" {
+		t.Errorf("got data %v, want the attributes", span.Data)
+	}
+}
+
+func TestPropagator(t *testing.T) {
+	p := sentryotel.NewSentryPropagator()
+	carrier := propagation.MapCarrier{
+		"sentry-trace": "bc6d53f15eb88f4320054569b8c553d4-b72fa28504b07285-0",
+		"baggage":      "sentry-environment=production",
+	}
+
+	ctx := p.Extract(context.Background(), carrier)
+	sc := trace.SpanContextFromContext(ctx)
+	if sc.TraceID().String() != "bc6d53f15eb88f4320054569b8c553d4" || sc.SpanID().String() != "b72fa28504b07285" {
+		t.Errorf("got span context %+v, want the trace of the header", sc)
+	}
+	if !sc.IsRemote() || sc.IsSampled() {
+		t.Errorf("got span context %+v, want a remote, unsampled span context", sc)
+	}
+
+	out := propagation.MapCarrier{}
+	p.Inject(ctx, out)
+	if out["sentry-trace"] != carrier["sentry-trace"] {
+		t.Errorf("got sentry-trace %q, want %q", out["sentry-trace"], carrier["sentry-trace"])
+	}
+	if out["baggage"] != carrier["baggage"] {
+		t.Errorf("got baggage %q, want %q", out["baggage"], carrier["baggage"])
+	}
+}
+
+func TestPropagatorContinuesTrace(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{})
+	tracer := newTracer()
+	ctx = sentryotel.NewSentryPropagator().Extract(ctx, propagation.MapCarrier{
+		"sentry-trace": "bc6d53f15eb88f4320054569b8c553d4-b72fa28504b07285-1",
+	})
+
+	_, span := tracer.Start(ctx, "process")
+	span.End()
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	tc := events[0].Contexts["trace"].(*sentry.TraceContext)
+	if tc.TraceID.String() != "bc6d53f15eb88f4320054569b8c553d4" || tc.ParentSpanID.String() != "b72fa28504b07285" {
+		t.Errorf("got trace context %+v, want to continue the trace of the header", tc)
+	}
+}
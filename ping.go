@@ -0,0 +1,96 @@
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Ping checks that the Sentry server of the DSN of the client, or the Relay
+// of ClientOptions.RelayURL, is reachable and accepts the DSN, for example in
+// the health checks of a deployment at startup:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	if err := sentry.CurrentHub().Client().Ping(ctx); err != nil {
+//		log.Printf("Sentry is misconfigured: %v", err)
+//	}
+//
+// It sends an envelope with no items, which records nothing, with the HTTP
+// client and proxy settings of the client options. It returns nil if the
+// server accepts the envelope, or is rate limiting the project, and an error
+// if the client has no DSN, the server cannot be reached before ctx is done,
+// or it rejects the envelope, typically because the DSN is invalid.
+func (client *Client) Ping(ctx context.Context) error {
+	if client.dsn == nil {
+		return errors.New("sentry: no DSN configured")
+	}
+
+	u := client.dsn.EnvelopeAPIURL()
+	if relay := relayURL(client.options); relay != nil {
+		u = client.dsn.relayEnvelopeURL(relay)
+	}
+	now := time.Now()
+	body, err := json.Marshal(struct {
+		SentAt time.Time `json:"sent_at"`
+	}{SentAt: now})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(append(body, '\n')))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set("Content-Type", "application/x-sentry-envelope")
+	for headerKey, headerValue := range client.dsn.requestHeaders(protocolVersion(client.options), now) {
+		request.Header.Set(headerKey, headerValue)
+	}
+
+	response, err := client.pingClient().Do(sdkRequest(request.WithContext(ctx)))
+	if err != nil {
+		return fmt.Errorf("sentry: could not reach %s: %w", u.Host, err)
+	}
+	defer drainAndClose(response)
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return nil
+	case response.StatusCode == http.StatusTooManyRequests:
+		Logger.Printf("Ping: %s is rate limiting the project", u.Host)
+		return nil
+	}
+	reason := response.Status
+	if msg := response.Header.Get("X-Sentry-Error"); msg != "" {
+		reason += ": " + msg
+	}
+	switch response.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("sentry: %s rejected the DSN: %s", u.Host, reason)
+	}
+	return fmt.Errorf("sentry: %s rejected the envelope: %s", u.Host, reason)
+}
+
+// pingClient returns the HTTP client to ping Sentry with, configured like the
+// ones of the HTTP transports.
+func (client *Client) pingClient() *http.Client {
+	options := client.options
+	if options.HTTPClient != nil {
+		return options.HTTPClient
+	}
+	transport := options.HTTPTransport
+	if transport == nil {
+		transport = &http.Transport{
+			Proxy:           getProxyConfig(options),
+			TLSClientConfig: getTLSConfig(options),
+			DialContext:     options.HTTPDialContext,
+			// The connection is not reused.
+			DisableKeepAlives: true,
+		}
+	}
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,105 @@
+package sentry
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		message string
+		wantErr string
+	}{
+		{name: "OK", status: http.StatusOK},
+		{name: "RateLimited", status: http.StatusTooManyRequests},
+		{
+			name:    "InvalidKey",
+			status:  http.StatusUnauthorized,
+			message: "invalid api key",
+			wantErr: "rejected the DSN: 401 Unauthorized: invalid api key",
+		},
+		{
+			name:    "NoEnvelopeEndpoint",
+			status:  http.StatusNotFound,
+			wantErr: "rejected the envelope: 404 Not Found",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/1/envelope/" {
+					t.Errorf("got path %q, want the envelope endpoint", r.URL.Path)
+				}
+				if !strings.Contains(r.Header.Get("X-Sentry-Auth"), "sentry_key=public") {
+					t.Errorf("missing authentication: %q", r.Header.Get("X-Sentry-Auth"))
+				}
+				body, _ := ioutil.ReadAll(r.Body)
+				if lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n"); len(lines) != 1 {
+					t.Errorf("got envelope %q, want a header and no items", body)
+				}
+				if tt.message != "" {
+					w.Header().Set("X-Sentry-Error", tt.message)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(ClientOptions{
+				Dsn:       strings.Replace(server.URL, "://", "://public@", 1) + "/1",
+				Transport: &TransportMock{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = client.Ping(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Ping() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Ping() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPingErrors(t *testing.T) {
+	t.Run("NoDSN", func(t *testing.T) {
+		client, err := NewClient(ClientOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Ping(context.Background()); err == nil {
+			t.Error("Ping() = nil without DSN")
+		}
+	})
+
+	t.Run("Unresponsive", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		client, err := NewClient(ClientOptions{
+			Dsn:       strings.Replace(server.URL, "://", "://public@", 1) + "/1",
+			Transport: &TransportMock{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := client.Ping(ctx); err == nil || !strings.Contains(err.Error(), "could not reach") {
+			t.Errorf("Ping() = %v, want an error", err)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+// Package sentryredis provides Sentry integration for Redis clients based on
+// the github.com/go-redis/redis/v8 package.
+package sentryredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/go-redis/redis/v8"
+)
+
+// operation is the operation of the spans created by a Hook.
+const operation = "db.redis"
+
+// A Hook is a redis.Hook that records Redis commands as spans and breadcrumbs.
+//
+// For every command, a Hook adds a breadcrumb to the hub of the command's
+// context and, if the context carries a transaction, creates a db.redis child
+// span. Command arguments other than the key are replaced with "?", so that
+// values stored in Redis are not sent to Sentry.
+//
+//	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	rdb.AddHook(sentryredis.NewHook())
+//	// Pass a context that carries a transaction to record spans.
+//	err := rdb.Set(ctx, "key", "value", 0).Err()
+type Hook struct{}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// NewHook returns a new Hook.
+func NewHook() *Hook {
+	return &Hook{}
+}
+
+// spanContextKey is used to store the span of a command in its context.
+type spanContextKey struct{}
+
+// BeforeProcess implements redis.Hook.
+func (h *Hook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return startSpan(ctx, describe(cmd)), nil
+}
+
+// AfterProcess implements redis.Hook.
+func (h *Hook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	addBreadcrumb(ctx, cmd)
+	finishSpan(ctx, []redis.Cmder{cmd})
+	return nil
+}
+
+// BeforeProcessPipeline implements redis.Hook.
+func (h *Hook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	descriptions := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		descriptions[i] = describe(cmd)
+	}
+	return startSpan(ctx, strings.Join(descriptions, "\n")), nil
+}
+
+// AfterProcessPipeline implements redis.Hook.
+func (h *Hook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		addBreadcrumb(ctx, cmd)
+	}
+	finishSpan(ctx, cmds)
+	return nil
+}
+
+// startSpan starts a span with the given description if ctx carries a
+// transaction, and returns a context that carries the span.
+func startSpan(ctx context.Context, description string) context.Context {
+	if sentry.TransactionFromContext(ctx) == nil {
+		return ctx
+	}
+	span := sentry.StartSpan(ctx, operation, sentry.WithSpanOrigin("auto.db.redis"))
+	span.Description = description
+	span.SetTag("db.system", "redis")
+	return context.WithValue(span.Context(), spanContextKey{}, span)
+}
+
+// finishSpan finishes the span started for cmds, if any.
+func finishSpan(ctx context.Context, cmds []redis.Cmder) {
+	span, ok := ctx.Value(spanContextKey{}).(*sentry.Span)
+	if !ok {
+		return
+	}
+	span.Status = sentry.SpanStatusOK
+	for _, cmd := range cmds {
+		if failed(cmd) {
+			span.Status = sentry.SpanStatusInternalError
+			break
+		}
+	}
+	span.Finish()
+}
+
+func addBreadcrumb(ctx context.Context, cmd redis.Cmder) {
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	breadcrumb := &sentry.Breadcrumb{
+		Type:     "query",
+		Category: "redis",
+		Message:  describe(cmd),
+		Level:    sentry.LevelInfo,
+	}
+	if failed(cmd) {
+		breadcrumb.Level = sentry.LevelError
+		breadcrumb.Data = map[string]interface{}{
+			"error": cmd.Err().Error(),
+		}
+	}
+	hub.AddBreadcrumb(breadcrumb, nil)
+}
+
+// failed reports whether cmd failed. A missing key is not a failure.
+func failed(cmd redis.Cmder) bool {
+	err := cmd.Err()
+	return err != nil && err != redis.Nil
+}
+
+// describe returns the name of cmd followed by its first argument, which for
+// most commands is the key, and a placeholder for every other argument. Every
+// argument of AUTH and HELLO, which take credentials, is a placeholder.
+func describe(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) == 0 {
+		return ""
+	}
+	parts := make([]string, len(args))
+	parts[0] = strings.ToUpper(fmt.Sprint(args[0]))
+	credentials := parts[0] == "AUTH" || parts[0] == "HELLO"
+	for i := 1; i < len(args); i++ {
+		if i == 1 && !credentials {
+			parts[i] = fmt.Sprint(args[i])
+		} else {
+			parts[i] = "?"
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,111 @@
+package sentryredis_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	sentryredis "github.com/getsentry/sentry-go/redis"
+	"github.com/getsentry/sentry-go/sentrytest"
+	"github.com/go-redis/redis/v8"
+)
+
+// process runs cmd through hook, failing the command with err.
+func process(ctx context.Context, hook redis.Hook, cmd redis.Cmder, err error) {
+	ctx, _ = hook.BeforeProcess(ctx, cmd)
+	if err != nil {
+		cmd.SetErr(err)
+	}
+	_ = hook.AfterProcess(ctx, cmd)
+}
+
+func TestHookSpans(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	transaction := sentry.StartSpan(ctx, "test", sentry.TransactionName("test"))
+	hook := sentryredis.NewHook()
+
+	process(transaction.Context(), hook, redis.NewStatusCmd(ctx, "set", "user:1", "secret"), nil)
+	process(transaction.Context(), hook, redis.NewStringCmd(ctx, "get", "user:2"), redis.Nil)
+	process(transaction.Context(), hook, redis.NewStringCmd(ctx, "get", "user:3"), errors.New("connection refused"))
+
+	pipeline := []redis.Cmder{
+		redis.NewIntCmd(ctx, "incr", "counter"),
+		redis.NewBoolCmd(ctx, "expire", "counter", 60),
+	}
+	pipelineCtx, _ := hook.BeforeProcessPipeline(transaction.Context(), pipeline)
+	_ = hook.AfterProcessPipeline(pipelineCtx, pipeline)
+	transaction.Finish()
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	spans := events[0].Spans
+	want := []struct {
+		description string
+		status      sentry.SpanStatus
+	}{
+		{"SET user:1 ?", sentry.SpanStatusOK},
+		{"GET user:2", sentry.SpanStatusOK},
+		{"GET user:3", sentry.SpanStatusInternalError},
+		{"INCR counter\nEXPIRE counter ?", sentry.SpanStatusOK},
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("got %d spans, want %d", len(spans), len(want))
+	}
+	for i, span := range spans {
+		if span.Op != "db.redis" || span.Description != want[i].description || span.Status != want[i].status {
+			t.Errorf("span %d: got (%q, %q, %s), want (%q, %q, %s)", i,
+				span.Op, span.Description, span.Status,
+				"db.redis", want[i].description, want[i].status)
+		}
+	}
+	breadcrumbs := events[0].Breadcrumbs
+	if len(breadcrumbs) != 5 {
+		t.Fatalf("got %d breadcrumbs, want 5", len(breadcrumbs))
+	}
+	if breadcrumbs[2].Level != sentry.LevelError || breadcrumbs[2].Data["error"] != "connection refused" {
+		t.Errorf("got breadcrumb %+v, want a failed command", breadcrumbs[2])
+	}
+}
+
+func TestHookMasksCredentials(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	transaction := sentry.StartSpan(ctx, "test", sentry.TransactionName("test"))
+	hook := sentryredis.NewHook()
+
+	process(transaction.Context(), hook, redis.NewStatusCmd(ctx, "auth", "secret"), nil)
+	process(transaction.Context(), hook, redis.NewStatusCmd(ctx, "auth", "user", "secret"), nil)
+	process(transaction.Context(), hook, redis.NewCmd(ctx, "hello", "3", "auth", "user", "secret"), nil)
+	transaction.Finish()
+
+	var got []string
+	for _, span := range transport.Events()[0].Spans {
+		got = append(got, span.Description)
+	}
+	want := []string{"AUTH ?", "AUTH ? ?", "HELLO ? ? ? ?"}
+	if len(got) != len(want) {
+		t.Fatalf("got spans %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got span %q, want %q", got[i], want[i])
+		}
+	}
+}
+
+func TestHookWithoutTransaction(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	process(ctx, sentryredis.NewHook(), redis.NewStringCmd(ctx, "get", "key"), nil)
+	sentry.GetHubFromContext(ctx).CaptureMessage("message")
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	breadcrumbs := events[0].Breadcrumbs
+	if len(breadcrumbs) != 1 || breadcrumbs[0].Message != "GET key" {
+		t.Errorf("got breadcrumbs %+v, want the command", breadcrumbs)
+	}
+}
@@ -0,0 +1,79 @@
+package sentry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// TraceRegion times a region of code, calling f in a child span of the span
+// in ctx with the given operation. It is a lightweight alternative to starting
+// and finishing a span by hand for ad-hoc timing:
+//
+//	sentry.TraceRegion(ctx, "serialize", func() {
+//		b, err = proto.Marshal(msg)
+//	}, sentry.WithDescription("checkout.Order"))
+//
+// If ctx has no span, f is called without recording anything: regions are
+// always part of an existing transaction. If f panics, the span is finished
+// with the status SpanStatusInternalError and the panic continues.
+func TraceRegion(ctx context.Context, operation string, f func(), options ...SpanOption) {
+	_ = traceRegion(ctx, operation, func() error {
+		f()
+		return nil
+	}, options...)
+}
+
+// traceRegion is like TraceRegion for a function that may fail. The span
+// status is SpanStatusInternalError if f returns an error.
+func traceRegion(ctx context.Context, operation string, f func() error, options ...SpanOption) error {
+	if spanFromContext(ctx) == nil {
+		return f()
+	}
+	span := StartSpan(ctx, operation, options...)
+	defer span.Finish()
+	// Until f returns, assume that it panics.
+	span.Status = SpanStatusInternalError
+	err := f()
+	if err == nil {
+		span.Status = SpanStatusOK
+	}
+	return err
+}
+
+// The WithDescription option sets the description of the span.
+func WithDescription(description string) SpanOption {
+	return func(s *Span) {
+		s.Description = description
+	}
+}
+
+// Template is implemented by the templates of both html/template and
+// text/template.
+type Template interface {
+	Name() string
+	Execute(w io.Writer, data interface{}) error
+}
+
+// RenderTemplate executes tmpl with data, writing the output to w, in a
+// "template.render" span described by the name of the template. It returns
+// the error of tmpl.Execute.
+//
+//	err := sentry.RenderTemplate(r.Context(), w, tmpl, page)
+func RenderTemplate(ctx context.Context, w io.Writer, tmpl Template, data interface{}) error {
+	return traceRegion(ctx, "template.render", func() error {
+		return tmpl.Execute(w, data)
+	}, WithDescription(tmpl.Name()))
+}
+
+// EncodeJSON writes the JSON encoding of v to w, followed by a newline, like
+// the Encode method of json.Encoder, in a "serialize" span described by the
+// type of v. It returns the error of the encoder.
+//
+//	err := sentry.EncodeJSON(r.Context(), w, response)
+func EncodeJSON(ctx context.Context, w io.Writer, v interface{}) error {
+	return traceRegion(ctx, "serialize", func() error {
+		return json.NewEncoder(w).Encode(v)
+	}, WithDescription(fmt.Sprintf("json %T", v)))
+}
@@ -0,0 +1,92 @@
+package sentry
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"testing"
+)
+
+func TestTraceRegion(t *testing.T) {
+	transport := &TransportMock{}
+	ctx := NewTestContext(ClientOptions{
+		Transport:        transport,
+		TracesSampleRate: 1.0,
+	})
+
+	// Without a span, the region is not recorded.
+	called := false
+	TraceRegion(ctx, "untraced", func() { called = true })
+	assertEqual(t, called, true)
+	assertEqual(t, len(transport.Events()), 0)
+
+	span := StartSpan(ctx, "top", TransactionName("test"))
+	TraceRegion(span.Context(), "compute", func() {}, WithDescription("sum"))
+	func() {
+		defer func() { _ = recover() }()
+		TraceRegion(span.Context(), "panic", func() { panic("test") })
+	}()
+	span.Finish()
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	spans := events[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	assertEqual(t, spans[0].Op, "compute")
+	assertEqual(t, spans[0].Description, "sum")
+	assertEqual(t, spans[0].Status, SpanStatusOK)
+	assertEqual(t, spans[1].Op, "panic")
+	assertEqual(t, spans[1].Status, SpanStatusInternalError)
+}
+
+func TestRenderTemplateAndEncodeJSON(t *testing.T) {
+	transport := &TransportMock{}
+	ctx := NewTestContext(ClientOptions{
+		Transport:        transport,
+		TracesSampleRate: 1.0,
+	})
+	span := StartSpan(ctx, "top", TransactionName("test"))
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("page").Parse("<p>{{.}}</p>"))
+	if err := RenderTemplate(span.Context(), &buf, tmpl, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, buf.String(), "<p>hello</p>")
+
+	buf.Reset()
+	if err := EncodeJSON(span.Context(), &buf, map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, buf.String(), "{\"n\":1}\n")
+
+	errEncode := errors.New("cannot encode")
+	if err := EncodeJSON(span.Context(), &buf, failingMarshaler{errEncode}); err == nil {
+		t.Error("got no error, want an encoding error")
+	}
+	span.Finish()
+
+	spans := transport.Events()[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	assertEqual(t, spans[0].Op, "template.render")
+	assertEqual(t, spans[0].Description, "page")
+	assertEqual(t, spans[0].Status, SpanStatusOK)
+	assertEqual(t, spans[1].Op, "serialize")
+	assertEqual(t, spans[1].Description, "json map[string]int")
+	assertEqual(t, spans[2].Status, SpanStatusInternalError)
+}
+
+// failingMarshaler is a json.Marshaler that always fails.
+type failingMarshaler struct {
+	err error
+}
+
+func (m failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, m.err
+}
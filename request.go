@@ -0,0 +1,227 @@
+package sentry
+
+import (
+	"encoding/json"
+	"mime"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// A RequestBodySize is the maximum size in bytes of the request bodies
+// attached to events. Larger bodies are omitted. Use one of the predefined
+// size classes or any positive size up to RequestBodySizeAlways.
+type RequestBodySize int
+
+// Size classes of request bodies.
+const (
+	// RequestBodySizeNone never attaches request bodies.
+	RequestBodySizeNone RequestBodySize = -1
+	// RequestBodySizeSmall attaches request bodies up to 1 KiB.
+	RequestBodySizeSmall RequestBodySize = 1024
+	// RequestBodySizeMedium attaches request bodies up to 10 KiB. It is the
+	// default.
+	RequestBodySizeMedium RequestBodySize = 10 * 1024
+	// RequestBodySizeAlways attaches request bodies up to the maximum size
+	// buffered by scopes, 64 KiB.
+	RequestBodySizeAlways RequestBodySize = maxRequestBodyBytes
+)
+
+// filteredValue replaces sensitive values in requests.
+const filteredValue = "[Filtered]"
+
+// piiHeaders are the request headers removed from events unless
+// ClientOptions.SendDefaultPII is set.
+var piiHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"X-Forwarded-For",
+	"X-Real-Ip",
+}
+
+// sensitiveKeys are the substrings of the names of query string and body
+// fields whose values are filtered from events.
+var sensitiveKeys = []string{
+	"api_key",
+	"apikey",
+	"credential",
+	"csrf",
+	"passwd",
+	"password",
+	"secret",
+	"session",
+	"token",
+}
+
+// sensitiveWords are the words of the names of query string and body fields
+// whose values are filtered from events. Unlike sensitiveKeys, they are
+// prefixes of harmless words, like "author", and must match whole words.
+var sensitiveWords = []string{
+	"auth",
+	"authentication",
+	"authorization",
+}
+
+// sanitizeRequest removes personally identifiable information from the
+// request of event, unless SendDefaultPII is set, filters sensitive fields of
+// the query string and of the body, and limits the size of the body.
+func (client *Client) sanitizeRequest(event *Event) {
+	r := event.Request
+	if r == nil {
+		return
+	}
+	options := client.Options()
+
+	if !options.SendDefaultPII {
+		r.Cookies = ""
+		if len(r.Headers) > 0 {
+			headers := make(map[string]string, len(r.Headers))
+			for k, v := range r.Headers {
+				headers[k] = v
+			}
+			for _, k := range piiHeaders {
+				delete(headers, k)
+			}
+			r.Headers = headers
+		}
+		if _, ok := r.Env["REMOTE_ADDR"]; ok {
+			env := make(map[string]string, len(r.Env))
+			for k, v := range r.Env {
+				if k != "REMOTE_ADDR" && k != "REMOTE_PORT" {
+					env[k] = v
+				}
+			}
+			r.Env = env
+		}
+	}
+
+	if r.QueryString != "" {
+		r.QueryString = sanitizeForm(r.QueryString)
+	}
+
+	if r.Data == "" {
+		return
+	}
+	max := RequestBodySizeMedium
+	if options.MaxRequestBodySize != 0 {
+		max = options.MaxRequestBodySize
+	}
+	if len(r.Data) > int(max) {
+		r.Data = ""
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Headers["Content-Type"])
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		r.Data = sanitizeForm(r.Data)
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		r.Data = sanitizeJSON(r.Data)
+	}
+}
+
+// isSensitiveKey reports whether the value of the field key must be filtered.
+func isSensitiveKey(key string) bool {
+	for _, word := range keyWords(key) {
+		for _, s := range sensitiveWords {
+			if word == s {
+				return true
+			}
+		}
+	}
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyWords returns the lowercase words of the name of a field, separated by
+// characters other than letters and digits or written in camel case, like
+// "x", "auth" and "token" for "x_auth_token" or "xAuthToken".
+func keyWords(key string) []string {
+	var words []string
+	start := -1
+	previous := rune(0)
+	for i, r := range key {
+		separator := !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		if start >= 0 && (separator || unicode.IsUpper(r) && unicode.IsLower(previous)) {
+			words = append(words, strings.ToLower(key[start:i]))
+			start = -1
+		}
+		if !separator && start < 0 {
+			start = i
+		}
+		previous = r
+	}
+	if start >= 0 {
+		words = append(words, strings.ToLower(key[start:]))
+	}
+	return words
+}
+
+// sanitizeForm filters the sensitive fields of a URL-encoded form. Forms that
+// cannot be parsed or without sensitive fields are returned unchanged.
+func sanitizeForm(form string) string {
+	values, err := url.ParseQuery(form)
+	if err != nil {
+		return form
+	}
+	filtered := false
+	for key, v := range values {
+		if isSensitiveKey(key) {
+			for i := range v {
+				v[i] = filteredValue
+			}
+			filtered = true
+		}
+	}
+	if !filtered {
+		return form
+	}
+	return values.Encode()
+}
+
+// sanitizeJSON filters the sensitive fields of the objects of a JSON document.
+// Documents that cannot be parsed or without sensitive fields are returned
+// unchanged.
+func sanitizeJSON(data string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return data
+	}
+	if !filterJSON(v) {
+		return data
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return data
+	}
+	return string(b)
+}
+
+// filterJSON filters the sensitive fields of the objects in v, a decoded JSON
+// value, and reports whether any field was filtered.
+func filterJSON(v interface{}) bool {
+	filtered := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if isSensitiveKey(key) {
+				v[key] = filteredValue
+				filtered = true
+			} else if filterJSON(value) {
+				filtered = true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if filterJSON(value) {
+				filtered = true
+			}
+		}
+	}
+	return filtered
+}
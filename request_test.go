@@ -0,0 +1,109 @@
+package sentry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSanitizeRequest(t *testing.T) {
+	newRequest := func() *Request {
+		return &Request{
+			URL:         "https://example.com/login",
+			Method:      "POST",
+			QueryString: "next=%2Fhome&token=abc",
+			Cookies:     "session=1",
+			Headers: map[string]string{
+				"Authorization": "Bearer abc",
+				"Content-Type":  "application/json; charset=utf-8",
+				"Cookie":        "session=1",
+				"User-Agent":    "test",
+			},
+			Env:  map[string]string{"REMOTE_ADDR": "192.0.2.1", "REMOTE_PORT": "1234"},
+			Data: `{"user":"jane","password":"hunter2","nested":[{"api_key":"k"}]}`,
+		}
+	}
+
+	client, _, _ := setupClientTest()
+	event := &Event{Request: newRequest()}
+	client.sanitizeRequest(event)
+	want := &Request{
+		URL:         "https://example.com/login",
+		Method:      "POST",
+		QueryString: "next=%2Fhome&token=%5BFiltered%5D",
+		Headers: map[string]string{
+			"Content-Type": "application/json; charset=utf-8",
+			"User-Agent":   "test",
+		},
+		Env:  map[string]string{},
+		Data: `{"nested":[{"api_key":"[Filtered]"}],"password":"[Filtered]","user":"jane"}`,
+	}
+	if diff := cmp.Diff(want, event.Request); diff != "" {
+		t.Errorf("Request mismatch (-want +got):\n%s", diff)
+	}
+
+	// Personally identifiable information is kept with SendDefaultPII.
+	client.options.SendDefaultPII = true
+	event = &Event{Request: newRequest()}
+	client.sanitizeRequest(event)
+	assertEqual(t, event.Request.Cookies, "session=1")
+	assertEqual(t, event.Request.Headers["Authorization"], "Bearer abc")
+	assertEqual(t, event.Request.Env["REMOTE_ADDR"], "192.0.2.1")
+}
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"auth", true},
+		{"Authorization", true},
+		{"x_auth_token", true},
+		{"X-Auth", true},
+		{"authKey", true},
+		{"access_token", true},
+		{"user[password]", true},
+		{"author", false},
+		{"authority", false},
+		{"coauthors", false},
+		{"user", false},
+	}
+	for _, tt := range tests {
+		if got := isSensitiveKey(tt.key); got != tt.want {
+			t.Errorf("isSensitiveKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeRequestBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		max         RequestBodySize
+		contentType string
+		data        string
+		want        string
+	}{
+		{"Form", 0, "application/x-www-form-urlencoded", "user=jane&passwd=x", "passwd=%5BFiltered%5D&user=jane"},
+		{"FormWithoutSensitiveFields", 0, "application/x-www-form-urlencoded", "b=2&a=1&author=jane", "b=2&a=1&author=jane"},
+		{"InvalidJSON", 0, "application/json", `{"password":`, `{"password":`},
+		{"Text", 0, "text/plain", "password=x", "password=x"},
+		{"Default", 0, "text/plain", strings.Repeat("x", int(RequestBodySizeMedium)+1), ""},
+		{"Small", RequestBodySizeSmall, "text/plain", strings.Repeat("x", 1025), ""},
+		{"Always", RequestBodySizeAlways, "text/plain", strings.Repeat("x", 20000), strings.Repeat("x", 20000)},
+		{"Limited", 4, "text/plain", "12345", ""},
+		{"None", RequestBodySizeNone, "text/plain", "1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _, _ := setupClientTest()
+			client.options.MaxRequestBodySize = tt.max
+			event := &Event{Request: &Request{
+				Headers: map[string]string{"Content-Type": tt.contentType},
+				Data:    tt.data,
+			}}
+			client.sanitizeRequest(event)
+			assertEqual(t, event.Request.Data, tt.want)
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"io"
 	"net/http"
-	"reflect"
 	"sync"
 	"time"
 )
@@ -33,7 +32,9 @@ type Scope struct {
 	fingerprint []string
 	level       Level
 	transaction string
-	request     *http.Request
+	// transactionSource is the source of the transaction name.
+	transactionSource TransactionSource
+	request           *http.Request
 	// requestBody holds a reference to the original request.Body.
 	requestBody interface {
 		// Bytes returns bytes from the original body, lazily buffered as the
@@ -44,6 +45,16 @@ type Scope struct {
 		Overflow() bool
 	}
 	eventProcessors []EventProcessor
+	// requestBodySize is the MaxRequestBodySize of the client of the scope,
+	// which bounds the request bodies recorded by SetRequest.
+	requestBodySize RequestBodySize
+
+	// limits are the size limits of the data of the scope. The sizes of
+	// extras, contexts and breadcrumbs are only tracked when limited.
+	limits          ScopeLimits
+	extraSizes      map[string]int
+	contextSizes    map[string]int
+	breadcrumbSizes []int
 }
 
 // NewScope creates a new Scope.
@@ -75,6 +86,11 @@ func (scope *Scope) AddBreadcrumb(breadcrumb *Breadcrumb, limit int) {
 	} else {
 		scope.breadcrumbs = breadcrumbs
 	}
+	if scope.breadcrumbSizes != nil {
+		sizes := append(scope.breadcrumbSizes, sizeOf(breadcrumb))
+		scope.breadcrumbSizes = sizes[len(sizes)-len(scope.breadcrumbs):]
+		scope.evictBreadcrumbs()
+	}
 }
 
 // ClearBreadcrumbs clears all breadcrumbs from the current scope.
@@ -83,6 +99,9 @@ func (scope *Scope) ClearBreadcrumbs() {
 	defer scope.mu.Unlock()
 
 	scope.breadcrumbs = []*Breadcrumb{}
+	if scope.breadcrumbSizes != nil {
+		scope.breadcrumbSizes = []int{}
+	}
 }
 
 // SetUser sets the user for the current scope.
@@ -94,25 +113,34 @@ func (scope *Scope) SetUser(user User) {
 }
 
 // SetRequest sets the request for the current scope.
+//
+// The body of the request is recorded lazily, as the handler reads it, up to
+// 64 KiB or the MaxRequestBodySize of the client, such that it is attached to
+// events captured once read. It is not recorded if the client is configured
+// with RequestBodySizeNone.
 func (scope *Scope) SetRequest(r *http.Request) {
 	scope.mu.Lock()
 	defer scope.mu.Unlock()
 
 	scope.request = r
 
-	if r == nil {
+	if r == nil || scope.requestBodySize == RequestBodySizeNone {
 		return
 	}
 
+	capacity := maxRequestBodyBytes
+	if scope.requestBodySize > 0 && int(scope.requestBodySize) < capacity {
+		capacity = int(scope.requestBodySize)
+	}
 	// Don't buffer request body if we know it is oversized.
-	if r.ContentLength > maxRequestBodyBytes {
+	if r.ContentLength > int64(capacity) {
 		return
 	}
 	// Don't buffer if there is no body.
 	if r.Body == nil || r.Body == http.NoBody {
 		return
 	}
-	buf := &limitedBuffer{Capacity: maxRequestBodyBytes}
+	buf := &limitedBuffer{Capacity: capacity}
 	r.Body = readCloser{
 		Reader: io.TeeReader(r.Body, buf),
 		Closer: r.Body,
@@ -142,9 +170,9 @@ func (scope *Scope) SetRequestBody(b []byte) {
 	}
 }
 
-// maxRequestBodyBytes is the default maximum request body size to send to
-// Sentry.
-const maxRequestBodyBytes = 10 * 1024
+// maxRequestBodyBytes is the maximum request body size buffered by scopes. The
+// bodies sent to Sentry are limited by ClientOptions.MaxRequestBodySize.
+const maxRequestBodyBytes = 64 * 1024
 
 // A limitedBuffer is like a bytes.Buffer, but limited to store at most Capacity
 // bytes. Any writes past the capacity are silently discarded, similar to
@@ -184,21 +212,32 @@ type readCloser struct {
 	io.Closer
 }
 
-// SetTag adds a tag to the current scope.
+// SetTag adds a tag to the current scope. Tags that Sentry would reject are
+// normalized: line breaks are replaced with spaces, and keys and values that
+// are too long are truncated. Tags with an empty or reserved key, like
+// "release" or "user", are dropped. Changes are logged in debug mode.
 func (scope *Scope) SetTag(key, value string) {
+	key, value, ok := normalizeTag(key, value)
+	if !ok {
+		return
+	}
+
 	scope.mu.Lock()
 	defer scope.mu.Unlock()
 
 	scope.tags[key] = value
 }
 
-// SetTags assigns multiple tags to the current scope.
+// SetTags assigns multiple tags to the current scope. Tags are normalized as
+// with SetTag.
 func (scope *Scope) SetTags(tags map[string]string) {
 	scope.mu.Lock()
 	defer scope.mu.Unlock()
 
 	for k, v := range tags {
-		scope.tags[k] = v
+		if k, v, ok := normalizeTag(k, v); ok {
+			scope.tags[k] = v
+		}
 	}
 }
 
@@ -215,7 +254,7 @@ func (scope *Scope) SetContext(key string, value interface{}) {
 	scope.mu.Lock()
 	defer scope.mu.Unlock()
 
-	scope.contexts[key] = value
+	setSized("context", scope.contexts, scope.contextSizes, scope.limits.MaxContextBytes, key, value)
 }
 
 // SetContexts assigns multiple contexts to the current scope.
@@ -224,7 +263,7 @@ func (scope *Scope) SetContexts(contexts map[string]interface{}) {
 	defer scope.mu.Unlock()
 
 	for k, v := range contexts {
-		scope.contexts[k] = v
+		setSized("context", scope.contexts, scope.contextSizes, scope.limits.MaxContextBytes, k, v)
 	}
 }
 
@@ -234,6 +273,26 @@ func (scope *Scope) RemoveContext(key string) {
 	defer scope.mu.Unlock()
 
 	delete(scope.contexts, key)
+	delete(scope.contextSizes, key)
+}
+
+// replaceTraceContext sets the trace context of the scope to tc, or removes it
+// if tc is nil, provided that the current trace context is the one of the span
+// with the given ID.
+func (scope *Scope) replaceTraceContext(spanID SpanID, tc *TraceContext) {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	current, ok := scope.contexts["trace"].(*TraceContext)
+	if !ok || current.SpanID != spanID {
+		return
+	}
+	if tc == nil {
+		delete(scope.contexts, "trace")
+		delete(scope.contextSizes, "trace")
+		return
+	}
+	setSized("context", scope.contexts, scope.contextSizes, scope.limits.MaxContextBytes, "trace", tc)
 }
 
 // SetExtra adds an extra to the current scope.
@@ -241,7 +300,7 @@ func (scope *Scope) SetExtra(key string, value interface{}) {
 	scope.mu.Lock()
 	defer scope.mu.Unlock()
 
-	scope.extra[key] = value
+	setSized("extra", scope.extra, scope.extraSizes, scope.limits.MaxExtraBytes, key, value)
 }
 
 // SetExtras assigns multiple extras to the current scope.
@@ -250,7 +309,7 @@ func (scope *Scope) SetExtras(extra map[string]interface{}) {
 	defer scope.mu.Unlock()
 
 	for k, v := range extra {
-		scope.extra[k] = v
+		setSized("extra", scope.extra, scope.extraSizes, scope.limits.MaxExtraBytes, k, v)
 	}
 }
 
@@ -260,14 +319,18 @@ func (scope *Scope) RemoveExtra(key string) {
 	defer scope.mu.Unlock()
 
 	delete(scope.extra, key)
+	delete(scope.extraSizes, key)
 }
 
-// SetFingerprint sets new fingerprint for the current scope.
+// SetFingerprint sets new fingerprint for the current scope. It applies to
+// events that do not have a fingerprint of their own; include
+// DefaultFingerprint in it to extend the default grouping instead of replacing
+// it.
 func (scope *Scope) SetFingerprint(fingerprint []string) {
 	scope.mu.Lock()
 	defer scope.mu.Unlock()
 
-	scope.fingerprint = fingerprint
+	scope.fingerprint = append([]string(nil), fingerprint...)
 }
 
 // SetLevel sets new level for the current scope.
@@ -278,12 +341,15 @@ func (scope *Scope) SetLevel(level Level) {
 	scope.level = level
 }
 
-// SetTransaction sets the transaction name for the current transaction.
+// SetTransaction sets the transaction name for the current transaction. The
+// source of the name is reset to SourceCustom, use SetTransactionSource to
+// change it.
 func (scope *Scope) SetTransaction(name string) {
 	scope.mu.Lock()
 	defer scope.mu.Unlock()
 
 	scope.transaction = name
+	scope.transactionSource = SourceCustom
 }
 
 // Transaction returns the transaction name for the current transaction.
@@ -294,37 +360,152 @@ func (scope *Scope) Transaction() (name string) {
 	return scope.transaction
 }
 
+// SetTransactionSource sets the source of the transaction name for the
+// current transaction.
+func (scope *Scope) SetTransactionSource(source TransactionSource) {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	scope.transactionSource = source
+}
+
+// TransactionSource returns the source of the transaction name for the
+// current transaction.
+func (scope *Scope) TransactionSource() TransactionSource {
+	scope.mu.RLock()
+	defer scope.mu.RUnlock()
+
+	return scope.transactionSource
+}
+
 // Clone returns a copy of the current scope with all data copied over.
+//
+// The copy is deep enough that modifying the clone never affects the original
+// scope and vice versa: maps, slices, breadcrumbs and their data, and
+// contexts set as a map[string]interface{} are all copied. Other values stored
+// in contexts and extras are shared.
 func (scope *Scope) Clone() *Scope {
 	scope.mu.RLock()
 	defer scope.mu.RUnlock()
 
 	clone := NewScope()
 	clone.user = scope.user
+	clone.user.Data = cloneStrings(scope.user.Data)
 	clone.breadcrumbs = make([]*Breadcrumb, len(scope.breadcrumbs))
-	copy(clone.breadcrumbs, scope.breadcrumbs)
+	for i, breadcrumb := range scope.breadcrumbs {
+		clone.breadcrumbs[i] = cloneBreadcrumb(breadcrumb)
+	}
 	for key, value := range scope.tags {
 		clone.tags[key] = value
 	}
 	for key, value := range scope.contexts {
-		clone.contexts[key] = value
+		clone.contexts[key] = cloneValue(value)
 	}
 	for key, value := range scope.extra {
-		clone.extra[key] = value
+		clone.extra[key] = cloneValue(value)
 	}
 	clone.fingerprint = make([]string, len(scope.fingerprint))
 	copy(clone.fingerprint, scope.fingerprint)
 	clone.level = scope.level
 	clone.transaction = scope.transaction
+	clone.transactionSource = scope.transactionSource
 	clone.request = scope.request
 	clone.requestBody = scope.requestBody
-	clone.eventProcessors = scope.eventProcessors
+	if scope.eventProcessors != nil {
+		clone.eventProcessors = make([]EventProcessor, len(scope.eventProcessors))
+		copy(clone.eventProcessors, scope.eventProcessors)
+	}
+	clone.requestBodySize = scope.requestBodySize
+	clone.limits = scope.limits
+	clone.extraSizes = cloneSizes(scope.extraSizes)
+	clone.contextSizes = cloneSizes(scope.contextSizes)
+	if scope.breadcrumbSizes != nil {
+		clone.breadcrumbSizes = append([]int(nil), scope.breadcrumbSizes...)
+	}
 	return clone
 }
 
-// Clear removes the data from the current scope. Not safe for concurrent use.
+// cloneBreadcrumb returns a copy of b that does not share its data map.
+func cloneBreadcrumb(b *Breadcrumb) *Breadcrumb {
+	if b == nil {
+		return nil
+	}
+	clone := *b
+	clone.Data = cloneMap(b.Data)
+	return &clone
+}
+
+// cloneValue returns a copy of v if it is a map or a slice of the types that
+// JSON decodes to, copying the maps and slices it holds recursively, and v
+// itself otherwise. Such values are routinely attached to scopes and events.
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return cloneMap(v)
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		clone := make([]interface{}, len(v))
+		for i, value := range v {
+			clone[i] = cloneValue(value)
+		}
+		return clone
+	case map[string]string:
+		return cloneStrings(v)
+	case []string:
+		if v == nil {
+			return v
+		}
+		return append([]string{}, v...)
+	}
+	return v
+}
+
+// cloneMap returns a copy of m, as documented in cloneValue, or nil if m is
+// nil.
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		clone[key] = cloneValue(value)
+	}
+	return clone
+}
+
+// cloneStrings returns a copy of m, or nil if m is nil.
+func cloneStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(m))
+	for key, value := range m {
+		clone[key] = value
+	}
+	return clone
+}
+
+// Clear removes the data from the current scope, keeping its limits.
 func (scope *Scope) Clear() {
-	*scope = *NewScope()
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	empty := NewScope()
+	scope.breadcrumbs = empty.breadcrumbs
+	scope.user = empty.user
+	scope.tags = empty.tags
+	scope.contexts = empty.contexts
+	scope.extra = empty.extra
+	scope.fingerprint = empty.fingerprint
+	scope.level = empty.level
+	scope.transaction = empty.transaction
+	scope.transactionSource = empty.transactionSource
+	scope.request = empty.request
+	scope.requestBody = empty.requestBody
+	scope.eventProcessors = empty.eventProcessors
+	scope.setLimits(scope.limits)
 }
 
 // AddEventProcessor adds an event processor to the current scope.
@@ -336,7 +517,41 @@ func (scope *Scope) AddEventProcessor(processor EventProcessor) {
 }
 
 // ApplyToEvent takes the data from the current scope and attaches it to the event.
+//
+// Values already set on the event take precedence over values from the scope:
+//
+//   - Breadcrumbs from the scope are appended to those of the event.
+//   - Tags, contexts and extra are merged; on conflicting keys the event value
+//     is kept.
+//   - User, fingerprint, transaction and request are taken from the scope only
+//     if they are unset in the event.
+//   - Level is the exception: the scope level, if set, overrides the event
+//     level, since captured events always have a level set by the SDK.
+//
+// The data is copied, including the maps and slices held by breadcrumbs,
+// contexts, extra and the user, so that modifying the event, for example in
+// BeforeSend, does not alter the scope, and modifying the scope does not alter
+// events already captured, for example while they wait in the queue of an
+// asynchronous transport. Other values, like pointers, are shared and should
+// not be modified once set on the scope. Finally, the scope's event
+// processors are run in the order they were added. They run after the data is
+// copied, with the scope unlocked, such that they may modify the scope.
 func (scope *Scope) ApplyToEvent(event *Event, hint *EventHint) *Event {
+	for _, processor := range scope.applyData(event) {
+		id := event.EventID
+		event = processor(event, hint)
+		if event == nil {
+			Logger.Printf("Event dropped by one of the Scope EventProcessors: %s\n", id)
+			return nil
+		}
+	}
+
+	return event
+}
+
+// applyData copies the data of the scope to the event, as documented in
+// ApplyToEvent, and returns the event processors of the scope.
+func (scope *Scope) applyData(event *Event) []EventProcessor {
 	scope.mu.RLock()
 	defer scope.mu.RUnlock()
 
@@ -345,7 +560,9 @@ func (scope *Scope) ApplyToEvent(event *Event, hint *EventHint) *Event {
 			event.Breadcrumbs = []*Breadcrumb{}
 		}
 
-		event.Breadcrumbs = append(event.Breadcrumbs, scope.breadcrumbs...)
+		for _, breadcrumb := range scope.breadcrumbs {
+			event.Breadcrumbs = append(event.Breadcrumbs, cloneBreadcrumb(breadcrumb))
+		}
 	}
 
 	if len(scope.tags) > 0 {
@@ -354,7 +571,9 @@ func (scope *Scope) ApplyToEvent(event *Event, hint *EventHint) *Event {
 		}
 
 		for key, value := range scope.tags {
-			event.Tags[key] = value
+			if _, ok := event.Tags[key]; !ok {
+				event.Tags[key] = value
+			}
 		}
 	}
 
@@ -372,7 +591,10 @@ func (scope *Scope) ApplyToEvent(event *Event, hint *EventHint) *Event {
 				// to link errors and traces/spans in Sentry.
 				continue
 			}
-			event.Contexts[key] = value
+			if _, ok := event.Contexts[key]; ok {
+				continue
+			}
+			event.Contexts[key] = cloneValue(value)
 		}
 	}
 
@@ -382,12 +604,15 @@ func (scope *Scope) ApplyToEvent(event *Event, hint *EventHint) *Event {
 		}
 
 		for key, value := range scope.extra {
-			event.Extra[key] = value
+			if _, ok := event.Extra[key]; !ok {
+				event.Extra[key] = cloneValue(value)
+			}
 		}
 	}
 
-	if (reflect.DeepEqual(event.User, User{})) {
+	if event.User.IsEmpty() {
 		event.User = scope.user
+		event.User.Data = cloneStrings(scope.user.Data)
 	}
 
 	if (event.Fingerprint == nil || len(event.Fingerprint) == 0) &&
@@ -400,8 +625,11 @@ func (scope *Scope) ApplyToEvent(event *Event, hint *EventHint) *Event {
 		event.Level = scope.level
 	}
 
-	if scope.transaction != "" {
+	if event.Transaction == "" && scope.transaction != "" {
 		event.Transaction = scope.transaction
+		if event.Type == transactionType && event.TransactionInfo == nil && scope.transactionSource != "" {
+			event.TransactionInfo = &TransactionInfo{Source: scope.transactionSource}
+		}
 	}
 
 	if event.Request == nil && scope.request != nil {
@@ -420,14 +648,5 @@ func (scope *Scope) ApplyToEvent(event *Event, hint *EventHint) *Event {
 		}
 	}
 
-	for _, processor := range scope.eventProcessors {
-		id := event.EventID
-		event = processor(event, hint)
-		if event == nil {
-			Logger.Printf("Event dropped by one of the Scope EventProcessors: %s\n", id)
-			return nil
-		}
-	}
-
-	return event
+	return scope.eventProcessors
 }
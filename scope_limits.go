@@ -0,0 +1,162 @@
+package sentry
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ScopeLimits are limits on the size of the data held by a Scope, so that a
+// long-lived scope, like the one of the hub of a daemon, cannot grow without
+// bound. Sizes are estimated from the JSON encoding of values when they are
+// set. Data evicted to enforce the limits is logged in debug mode. Zero
+// values mean no limit.
+type ScopeLimits struct {
+	// Maximum total size in bytes of the extras. The largest extras are
+	// evicted first.
+	MaxExtraBytes int
+	// Maximum total size in bytes of the contexts. The largest contexts are
+	// evicted first. The trace context, which links events to their trace, is
+	// never evicted.
+	MaxContextBytes int
+	// Maximum total size in bytes of the breadcrumbs. The oldest breadcrumbs
+	// are evicted first.
+	MaxBreadcrumbBytes int
+}
+
+// SetLimits sets the size limits of the scope, evicting the data that exceeds
+// them. Clones of the scope have the same limits.
+func (scope *Scope) SetLimits(limits ScopeLimits) {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	scope.setLimits(limits)
+}
+
+// setLimits is SetLimits for callers that hold the lock of the scope.
+func (scope *Scope) setLimits(limits ScopeLimits) {
+	scope.limits = limits
+	scope.extraSizes = measureMap(scope.extra, limits.MaxExtraBytes)
+	scope.contextSizes = measureMap(scope.contexts, limits.MaxContextBytes)
+	scope.breadcrumbSizes = nil
+	if limits.MaxBreadcrumbBytes > 0 {
+		scope.breadcrumbSizes = make([]int, len(scope.breadcrumbs))
+		for i, breadcrumb := range scope.breadcrumbs {
+			scope.breadcrumbSizes[i] = sizeOf(breadcrumb)
+		}
+	}
+	evictLargest("extra", scope.extra, scope.extraSizes, limits.MaxExtraBytes)
+	evictLargest("context", scope.contexts, scope.contextSizes, limits.MaxContextBytes)
+	scope.evictBreadcrumbs()
+}
+
+// Limits returns the size limits of the scope.
+func (scope *Scope) Limits() ScopeLimits {
+	scope.mu.RLock()
+	defer scope.mu.RUnlock()
+
+	return scope.limits
+}
+
+// evictBreadcrumbs evicts the oldest breadcrumbs until their total size is
+// within the limit. The caller must hold scope.mu.
+func (scope *Scope) evictBreadcrumbs() {
+	if scope.breadcrumbSizes == nil {
+		return
+	}
+	total := 0
+	for _, size := range scope.breadcrumbSizes {
+		total += size
+	}
+	n := 0
+	for ; total > scope.limits.MaxBreadcrumbBytes && n < len(scope.breadcrumbs); n++ {
+		total -= scope.breadcrumbSizes[n]
+	}
+	if n > 0 {
+		Logger.Printf("Evicted %d breadcrumbs exceeding the scope limit of %d bytes", n, scope.limits.MaxBreadcrumbBytes)
+		scope.breadcrumbs = scope.breadcrumbs[n:]
+		scope.breadcrumbSizes = scope.breadcrumbSizes[n:]
+	}
+}
+
+// measureMap returns the sizes of the values of m, or nil if max is not
+// positive.
+func measureMap(m map[string]interface{}, max int) map[string]int {
+	if max <= 0 {
+		return nil
+	}
+	sizes := make(map[string]int, len(m))
+	for key, value := range m {
+		sizes[key] = sizeOf(value)
+	}
+	return sizes
+}
+
+// cloneSizes returns a copy of sizes, or nil if sizes is nil.
+func cloneSizes(sizes map[string]int) map[string]int {
+	if sizes == nil {
+		return nil
+	}
+	clone := make(map[string]int, len(sizes))
+	for key, size := range sizes {
+		clone[key] = size
+	}
+	return clone
+}
+
+// setSized sets the value of m at key and, if sizes is not nil, records its
+// size and evicts the largest values of m until their total size is within
+// max.
+func setSized(kind string, m map[string]interface{}, sizes map[string]int, max int, key string, value interface{}) {
+	m[key] = value
+	if sizes == nil {
+		return
+	}
+	sizes[key] = sizeOf(value)
+	evictLargest(kind, m, sizes, max)
+}
+
+// evictLargest evicts the largest evictable values of m, whose sizes are in
+// sizes, until their total size is within max.
+func evictLargest(kind string, m map[string]interface{}, sizes map[string]int, max int) {
+	if sizes == nil {
+		return
+	}
+	total := 0
+	for _, size := range sizes {
+		total += size
+	}
+	for total > max {
+		largest, largestSize := "", -1
+		for key, size := range sizes {
+			if !evictable(kind, key) {
+				continue
+			}
+			if size > largestSize || size == largestSize && key < largest {
+				largest, largestSize = key, size
+			}
+		}
+		if largestSize < 0 {
+			return
+		}
+		Logger.Printf("Evicted %s %q of %d bytes exceeding the scope limit of %d bytes", kind, largest, largestSize, max)
+		total -= largestSize
+		delete(m, largest)
+		delete(sizes, largest)
+	}
+}
+
+// evictable reports whether the value of m at key can be evicted, which is
+// the case of all values but the trace context.
+func evictable(kind, key string) bool {
+	return kind != "context" || key != "trace"
+}
+
+// sizeOf returns the estimated size in bytes of v, the length of its JSON
+// encoding, or of its formatted value if it cannot be encoded.
+func sizeOf(v interface{}) int {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return len(fmt.Sprintf("%+v", v))
+	}
+	return len(b)
+}
@@ -0,0 +1,110 @@
+package sentry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopeLimitsEvictLargestExtras(t *testing.T) {
+	scope := NewScope()
+	scope.SetLimits(ScopeLimits{MaxExtraBytes: 30})
+
+	scope.SetExtra("a", "x")                     // 3 bytes
+	scope.SetExtra("b", strings.Repeat("x", 20)) // 22 bytes
+	scope.SetExtra("c", strings.Repeat("x", 10)) // 12 bytes
+
+	assertEqual(t, scope.extra, map[string]interface{}{"a": "x", "c": strings.Repeat("x", 10)})
+
+	scope.RemoveExtra("c")
+	scope.SetExtra("d", strings.Repeat("x", 25)) // 27 bytes
+	assertEqual(t, scope.extra, map[string]interface{}{"a": "x", "d": strings.Repeat("x", 25)})
+}
+
+func TestScopeLimitsEvictLargestContexts(t *testing.T) {
+	scope := NewScope()
+	scope.SetLimits(ScopeLimits{MaxContextBytes: 40})
+
+	scope.SetContexts(map[string]interface{}{
+		"small": map[string]interface{}{"k": "v"},                     // 11 bytes
+		"large": map[string]interface{}{"k": strings.Repeat("v", 30)}, // 40 bytes
+	})
+
+	assertEqual(t, scope.contexts, map[string]interface{}{"small": map[string]interface{}{"k": "v"}})
+}
+
+func TestScopeLimitsKeepTraceContext(t *testing.T) {
+	scope := NewScope()
+	scope.SetLimits(ScopeLimits{MaxContextBytes: 40})
+
+	scope.SetContext("small", map[string]interface{}{"k": "v"})
+	scope.SetContext("trace", map[string]interface{}{"trace_id": strings.Repeat("0", 32)})
+	assertEqual(t, scope.contexts, map[string]interface{}{
+		"trace": map[string]interface{}{"trace_id": strings.Repeat("0", 32)},
+	})
+
+	scope.SetLimits(ScopeLimits{MaxContextBytes: 10})
+	assertEqual(t, len(scope.contexts), 1)
+	if _, ok := scope.contexts["trace"]; !ok {
+		t.Error("trace context evicted")
+	}
+}
+
+func TestScopeLimitsEvictOldestBreadcrumbs(t *testing.T) {
+	scope := NewScope()
+	breadcrumb := func(message string) *Breadcrumb {
+		return &Breadcrumb{Message: message, Timestamp: testNow}
+	}
+	size := sizeOf(breadcrumb("0"))
+	scope.SetLimits(ScopeLimits{MaxBreadcrumbBytes: 2 * size})
+
+	for _, message := range []string{"1", "2", "3"} {
+		scope.AddBreadcrumb(breadcrumb(message), maxBreadcrumbs)
+	}
+
+	assertEqual(t, scope.breadcrumbs, []*Breadcrumb{breadcrumb("2"), breadcrumb("3")})
+}
+
+func TestSetLimitsEvictsExistingData(t *testing.T) {
+	scope := NewScope()
+	scope.SetExtra("a", "x")
+	scope.SetExtra("b", strings.Repeat("x", 20))
+	for _, message := range []string{"1", "2", "3"} {
+		scope.AddBreadcrumb(&Breadcrumb{Message: message, Timestamp: testNow}, maxBreadcrumbs)
+	}
+
+	scope.SetLimits(ScopeLimits{MaxExtraBytes: 10, MaxBreadcrumbBytes: 1})
+
+	assertEqual(t, scope.extra, map[string]interface{}{"a": "x"})
+	assertEqual(t, len(scope.breadcrumbs), 0)
+}
+
+func TestScopeLimitsInheritedByClones(t *testing.T) {
+	limits := ScopeLimits{MaxExtraBytes: 10}
+	scope := NewScope()
+	scope.SetLimits(limits)
+
+	clone := scope.Clone()
+	clone.SetExtra("a", strings.Repeat("x", 20))
+
+	assertEqual(t, clone.Limits(), limits)
+	assertEqual(t, clone.extra, map[string]interface{}{})
+
+	scope.Clear()
+	assertEqual(t, scope.Limits(), limits)
+}
+
+func TestHubAppliesScopeLimitsOfClient(t *testing.T) {
+	limits := ScopeLimits{MaxContextBytes: 100}
+	client, err := NewClient(ClientOptions{ScopeLimits: limits})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := NewHub(client, NewScope())
+	assertEqual(t, hub.Scope().Limits(), limits)
+
+	hub = NewHub(nil, NewScope())
+	hub.BindClient(client)
+	assertEqual(t, hub.Scope().Limits(), limits)
+	assertEqual(t, hub.PushScope().Limits(), limits)
+}
@@ -1,8 +1,10 @@
 package sentry
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -57,6 +59,47 @@ func TestScopeSetRequest(t *testing.T) {
 	assertEqual(t, r, scope.request)
 }
 
+func TestScopeSetRequestBuffersBody(t *testing.T) {
+	const payload = `{"id": 1}`
+	r := httptest.NewRequest("POST", "/foo", strings.NewReader(payload))
+	scope := NewScope()
+	scope.SetRequest(r)
+
+	// The body is recorded as the handler reads it.
+	assertEqual(t, len(scope.requestBody.Bytes()), 0)
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(b), payload)
+	assertEqual(t, string(scope.requestBody.Bytes()), payload)
+}
+
+func TestScopeSetRequestBodySize(t *testing.T) {
+	client, err := NewClient(ClientOptions{MaxRequestBodySize: RequestBodySizeNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(client, NewScope())
+	r := httptest.NewRequest("POST", "/foo", strings.NewReader("secret"))
+	body := r.Body
+	hub.Scope().SetRequest(r)
+	if r.Body != body || hub.Scope().requestBody != nil {
+		t.Error("request body recorded with RequestBodySizeNone")
+	}
+
+	client, err = NewClient(ClientOptions{MaxRequestBodySize: RequestBodySizeSmall})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub = NewHub(client, NewScope())
+	r = httptest.NewRequest("POST", "/foo", strings.NewReader(strings.Repeat("x", 2048)))
+	hub.Scope().SetRequest(r)
+	if hub.Scope().requestBody != nil {
+		t.Error("oversized request body recorded")
+	}
+}
+
 func TestScopeSetRequestOverrides(t *testing.T) {
 	r1 := httptest.NewRequest("GET", "/foo", nil)
 	r2 := httptest.NewRequest("GET", "/bar", nil)
@@ -322,6 +365,38 @@ func TestScopeSetTransactionOverrides(t *testing.T) {
 	assertEqual(t, scope.transaction, "def")
 }
 
+func TestScopeSetTransactionSource(t *testing.T) {
+	scope := NewScope()
+	scope.SetTransaction("GET /users/{id}")
+	assertEqual(t, scope.TransactionSource(), SourceCustom)
+
+	scope.SetTransactionSource(SourceRoute)
+	assertEqual(t, scope.TransactionSource(), SourceRoute)
+	assertEqual(t, scope.Clone().TransactionSource(), SourceRoute)
+
+	scope.SetTransaction("custom")
+	assertEqual(t, scope.TransactionSource(), SourceCustom)
+}
+
+func TestApplyToEventTransactionSource(t *testing.T) {
+	scope := NewScope()
+	scope.SetTransaction("GET /users/{id}")
+	scope.SetTransactionSource(SourceRoute)
+
+	transaction := scope.ApplyToEvent(&Event{Type: transactionType}, nil)
+	assertEqual(t, transaction.Transaction, "GET /users/{id}")
+	assertEqual(t, transaction.TransactionInfo, &TransactionInfo{Source: SourceRoute})
+
+	// Error events have no transaction info.
+	event := scope.ApplyToEvent(NewEvent(), nil)
+	assertEqual(t, event.Transaction, "GET /users/{id}")
+	assertEqual(t, event.TransactionInfo, (*TransactionInfo)(nil))
+
+	// The source does not apply to names set on the event.
+	named := scope.ApplyToEvent(&Event{Type: transactionType, Transaction: "named"}, nil)
+	assertEqual(t, named.TransactionInfo, (*TransactionInfo)(nil))
+}
+
 func TestAddBreadcrumbAddsBreadcrumb(t *testing.T) {
 	scope := NewScope()
 	scope.AddBreadcrumb(&Breadcrumb{Timestamp: testNow, Message: "test"}, maxBreadcrumbs)
@@ -375,7 +450,7 @@ func TestScopeBasicInheritance(t *testing.T) {
 
 	assertEqual(t, scope.extra, clone.extra)
 	assertEqual(t, scope.requestBody, clone.requestBody)
-	assertEqual(t, scope.eventProcessors, clone.eventProcessors)
+	assertEqual(t, len(scope.eventProcessors), len(clone.eventProcessors))
 }
 
 func TestScopeParentChangedInheritance(t *testing.T) {
@@ -543,12 +618,94 @@ func TestApplyToEventWithCorrectScopeAndEvent(t *testing.T) {
 	assertEqual(t, len(processedEvent.Contexts), 2, "should merge contexts")
 	assertEqual(t, len(processedEvent.Extra), 2, "should merge extra")
 	assertEqual(t, processedEvent.Level, scope.level, "should use scope level if its set")
-	assertEqual(t, processedEvent.Transaction, scope.transaction, "should use scope transaction if its set")
+	assertNotEqual(t, processedEvent.Transaction, scope.transaction, "should use event transaction if one exist")
 	assertNotEqual(t, processedEvent.User, scope.user, "should use event user if one exist")
 	assertNotEqual(t, processedEvent.Request, scope.request, "should use event request if one exist")
 	assertNotEqual(t, processedEvent.Fingerprint, scope.fingerprint, "should use event fingerprints if they exist")
 }
 
+func TestApplyToEventMergeRules(t *testing.T) {
+	scope := NewScope()
+	scope.AddBreadcrumb(&Breadcrumb{Timestamp: testNow, Message: "scope"}, maxBreadcrumbs)
+	scope.SetUser(User{ID: "scope"})
+	scope.SetTags(map[string]string{"shared": "scope", "scope": "scope"})
+	scope.SetContexts(map[string]interface{}{"shared": "scope", "scope": "scope"})
+	scope.SetExtras(map[string]interface{}{"shared": "scope", "scope": "scope"})
+	scope.SetFingerprint([]string{"scope"})
+	scope.SetLevel(LevelDebug)
+	scope.SetTransaction("scope")
+	scope.SetRequest(httptest.NewRequest("GET", "/scope", nil))
+
+	event := NewEvent()
+	event.Breadcrumbs = []*Breadcrumb{{Timestamp: testNow, Message: "event"}}
+	event.User = User{ID: "event"}
+	event.Tags = map[string]string{"shared": "event", "event": "event"}
+	event.Contexts = map[string]interface{}{"shared": "event", "event": "event"}
+	event.Extra = map[string]interface{}{"shared": "event", "event": "event"}
+	event.Fingerprint = []string{"event"}
+	event.Level = LevelInfo
+	event.Transaction = "event"
+	event.Request = &Request{URL: "event"}
+
+	got := scope.ApplyToEvent(event, nil)
+
+	assertEqual(t, got.Breadcrumbs, []*Breadcrumb{
+		{Timestamp: testNow, Message: "event"},
+		{Timestamp: testNow, Message: "scope"},
+	})
+	assertEqual(t, got.User, User{ID: "event"})
+	assertEqual(t, got.Tags, map[string]string{"shared": "event", "event": "event", "scope": "scope"})
+	assertEqual(t, got.Contexts, map[string]interface{}{"shared": "event", "event": "event", "scope": "scope"})
+	assertEqual(t, got.Extra, map[string]interface{}{"shared": "event", "event": "event", "scope": "scope"})
+	assertEqual(t, got.Fingerprint, []string{"event"})
+	assertEqual(t, got.Level, LevelDebug)
+	assertEqual(t, got.Transaction, "event")
+	assertEqual(t, got.Request, &Request{URL: "event"})
+}
+
+func TestApplyToEventDoesNotAliasScope(t *testing.T) {
+	scope := NewScope()
+	scope.AddBreadcrumb(&Breadcrumb{Timestamp: testNow, Message: "scope", Data: map[string]interface{}{"key": "scope"}}, maxBreadcrumbs)
+	scope.SetContext("context", map[string]interface{}{"key": "scope"})
+
+	event := scope.ApplyToEvent(NewEvent(), nil)
+	event.Breadcrumbs[0].Message = "event"
+	event.Breadcrumbs[0].Data["key"] = "event"
+	event.Contexts["context"].(map[string]interface{})["key"] = "event"
+
+	assertEqual(t, scope.breadcrumbs, []*Breadcrumb{
+		{Timestamp: testNow, Message: "scope", Data: map[string]interface{}{"key": "scope"}},
+	})
+	assertEqual(t, scope.contexts, map[string]interface{}{"context": map[string]interface{}{"key": "scope"}})
+}
+
+func TestApplyToEventSnapshotsScope(t *testing.T) {
+	data := map[string]interface{}{"key": "before", "list": []interface{}{"before"}}
+	userData := map[string]string{"key": "before"}
+
+	scope := NewScope()
+	scope.AddBreadcrumb(&Breadcrumb{Timestamp: testNow, Data: map[string]interface{}{"nested": data}}, maxBreadcrumbs)
+	scope.SetContext("context", map[string]interface{}{"nested": data})
+	scope.SetExtra("extra", data)
+	scope.SetUser(User{ID: "user", Data: userData})
+	scope.SetTag("tag", "before")
+
+	event := scope.ApplyToEvent(NewEvent(), nil)
+
+	data["key"] = "after"
+	data["list"].([]interface{})[0] = "after"
+	userData["key"] = "after"
+	scope.SetTag("tag", "after")
+	scope.SetExtra("extra", "after")
+
+	want := map[string]interface{}{"key": "before", "list": []interface{}{"before"}}
+	assertEqual(t, event.Breadcrumbs[0].Data, map[string]interface{}{"nested": want})
+	assertEqual(t, event.Contexts["context"], map[string]interface{}{"nested": want})
+	assertEqual(t, event.Extra["extra"], want)
+	assertEqual(t, event.User.Data, map[string]string{"key": "before"})
+	assertEqual(t, event.Tags["tag"], "before")
+}
+
 func TestApplyToEventUsingEmptyScope(t *testing.T) {
 	scope := NewScope()
 	event := fillEventWithData(NewEvent())
@@ -638,3 +795,23 @@ func TestEventProcessorsAddEventProcessor(t *testing.T) {
 		t.Error("event should be dropped")
 	}
 }
+
+func TestEventProcessorsCanModifyScope(t *testing.T) {
+	scope := NewScope()
+	scope.AddEventProcessor(func(event *Event, hint *EventHint) *Event {
+		scope.SetTag("processed", "yes")
+		return event
+	})
+
+	done := make(chan *Event)
+	go func() { done <- scope.ApplyToEvent(NewEvent(), nil) }()
+	select {
+	case event := <-done:
+		assertEqual(t, event.Tags, map[string]string{})
+	case <-time.After(time.Second):
+		t.Fatal("ApplyToEvent deadlocked")
+	}
+
+	event := scope.ApplyToEvent(NewEvent(), nil)
+	assertEqual(t, event.Tags, map[string]string{"processed": "yes"})
+}
@@ -36,16 +36,64 @@ func AddBreadcrumb(breadcrumb *Breadcrumb) {
 	hub.AddBreadcrumb(breadcrumb, nil)
 }
 
-// CaptureMessage captures an arbitrary message.
-func CaptureMessage(message string) *EventID {
+// CaptureMessage captures an arbitrary message. Options, if any, only apply to
+// this event.
+func CaptureMessage(message string, options ...CaptureOption) *EventID {
 	hub := CurrentHub()
-	return hub.CaptureMessage(message)
+	return hub.CaptureMessage(message, options...)
 }
 
-// CaptureException captures an error.
-func CaptureException(exception error) *EventID {
+// CaptureMessagef captures a message formatted according to a format
+// specifier, as in fmt.Sprintf.
+func CaptureMessagef(format string, args ...interface{}) *EventID {
 	hub := CurrentHub()
-	return hub.CaptureException(exception)
+	return hub.CaptureMessagef(format, args...)
+}
+
+// CaptureWarning captures an arbitrary message with level warning.
+func CaptureWarning(message string) *EventID {
+	hub := CurrentHub()
+	return hub.CaptureWarning(message)
+}
+
+// CaptureInfo captures an arbitrary message with level info.
+func CaptureInfo(message string) *EventID {
+	hub := CurrentHub()
+	return hub.CaptureInfo(message)
+}
+
+// CaptureException captures an error. Options, if any, only apply to this
+// event:
+//
+//	sentry.CaptureException(err, sentry.WithLevel(sentry.LevelFatal))
+func CaptureException(exception error, options ...CaptureOption) *EventID {
+	hub := CurrentHub()
+	return hub.CaptureException(exception, options...)
+}
+
+// CaptureExceptionCtx captures an error on the hub of ctx, or on the current
+// hub if ctx has none, so that code without access to a hub reports errors in
+// the scope of the request or task it runs for. If ctx carries a span, the
+// event is linked to it.
+func CaptureExceptionCtx(ctx context.Context, exception error, options ...CaptureOption) *EventID {
+	return hubFromContext(ctx).CaptureException(exception, withSpanFromContext(ctx, options)...)
+}
+
+// CaptureMessageCtx is like CaptureExceptionCtx, for an arbitrary message.
+func CaptureMessageCtx(ctx context.Context, message string, options ...CaptureOption) *EventID {
+	return hubFromContext(ctx).CaptureMessage(message, withSpanFromContext(ctx, options)...)
+}
+
+// AddBreadcrumbCtx records a new breadcrumb on the hub of ctx, or on the
+// current hub if ctx has none.
+func AddBreadcrumbCtx(ctx context.Context, breadcrumb *Breadcrumb) {
+	hubFromContext(ctx).AddBreadcrumb(breadcrumb, nil)
+}
+
+// CaptureCheckIn captures a check-in of a monitored job.
+func CaptureCheckIn(checkIn *CheckIn) *EventID {
+	hub := CurrentHub()
+	return hub.CaptureCheckIn(checkIn)
 }
 
 // CaptureEvent captures an event on the currently active client if any.
@@ -58,6 +106,13 @@ func CaptureEvent(event *Event) *EventID {
 	return hub.CaptureEvent(event)
 }
 
+// CaptureEventWithHint is like CaptureEvent, but also passes a hint that is
+// made available to event processors and to BeforeSend.
+func CaptureEventWithHint(event *Event, hint *EventHint) *EventID {
+	hub := CurrentHub()
+	return hub.CaptureEventWithHint(event, hint)
+}
+
 // Recover captures a panic.
 func Recover() *EventID {
 	if err := recover(); err != nil {
@@ -123,6 +178,20 @@ func Flush(timeout time.Duration) bool {
 	return hub.Flush(timeout)
 }
 
+// FlushWithContext is like Flush, but blocks until ctx is done rather than for
+// a timeout. It lets shutdown sequences bound flushing with their own context.
+func FlushWithContext(ctx context.Context) bool {
+	hub := CurrentHub()
+	return hub.FlushWithContext(ctx)
+}
+
+// Close shuts down the client of the current hub, waiting for at most the
+// given timeout for buffered events to be sent. See Client.Close.
+func Close(timeout time.Duration) bool {
+	hub := CurrentHub()
+	return hub.Close(timeout)
+}
+
 // LastEventID returns an ID of last captured event.
 func LastEventID() EventID {
 	hub := CurrentHub()
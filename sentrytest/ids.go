@@ -0,0 +1,57 @@
+package sentrytest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// A SequentialIDGenerator is a sentry.IDGenerator that generates deterministic
+// IDs from a counter shared by events, traces and spans, so that the IDs of a
+// test run are the same every time. It is safe for concurrent use.
+//
+//	client, _ := sentry.NewClient(sentry.ClientOptions{
+//		Transport:   transport,
+//		IDGenerator: sentrytest.NewSequentialIDGenerator(),
+//	})
+//
+// The first ID is 1: the first event ID is "00000000000000000000000000000001".
+type SequentialIDGenerator struct {
+	mu sync.Mutex
+	n  uint64
+}
+
+var _ sentry.IDGenerator = (*SequentialIDGenerator)(nil)
+
+// NewSequentialIDGenerator returns a new SequentialIDGenerator.
+func NewSequentialIDGenerator() *SequentialIDGenerator {
+	return &SequentialIDGenerator{}
+}
+
+func (g *SequentialIDGenerator) next() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.n++
+	return g.n
+}
+
+// EventID implements sentry.IDGenerator.
+func (g *SequentialIDGenerator) EventID() sentry.EventID {
+	return sentry.EventID(fmt.Sprintf("%032x", g.next()))
+}
+
+// TraceID implements sentry.IDGenerator.
+func (g *SequentialIDGenerator) TraceID() sentry.TraceID {
+	var id sentry.TraceID
+	binary.BigEndian.PutUint64(id[8:], g.next())
+	return id
+}
+
+// SpanID implements sentry.IDGenerator.
+func (g *SequentialIDGenerator) SpanID() sentry.SpanID {
+	var id sentry.SpanID
+	binary.BigEndian.PutUint64(id[:], g.next())
+	return id
+}
@@ -0,0 +1,39 @@
+package sentrytest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/getsentry/sentry-go/sentrytest"
+)
+
+func TestSequentialIDGenerator(t *testing.T) {
+	transport := sentrytest.NewRecordingTransport()
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Transport:        transport,
+		TracesSampleRate: 1.0,
+		IDGenerator:      sentrytest.NewSequentialIDGenerator(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := sentry.NewHub(client, sentry.NewScope())
+
+	hub.CaptureMessage("hello")
+	span := sentry.StartSpan(sentry.SetHubOnContext(context.Background(), hub), "op")
+	span.Finish()
+
+	if got := transport.Events()[0].EventID; got != "00000000000000000000000000000001" {
+		t.Errorf("got event ID %s", got)
+	}
+	if got := span.TraceID.String(); got != "00000000000000000000000000000002" {
+		t.Errorf("got trace ID %s", got)
+	}
+	if got := span.SpanID.String(); got != "0000000000000003" {
+		t.Errorf("got span ID %s", got)
+	}
+	if got := transport.Transactions()[0].EventID; got != "00000000000000000000000000000004" {
+		t.Errorf("got transaction ID %s", got)
+	}
+}
@@ -0,0 +1,234 @@
+package sentrytest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// serverPublicKey is the public key in the DSN of a Server.
+const serverPublicKey = "sentrytest"
+
+// serverProjectID is the project ID in the DSN of a Server.
+const serverProjectID = "1"
+
+// A Server is an HTTP server that emulates the ingestion endpoints of Sentry,
+// both the legacy store endpoint and the envelope endpoint. It decodes the
+// payloads it receives, including gzip-compressed request bodies, and records
+// the events for inspection.
+//
+// Use a Server to test a real transport end-to-end:
+//
+//	server := sentrytest.NewServer()
+//	defer server.Close()
+//	client, _ := sentry.NewClient(sentry.ClientOptions{Dsn: server.DSN()})
+//	// ... exercise code that reports events ...
+//	client.Flush(time.Second)
+//	events := server.Events()
+type Server struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	events []*sentry.Event
+	errs   []error
+}
+
+// NewServer starts and returns a new Server. The caller should call Close when
+// finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// DSN returns a DSN that points to the server.
+func (s *Server) DSN() string {
+	return strings.Replace(s.server.URL, "://", "://"+serverPublicKey+"@", 1) + "/" + serverProjectID
+}
+
+// URL returns the base URL of the server, of the form http://ipaddr:port with
+// no trailing slash.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close shuts down the server and blocks until all outstanding requests on
+// the server have completed.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Events returns all events received by the server, including transactions,
+// in the order they were received.
+func (s *Server) Events() []*sentry.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := make([]*sentry.Event, len(s.events))
+	copy(events, s.events)
+	return events
+}
+
+// Errors returns the errors found while handling requests, for example
+// payloads that could not be decoded or missing authentication. Requests that
+// resulted in an error are answered with a 4xx status code.
+func (s *Server) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	errs := make([]error, len(s.errs))
+	copy(errs, s.errs)
+	return errs
+}
+
+// Reset discards all recorded events and errors.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = nil
+	s.errs = nil
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	events, status, err := s.decode(r)
+	s.mu.Lock()
+	if err != nil {
+		s.errs = append(s.errs, err)
+	}
+	s.events = append(s.events, events...)
+	s.mu.Unlock()
+
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	var id sentry.EventID
+	if len(events) > 0 {
+		id = events[0].EventID
+	}
+	_ = json.NewEncoder(w).Encode(struct {
+		ID sentry.EventID `json:"id"`
+	}{id})
+}
+
+// decode authenticates and decodes the events in r. In case of error, it
+// returns the HTTP status code to respond with.
+func (s *Server) decode(r *http.Request) ([]*sentry.Event, int, error) {
+	if r.Method != http.MethodPost {
+		return nil, http.StatusMethodNotAllowed, fmt.Errorf("unexpected method %s", r.Method)
+	}
+	if !s.authenticated(r) {
+		return nil, http.StatusUnauthorized, fmt.Errorf("missing or invalid authentication on %s", r.URL.Path)
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		defer zr.Close()
+		body = zr
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	switch r.URL.Path {
+	case "/api/" + serverProjectID + "/store/":
+		event := new(sentry.Event)
+		if err := json.Unmarshal(b, event); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("store: %w", err)
+		}
+		return []*sentry.Event{event}, 0, nil
+	case "/api/" + serverProjectID + "/envelope/":
+		events, err := decodeEnvelope(b)
+		if err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("envelope: %w", err)
+		}
+		return events, 0, nil
+	default:
+		return nil, http.StatusNotFound, fmt.Errorf("unexpected path %s", r.URL.Path)
+	}
+}
+
+// authenticated reports whether r carries the public key of the server, either
+// in the X-Sentry-Auth header or in the query string.
+func (s *Server) authenticated(r *http.Request) bool {
+	want := "sentry_key=" + serverPublicKey
+	for _, kv := range strings.Split(strings.TrimPrefix(r.Header.Get("X-Sentry-Auth"), "Sentry "), ",") {
+		if strings.TrimSpace(kv) == want {
+			return true
+		}
+	}
+	return r.URL.Query().Get("sentry_key") == serverPublicKey
+}
+
+// decodeEnvelope decodes the event and transaction items of an envelope. Other
+// item types are ignored.
+//
+// See https://develop.sentry.dev/sdk/envelopes/.
+func decodeEnvelope(b []byte) ([]*sentry.Event, error) {
+	rd := bufio.NewReader(bytes.NewReader(b))
+	// The envelope header is not used.
+	if _, err := readEnvelopeLine(rd); err != nil {
+		return nil, err
+	}
+	var events []*sentry.Event
+	for {
+		line, err := readEnvelopeLine(rd)
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var header struct {
+			Type   string `json:"type"`
+			Length *int   `json:"length"`
+		}
+		if err := json.Unmarshal(line, &header); err != nil {
+			return nil, fmt.Errorf("item header: %w", err)
+		}
+		var payload []byte
+		if header.Length != nil {
+			payload = make([]byte, *header.Length)
+			if _, err := io.ReadFull(rd, payload); err != nil {
+				return nil, fmt.Errorf("item payload: %w", err)
+			}
+		} else if payload, err = readEnvelopeLine(rd); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("item payload: %w", err)
+		}
+		if header.Type != "event" && header.Type != "transaction" {
+			continue
+		}
+		event := new(sentry.Event)
+		if err := json.Unmarshal(payload, event); err != nil {
+			return nil, fmt.Errorf("%s item: %w", header.Type, err)
+		}
+		events = append(events, event)
+	}
+}
+
+// readEnvelopeLine reads a newline-terminated line without the trailing
+// newline. It returns io.EOF only if no bytes were read.
+func readEnvelopeLine(rd *bufio.Reader) ([]byte, error) {
+	line, err := rd.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return bytes.TrimSuffix(line, []byte("\n")), err
+}
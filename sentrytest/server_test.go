@@ -0,0 +1,113 @@
+package sentrytest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/getsentry/sentry-go/sentrytest"
+)
+
+func TestServer(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              server.DSN(),
+		TracesSampleRate: 1.0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := sentry.NewHub(client, sentry.NewScope())
+
+	hub.CaptureException(errors.New("oops"))
+	hub.Flush(time.Second)
+	span := sentry.StartSpan(sentry.SetHubOnContext(context.Background(), hub), "op", sentry.TransactionName("tx"))
+	span.Finish()
+	hub.Flush(time.Second)
+
+	if errs := server.Errors(); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	events := server.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if got := events[0].Exception[0].Value; got != "oops" {
+		t.Errorf("got exception value %q, want %q", got, "oops")
+	}
+	if got := events[1].Type; got != "transaction" {
+		t.Errorf("got event type %q, want %q", got, "transaction")
+	}
+	if got := events[1].Transaction; got != "tx" {
+		t.Errorf("got transaction %q, want %q", got, "tx")
+	}
+	if got := events[1].Contexts["trace"]; got == nil {
+		t.Error("missing trace context")
+	}
+
+	server.Reset()
+	if got := len(server.Events()); got != 0 {
+		t.Errorf("got %d events after Reset, want 0", got)
+	}
+}
+
+func TestServerGzip(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	_, _ = zw.Write([]byte(`{"event_id":"9ec79c33ec9942ab8353589fcb2e04dc","message":"hello"}`))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, server.URL()+"/api/1/store/", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_key=sentrytest")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	events := server.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if got := events[0].Message; got != "hello" {
+		t.Errorf("got message %q, want %q", got, "hello")
+	}
+}
+
+func TestServerRequiresAuth(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	resp, err := http.Post(server.URL()+"/api/1/store/", "application/json", bytes.NewReader([]byte(`{}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if got := len(server.Errors()); got != 1 {
+		t.Errorf("got %d errors, want 1", got)
+	}
+	if got := len(server.Events()); got != 0 {
+		t.Errorf("got %d events, want 0", got)
+	}
+}
@@ -0,0 +1,127 @@
+// Package sentrytest provides utilities for testing programs instrumented
+// with the Sentry SDK.
+package sentrytest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// A RecordingTransport is a sentry.Transport that records events in memory
+// instead of sending them to Sentry. It is safe for concurrent use.
+//
+// Use it to assert that code under test reports events:
+//
+//	transport := sentrytest.NewRecordingTransport()
+//	client, _ := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+//	hub := sentry.NewHub(client, sentry.NewScope())
+//	// ... exercise code that reports to hub ...
+//	if len(transport.Events()) != 1 {
+//		t.Fatal("no event reported")
+//	}
+type RecordingTransport struct {
+	mu      sync.Mutex
+	events  []*sentry.Event
+	flushes int
+}
+
+var _ sentry.Transport = (*RecordingTransport)(nil)
+
+// NewRecordingTransport returns a new RecordingTransport.
+func NewRecordingTransport() *RecordingTransport {
+	return &RecordingTransport{}
+}
+
+// NewContext returns a context carrying a new hub, bound to a client created
+// with options that records its events with a new RecordingTransport, for
+// testing code that reports to the hub of a context:
+//
+//	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+//	// ... exercise code that reports to the hub of ctx ...
+//	events := transport.Events()
+//
+// The Transport of options is replaced. NewContext fails the test if the
+// client cannot be created.
+func NewContext(t testing.TB, options sentry.ClientOptions) (context.Context, *RecordingTransport) {
+	t.Helper()
+	transport := NewRecordingTransport()
+	options.Transport = transport
+	client, err := sentry.NewClient(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := sentry.NewHub(client, sentry.NewScope())
+	return sentry.SetHubOnContext(context.Background(), hub), transport
+}
+
+// Configure implements sentry.Transport. It is a no-op.
+func (t *RecordingTransport) Configure(options sentry.ClientOptions) {}
+
+// SendEvent implements sentry.Transport. It records the event.
+func (t *RecordingTransport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+// Flush implements sentry.Transport. It records the call and returns true
+// immediately, since events are never buffered.
+func (t *RecordingTransport) Flush(timeout time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flushes++
+	return true
+}
+
+// Events returns all recorded events, including transactions, in the order
+// they were sent.
+func (t *RecordingTransport) Events() []*sentry.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	events := make([]*sentry.Event, len(t.events))
+	copy(events, t.events)
+	return events
+}
+
+// Transactions returns the recorded transactions, in the order they were sent.
+func (t *RecordingTransport) Transactions() []*sentry.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var transactions []*sentry.Event
+	for _, event := range t.events {
+		if event.Type == "transaction" {
+			transactions = append(transactions, event)
+		}
+	}
+	return transactions
+}
+
+// LastEvent returns the most recently recorded event, or nil if no events were
+// recorded.
+func (t *RecordingTransport) LastEvent() *sentry.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.events) == 0 {
+		return nil
+	}
+	return t.events[len(t.events)-1]
+}
+
+// Flushes returns the number of times Flush was called.
+func (t *RecordingTransport) Flushes() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.flushes
+}
+
+// Reset discards all recorded events and flush calls.
+func (t *RecordingTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = nil
+	t.flushes = 0
+}
@@ -0,0 +1,64 @@
+package sentrytest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/getsentry/sentry-go/sentrytest"
+)
+
+func TestRecordingTransport(t *testing.T) {
+	transport := sentrytest.NewRecordingTransport()
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Transport:        transport,
+		TracesSampleRate: 1.0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := sentry.NewHub(client, sentry.NewScope())
+
+	if transport.LastEvent() != nil {
+		t.Fatal("unexpected event before capture")
+	}
+
+	hub.CaptureException(errors.New("oops"))
+	span := sentry.StartSpan(sentry.SetHubOnContext(context.Background(), hub), "op")
+	span.Finish()
+	hub.Flush(time.Second)
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if got := events[0].Exception[0].Value; got != "oops" {
+		t.Errorf("got exception value %q, want %q", got, "oops")
+	}
+	if got := len(transport.Transactions()); got != 1 {
+		t.Errorf("got %d transactions, want 1", got)
+	}
+	if transport.LastEvent() != events[1] {
+		t.Error("LastEvent should return the transaction")
+	}
+	if got := transport.Flushes(); got != 1 {
+		t.Errorf("got %d flushes, want 1", got)
+	}
+
+	transport.Reset()
+	if got := len(transport.Events()); got != 0 {
+		t.Errorf("got %d events after Reset, want 0", got)
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{Release: "v1"})
+	sentry.GetHubFromContext(ctx).CaptureMessage("hello")
+
+	event := transport.LastEvent()
+	if event == nil || event.Message != "hello" || event.Release != "v1" {
+		t.Errorf("got event %+v, want the message of the hub of the context", event)
+	}
+}
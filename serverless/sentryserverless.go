@@ -0,0 +1,148 @@
+// Package sentryserverless provides Sentry integration for Google Cloud
+// Functions and Cloud Run.
+package sentryserverless
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	sentryhttp "github.com/getsentry/sentry-go/http"
+)
+
+// defaultFlushTimeout is the maximum time to wait for events to be delivered
+// at the end of an invocation or on shutdown.
+const defaultFlushTimeout = 2 * time.Second
+
+// functionName returns the name of the running function or service, as set
+// by the Cloud Functions and Cloud Run runtimes.
+func functionName() string {
+	for _, key := range []string{"FUNCTION_TARGET", "K_SERVICE", "FUNCTION_NAME"} {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// WrapHTTP wraps an HTTP function. Like sentryhttp, it reports panics and
+// provides a request-specific hub, and in addition it waits for the delivery
+// of pending events at the end of every request, since the instance may be
+// throttled or frozen as soon as the response is sent. Panics are repanicked.
+//
+//	func init() {
+//		sentry.Init(sentry.ClientOptions{
+//			// ...
+//		})
+//	}
+//
+//	var HelloHTTP = sentryserverless.WrapHTTP(func(w http.ResponseWriter, r *http.Request) {
+//		// ...
+//	})
+func WrapHTTP(f http.HandlerFunc) http.HandlerFunc {
+	handler := sentryhttp.New(sentryhttp.Options{
+		Repanic:         true,
+		WaitForDelivery: true,
+		Timeout:         defaultFlushTimeout,
+	}).HandleFunc(f)
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer sentry.Flush(defaultFlushTimeout)
+		handler(w, r)
+	}
+}
+
+// Background runs the body of a background (event-driven) function with its
+// own hub, available with sentry.GetHubFromContext, in a transaction named
+// after the function. Panics and errors returned by f are captured, and
+// pending events are delivered before Background returns or repanics:
+//
+//	func HelloPubSub(ctx context.Context, m PubSubMessage) error {
+//		return sentryserverless.Background(ctx, func(ctx context.Context) error {
+//			// ...
+//		})
+//	}
+func Background(ctx context.Context, f func(ctx context.Context) error) (err error) {
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	hub = hub.Clone()
+	ctx = sentry.SetHubOnContext(ctx, hub)
+
+	span := sentry.StartSpan(ctx, "serverless.function",
+		sentry.TransactionName(functionName()),
+		sentry.WithTransactionSource(sentry.SourceComponent),
+		sentry.WithSpanOrigin("auto.function.serverless"),
+	)
+	defer func() {
+		if r := recover(); r != nil {
+			span.Status = sentry.SpanStatusInternalError
+			span.Finish()
+			hub.RecoverWithContext(span.Context(), r)
+			hub.Flush(defaultFlushTimeout)
+			panic(r)
+		}
+	}()
+
+	err = f(span.Context())
+	if err != nil {
+		span.Status = sentry.SpanStatusInternalError
+		hub.CaptureException(err)
+	} else {
+		span.Status = sentry.SpanStatusOK
+	}
+	span.Finish()
+	hub.Flush(defaultFlushTimeout)
+	return err
+}
+
+// FlushOnShutdown waits for the delivery of pending events, for at most
+// timeout, when the process receives SIGTERM, which is how Cloud Run and other
+// container platforms announce that an instance is about to shut down. Choose
+// a timeout well within the grace period of the platform, 10 seconds on Cloud
+// Run. If timeout is zero, it defaults to 2 seconds.
+//
+// FlushOnShutdown only flushes: SIGTERM is not raised again, and programs
+// that handle it themselves, for example to shut down an HTTP server
+// gracefully, receive it as usual. Note that while FlushOnShutdown waits for
+// SIGTERM, the signal does not terminate programs that do not handle it; the
+// platform kills them at the end of the grace period, or when SIGTERM is sent
+// again.
+//
+// The returned function stops waiting for SIGTERM.
+//
+//	func main() {
+//		sentry.Init(sentry.ClientOptions{
+//			// ...
+//		})
+//		defer sentryserverless.FlushOnShutdown(5 * time.Second)()
+//		// ...
+//	}
+func FlushOnShutdown(timeout time.Duration) (stop func()) {
+	if timeout == 0 {
+		timeout = defaultFlushTimeout
+	}
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-done:
+		case <-c:
+			sentry.Flush(timeout)
+			signal.Stop(c)
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
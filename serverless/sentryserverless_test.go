@@ -0,0 +1,123 @@
+package sentryserverless_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/getsentry/sentry-go/sentrytest"
+	sentryserverless "github.com/getsentry/sentry-go/serverless"
+)
+
+func TestWrapHTTPRepanics(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	handler := sentryserverless.WrapHTTP(func(w http.ResponseWriter, r *http.Request) {
+		panic("function panic")
+	})
+
+	func() {
+		defer func() {
+			if r := recover(); r != "function panic" {
+				t.Errorf("got panic %v, want %q", r, "function panic")
+			}
+		}()
+		r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+		handler(httptest.NewRecorder(), r)
+	}()
+
+	var panicEvent *sentry.Event
+	for _, event := range transport.Events() {
+		if event.Type == "" {
+			panicEvent = event
+		}
+	}
+	if panicEvent == nil || panicEvent.Message != "function panic" {
+		t.Errorf("got events %+v, want a panic event", transport.Events())
+	}
+	if transport.Flushes() == 0 {
+		t.Error("events were not flushed")
+	}
+}
+
+func TestBackgroundCapturesErrors(t *testing.T) {
+	os.Setenv("FUNCTION_TARGET", "HelloPubSub")
+	defer os.Unsetenv("FUNCTION_TARGET")
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	errFunction := errors.New("function failed")
+
+	err := sentryserverless.Background(ctx, func(ctx context.Context) error {
+		if sentry.TransactionFromContext(ctx) == nil {
+			t.Error("missing transaction in function context")
+		}
+		return errFunction
+	})
+	if err != errFunction {
+		t.Errorf("got error %v, want %v", err, errFunction)
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	errorEvent, transaction := events[0], events[1]
+	if errorEvent.Exception[0].Value != "function failed" {
+		t.Errorf("got exception %+v", errorEvent.Exception)
+	}
+	if transaction.Transaction != "HelloPubSub" {
+		t.Errorf("got transaction %q, want %q", transaction.Transaction, "HelloPubSub")
+	}
+	if trace := transaction.Contexts["trace"].(*sentry.TraceContext); trace.Status != sentry.SpanStatusInternalError {
+		t.Errorf("got status %s, want %s", trace.Status, sentry.SpanStatusInternalError)
+	}
+	if transport.Flushes() == 0 {
+		t.Error("events were not flushed")
+	}
+}
+
+func TestFlushOnShutdown(t *testing.T) {
+	ctx, transport := sentrytest.NewContext(t, sentry.ClientOptions{TracesSampleRate: 1.0})
+	sentry.CurrentHub().BindClient(sentry.GetHubFromContext(ctx).Client())
+	defer sentry.CurrentHub().BindClient(nil)
+
+	// Receive SIGTERM in the test too, like programs that shut down
+	// gracefully, so that it does not terminate the process.
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, syscall.SIGTERM)
+	defer signal.Stop(c)
+
+	stop := sentryserverless.FlushOnShutdown(time.Second)
+	defer stop()
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		t.Skipf("cannot send SIGTERM: %v", err)
+	}
+	select {
+	case <-c:
+	case <-time.After(time.Second):
+		t.Fatal("signal not received")
+	}
+	deadline := time.Now().Add(time.Second)
+	for transport.Flushes() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("events were not flushed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// SIGTERM is not raised again.
+	select {
+	case <-c:
+		t.Error("SIGTERM raised again")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,203 @@
+package sentry
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultCrashFlushTimeout is the default time to wait for the delivery of
+// the event that reports a fatal condition before the process exits.
+const defaultCrashFlushTimeout = 2 * time.Second
+
+// SignalHandlerOptions configure a SignalHandler.
+type SignalHandlerOptions struct {
+	// Signals are the signals to handle. Defaults to SIGABRT and SIGSEGV.
+	Signals []os.Signal
+	// FlushTimeout is the maximum time to wait for the event that reports a
+	// signal to be delivered before exiting. Defaults to 2 seconds.
+	FlushTimeout time.Duration
+}
+
+// A SignalHandler is an integration that reports fatal signals received by
+// the process. On receipt of one of the handled signals, it captures an event
+// with the stack traces of all goroutines, waits for the event to be
+// delivered and stops handling signals. Crash signals, like SIGABRT and
+// SIGSEGV, are then raised again, so that the process terminates as it would
+// have without the handler, unless the program watches them with
+// signal.Notify too.
+//
+// Other signals, like SIGTERM, are only reported: the process does not exit,
+// as programs usually handle them to shut down gracefully. Since every
+// channel registered with signal.Notify receives the signal, handling it in
+// the program is not affected by the SignalHandler.
+//
+// Handling signals is best-effort. In particular, the Go runtime turns
+// segmentation faults caused by the program itself into panics, which never
+// reach a SignalHandler; use CrashHandler to report those. Only SIGSEGV sent
+// by another process, for example with kill(1), is delivered to the handler.
+//
+// A SignalHandler is installed like any other integration:
+//
+//	sentry.Init(sentry.ClientOptions{
+//		Integrations: func(integrations []sentry.Integration) []sentry.Integration {
+//			return append(integrations, sentry.NewSignalHandler(sentry.SignalHandlerOptions{}))
+//		},
+//	})
+type SignalHandler struct {
+	signals      []os.Signal
+	flushTimeout time.Duration
+	// exit terminates the process after a signal was reported. It is
+	// replaced in tests.
+	exit func(sig os.Signal)
+
+	c     chan os.Signal
+	start sync.Once
+	stop  chan struct{}
+	close sync.Once
+}
+
+// NewSignalHandler returns a new SignalHandler. It starts handling signals
+// when installed as an integration of a Client.
+func NewSignalHandler(options SignalHandlerOptions) *SignalHandler {
+	signals := options.Signals
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGABRT, syscall.SIGSEGV}
+	}
+	flushTimeout := options.FlushTimeout
+	if flushTimeout <= 0 {
+		flushTimeout = defaultCrashFlushTimeout
+	}
+	return &SignalHandler{
+		signals:      signals,
+		flushTimeout: flushTimeout,
+		exit:         reraise,
+		c:            make(chan os.Signal, 1),
+		stop:         make(chan struct{}),
+	}
+}
+
+// Name implements Integration.
+func (h *SignalHandler) Name() string {
+	return "SignalHandler"
+}
+
+// SetupOnce implements Integration. It starts handling signals until Stop is
+// called.
+func (h *SignalHandler) SetupOnce(client *Client) {
+	h.start.Do(func() {
+		signal.Notify(h.c, h.signals...)
+		go h.run(client)
+	})
+}
+
+// Stop stops handling signals and restores their previous behavior. It is
+// safe to call Stop multiple times.
+func (h *SignalHandler) Stop() {
+	h.close.Do(func() {
+		signal.Stop(h.c)
+		close(h.stop)
+	})
+}
+
+func (h *SignalHandler) run(client *Client) {
+	select {
+	case <-h.stop:
+	case sig := <-h.c:
+		h.handle(client, sig)
+	}
+}
+
+// handle reports sig, waits for the event to be delivered and, for crash
+// signals, terminates the process. Only crash signals leave a crash marker.
+func (h *SignalHandler) handle(client *Client, sig os.Signal) {
+	Logger.Printf("SignalHandler: received signal %s", sig)
+	crash := isCrashSignal(sig)
+	if crash {
+		client.markCrash(fmt.Sprintf("received signal %s", sig))
+	}
+	// Like the Watchdog, the handler runs in its own goroutine and events
+	// are enriched with the scope of the current hub.
+	client.CaptureEvent(eventFromSignal(sig), &EventHint{Data: sig}, CurrentHub().Scope())
+	client.Flush(h.flushTimeout)
+	h.Stop()
+	if crash {
+		h.exit(sig)
+	}
+}
+
+// isCrashSignal reports whether sig reports a crash, as opposed to a request
+// to terminate, like SIGTERM, or another event, like SIGHUP.
+func isCrashSignal(sig os.Signal) bool {
+	switch sig {
+	case syscall.SIGABRT, syscall.SIGSEGV, syscall.SIGBUS, syscall.SIGILL, syscall.SIGFPE, syscall.SIGQUIT:
+		return true
+	}
+	return false
+}
+
+func eventFromSignal(sig os.Signal) *Event {
+	event := NewEvent()
+	event.Level = LevelFatal
+	event.Threads = parseGoroutineStacks(allGoroutinesStack())
+
+	data := map[string]interface{}{
+		"signal": sig.String(),
+	}
+	if s, ok := sig.(syscall.Signal); ok {
+		data["number"] = int(s)
+	}
+	mechanism := &Mechanism{Type: "signal", Data: data}
+	mechanism.SetHandled(false)
+	event.Exception = []Exception{{
+		Type:      "Signal",
+		Value:     fmt.Sprintf("Received signal %s", sig),
+		Mechanism: mechanism,
+	}}
+	return event
+}
+
+// reraise sends sig to the current process again, once the handler stopped
+// watching it. Without other channels registered with signal.Notify for sig,
+// its default behavior is restored and the process terminates. Otherwise,
+// the program receives the signal and decides what to do.
+func reraise(sig os.Signal) {
+	if p, err := os.FindProcess(os.Getpid()); err == nil {
+		_ = p.Signal(sig)
+	}
+}
+
+// CrashHandler reports a panic in the calling goroutine as a fatal event,
+// waits for the event to be delivered and re-panics, so that the program
+// still crashes with the original panic. It must be deferred directly, usually
+// as the first statement of main:
+//
+//	func main() {
+//		sentry.Init(sentry.ClientOptions{
+//			// ...
+//		})
+//		defer sentry.CrashHandler()
+//		// ...
+//	}
+//
+// Unlike Recover, CrashHandler does not stop the panic. Note that panics in
+// other goroutines must be handled in those goroutines.
+func CrashHandler() {
+	if err := recover(); err != nil {
+		handleCrash(CurrentHub(), err, defaultCrashFlushTimeout)
+	}
+}
+
+// handleCrash reports err on hub, waits at most timeout for its delivery
+// and re-panics with err.
+func handleCrash(hub *Hub, err interface{}, timeout time.Duration) {
+	if client := hub.Client(); client != nil {
+		client.markCrash(fmt.Sprintf("panic: %v", err))
+	}
+	hub.Recover(err)
+	hub.Flush(timeout)
+	panic(err)
+}
@@ -0,0 +1,137 @@
+package sentry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalHandlerReportsSignal(t *testing.T) {
+	transport := &TransportMock{}
+	handler := NewSignalHandler(SignalHandlerOptions{
+		Signals:      []os.Signal{syscall.SIGABRT},
+		FlushTimeout: time.Second,
+	})
+	defer handler.Stop()
+	exited := make(chan os.Signal, 1)
+	handler.exit = func(sig os.Signal) { exited <- sig }
+	_, err := NewClient(ClientOptions{
+		Transport: transport,
+		Integrations: func(integrations []Integration) []Integration {
+			return append(integrations, handler)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate the delivery of a signal.
+	handler.c <- syscall.SIGABRT
+	select {
+	case sig := <-exited:
+		assertEqual(t, sig, syscall.SIGABRT)
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not exit")
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	event := events[0]
+	assertEqual(t, event.Level, LevelFatal)
+	if len(event.Exception) != 1 {
+		t.Fatalf("got %d exceptions, want 1", len(event.Exception))
+	}
+	mechanism := newMechanism("signal", false)
+	mechanism.Data = map[string]interface{}{
+		"signal": syscall.SIGABRT.String(),
+		"number": int(syscall.SIGABRT),
+	}
+	assertEqual(t, event.Exception[0].Mechanism, mechanism)
+	if len(event.Threads) == 0 {
+		t.Error("missing goroutine stacks")
+	}
+}
+
+func TestSignalHandlerDoesNotExitOnSIGTERM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sentry-crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "crashed")
+
+	transport := &TransportMock{}
+	handler := NewSignalHandler(SignalHandlerOptions{
+		Signals:      []os.Signal{syscall.SIGTERM},
+		FlushTimeout: time.Second,
+	})
+	defer handler.Stop()
+	handler.exit = func(sig os.Signal) { t.Errorf("exited on %s", sig) }
+	client, err := NewClient(ClientOptions{
+		Transport:       transport,
+		CrashMarkerPath: path,
+		Integrations: func(integrations []Integration) []Integration {
+			return append(integrations, handler)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler.handle(client, syscall.SIGTERM)
+	assertEqual(t, len(transport.Events()), 1)
+	// SIGTERM is not a crash.
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("crash marker written on SIGTERM: %v", err)
+	}
+}
+
+func TestSignalHandlerDefaultSignals(t *testing.T) {
+	handler := NewSignalHandler(SignalHandlerOptions{})
+	assertEqual(t, handler.signals, []os.Signal{syscall.SIGABRT, syscall.SIGSEGV})
+}
+
+func TestSignalHandlerStop(t *testing.T) {
+	handler := NewSignalHandler(SignalHandlerOptions{})
+	handler.SetupOnce(nil)
+	handler.Stop()
+	// Stopping again must not panic.
+	handler.Stop()
+}
+
+func TestHandleCrash(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(client, NewScope())
+
+	var repanicked interface{}
+	func() {
+		defer func() {
+			repanicked = recover()
+		}()
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					handleCrash(hub, err, time.Second)
+				}
+			}()
+			panic("crash")
+		}()
+	}()
+
+	assertEqual(t, repanicked, "crash")
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	assertEqual(t, events[0].Message, "crash")
+	assertEqual(t, events[0].Level, LevelFatal)
+}
@@ -15,6 +15,8 @@ type spanRecorder struct {
 	mu           sync.Mutex
 	spans        []*Span
 	overflowOnce sync.Once
+	// measures holds the custom measurements of the transaction.
+	measures map[string]Measurement
 }
 
 // record stores a span. The first stored span is assumed to be the root of a
@@ -55,3 +57,28 @@ func (r *spanRecorder) children() []*Span {
 	}
 	return r.spans[1:]
 }
+
+// setMeasurement stores a custom measurement of the transaction.
+func (r *spanRecorder) setMeasurement(name string, m Measurement) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.measures == nil {
+		r.measures = make(map[string]Measurement)
+	}
+	r.measures[name] = m
+}
+
+// measurements returns a copy of the custom measurements of the transaction.
+// Returns nil if none have been stored.
+func (r *spanRecorder) measurements() map[string]Measurement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.measures) == 0 {
+		return nil
+	}
+	measurements := make(map[string]Measurement, len(r.measures))
+	for name, m := range r.measures {
+		measurements[name] = m
+	}
+	return measurements
+}
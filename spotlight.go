@@ -0,0 +1,114 @@
+package sentry
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultSpotlightURL is the URL of the envelope endpoint of a Spotlight
+// sidecar running with its default configuration.
+const defaultSpotlightURL = "http://localhost:8969/stream"
+
+// spotlightTransport is a Transport that mirrors all events sent to the
+// wrapped transport to a Spotlight sidecar, for local development.
+//
+// See https://spotlightjs.com/.
+type spotlightTransport struct {
+	Transport
+
+	url    string
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+func newSpotlightTransport(transport Transport, url string) *spotlightTransport {
+	if url == "" {
+		url = defaultSpotlightURL
+	}
+	return &spotlightTransport{
+		Transport: transport,
+		url:       url,
+		client:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Configure is called by the Client itself, providing it it's own ClientOptions.
+func (t *spotlightTransport) Configure(options ClientOptions) {
+	t.Transport.Configure(options)
+	Logger.Printf("Mirroring events to Spotlight at %s", t.url)
+}
+
+// SendEvent sends the event to the wrapped transport and to Spotlight.
+func (t *spotlightTransport) SendEvent(event *Event) {
+	// Serialize the event before handing it over to the wrapped transport,
+	// which may modify it asynchronously.
+	body := getRequestBodyFromEvent(event)
+	t.Transport.SendEvent(event)
+	if body == nil {
+		return
+	}
+
+	itemType := event.Type
+	if itemType == "" {
+		itemType = "event"
+	}
+	envelope, err := envelopeFromBody(event.EventID, itemType, time.Now(), body)
+	if err != nil {
+		Logger.Printf("Could not create Spotlight envelope: %v", err)
+		return
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		request, err := newPooledRequest(t.url, envelope)
+		if err != nil {
+			Logger.Printf("Could not create Spotlight request: %v", err)
+			return
+		}
+		request.Header.Set("Content-Type", "application/x-sentry-envelope")
+		request.Header.Set("User-Agent", userAgent)
+		response, err := doRequest(t.client, request)
+		if err != nil {
+			Logger.Printf("There was an issue with sending an event to Spotlight: %v", err)
+			return
+		}
+		_, _ = io.CopyN(ioutil.Discard, response.Body, maxDrainResponseBytes)
+		response.Body.Close()
+	}()
+}
+
+// Close closes the wrapped transport, if it supports it.
+func (t *spotlightTransport) Close() {
+	if c, ok := t.Transport.(transportCloser); ok {
+		c.Close()
+	}
+}
+
+// diagnose reports the state of the wrapped transport, if it supports it.
+func (t *spotlightTransport) diagnose(report *DiagnosticReport) {
+	if r, ok := t.Transport.(diagnosticsReporter); ok {
+		r.diagnose(report)
+	}
+}
+
+// Flush waits until events are sent both by the wrapped transport and to
+// Spotlight, blocking for at most the given timeout.
+func (t *spotlightTransport) Flush(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	ok := t.Transport.Flush(timeout)
+	select {
+	case <-done:
+		return ok
+	case <-time.After(time.Until(deadline)):
+		return false
+	}
+}
@@ -0,0 +1,89 @@
+package sentry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSpotlightTransport(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, r)
+		bodies = append(bodies, b)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport:       transport,
+		EnableSpotlight: true,
+		SpotlightURL:    srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventID := client.CaptureMessage("hello", nil, nil)
+	if eventID == nil {
+		t.Fatal("event was not captured")
+	}
+	if !client.Flush(time.Second) {
+		t.Fatal("Flush timed out")
+	}
+
+	assertEqual(t, len(transport.Events()), 1, "events should still be sent to the wrapped transport")
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests to Spotlight, want 1", len(requests))
+	}
+	assertEqual(t, requests[0].Header.Get("Content-Type"), "application/x-sentry-envelope")
+	lines := bytes.Split(bodies[0], []byte("\n"))
+	if len(lines) < 3 {
+		t.Fatalf("invalid envelope:\n%s", bodies[0])
+	}
+	if !bytes.Contains(lines[0], []byte(*eventID)) {
+		t.Errorf("envelope header %s does not contain event ID %s", lines[0], *eventID)
+	}
+	if !bytes.HasPrefix(lines[1], []byte(`{"type":"event",`)) {
+		t.Errorf("got item header %s, want an event item", lines[1])
+	}
+	if !bytes.Contains(lines[2], []byte(`"message":"hello"`)) {
+		t.Errorf("payload %s does not contain the message", lines[2])
+	}
+}
+
+func TestSpotlightEnvironmentVariable(t *testing.T) {
+	tests := []struct {
+		value   string
+		enabled bool
+		url     string
+	}{
+		{"true", true, defaultSpotlightURL},
+		{"false", false, ""},
+		{"http://localhost:1234/stream", true, "http://localhost:1234/stream"},
+	}
+	for _, tt := range tests {
+		restore := setenv("SENTRY_SPOTLIGHT", tt.value)
+		client, err := NewClient(ClientOptions{Transport: &TransportMock{}})
+		restore()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, client.Options().EnableSpotlight, tt.enabled, tt.value)
+		spotlight, ok := client.Transport.(*spotlightTransport)
+		assertEqual(t, ok, tt.enabled, tt.value)
+		if ok {
+			assertEqual(t, spotlight.url, tt.url, tt.value)
+		}
+	}
+}
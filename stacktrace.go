@@ -244,22 +244,17 @@ func splitQualifiedFunctionName(name string) (pkg string, fun string) {
 	return
 }
 
+// extractFrames returns the frames of pcs, return program counters as
+// returned by runtime.Callers, the outermost first. Frames are resolved once
+// per program counter and cached.
 func extractFrames(pcs []uintptr) []Frame {
 	var frames []Frame
-	callersFrames := runtime.CallersFrames(pcs)
-
-	for {
-		callerFrame, more := callersFrames.Next()
-
-		frames = append([]Frame{
-			NewFrame(callerFrame),
-		}, frames...)
-
-		if !more {
-			break
-		}
+	for _, pc := range pcs {
+		frames = append(frames, frameCache.frames(pc)...)
+	}
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
 	}
-
 	return frames
 }
 
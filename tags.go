@@ -0,0 +1,91 @@
+package sentry
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the length of tags, in characters. Sentry discards longer tags.
+const (
+	maxTagKeyLength   = 32
+	maxTagValueLength = 200
+)
+
+// reservedTagKeys are the keys of tags that Sentry derives from the fields of
+// events, and discards when they are set as tags.
+var reservedTagKeys = map[string]bool{
+	"release":  true,
+	"dist":     true,
+	"user":     true,
+	"filename": true,
+	"function": true,
+}
+
+// normalizeTag returns the key and the value of a tag in the form accepted by
+// Sentry, logging a warning when they need changes:
+//
+//   - characters of the key other than letters, digits and "_.:-" are
+//     replaced with underscores;
+//   - line breaks in the value are replaced with spaces;
+//   - keys and values that are too long are truncated.
+//
+// It reports false for tags that cannot be set, with an empty or reserved key.
+func normalizeTag(key, value string) (string, string, bool) {
+	if key == "" {
+		Logger.Printf("Tag with value %q dropped: empty key", value)
+		return "", "", false
+	}
+	if reservedTagKeys[key] {
+		Logger.Printf("Tag %q dropped: reserved key, set the field of the event instead", key)
+		return "", "", false
+	}
+	if k := strings.Map(tagKeyRune, key); k != key {
+		Logger.Printf("Tag key %q contains invalid characters, replaced with %q", key, k)
+		key = k
+	}
+	if k := truncate(key, maxTagKeyLength); k != key {
+		Logger.Printf("Tag key %q longer than %d characters, truncated", key, maxTagKeyLength)
+		key = k
+	}
+	if v := strings.Map(tagValueRune, value); v != value {
+		Logger.Printf("Tag %q contains line breaks, replaced with spaces", key)
+		value = v
+	}
+	if v := truncate(value, maxTagValueLength); v != value {
+		Logger.Printf("Tag %q longer than %d characters, truncated", key, maxTagValueLength)
+		value = v
+	}
+	return key, value, true
+}
+
+func tagKeyRune(r rune) rune {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		return r
+	case strings.ContainsRune("_.:-", r):
+		return r
+	}
+	return '_'
+}
+
+func tagValueRune(r rune) rune {
+	if r == '\n' || r == '\r' {
+		return ' '
+	}
+	return r
+}
+
+// truncate returns s truncated to at most n characters.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	i := 0
+	for j := range s {
+		if i == n {
+			return s[:j]
+		}
+		i++
+	}
+	return s
+}
@@ -0,0 +1,43 @@
+package sentry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		key, value         string
+		wantKey, wantValue string
+		wantOK             bool
+	}{
+		{"http.status_code", "200", "http.status_code", "200", true},
+		{"", "value", "", "", false},
+		{"release", "1.0", "", "", false},
+		{"user", "jane", "", "", false},
+		{"my tag/name", "value", "my_tag_name", "value", true},
+		{strings.Repeat("k", 40), "value", strings.Repeat("k", 32), "value", true},
+		{"query", "SELECT *\r\nFROM users\n", "query", "SELECT *  FROM users ", true},
+		{"name", strings.Repeat("é", 250), "name", strings.Repeat("é", 200), true},
+	}
+	for _, tt := range tests {
+		key, value, ok := normalizeTag(tt.key, tt.value)
+		assertEqual(t, ok, tt.wantOK, tt.key)
+		assertEqual(t, key, tt.wantKey, tt.key)
+		assertEqual(t, value, tt.wantValue, tt.key)
+	}
+}
+
+func TestSetTagNormalizes(t *testing.T) {
+	scope := NewScope()
+	scope.SetTag("release", "1.0")
+	scope.SetTag("multi\nline", "a\nb")
+	scope.SetTags(map[string]string{"user": "jane", "ok": "yes"})
+	assertEqual(t, scope.tags, map[string]string{"multi_line": "a b", "ok": "yes"})
+
+	span := &Span{}
+	span.SetTag("dist", "x")
+	span.SetTag("value", strings.Repeat("v", 300))
+	assertEqual(t, len(span.Tags), 1)
+	assertEqual(t, len(span.Tags["value"]), maxTagValueLength)
+}
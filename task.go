@@ -0,0 +1,52 @@
+package sentry
+
+import (
+	"context"
+)
+
+// taskOperation is the operation of spans created by WrapTask.
+const taskOperation = "task"
+
+// WrapTask instruments a background task, for job frameworks and worker
+// pools without a dedicated integration. Every call to the returned function
+// calls fn in a span named after the task: a child span if ctx already
+// carries a span, or otherwise a new transaction on a clone of the hub of ctx,
+// so that concurrent tasks do not share their scope. The span records the
+// duration of the task and its status.
+//
+// An error returned by fn is captured, with the task name as the transaction,
+// and returned to the caller:
+//
+//	resize := sentry.WrapTask("resize-image", func(ctx context.Context) error {
+//		// ...
+//	})
+//	for job := range jobs {
+//		_ = resize(job.Context())
+//	}
+func WrapTask(name string, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var options []SpanOption
+		if spanFromContext(ctx) == nil {
+			ctx = SetHubOnContext(ctx, hubFromContext(ctx).Clone())
+			options = append(options, TransactionName(name), WithTransactionSource(SourceTask))
+		}
+		span := StartSpan(ctx, taskOperation, options...)
+		span.Description = name
+		defer span.Finish()
+
+		err := fn(span.Context())
+		if err == nil {
+			span.Status = SpanStatusOK
+			return nil
+		}
+		span.Status = SpanStatusInternalError
+		hub := hubFromContext(span.Context())
+		hub.WithScope(func(scope *Scope) {
+			scope.SetTransaction(name)
+			scope.SetTransactionSource(SourceTask)
+			scope.SetExtra("task.duration", clockFor(hub.Client()).Now().Sub(span.StartTime).Seconds())
+			hub.CaptureException(err)
+		})
+		return err
+	}
+}
@@ -0,0 +1,79 @@
+package sentry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWrapTaskTransaction(t *testing.T) {
+	transport := &TransportMock{}
+	clock := &ClockMock{now: time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)}
+	ctx := NewTestContext(ClientOptions{
+		Transport:        transport,
+		Clock:            clock,
+		TracesSampleRate: 1.0,
+	})
+
+	errTask := errors.New("task failed")
+	err := WrapTask("resize", func(ctx context.Context) error {
+		if TransactionFromContext(ctx) == nil {
+			t.Error("missing transaction in task context")
+		}
+		clock.Advance(2 * time.Second)
+		return errTask
+	})(ctx)
+	assertEqual(t, err, errTask)
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	errorEvent, transaction := events[0], events[1]
+	assertEqual(t, errorEvent.Transaction, "resize")
+	assertEqual(t, errorEvent.Exception[0].Value, "task failed")
+	assertEqual(t, errorEvent.Extra["task.duration"], 2.0)
+	assertEqual(t, transaction.Type, transactionType)
+	assertEqual(t, transaction.Transaction, "resize")
+	trace := transaction.Contexts["trace"].(*TraceContext)
+	assertEqual(t, trace.Op, taskOperation)
+	assertEqual(t, trace.Status, SpanStatusInternalError)
+	assertEqual(t, transaction.Timestamp.Sub(transaction.StartTime), 2*time.Second)
+
+	// The task does not leak its transaction into the scope of the caller.
+	if _, ok := GetHubFromContext(ctx).Scope().contexts["trace"]; ok {
+		t.Error("task modified the scope of the caller")
+	}
+}
+
+func TestWrapTaskChildSpan(t *testing.T) {
+	transport := &TransportMock{}
+	ctx := NewTestContext(ClientOptions{
+		Transport:        transport,
+		TracesSampleRate: 1.0,
+	})
+	parent := StartSpan(ctx, "parent", TransactionName("parent"))
+
+	err := WrapTask("child", func(ctx context.Context) error {
+		return nil
+	})(parent.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(transport.Events()), 0)
+	parent.Finish()
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	spans := events[0].Spans
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	assertEqual(t, spans[0].Op, taskOperation)
+	assertEqual(t, spans[0].Description, "child")
+	assertEqual(t, spans[0].Status, SpanStatusOK)
+	assertEqual(t, spans[0].ParentSpanID, parent.SpanID)
+}
@@ -0,0 +1,174 @@
+// Package sentrytemporal provides Sentry integration for Temporal workers
+// based on the go.temporal.io/sdk package.
+package sentrytemporal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Options configure a worker interceptor.
+type Options struct {
+	// WaitForDelivery indicates, in case of a panic or a failure of an
+	// activity, whether to block the activity and wait until the event has
+	// been reported to Sentry before the activity completes. Workflows never
+	// wait, as they must not block.
+	WaitForDelivery bool
+	// Timeout for the delivery of events. Defaults to 2s. Only relevant when
+	// WaitForDelivery is true.
+	Timeout time.Duration
+}
+
+// workerInterceptor is a Temporal worker interceptor that provides integration
+// with Sentry.
+type workerInterceptor struct {
+	interceptor.WorkerInterceptorBase
+	waitForDelivery bool
+	timeout         time.Duration
+}
+
+// New returns a worker interceptor that reports the panics and the failures of
+// activities and workflows to Sentry, with the workflow ID, the run ID and the
+// task queue in tags. Every activity execution is recorded in a transaction
+// named after the type of the activity, with the retry attempt.
+//
+//	w := worker.New(c, "orders", worker.Options{
+//		Interceptors: []interceptor.WorkerInterceptor{
+//			sentrytemporal.New(sentrytemporal.Options{}),
+//		},
+//	})
+//
+// Panics are then handled by Temporal, which fails the activity or the
+// workflow task. The events of workflows are not reported again when workflows
+// are replayed.
+func New(options Options) interceptor.WorkerInterceptor {
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	return &workerInterceptor{
+		waitForDelivery: options.WaitForDelivery,
+		timeout:         timeout,
+	}
+}
+
+func (w *workerInterceptor) InterceptActivity(ctx context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	a := &activityInterceptor{root: w}
+	a.Next = next
+	return a
+}
+
+func (w *workerInterceptor) InterceptWorkflow(ctx workflow.Context, next interceptor.WorkflowInboundInterceptor) interceptor.WorkflowInboundInterceptor {
+	i := &workflowInterceptor{}
+	i.Next = next
+	return i
+}
+
+type activityInterceptor struct {
+	interceptor.ActivityInboundInterceptorBase
+	root *workerInterceptor
+}
+
+func (a *activityInterceptor) ExecuteActivity(ctx context.Context, in *interceptor.ExecuteActivityInput) (result interface{}, err error) {
+	info := activity.GetInfo(ctx)
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = sentry.CurrentHub().Clone()
+		ctx = sentry.SetHubOnContext(ctx, hub)
+	}
+	workflowType := ""
+	if info.WorkflowType != nil {
+		workflowType = info.WorkflowType.Name
+	}
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		setTags(scope, info.WorkflowExecution.ID, info.WorkflowExecution.RunID, info.TaskQueue, workflowType)
+		scope.SetTag("temporal.activity_type", info.ActivityType.Name)
+		scope.SetContext("temporal", map[string]interface{}{
+			"namespace":   info.WorkflowNamespace,
+			"activity_id": info.ActivityID,
+			"attempt":     info.Attempt,
+		})
+	})
+
+	span := sentry.StartSpan(ctx, "temporal.activity",
+		sentry.TransactionName(info.ActivityType.Name),
+		sentry.WithTransactionSource(sentry.SourceTask),
+		sentry.WithSpanOrigin("auto.temporal"),
+	)
+	span.SetData("temporal.attempt", info.Attempt)
+	defer span.Finish()
+	defer func() {
+		if r := recover(); r != nil {
+			span.Status = sentry.SpanStatusInternalError
+			eventID := hub.RecoverWithContext(ctx, r)
+			a.root.wait(hub, eventID)
+			panic(r)
+		}
+	}()
+
+	result, err = a.Next.ExecuteActivity(span.Context(), in)
+	switch {
+	case err == nil:
+		span.Status = sentry.SpanStatusOK
+	case errors.Is(err, context.Canceled):
+		span.Status = sentry.SpanStatusCanceled
+	default:
+		span.Status = sentry.SpanStatusInternalError
+		a.root.wait(hub, hub.CaptureException(err))
+	}
+	return result, err
+}
+
+// wait waits for the delivery of the event eventID, if any, when configured
+// to do so.
+func (w *workerInterceptor) wait(hub *sentry.Hub, eventID *sentry.EventID) {
+	if eventID != nil && w.waitForDelivery {
+		hub.Flush(w.timeout)
+	}
+}
+
+type workflowInterceptor struct {
+	interceptor.WorkflowInboundInterceptorBase
+}
+
+func (i *workflowInterceptor) ExecuteWorkflow(ctx workflow.Context, in *interceptor.ExecuteWorkflowInput) (result interface{}, err error) {
+	info := workflow.GetInfo(ctx)
+	hub := sentry.CurrentHub().Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		setTags(scope, info.WorkflowExecution.ID, info.WorkflowExecution.RunID, info.TaskQueueName, info.WorkflowType.Name)
+		scope.SetContext("temporal", map[string]interface{}{
+			"namespace": info.Namespace,
+			"attempt":   info.Attempt,
+		})
+	})
+	defer func() {
+		if r := recover(); r != nil {
+			if !workflow.IsReplaying(ctx) {
+				hub.Recover(r)
+			}
+			panic(r)
+		}
+	}()
+
+	result, err = i.Next.ExecuteWorkflow(ctx, in)
+	if err != nil && !workflow.IsReplaying(ctx) {
+		hub.CaptureException(err)
+	}
+	return result, err
+}
+
+// setTags sets the tags identifying a workflow execution.
+func setTags(scope *sentry.Scope, workflowID, runID, taskQueue, workflowType string) {
+	scope.SetTag("temporal.workflow_id", workflowID)
+	scope.SetTag("temporal.run_id", runID)
+	scope.SetTag("temporal.task_queue", taskQueue)
+	if workflowType != "" {
+		scope.SetTag("temporal.workflow_type", workflowType)
+	}
+}
@@ -0,0 +1,111 @@
+package sentrytemporal_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/getsentry/sentry-go/sentrytest"
+	sentrytemporal "github.com/getsentry/sentry-go/temporal"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// bindClient binds a new client to the current hub for the duration of the
+// test, and returns its transport.
+func bindClient(t *testing.T) *sentrytest.RecordingTransport {
+	t.Helper()
+	transport := sentrytest.NewRecordingTransport()
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Transport:        transport,
+		TracesSampleRate: 1.0,
+		Integrations:     func([]sentry.Integration) []sentry.Integration { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := sentry.CurrentHub().Client()
+	sentry.CurrentHub().BindClient(client)
+	t.Cleanup(func() { sentry.CurrentHub().BindClient(previous) })
+	return transport
+}
+
+var errCharge = errors.New("card declined")
+
+func ChargeCard(ctx context.Context, amount int) error {
+	return errCharge
+}
+
+func Checkout(ctx workflow.Context) error {
+	panic("checkout")
+}
+
+func workerOptions() worker.Options {
+	return worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{sentrytemporal.New(sentrytemporal.Options{})},
+	}
+}
+
+func TestActivity(t *testing.T) {
+	transport := bindClient(t)
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.SetWorkerOptions(workerOptions())
+	env.RegisterActivity(ChargeCard)
+
+	if _, err := env.ExecuteActivity(ChargeCard, 42); err == nil {
+		t.Fatal("got no error, want the activity error")
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	failure, transaction := events[0], events[1]
+	if failure.Exception[0].Value != errCharge.Error() {
+		t.Errorf("got exception %+v, want the activity error", failure.Exception)
+	}
+	for _, tag := range []string{"temporal.workflow_id", "temporal.run_id", "temporal.task_queue", "temporal.activity_type"} {
+		if failure.Tags[tag] == "" {
+			t.Errorf("missing tag %s in %v", tag, failure.Tags)
+		}
+	}
+	if failure.Tags["temporal.activity_type"] != "ChargeCard" {
+		t.Errorf("got activity type %q", failure.Tags["temporal.activity_type"])
+	}
+
+	if transaction.Type != "transaction" || transaction.Transaction != "ChargeCard" {
+		t.Fatalf("got event %q of type %q, want the ChargeCard transaction", transaction.Transaction, transaction.Type)
+	}
+	trace := transaction.Contexts["trace"].(*sentry.TraceContext)
+	if trace.Op != "temporal.activity" || trace.Status != sentry.SpanStatusInternalError {
+		t.Errorf("got trace context %+v", trace)
+	}
+	if transaction.Extra["temporal.attempt"] != int32(1) {
+		t.Errorf("got data %v, want attempt 1", transaction.Extra)
+	}
+}
+
+func TestWorkflowPanic(t *testing.T) {
+	transport := bindClient(t)
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(workerOptions())
+	env.RegisterWorkflow(Checkout)
+
+	env.ExecuteWorkflow(Checkout)
+	if env.GetWorkflowError() == nil {
+		t.Fatal("got no error, want the workflow panic")
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].Message != "checkout" || events[0].Tags["temporal.workflow_type"] != "Checkout" {
+		t.Errorf("got event %q with tags %v, want the workflow panic", events[0].Message, events[0].Tags)
+	}
+}
@@ -0,0 +1,171 @@
+package sentry
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxGoroutinesStackSize bounds the size of the buffer used to collect the
+// stack traces of all goroutines. Programs with a very large number of
+// goroutines will have their dump truncated.
+const maxGoroutinesStackSize = 64 << 20
+
+// goroutineThreads returns the goroutines of the running program in the format
+// of the Sentry threads interface.
+//
+// The current goroutine is always the first thread, marked as current and, if
+// crashed is true, as crashed. Other goroutines are only included if all is
+// true. Collecting the stacks of all goroutines stops the world, therefore it
+// should be avoided in hot code paths.
+func goroutineThreads(crashed, all bool) []Thread {
+	current := Thread{
+		ID:         currentGoroutineID(),
+		Stacktrace: NewStacktrace(),
+		Crashed:    crashed,
+		Current:    true,
+	}
+	threads := []Thread{current}
+	if !all {
+		return threads
+	}
+	for _, t := range parseGoroutineStacks(allGoroutinesStack()) {
+		if t.ID == current.ID {
+			continue
+		}
+		threads = append(threads, t)
+	}
+	return threads
+}
+
+// currentGoroutineID returns the ID of the calling goroutine as reported by
+// runtime.Stack. It returns the empty string if the ID cannot be determined.
+func currentGoroutineID() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	id, _ := parseGoroutineHeader(buf)
+	return id
+}
+
+// allGoroutinesStack returns the output of runtime.Stack for all goroutines,
+// growing the buffer as needed up to maxGoroutinesStackSize.
+func allGoroutinesStack() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxGoroutinesStackSize {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parseGoroutineStacks parses the text format of runtime.Stack into threads,
+// one per goroutine. Goroutines without any frame that can be reported to
+// Sentry are included without a stack trace.
+//
+// The format is not a stable API of the Go runtime. Lines that cannot be
+// recognized are ignored.
+func parseGoroutineStacks(b []byte) []Thread {
+	var threads []Thread
+	for _, block := range bytes.Split(b, []byte("\n\n")) {
+		lines := strings.Split(strings.TrimSpace(string(block)), "\n")
+		id, ok := parseGoroutineHeader([]byte(lines[0]))
+		if !ok {
+			continue
+		}
+		var frames []Frame
+		var function string
+		for _, s := range lines[1:] {
+			file, line, ok := parseGoroutineLocation(s)
+			if !ok {
+				function = parseGoroutineFunction(s)
+				continue
+			}
+			if function == "" {
+				continue
+			}
+			// runtime.Stack lists the innermost call first, Sentry expects
+			// the opposite order.
+			frames = append([]Frame{NewFrame(runtime.Frame{
+				Function: function,
+				File:     file,
+				Line:     line,
+			})}, frames...)
+			function = ""
+		}
+		thread := Thread{ID: id}
+		if frames = filterFrames(frames); len(frames) > 0 {
+			thread.Stacktrace = &Stacktrace{Frames: frames}
+		}
+		threads = append(threads, thread)
+	}
+	return threads
+}
+
+// parseGoroutineHeader parses a line like
+//
+//	goroutine 18 [chan receive]:
+//
+// returning the goroutine ID.
+func parseGoroutineHeader(b []byte) (id string, ok bool) {
+	const prefix = "goroutine "
+	if !bytes.HasPrefix(b, []byte(prefix)) {
+		return "", false
+	}
+	b = b[len(prefix):]
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	if _, err := strconv.ParseUint(string(b), 10, 64); err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// parseGoroutineFunction parses a line like
+//
+//	main.(*T).run(0xc00009a000, {0x0, 0x1})
+//	created by main.main in goroutine 1
+//
+// returning the qualified function name.
+func parseGoroutineFunction(s string) string {
+	if strings.HasPrefix(s, "created by ") {
+		s = strings.TrimPrefix(s, "created by ")
+		if i := strings.Index(s, " in goroutine "); i >= 0 {
+			s = s[:i]
+		}
+		return s
+	}
+	if strings.HasSuffix(s, ")") {
+		if i := strings.LastIndex(s, "("); i > 0 {
+			s = s[:i]
+		}
+	}
+	return s
+}
+
+// parseGoroutineLocation parses a line like
+//
+//	/path/to/file.go:42 +0x1d
+//
+// returning the file name and line number.
+func parseGoroutineLocation(s string) (file string, line int, ok bool) {
+	if !strings.HasPrefix(s, "\t") {
+		return "", 0, false
+	}
+	s = strings.TrimPrefix(s, "\t")
+	if i := strings.LastIndex(s, " +0x"); i >= 0 {
+		s = s[:i]
+	}
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return s[:i], line, true
+}
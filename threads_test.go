@@ -0,0 +1,136 @@
+package sentry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testGoroutineStacks = `goroutine 7 [running]:
+main.(*server).serve(0xc00009a000, {0x0, 0x1})
+	/home/user/app/server.go:42 +0x1d
+main.main()
+	/home/user/app/main.go:10 +0x25
+
+goroutine 8 [chan receive, 2 minutes]:
+main.worker(...)
+	/home/user/app/worker.go:17
+created by main.main in goroutine 7
+	/home/user/app/main.go:9 +0x3f
+
+goroutine 9 [running]:
+	goroutine running on other thread; stack unavailable
+`
+
+func TestParseGoroutineStacks(t *testing.T) {
+	got := parseGoroutineStacks([]byte(testGoroutineStacks))
+	want := []Thread{
+		{
+			ID: "7",
+			Stacktrace: &Stacktrace{Frames: []Frame{
+				{Function: "main", Module: "main", AbsPath: "/home/user/app/main.go", Lineno: 10, InApp: true},
+				{Function: "(*server).serve", Module: "main", AbsPath: "/home/user/app/server.go", Lineno: 42, InApp: true},
+			}},
+		},
+		{
+			ID: "8",
+			Stacktrace: &Stacktrace{Frames: []Frame{
+				{Function: "main", Module: "main", AbsPath: "/home/user/app/main.go", Lineno: 9, InApp: true},
+				{Function: "worker", Module: "main", AbsPath: "/home/user/app/worker.go", Lineno: 17, InApp: true},
+			}},
+		},
+		{
+			ID: "9",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Threads mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseGoroutineHeader(t *testing.T) {
+	tests := map[string]string{
+		"goroutine 1 [running]:":             "1",
+		"goroutine 42 [select, 3 minutes]:":  "42",
+		"goroutine x [running]:":             "",
+		"main.main()":                        "",
+		"goroutine 18446744073709551615 [?]": "18446744073709551615",
+	}
+	for input, want := range tests {
+		got, _ := parseGoroutineHeader([]byte(input))
+		if got != want {
+			t.Errorf("parseGoroutineHeader(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestCaptureExceptionAttachThreads(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport:     transport,
+		AttachThreads: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.CaptureException(errors.New("oops"), nil, nil)
+
+	event := transport.lastEvent
+	if len(event.Threads) != 1 {
+		t.Fatalf("got %d threads, want 1", len(event.Threads))
+	}
+	thread := event.Threads[0]
+	if !thread.Current || thread.Crashed {
+		t.Errorf("got Current=%v Crashed=%v, want Current=true Crashed=false", thread.Current, thread.Crashed)
+	}
+	if thread.ID == "" || thread.ID != currentGoroutineID() {
+		t.Errorf("got thread ID %q, want %q", thread.ID, currentGoroutineID())
+	}
+	if got := event.Exception[len(event.Exception)-1].ThreadID; got != thread.ID {
+		t.Errorf("got exception ThreadID %q, want %q", got, thread.ID)
+	}
+}
+
+func TestRecoverAttachAllThreads(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport:        transport,
+		AttachAllThreads: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	go func() {
+		close(started)
+		<-block
+	}()
+	<-started
+
+	func() {
+		defer func() {
+			client.Recover(recover(), nil, nil)
+		}()
+		panic("test")
+	}()
+
+	event := transport.lastEvent
+	if len(event.Threads) < 2 {
+		t.Fatalf("got %d threads, want at least 2", len(event.Threads))
+	}
+	if !event.Threads[0].Crashed || !event.Threads[0].Current {
+		t.Errorf("first thread should be current and crashed: %+v", event.Threads[0])
+	}
+	for _, thread := range event.Threads[1:] {
+		if thread.Current || thread.Crashed {
+			t.Errorf("other threads should not be current nor crashed: %+v", thread)
+		}
+		if thread.ID == event.Threads[0].ID {
+			t.Errorf("current goroutine reported twice: %s", thread.ID)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package sentry
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -28,6 +27,9 @@ type Span struct { //nolint: maligned // prefer readability over optimal memory
 	StartTime    time.Time              `json:"start_timestamp"`
 	EndTime      time.Time              `json:"timestamp"`
 	Data         map[string]interface{} `json:"data,omitempty"`
+	// Origin tells what created the span, for example "manual" or an
+	// integration such as "auto.http.stdlib". Empty means "manual".
+	Origin string `json:"origin,omitempty"`
 
 	Sampled Sampled `json:"-"`
 
@@ -76,11 +78,13 @@ type Span struct { //nolint: maligned // prefer readability over optimal memory
 // transaction to Sentry.
 func StartSpan(ctx context.Context, operation string, options ...SpanOption) *Span {
 	parent, hasParent := ctx.Value(spanContextKey{}).(*Span)
+	client := hubFromContext(ctx).Client()
+	ids := idGeneratorFor(client)
 	var span Span
 	span = Span{
 		// defaults
 		Op:        operation,
-		StartTime: time.Now(),
+		StartTime: clockFor(client).Now(),
 
 		ctx:           context.WithValue(ctx, spanContextKey{}, &span),
 		parent:        parent,
@@ -89,45 +93,9 @@ func StartSpan(ctx context.Context, operation string, options ...SpanOption) *Sp
 	if hasParent {
 		span.TraceID = parent.TraceID
 	} else {
-		// Implementation note:
-		//
-		// While math/rand is ~2x faster than crypto/rand (exact
-		// difference depends on hardware / OS), crypto/rand is probably
-		// fast enough and a safer choice.
-		//
-		// For reference, OpenTelemetry [1] uses crypto/rand to seed
-		// math/rand. AFAICT this approach does not preserve the
-		// properties from crypto/rand that make it suitable for
-		// cryptography. While it might be debatable whether those
-		// properties are important for us here, again, we're taking the
-		// safer path.
-		//
-		// See [2a] & [2b] for a discussion of some of the properties we
-		// obtain by using crypto/rand and [3a] & [3b] for why we avoid
-		// math/rand.
-		//
-		// Because the math/rand seed has only 64 bits (int64), if the
-		// first thing we do after seeding an RNG is to read in a random
-		// TraceID, there are only 2^64 possible values. Compared to
-		// UUID v4 that have 122 random bits, there is a much greater
-		// chance of collision [4a] & [4b].
-		//
-		// [1]:  https://github.com/open-telemetry/opentelemetry-go/blob/958041ddf619a128/sdk/trace/trace.go#L25-L31
-		// [2a]: https://security.stackexchange.com/q/120352/246345
-		// [2b]: https://security.stackexchange.com/a/120365/246345
-		// [3a]: https://github.com/golang/go/issues/11871#issuecomment-126333686
-		// [3b]: https://github.com/golang/go/issues/11871#issuecomment-126357889
-		// [4a]: https://en.wikipedia.org/wiki/Universally_unique_identifier#Collisions
-		// [4b]: https://www.wolframalpha.com/input/?i=sqrt%282*2%5E64*ln%281%2F%281-0.5%29%29%29
-		_, err := rand.Read(span.TraceID[:])
-		if err != nil {
-			panic(err)
-		}
-	}
-	_, err := rand.Read(span.SpanID[:])
-	if err != nil {
-		panic(err)
+		span.TraceID = ids.TraceID()
 	}
+	span.SpanID = ids.SpanID()
 	if hasParent {
 		span.ParentSpanID = parent.SpanID
 	}
@@ -160,8 +128,9 @@ func (s *Span) Finish() {
 	// (incorrectly) calling it twice never double sends to Sentry.
 
 	if s.EndTime.IsZero() {
-		s.EndTime = monotonicTimeSince(s.StartTime)
+		s.EndTime = monotonicTimeSince(s.StartTime, clockFor(hubFromContext(s.ctx).Client()))
 	}
+	s.restoreTraceContext()
 	if !s.Sampled.Bool() {
 		return
 	}
@@ -180,6 +149,20 @@ func (s *Span) Finish() {
 	hub.CaptureEvent(event)
 }
 
+// restoreTraceContext links the events captured after the span is finished to
+// its closest unfinished ancestor, or to no span at all, by updating the trace
+// context of the scope if it still refers to the span.
+func (s *Span) restoreTraceContext() {
+	var tc *TraceContext
+	for p := s.parent; p != nil; p = p.parent {
+		if p.EndTime.IsZero() {
+			tc = p.traceContext()
+			break
+		}
+	}
+	hubFromContext(s.ctx).Scope().replaceTraceContext(s.SpanID, tc)
+}
+
 // Context returns the context containing the span.
 func (s *Span) Context() context.Context { return s.ctx }
 
@@ -193,14 +176,38 @@ func (s *Span) StartChild(operation string, options ...SpanOption) *Span {
 
 // SetTag sets a tag on the span. It is recommended to use SetTag instead of
 // accessing the tags map directly as SetTag takes care of initializing the map
-// when necessary.
+// when necessary, and normalizes tags as with Scope.SetTag.
 func (s *Span) SetTag(name, value string) {
+	name, value, ok := normalizeTag(name, value)
+	if !ok {
+		return
+	}
 	if s.Tags == nil {
 		s.Tags = make(map[string]string)
 	}
 	s.Tags[name] = value
 }
 
+// SetData sets a data attribute on the span, such as "http.method" or
+// "db.system". It is recommended to use SetData instead of accessing the data
+// map directly as SetData takes care of initializing the map when necessary.
+func (s *Span) SetData(name string, value interface{}) {
+	if s.Data == nil {
+		s.Data = make(map[string]interface{})
+	}
+	s.Data[name] = value
+}
+
+// SetMeasurement records a custom measurement on the transaction the span
+// belongs to, replacing any previous measurement with the same name. The unit
+// is one of those supported by Sentry, such as "millisecond", "byte" or
+// "percent", or empty for a unitless value.
+//
+//	span.SetMeasurement("queue_wait", 12.3, "millisecond")
+func (s *Span) SetMeasurement(name string, value float64, unit string) {
+	s.recorder.setMeasurement(name, Measurement{Value: value, Unit: unit})
+}
+
 // TODO(tracing): maybe add shortcuts to get/set transaction name. Right now the
 // transaction name is in the Scope, as it has existed there historically, prior
 // to tracing.
@@ -277,6 +284,15 @@ func (s *Span) MarshalJSON() ([]byte, error) {
 
 func (s *Span) sample() Sampled {
 	// https://develop.sentry.dev/sdk/unified-api/tracing/#sampling
+	// Non-transaction spans always inherit the parent decision, so that a
+	// span tree is either sent as a whole or not at all. TracesSampler and
+	// explicit decisions only apply to the root span.
+	// Note: non-transaction should always have a parent, but we check both
+	// conditions anyway -- the first for semantic meaning, the second to
+	// avoid a nil pointer dereference.
+	if !s.isTransaction && s.parent != nil {
+		return s.parent.Sampled
+	}
 	// #1 explicit sampling decision via StartSpan options.
 	if s.Sampled != SampledUndefined {
 		return s.Sampled
@@ -288,18 +304,10 @@ func (s *Span) sample() Sampled {
 		clientOptions = hub.Client().Options()
 	}
 	samplingContext := SamplingContext{Span: s, Parent: s.parent}
-	// Variant for non-transaction spans: they inherit the parent decision.
-	// TracesSampler only runs for the root span.
-	// Note: non-transaction should always have a parent, but we check both
-	// conditions anyway -- the first for semantic meaning, the second to
-	// avoid a nil pointer dereference.
-	if !s.isTransaction && s.parent != nil {
-		return s.parent.Sampled
-	}
 	// #2 use TracesSampler from ClientOptions.
 	sampler := clientOptions.TracesSampler
 	if sampler != nil {
-		return sampler.Sample(samplingContext)
+		return downsample(client, sampler.Sample(samplingContext))
 	}
 	// #3 inherit parent decision.
 	if s.parent != nil {
@@ -307,7 +315,7 @@ func (s *Span) sample() Sampled {
 	}
 	// #4 uniform sampling using TracesSampleRate.
 	sampler = UniformTracesSampler(clientOptions.TracesSampleRate)
-	return sampler.Sample(samplingContext)
+	return downsample(client, sampler.Sample(samplingContext))
 }
 
 func (s *Span) toEvent() *Event {
@@ -326,17 +334,24 @@ func (s *Span) toEvent() *Event {
 		finished = append(finished, child)
 	}
 
+	var info *TransactionInfo
+	if source := hub.Scope().TransactionSource(); source != "" {
+		info = &TransactionInfo{Source: source}
+	}
+
 	return &Event{
-		Type:        transactionType,
-		Transaction: hub.Scope().Transaction(),
+		Type:            transactionType,
+		Transaction:     hub.Scope().Transaction(),
+		TransactionInfo: info,
 		Contexts: map[string]interface{}{
 			"trace": s.traceContext(),
 		},
-		Tags:      s.Tags,
-		Extra:     s.Data,
-		Timestamp: s.EndTime,
-		StartTime: s.StartTime,
-		Spans:     finished,
+		Tags:         s.Tags,
+		Extra:        s.Data,
+		Timestamp:    s.EndTime,
+		StartTime:    s.StartTime,
+		Spans:        finished,
+		Measurements: s.recorder.measurements(),
 	}
 }
 
@@ -348,6 +363,7 @@ func (s *Span) traceContext() *TraceContext {
 		Op:           s.Op,
 		Description:  s.Description,
 		Status:       s.Status,
+		Origin:       s.Origin,
 	}
 }
 
@@ -371,6 +387,10 @@ func (id TraceID) MarshalText() ([]byte, error) {
 	return id.Hex(), nil
 }
 
+func (id *TraceID) UnmarshalText(text []byte) error {
+	return unmarshalHexID(id[:], text)
+}
+
 // SpanID identifies a span.
 type SpanID [8]byte
 
@@ -388,6 +408,20 @@ func (id SpanID) MarshalText() ([]byte, error) {
 	return id.Hex(), nil
 }
 
+func (id *SpanID) UnmarshalText(text []byte) error {
+	return unmarshalHexID(id[:], text)
+}
+
+// unmarshalHexID decodes the hex-encoded text into id. The length of text must
+// match the length of id.
+func unmarshalHexID(id []byte, text []byte) error {
+	if hex.DecodedLen(len(text)) != len(id) {
+		return fmt.Errorf("invalid ID length: %q", text)
+	}
+	_, err := hex.Decode(id, text)
+	return err
+}
+
 // Zero values of TraceID and SpanID used for comparisons.
 var (
 	zeroTraceID TraceID
@@ -462,6 +496,61 @@ func (ss SpanStatus) String() string {
 	return m[ss]
 }
 
+// HTTPtoSpanStatus returns the span status corresponding to an HTTP response
+// status code. Codes below 400 are successful, other codes map to the closest
+// gRPC-style status, and codes outside of the range of HTTP status codes map
+// to SpanStatusUnknown.
+func HTTPtoSpanStatus(code int) SpanStatus {
+	switch {
+	case code < 100 || code >= 600:
+		return SpanStatusUnknown
+	case code < 400:
+		return SpanStatusOK
+	case code < 500:
+		switch code {
+		case http.StatusUnauthorized:
+			return SpanStatusUnauthenticated
+		case http.StatusForbidden:
+			return SpanStatusPermissionDenied
+		case http.StatusNotFound:
+			return SpanStatusNotFound
+		case http.StatusConflict:
+			return SpanStatusAlreadyExists
+		case http.StatusRequestEntityTooLarge:
+			return SpanStatusFailedPrecondition
+		case http.StatusTooManyRequests:
+			return SpanStatusResourceExhausted
+		case 499: // Client Closed Request
+			return SpanStatusCanceled
+		}
+		return SpanStatusInvalidArgument
+	}
+	switch code {
+	case http.StatusNotImplemented:
+		return SpanStatusUnimplemented
+	case http.StatusServiceUnavailable:
+		return SpanStatusUnavailable
+	case http.StatusGatewayTimeout:
+		return SpanStatusDeadlineExceeded
+	}
+	return SpanStatusInternalError
+}
+
+// GRPCtoSpanStatus returns the span status corresponding to a gRPC status
+// code, as defined by google.golang.org/grpc/codes. Pass codes as uint32:
+//
+//	span.Status = sentry.GRPCtoSpanStatus(uint32(status.Code(err)))
+//
+// Unknown codes map to SpanStatusUnknown.
+func GRPCtoSpanStatus(code uint32) SpanStatus {
+	// Span statuses are declared in the order of gRPC codes, starting with
+	// OK = 0, after SpanStatusUndefined.
+	if code >= uint32(maxSpanStatus-1) {
+		return SpanStatusUnknown
+	}
+	return SpanStatus(code + 1)
+}
+
 func (ss SpanStatus) MarshalJSON() ([]byte, error) {
 	s := ss.String()
 	if s == "" {
@@ -470,6 +559,24 @@ func (ss SpanStatus) MarshalJSON() ([]byte, error) {
 	return json.Marshal(s)
 }
 
+func (ss *SpanStatus) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*ss = SpanStatusUndefined
+	if s == nil {
+		return nil
+	}
+	for i := SpanStatusUndefined; i < maxSpanStatus; i++ {
+		if i.String() == *s {
+			*ss = i
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown span status: %q", *s)
+}
+
 // A TraceContext carries information about an ongoing trace and is meant to be
 // stored in Event.Contexts (as *TraceContext).
 type TraceContext struct {
@@ -479,6 +586,7 @@ type TraceContext struct {
 	Op           string     `json:"op,omitempty"`
 	Description  string     `json:"description,omitempty"`
 	Status       SpanStatus `json:"status,omitempty"`
+	Origin       string     `json:"origin,omitempty"`
 }
 
 func (tc *TraceContext) MarshalJSON() ([]byte, error) {
@@ -531,7 +639,8 @@ func (s Sampled) Bool() bool {
 // A SpanOption is a function that can modify the properties of a span.
 type SpanOption func(s *Span)
 
-// The TransactionName option sets the name of the current transaction.
+// The TransactionName option sets the name of the current transaction, with
+// the source SourceCustom.
 //
 // A span tree has a single transaction name, therefore using this option when
 // starting a span affects the span tree as a whole, potentially overwriting a
@@ -542,12 +651,33 @@ func TransactionName(name string) SpanOption {
 	}
 }
 
-// ContinueFromRequest returns a span option that updates the span to continue
-// an existing trace. If it cannot detect an existing trace in the request, the
-// span will be left unchanged.
-func ContinueFromRequest(r *http.Request) SpanOption {
+// The WithTransactionName option is the same as TransactionName, named after
+// the other span options.
+func WithTransactionName(name string) SpanOption {
+	return TransactionName(name)
+}
+
+// The WithSpanSampled option forces the sampling decision of a transaction,
+// bypassing TracesSampleRate and TracesSampler. Use it for critical paths that
+// must always be traced, or in tests:
+//
+//	span := sentry.StartSpan(ctx, "checkout", sentry.WithSpanSampled(sentry.SampledTrue))
+//
+// Child spans always inherit the decision of their parent, therefore the
+// option has no effect on them.
+func WithSpanSampled(sampled Sampled) SpanOption {
+	return func(s *Span) {
+		s.Sampled = sampled
+	}
+}
+
+// ContinueFromTrace returns a span option that updates the span to continue
+// the trace described by trace, the value of a sentry-trace header as returned
+// by Span.ToSentryTrace. Use it to continue traces propagated by means other
+// than HTTP requests, for example in the headers of queue messages. If trace
+// is empty, the span will be left unchanged.
+func ContinueFromTrace(trace string) SpanOption {
 	return func(s *Span) {
-		trace := r.Header.Get("sentry-trace")
 		if trace == "" {
 			return
 		}
@@ -555,6 +685,61 @@ func ContinueFromRequest(r *http.Request) SpanOption {
 	}
 }
 
+// The WithTransactionSource option sets the source of the name of the current
+// transaction. Use it after TransactionName, which resets the source to
+// SourceCustom:
+//
+//	span := sentry.StartSpan(ctx, "http.server",
+//		sentry.TransactionName("GET /users/{id}"),
+//		sentry.WithTransactionSource(sentry.SourceRoute),
+//	)
+func WithTransactionSource(source TransactionSource) SpanOption {
+	return func(s *Span) {
+		hubFromContext(s.Context()).Scope().SetTransactionSource(source)
+	}
+}
+
+// The WithSpanOrigin option sets the origin of the span. Integrations use it
+// to tell the spans they create from those created by users:
+//
+//	span := sentry.StartSpan(ctx, "db.sql", sentry.WithSpanOrigin("auto.db.gorm"))
+func WithSpanOrigin(origin string) SpanOption {
+	return func(s *Span) {
+		s.Origin = origin
+	}
+}
+
+// TransactionSource tells how the name of a transaction was determined. Sentry
+// uses it to decide whether names can be grouped further: names made from raw
+// URLs contain identifiers and are clustered server-side, while route patterns
+// and custom names are kept as they are.
+type TransactionSource string
+
+// Transaction sources, as defined by the Sentry protocol.
+const (
+	// SourceCustom is for names set by the user.
+	SourceCustom TransactionSource = "custom"
+	// SourceURL is for names made from the raw URL of a request, which may
+	// contain identifiers.
+	SourceURL TransactionSource = "url"
+	// SourceRoute is for names made from the parameterized route of a
+	// request, for example "/users/{id}".
+	SourceRoute TransactionSource = "route"
+	// SourceView is for names of the handler of a request.
+	SourceView TransactionSource = "view"
+	// SourceComponent is for names of components, like serverless functions.
+	SourceComponent TransactionSource = "component"
+	// SourceTask is for names of background tasks and message consumers.
+	SourceTask TransactionSource = "task"
+)
+
+// ContinueFromRequest returns a span option that updates the span to continue
+// an existing trace. If it cannot detect an existing trace in the request, the
+// span will be left unchanged.
+func ContinueFromRequest(r *http.Request) SpanOption {
+	return ContinueFromTrace(r.Header.Get("sentry-trace"))
+}
+
 // spanContextKey is used to store span values in contexts.
 type spanContextKey struct{}
 
@@ -63,6 +63,51 @@ func TestSpanStatusMarshalJSON(t *testing.T) {
 	}
 }
 
+func TestHTTPtoSpanStatus(t *testing.T) {
+	tests := map[int]SpanStatus{
+		0:   SpanStatusUnknown,
+		200: SpanStatusOK,
+		204: SpanStatusOK,
+		302: SpanStatusOK,
+		400: SpanStatusInvalidArgument,
+		401: SpanStatusUnauthenticated,
+		403: SpanStatusPermissionDenied,
+		404: SpanStatusNotFound,
+		409: SpanStatusAlreadyExists,
+		418: SpanStatusInvalidArgument,
+		429: SpanStatusResourceExhausted,
+		499: SpanStatusCanceled,
+		500: SpanStatusInternalError,
+		501: SpanStatusUnimplemented,
+		503: SpanStatusUnavailable,
+		504: SpanStatusDeadlineExceeded,
+		600: SpanStatusUnknown,
+	}
+	for code, want := range tests {
+		if got := HTTPtoSpanStatus(code); got != want {
+			t.Errorf("HTTPtoSpanStatus(%d) = %s, want %s", code, got, want)
+		}
+	}
+}
+
+func TestGRPCtoSpanStatus(t *testing.T) {
+	tests := map[uint32]SpanStatus{
+		0:  SpanStatusOK,
+		1:  SpanStatusCanceled,
+		2:  SpanStatusUnknown,
+		5:  SpanStatusNotFound,
+		13: SpanStatusInternalError,
+		14: SpanStatusUnavailable,
+		16: SpanStatusUnauthenticated,
+		17: SpanStatusUnknown,
+	}
+	for code, want := range tests {
+		if got := GRPCtoSpanStatus(code); got != want {
+			t.Errorf("GRPCtoSpanStatus(%d) = %s, want %s", code, got, want)
+		}
+	}
+}
+
 func TestTraceContextMarshalJSON(t *testing.T) {
 	tc := &TraceContext{}
 	testMarshalJSONOmitEmptyParentSpanID(t, tc)
@@ -128,8 +173,9 @@ func TestStartSpan(t *testing.T) {
 		t.Fatalf("sent %d events, want 1", got)
 	}
 	want := &Event{
-		Type:        transactionType,
-		Transaction: transaction,
+		Type:            transactionType,
+		Transaction:     transaction,
+		TransactionInfo: &TransactionInfo{Source: SourceCustom},
 		Contexts: map[string]interface{}{
 			"trace": &TraceContext{
 				TraceID:      span.TraceID,
@@ -150,7 +196,7 @@ func TestStartSpan(t *testing.T) {
 	}
 	opts := cmp.Options{
 		cmpopts.IgnoreFields(Event{},
-			"Contexts", "EventID", "Level", "Platform",
+			"Contexts", "EventID", "Level", "Modules", "Platform",
 			"Release", "Sdk", "ServerName",
 		),
 		cmpopts.EquateEmpty(),
@@ -188,8 +234,9 @@ func TestStartChild(t *testing.T) {
 		t.Fatalf("sent %d events, want 1", got)
 	}
 	want := &Event{
-		Type:        transactionType,
-		Transaction: "Test Transaction",
+		Type:            transactionType,
+		Transaction:     "Test Transaction",
+		TransactionInfo: &TransactionInfo{Source: SourceCustom},
 		Contexts: map[string]interface{}{
 			"trace": &TraceContext{
 				TraceID: span.TraceID,
@@ -209,7 +256,7 @@ func TestStartChild(t *testing.T) {
 	}
 	opts := cmp.Options{
 		cmpopts.IgnoreFields(Event{},
-			"EventID", "Level", "Platform",
+			"EventID", "Level", "Modules", "Platform",
 			"Release", "Sdk", "ServerName", "Timestamp", "StartTime",
 		),
 		cmpopts.IgnoreMapEntries(func(k string, v interface{}) bool {
@@ -226,6 +273,107 @@ func TestStartChild(t *testing.T) {
 	}
 }
 
+func TestSetMeasurement(t *testing.T) {
+	transport := &TransportMock{}
+	ctx := NewTestContext(ClientOptions{
+		TracesSampleRate: 1.0,
+		Transport:        transport,
+	})
+	span := StartSpan(ctx, "top", TransactionName("Test Transaction"))
+	span.SetMeasurement("queue_wait", 10, "millisecond")
+	child := span.StartChild("child")
+	child.SetMeasurement("queue_wait", 12.3, "millisecond")
+	child.SetMeasurement("items", 3, "")
+	child.Finish()
+	span.Finish()
+
+	events := transport.Events()
+	if got := len(events); got != 1 {
+		t.Fatalf("sent %d events, want 1", got)
+	}
+	want := map[string]Measurement{
+		"queue_wait": {Value: 12.3, Unit: "millisecond"},
+		"items":      {Value: 3},
+	}
+	assertEqual(t, events[0].Measurements, want)
+}
+
+func TestSpanOriginAndData(t *testing.T) {
+	transport := &TransportMock{}
+	ctx := NewTestContext(ClientOptions{
+		TracesSampleRate: 1.0,
+		Transport:        transport,
+	})
+	span := StartSpan(ctx, "top", TransactionName("Test Transaction"), WithSpanOrigin("auto.test"))
+	child := span.StartChild("child")
+	child.SetData("db.system", "sqlite")
+	child.SetTag("db.table", "users")
+	child.Finish()
+	span.Finish()
+
+	events := transport.Events()
+	if got := len(events); got != 1 {
+		t.Fatalf("sent %d events, want 1", got)
+	}
+	trace := events[0].Contexts["trace"].(*TraceContext)
+	assertEqual(t, trace.Origin, "auto.test")
+	spans := events[0].Spans
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	assertEqual(t, spans[0].Origin, "")
+	assertEqual(t, spans[0].Data, map[string]interface{}{"db.system": "sqlite"})
+	assertEqual(t, spans[0].Tags, map[string]string{"db.table": "users"})
+
+	b, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte(`"origin":"auto.test"`)) {
+		t.Errorf("missing origin: %s", b)
+	}
+}
+
+func TestErrorTraceContext(t *testing.T) {
+	transport := &TransportMock{}
+	ctx := NewTestContext(ClientOptions{
+		TracesSampleRate: 1.0,
+		Transport:        transport,
+	})
+	hub := GetHubFromContext(ctx)
+	traceContext := func() *TraceContext {
+		t.Helper()
+		events := transport.Events()
+		tc, _ := events[len(events)-1].Contexts["trace"].(*TraceContext)
+		return tc
+	}
+
+	span := StartSpan(ctx, "top", TransactionName("Test Transaction"))
+	child := span.StartChild("child")
+	hub.CaptureMessage("in child")
+	tc := traceContext()
+	if tc == nil {
+		t.Fatal("missing trace context")
+	}
+	assertEqual(t, tc.TraceID, span.TraceID)
+	assertEqual(t, tc.SpanID, child.SpanID)
+	assertEqual(t, tc.ParentSpanID, span.SpanID)
+
+	child.Finish()
+	hub.CaptureMessage("in transaction")
+	tc = traceContext()
+	if tc == nil {
+		t.Fatal("missing trace context")
+	}
+	assertEqual(t, tc.SpanID, span.SpanID)
+
+	span.Finish()
+	hub.CaptureMessage("after transaction")
+	if tc := traceContext(); tc != nil {
+		t.Errorf("got trace context %+v after the transaction finished, want none", tc)
+	}
+}
+
 // testContextKey is used to store a value in a context so that we can check
 // that SDK operations on that context preserve the original context values.
 type testContextKey struct{}
@@ -392,3 +540,110 @@ func TestDoubleSampling(t *testing.T) {
 		t.Fatalf("got %v event, want %v", got, transactionType)
 	}
 }
+
+func TestWithSpanSampled(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampled    Sampled
+		wantEvents int
+	}{
+		{"ForceSampled", SampledTrue, 1},
+		{"ForceNotSampled", SampledFalse, 0},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &TransportMock{}
+			ctx := NewTestContext(ClientOptions{
+				TracesSampler: TracesSamplerFunc(func(ctx SamplingContext) Sampled {
+					t.Error("TracesSampler called for a forced decision")
+					return -tt.sampled
+				}),
+				Transport: transport,
+			})
+			span := StartSpan(ctx, "op", WithTransactionName("name"), WithSpanSampled(tt.sampled))
+			// Children inherit the decision of their parent whatever
+			// their options.
+			child := span.StartChild("child", WithSpanSampled(-tt.sampled))
+			assertEqual(t, span.Sampled, tt.sampled)
+			assertEqual(t, child.Sampled, tt.sampled)
+			child.Finish()
+			span.Finish()
+			if got := len(transport.Events()); got != tt.wantEvents {
+				t.Fatalf("got %d events, want %d", got, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestContinueFromTrace(t *testing.T) {
+	traceID := TraceIDFromHex("bc6d53f15eb88f4320054569b8c553d4")
+	spanID := SpanIDFromHex("b72fa28504b07285")
+
+	var s Span
+	ContinueFromTrace("")(&s)
+	assertEqual(t, s, Span{})
+
+	ContinueFromTrace("bc6d53f15eb88f4320054569b8c553d4-b72fa28504b07285-1")(&s)
+	assertEqual(t, s.TraceID, traceID)
+	assertEqual(t, s.ParentSpanID, spanID)
+	assertEqual(t, s.Sampled, SampledTrue)
+}
+
+func TestSpanClock(t *testing.T) {
+	clock := &ClockMock{now: time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)}
+	transport := &TransportMock{}
+	ctx := NewTestContext(ClientOptions{
+		TracesSampleRate: 1.0,
+		Transport:        transport,
+		Clock:            clock,
+	})
+	span := StartSpan(ctx, "op", TransactionName("Test Transaction"))
+	clock.Advance(3 * time.Second)
+	span.Finish()
+
+	assertEqual(t, span.StartTime, time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC))
+	assertEqual(t, span.EndTime.Sub(span.StartTime), 3*time.Second)
+}
+
+func TestSpanMonotonicDuration(t *testing.T) {
+	ctx := NewTestContext(ClientOptions{Transport: &TransportMock{}})
+	span := StartSpan(ctx, "op")
+	time.Sleep(time.Millisecond)
+	span.Finish()
+
+	// Round(0) strips monotonic clock readings, leaving the wall clock times
+	// that are serialized and sent to Sentry.
+	if d := span.EndTime.Round(0).Sub(span.StartTime.Round(0)); d < time.Millisecond {
+		t.Errorf("got duration %v, want at least 1ms", d)
+	}
+}
+
+func TestSpanUnmarshalJSON(t *testing.T) {
+	want := &Span{
+		TraceID:      TraceIDFromHex("d6c4f03650bd47699ec65c84352b6208"),
+		SpanID:       SpanIDFromHex("1cc4b26ab9094ef0"),
+		ParentSpanID: SpanIDFromHex("442bd97bbe564317"),
+		Op:           "op",
+		Status:       SpanStatusDeadlineExceeded,
+		StartTime:    time.Unix(8, 0).UTC(),
+		EndTime:      time.Unix(10, 0).UTC(),
+	}
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &Span{}
+	if err := json.Unmarshal(b, got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(Span{})); diff != "" {
+		t.Errorf("Span mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, s := range []string{`{"trace_id":"abc"}`, `{"status":"bogus"}`} {
+		if err := json.Unmarshal([]byte(s), &Span{}); err == nil {
+			t.Errorf("expected error unmarshaling %s", s)
+		}
+	}
+}
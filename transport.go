@@ -2,15 +2,19 @@ package sentry
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/getsentry/sentry-go/internal/ratelimit"
@@ -18,6 +22,8 @@ import (
 
 const defaultBufferSize = 30
 const defaultTimeout = time.Second * 30
+const defaultBlockTimeout = time.Second
+const defaultPriorityBufferSize = 5
 
 // maxDrainResponseBytes is the maximum number of bytes that transport
 // implementations will read from response bodies when draining them.
@@ -37,6 +43,63 @@ type Transport interface {
 	SendEvent(event *Event)
 }
 
+// A ContextTransport is a Transport that honors contexts, such that callers can
+// bound how long sending and flushing events block, for example during the
+// shutdown of a program or when the request that captured an event is
+// canceled.
+//
+// Clients send events with the Context of the EventHint, if any, and flush
+// with the given context in Client.FlushWithContext.
+type ContextTransport interface {
+	Transport
+	SendEventWithContext(ctx context.Context, event *Event)
+	FlushWithContext(ctx context.Context) bool
+}
+
+// detachedContext is a context with the values of another one, but not its
+// deadline and cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// sendEventWithContext sends the event with transport, honoring ctx if the
+// transport is a ContextTransport.
+func sendEventWithContext(ctx context.Context, transport Transport, event *Event) {
+	if t, ok := transport.(ContextTransport); ok {
+		t.SendEventWithContext(ctx, event)
+		return
+	}
+	transport.SendEvent(event)
+}
+
+// flushWithContext flushes transport until ctx is done. Transports that are
+// not a ContextTransport are flushed with a timeout until the deadline of ctx,
+// or of defaultTimeout if it has none, and flushing is abandoned once ctx is
+// done.
+func flushWithContext(ctx context.Context, transport Transport) bool {
+	if t, ok := transport.(ContextTransport); ok {
+		return t.FlushWithContext(ctx)
+	}
+	timeout := defaultTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	done := make(chan bool, 1)
+	go func() {
+		done <- transport.Flush(timeout)
+	}()
+	select {
+	case ok := <-done:
+		return ok
+	case <-ctx.Done():
+		return false
+	}
+}
+
 func getProxyConfig(options ClientOptions) func(*http.Request) (*url.URL, error) {
 	if options.HTTPSProxy != "" {
 		return func(_ *http.Request) (*url.URL, error) {
@@ -61,12 +124,40 @@ func getTLSConfig(options ClientOptions) *tls.Config {
 	return nil
 }
 
+// UnixSocketDialer returns a function that dials the Unix socket at path,
+// whatever the address, for use as ClientOptions.HTTPDialContext when Sentry,
+// or a relay, is only reachable through a local socket:
+//
+//	sentry.Init(sentry.ClientOptions{
+//		Dsn:             "http://public@relay/1",
+//		HTTPDialContext: sentry.UnixSocketDialer("/run/relay/relay.sock"),
+//	})
+func UnixSocketDialer(path string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+}
+
 func getRequestBodyFromEvent(event *Event) []byte {
-	body, err := json.Marshal(event)
+	body, err := marshalEvent(event)
 	if err == nil {
 		return body
 	}
 
+	var p serializationPanic
+	if errors.As(err, &p) {
+		// A panic is likely caused by a method of a value attached to the
+		// event, like MarshalJSON. Replace all such values.
+		reduceEvent(event, err.Error())
+		if body, err = marshalEvent(event); err == nil {
+			Logger.Printf("Could not encode original event as JSON. "+
+				"Succeeded by reducing Breadcrumbs, Contexts and Extra to primitive values. "+
+				"Error: %s", p)
+			return body
+		}
+	}
+
 	msg := fmt.Sprintf("Could not encode original event as JSON. "+
 		"Succeeded by removing Breadcrumbs, Contexts and Extra. "+
 		"Please verify the data you attach to the scope. "+
@@ -77,7 +168,7 @@ func getRequestBodyFromEvent(event *Event) []byte {
 	event.Extra = map[string]interface{}{
 		"info": msg,
 	}
-	body, err = json.Marshal(event)
+	body, err = marshalEvent(event)
 	if err == nil {
 		Logger.Println(msg)
 		return body
@@ -91,9 +182,250 @@ func getRequestBodyFromEvent(event *Event) []byte {
 	return nil
 }
 
-func transactionEnvelopeFromBody(eventID EventID, sentAt time.Time, body json.RawMessage) (*bytes.Buffer, error) {
-	var b bytes.Buffer
-	enc := json.NewEncoder(&b)
+// serializationPanic is the error of marshalEvent when the encoding panics.
+type serializationPanic struct {
+	value interface{}
+}
+
+func (p serializationPanic) Error() string {
+	return fmt.Sprintf("panic while encoding the event: %v", p.value)
+}
+
+// marshalEvent returns the JSON encoding of the event. Panics, of the
+// MarshalJSON method of a value attached to the event for example, are
+// returned as a serializationPanic.
+func marshalEvent(event *Event) (body []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			body, err = nil, serializationPanic{r}
+		}
+	}()
+	return json.Marshal(event)
+}
+
+// serializationErrorTag is the tag of the events that were reduced because
+// they could not be prepared or encoded, with the reason as value.
+const serializationErrorTag = "serialization_error"
+
+// reduceEvent replaces the values of arbitrary types attached to the event,
+// in Extra, Contexts and the data of breadcrumbs, spans and frames, with
+// primitive values, and tags the event with the reason.
+func reduceEvent(event *Event, reason string) {
+	// Maps, breadcrumbs and spans may be shared with scopes and transactions,
+	// so they are replaced rather than modified.
+	event.Extra = primitiveMap(event.Extra)
+	if event.Contexts != nil {
+		contexts := make(map[string]interface{}, len(event.Contexts))
+		for key, value := range event.Contexts {
+			switch value.(type) {
+			case *TraceContext, TraceContext:
+				// The trace context is encoded by the SDK.
+				contexts[key] = value
+			default:
+				contexts[key] = primitive(value)
+			}
+		}
+		event.Contexts = contexts
+	}
+	breadcrumbs := make([]*Breadcrumb, 0, len(event.Breadcrumbs))
+	for _, b := range event.Breadcrumbs {
+		if b != nil {
+			reduced := *b
+			reduced.Data = primitiveMap(b.Data)
+			breadcrumbs = append(breadcrumbs, &reduced)
+		}
+	}
+	event.Breadcrumbs = breadcrumbs
+	spans := make([]*Span, 0, len(event.Spans))
+	for _, span := range event.Spans {
+		if span != nil {
+			reduced := *span
+			reduced.Data = primitiveMap(span.Data)
+			spans = append(spans, &reduced)
+		}
+	}
+	event.Spans = spans
+	reduceFrames := func(st *Stacktrace) {
+		if st == nil {
+			return
+		}
+		for i := range st.Frames {
+			st.Frames[i].Vars = primitiveMap(st.Frames[i].Vars)
+		}
+	}
+	for _, e := range event.Exception {
+		reduceFrames(e.Stacktrace)
+	}
+	for _, t := range event.Threads {
+		reduceFrames(t.Stacktrace)
+	}
+	tags := make(map[string]string, len(event.Tags)+1)
+	for key, value := range event.Tags {
+		tags[key] = value
+	}
+	tags[serializationErrorTag] = truncate(reason, maxTagValueLength)
+	event.Tags = tags
+}
+
+// primitiveMap returns a copy of m with its values replaced by primitive
+// values.
+func primitiveMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	reduced := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		reduced[key] = primitive(value)
+	}
+	return reduced
+}
+
+// primitive returns v if it is a primitive value, the reduced map or slice if
+// it is a map[string]interface{} or an []interface{}, and the formatted value
+// otherwise. The fmt package recovers from panics of the methods of v, like
+// String.
+func primitive(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool, string,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, uintptr,
+		float32, float64:
+		return v
+	case map[string]interface{}:
+		return primitiveMap(v)
+	case []interface{}:
+		reduced := make([]interface{}, len(v))
+		for i, value := range v {
+			reduced[i] = primitive(value)
+		}
+		return reduced
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+// maxPooledBufferBytes is the maximum capacity of the buffers returned to
+// bufferPool, so that an unusually large event does not stay in memory.
+const maxPooledBufferBytes = 256 * 1024
+
+// bufferPool holds the buffers envelopes are serialized into, so that
+// transports sending many events reuse them rather than allocating new ones.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer from bufferPool.
+func getBuffer() *bytes.Buffer {
+	b := bufferPool.Get().(*bytes.Buffer)
+	b.Reset()
+	return b
+}
+
+// putBuffer returns b to bufferPool. b must not be used afterwards.
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBufferBytes {
+		return
+	}
+	bufferPool.Put(b)
+}
+
+// A pooledBody is a request body read from a buffer of bufferPool. The buffer
+// is returned to the pool once the body is closed and the request is sent,
+// not on Close alone: net/http closes the body before calling GetBody to send
+// the request again, after a stale keep-alive connection or a 307 or 308
+// redirect. HTTP transports may close bodies concurrently with reads.
+type pooledBody struct {
+	mu  sync.Mutex
+	buf *bytes.Buffer
+	// data is the whole body, for GetBody.
+	data   []byte
+	closed bool
+	sent   bool
+}
+
+func (b *pooledBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed || b.buf == nil {
+		return 0, io.EOF
+	}
+	return b.buf.Read(p)
+}
+
+func (b *pooledBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.release()
+	return nil
+}
+
+// finish records that the request was sent, after which GetBody is no longer
+// called.
+func (b *pooledBody) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent = true
+	b.release()
+}
+
+// release returns the buffer to the pool once the body is closed and the
+// request sent. b.mu must be held.
+func (b *pooledBody) release() {
+	if b.closed && b.sent && b.buf != nil {
+		putBuffer(b.buf)
+		b.buf, b.data = nil, nil
+	}
+}
+
+// getBody returns a copy of the body, to send the request again.
+func (b *pooledBody) getBody() (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf == nil {
+		return nil, errors.New("request body already released")
+	}
+	return ioutil.NopCloser(bytes.NewReader(append([]byte(nil), b.data...))), nil
+}
+
+// newPooledRequest returns a POST request to url with the content of b, a
+// buffer of bufferPool, as body. b is returned to the pool once the request is
+// sent with doRequest.
+func newPooledRequest(url string, b *bytes.Buffer) (*http.Request, error) {
+	body := &pooledBody{buf: b, data: b.Bytes()}
+	request, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		putBuffer(b)
+		return nil, err
+	}
+	request.ContentLength = int64(b.Len())
+	request.GetBody = body.getBody
+	return request, nil
+}
+
+// sdkRequestContextKey marks the requests sent by the SDK, such that they are
+// not recorded by NewBreadcrumbTransport.
+const sdkRequestContextKey = contextKey(3)
+
+// sdkRequest returns request marked as sent by the SDK.
+func sdkRequest(request *http.Request) *http.Request {
+	return request.WithContext(context.WithValue(request.Context(), sdkRequestContextKey, true))
+}
+
+// doRequest sends request with client, and then releases its body if it is a
+// pooledBody.
+func doRequest(client *http.Client, request *http.Request) (*http.Response, error) {
+	response, err := client.Do(sdkRequest(request))
+	if body, ok := request.Body.(*pooledBody); ok {
+		body.finish()
+	}
+	return response, err
+}
+
+// envelopeFromBody returns an envelope with the body as single item, in a
+// buffer of bufferPool.
+func envelopeFromBody(eventID EventID, itemType string, sentAt time.Time, body json.RawMessage) (*bytes.Buffer, error) {
+	b := getBuffer()
+	enc := json.NewEncoder(b)
 	// envelope header
 	err := enc.Encode(struct {
 		EventID EventID   `json:"event_id"`
@@ -103,6 +435,7 @@ func transactionEnvelopeFromBody(eventID EventID, sentAt time.Time, body json.Ra
 		SentAt:  sentAt,
 	})
 	if err != nil {
+		putBuffer(b)
 		return nil, err
 	}
 	// item header
@@ -110,18 +443,17 @@ func transactionEnvelopeFromBody(eventID EventID, sentAt time.Time, body json.Ra
 		Type   string `json:"type"`
 		Length int    `json:"length"`
 	}{
-		Type:   transactionType,
+		Type:   itemType,
 		Length: len(body),
 	})
 	if err != nil {
+		putBuffer(b)
 		return nil, err
 	}
-	// payload
-	err = enc.Encode(body)
-	if err != nil {
-		return nil, err
-	}
-	return &b, nil
+	// payload, already encoded by getRequestBodyFromEvent
+	b.Write(body)
+	b.WriteByte('\n')
+	return b, nil
 }
 
 func getRequestFromEvent(event *Event, dsn *Dsn) (r *http.Request, err error) {
@@ -134,16 +466,12 @@ func getRequestFromEvent(event *Event, dsn *Dsn) (r *http.Request, err error) {
 	if body == nil {
 		return nil, errors.New("event could not be marshaled")
 	}
-	if event.Type == transactionType {
-		b, err := transactionEnvelopeFromBody(event.EventID, time.Now(), body)
+	if event.Type == transactionType || event.Type == checkInType {
+		b, err := envelopeFromBody(event.EventID, event.Type, time.Now(), body)
 		if err != nil {
 			return nil, err
 		}
-		return http.NewRequest(
-			http.MethodPost,
-			dsn.EnvelopeAPIURL().String(),
-			b,
-		)
+		return newPooledRequest(dsn.EnvelopeAPIURL().String(), b)
 	}
 	return http.NewRequest(
 		http.MethodPost,
@@ -152,6 +480,165 @@ func getRequestFromEvent(event *Event, dsn *Dsn) (r *http.Request, err error) {
 	)
 }
 
+// An EventEndpoint selects the endpoint of the Sentry server that the HTTP
+// transports send error events to. Transactions and check-ins are always sent
+// to the envelope endpoint, and all events are sent as envelopes to a Relay.
+type EventEndpoint int
+
+// Endpoints of error events.
+const (
+	// EventEndpointAuto sends error events to the envelope endpoint, and
+	// falls back to the legacy store endpoint for subsequent events once the
+	// server rejects an envelope with a 400 or 404 status, as older
+	// self-hosted versions of Sentry do. The rejected event is sent again to
+	// the store endpoint. It is the default.
+	EventEndpointAuto EventEndpoint = iota
+	// EventEndpointEnvelope always sends error events to the envelope
+	// endpoint.
+	EventEndpointEnvelope
+	// EventEndpointStore always sends error events to the legacy store
+	// endpoint.
+	EventEndpointStore
+)
+
+// An eventEndpoint tracks the endpoint of error events of a transport.
+type eventEndpoint struct {
+	mode EventEndpoint
+	// fellBack is set to 1 once the server rejected an envelope in
+	// EventEndpointAuto mode.
+	fellBack int32
+}
+
+// store reports whether error events are sent to the store endpoint.
+func (e *eventEndpoint) store() bool {
+	switch e.mode {
+	case EventEndpointStore:
+		return true
+	case EventEndpointAuto:
+		return atomic.LoadInt32(&e.fellBack) == 1
+	}
+	return false
+}
+
+// canFallBack reports whether the event, sent with store set as returned by
+// the store method, can be sent again to the store endpoint.
+func (e *eventEndpoint) canFallBack(event *Event, store bool) bool {
+	return e.mode == EventEndpointAuto && !store && event.Type == ""
+}
+
+// fallBack reports whether response rejects an error event envelope, in which
+// case subsequent error events are sent to the store endpoint.
+func (e *eventEndpoint) fallBack(response *http.Response) bool {
+	if response.StatusCode != http.StatusBadRequest && response.StatusCode != http.StatusNotFound {
+		return false
+	}
+	if atomic.CompareAndSwapInt32(&e.fellBack, 0, 1) {
+		Logger.Printf("Envelope rejected with status %d, sending error events to the store endpoint", response.StatusCode)
+	}
+	return true
+}
+
+// newEventRequest returns the request sending the event to the project of
+// dsn, using the Sentry protocol version, or through the Relay at relay if not
+// nil. Error events are sent to the store endpoint if store is set, and as
+// envelopes otherwise.
+func newEventRequest(event *Event, dsn *Dsn, relay *url.URL, version string, store bool) (*http.Request, error) {
+	if relay != nil {
+		// Relay accepts all kinds of events as envelopes.
+		request, err := newEnvelopeRequest(event, dsn.relayEnvelopeURL(relay))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/x-sentry-envelope")
+		return request, nil
+	}
+
+	var request *http.Request
+	var err error
+	if store || event.Type == transactionType || event.Type == checkInType {
+		request, err = getRequestFromEvent(event, dsn)
+	} else {
+		request, err = newEnvelopeRequest(event, dsn.EnvelopeAPIURL())
+	}
+	if err != nil {
+		return nil, err
+	}
+	for headerKey, headerValue := range dsn.requestHeaders(version, time.Now()) {
+		request.Header.Set(headerKey, headerValue)
+	}
+	return request, nil
+}
+
+// newEnvelopeRequest returns the request sending the event as an envelope to
+// u.
+func newEnvelopeRequest(event *Event, u *url.URL) (*http.Request, error) {
+	body := getRequestBodyFromEvent(event)
+	if body == nil {
+		return nil, errors.New("event could not be marshaled")
+	}
+	itemType := event.Type
+	if itemType == "" {
+		itemType = "event"
+	}
+	b, err := envelopeFromBody(event.EventID, itemType, time.Now(), body)
+	if err != nil {
+		return nil, err
+	}
+	request, err := newPooledRequest(u.String(), b)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", userAgent)
+	return request, nil
+}
+
+// drainAndClose drains the body of response up to a limit and closes it,
+// allowing the transport to reuse TCP connections.
+func drainAndClose(response *http.Response) {
+	_, _ = io.CopyN(ioutil.Discard, response.Body, maxDrainResponseBytes)
+	response.Body.Close()
+}
+
+// relayURL returns the parsed RelayURL of the options, or nil if it is empty
+// or invalid.
+func relayURL(options ClientOptions) *url.URL {
+	if options.RelayURL == "" {
+		return nil
+	}
+	u, err := url.Parse(options.RelayURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		Logger.Printf("Ignoring invalid RelayURL %q", options.RelayURL)
+		return nil
+	}
+	Logger.Printf("Sending events through the Relay at %s", u.Host)
+	return u
+}
+
+// protocolVersion returns the ProtocolVersion of the options, or apiVersion
+// if it is empty or invalid.
+func protocolVersion(options ClientOptions) string {
+	if options.ProtocolVersion == "" {
+		return apiVersion
+	}
+	if v, err := strconv.Atoi(options.ProtocolVersion); err != nil || v < 1 {
+		Logger.Printf("Ignoring invalid ProtocolVersion %q", options.ProtocolVersion)
+		return apiVersion
+	}
+	return options.ProtocolVersion
+}
+
+// describeEvent returns a description of the kind of event, for logging.
+func describeEvent(event *Event) string {
+	switch event.Type {
+	case transactionType:
+		return "transaction"
+	case checkInType:
+		return "check-in"
+	default:
+		return fmt.Sprintf("%s event", event.Level)
+	}
+}
+
 func categoryFor(eventType string) ratelimit.Category {
 	switch eventType {
 	case "":
@@ -169,16 +656,41 @@ func categoryFor(eventType string) ratelimit.Category {
 
 // A batch groups items that are processed sequentially.
 type batch struct {
-	items   chan batchItem
-	started chan struct{} // closed to signal items started to be worked on
-	done    chan struct{} // closed to signal completion of all items
+	items chan batchItem
+	// priority holds fatal events, processed before items. It is never
+	// closed: the worker drains it once items is closed.
+	priority chan batchItem
+	started  chan struct{} // closed to signal items started to be worked on
+	done     chan struct{} // closed to signal completion of all items
 }
 
 type batchItem struct {
+	eventID  EventID
 	request  *http.Request
 	category ratelimit.Category
+	// fallback is the event of an error event envelope request, sent again
+	// to the store endpoint if the server rejects the envelope.
+	fallback *Event
 }
 
+// An OverflowPolicy decides what HTTPTransport does with events sent when its
+// buffer is full.
+type OverflowPolicy int
+
+// Overflow policies.
+const (
+	// DropNewest drops the event being sent, keeping SendEvent non-blocking.
+	// It is the default.
+	DropNewest OverflowPolicy = iota
+	// DropOldest drops the oldest buffered event to make room for the event
+	// being sent, keeping SendEvent non-blocking.
+	DropOldest
+	// BlockWithTimeout blocks SendEvent until there is room in the buffer, and
+	// drops the event being sent after HTTPTransport.BlockTimeout. Use it in
+	// low-volume applications where every event is valuable.
+	BlockWithTimeout
+)
+
 // HTTPTransport is the default, non-blocking, implementation of Transport.
 //
 // Clients using this transport will enqueue requests in a buffer and return to
@@ -186,6 +698,9 @@ type batchItem struct {
 // to Sentry sequentially from a background goroutine.
 type HTTPTransport struct {
 	dsn       *Dsn
+	relay     *url.URL
+	version   string
+	endpoint  eventEndpoint
 	client    *http.Client
 	transport http.RoundTripper
 
@@ -197,19 +712,54 @@ type HTTPTransport struct {
 
 	// Size of the transport buffer. Defaults to 30.
 	BufferSize int
+	// Size of the buffer reserved for fatal events, sent before other
+	// buffered events. Fatal events are buffered with other events once it
+	// is full. Defaults to 5.
+	PriorityBufferSize int
 	// HTTP Client request timeout. Defaults to 30 seconds.
 	Timeout time.Duration
+	// What to do with events sent when the buffer is full. Defaults to
+	// DropNewest.
+	OverflowPolicy OverflowPolicy
+	// How long SendEvent blocks with the BlockWithTimeout policy before
+	// dropping the event. Defaults to 1 second.
+	BlockTimeout time.Duration
+	// If positive, buffered events are sent every BatchInterval, with
+	// concurrent requests that share a single connection when the server
+	// supports HTTP/2, rather than one request at a time as they are
+	// buffered. It reduces the overhead of requests of applications sending
+	// many events. Defaults to 0, disabled.
+	BatchInterval time.Duration
 
 	mu     sync.RWMutex
 	limits ratelimit.Map
+
+	// sent counts the events accepted by Sentry, protected by mu, and drops
+	// those dropped by the transport. See Diagnose.
+	sent  uint64
+	drops dropCounts
+
+	// saturated is set to 1 when an event is dropped because the buffer is
+	// full, and reset when health is reported.
+	saturated int32
+
+	// done is closed by Close to stop the worker.
+	done      chan struct{}
+	closeOnce sync.Once
+	// ctx is the context of the requests, canceled by Close to abort the
+	// requests in flight.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewHTTPTransport returns a new pre-configured instance of HTTPTransport.
 func NewHTTPTransport() *HTTPTransport {
 	transport := HTTPTransport{
-		BufferSize: defaultBufferSize,
-		Timeout:    defaultTimeout,
-		limits:     make(ratelimit.Map),
+		BufferSize:         defaultBufferSize,
+		PriorityBufferSize: defaultPriorityBufferSize,
+		Timeout:            defaultTimeout,
+		BlockTimeout:       defaultBlockTimeout,
+		limits:             make(ratelimit.Map),
 	}
 	return &transport
 }
@@ -222,16 +772,22 @@ func (t *HTTPTransport) Configure(options ClientOptions) {
 		return
 	}
 	t.dsn = dsn
+	t.relay = relayURL(options)
+	t.version = protocolVersion(options)
+	t.endpoint = eventEndpoint{mode: options.EventEndpoint}
 
 	// A buffered channel with capacity 1 works like a mutex, ensuring only one
 	// goroutine can access the current batch at a given time. Access is
 	// synchronized by reading from and writing to the channel.
 	t.buffer = make(chan batch, 1)
 	t.buffer <- batch{
-		items:   make(chan batchItem, t.BufferSize),
-		started: make(chan struct{}),
-		done:    make(chan struct{}),
+		items:    make(chan batchItem, t.BufferSize),
+		priority: make(chan batchItem, t.PriorityBufferSize),
+		started:  make(chan struct{}),
+		done:     make(chan struct{}),
 	}
+	t.done = make(chan struct{})
+	t.ctx, t.cancel = context.WithCancel(context.Background())
 
 	if options.HTTPTransport != nil {
 		t.transport = options.HTTPTransport
@@ -239,6 +795,9 @@ func (t *HTTPTransport) Configure(options ClientOptions) {
 		t.transport = &http.Transport{
 			Proxy:           getProxyConfig(options),
 			TLSClientConfig: getTLSConfig(options),
+			DialContext:     options.HTTPDialContext,
+			// Batched requests are multiplexed over HTTP/2 connections.
+			ForceAttemptHTTP2: t.BatchInterval > 0,
 		}
 	}
 
@@ -258,61 +817,150 @@ func (t *HTTPTransport) Configure(options ClientOptions) {
 
 // SendEvent assembles a new packet out of Event and sends it to remote server.
 func (t *HTTPTransport) SendEvent(event *Event) {
+	t.SendEventWithContext(context.Background(), event)
+}
+
+// SendEventWithContext is like SendEvent. With the BlockWithTimeout overflow
+// policy, it stops waiting for room in the buffer once ctx is done. Buffered
+// events are sent regardless of ctx, such that errors of canceled operations
+// are reported.
+func (t *HTTPTransport) SendEventWithContext(ctx context.Context, event *Event) {
 	if t.dsn == nil {
 		return
 	}
 
+	select {
+	case <-t.done:
+		Logger.Printf("Event %s dropped due to transport being closed.", event.EventID)
+		t.drops.add(DropTransportClosed)
+		return
+	default:
+	}
+
 	category := categoryFor(event.Type)
 
 	if t.disabled(category) {
+		Logger.Printf("Event %s dropped due to rate limiting.", event.EventID)
+		t.drops.add(DropRateLimit)
 		return
 	}
 
-	request, err := getRequestFromEvent(event, t.dsn)
+	store := t.endpoint.store()
+	request, err := newEventRequest(event, t.dsn, t.relay, t.version, store)
 	if err != nil {
 		return
 	}
-
-	for headerKey, headerValue := range t.dsn.RequestHeaders() {
-		request.Header.Set(headerKey, headerValue)
+	var fallback *Event
+	if t.relay == nil && t.endpoint.canFallBack(event, store) {
+		fallback = event
 	}
 
-	// <-t.buffer is equivalent to acquiring a lock to access the current batch.
-	// A few lines below, t.buffer <- b releases the lock.
-	//
-	// The lock must be held during the select block below to guarantee that
-	// b.items is not closed while trying to send to it. Remember that sending
-	// on a closed channel panics.
-	//
-	// Note that the select block takes a bounded amount of CPU time because of
-	// the default case that is executed if sending on b.items would block. That
-	// is, the event is dropped if it cannot be sent immediately to the b.items
-	// channel (used as a queue).
-	b := <-t.buffer
-
-	select {
-	case b.items <- batchItem{
+	item := batchItem{
+		eventID:  event.EventID,
 		request:  request,
 		category: category,
-	}:
-		var eventType string
-		if event.Type == transactionType {
-			eventType = "transaction"
-		} else {
-			eventType = fmt.Sprintf("%s event", event.Level)
-		}
-		Logger.Printf(
-			"Sending %s [%s] to %s project: %d",
-			eventType,
-			event.EventID,
-			t.dsn.host,
-			t.dsn.projectID,
-		)
-	default:
-		Logger.Println("Event dropped due to transport buffer being full.")
+		fallback: fallback,
+	}
+	if !t.enqueue(ctx, item, event.Level == LevelFatal) {
+		atomic.StoreInt32(&t.saturated, 1)
+		Logger.Printf("Event %s dropped due to transport buffer being full.", event.EventID)
+		t.drops.add(DropQueueOverflow)
+		return
 	}
+	eventType := describeEvent(event)
+	Logger.Printf(
+		"Sending %s [%s] to %s project: %d",
+		eventType,
+		event.EventID,
+		request.URL.Host,
+		t.dsn.projectID,
+	)
+}
+
+// enqueue adds item to the current batch, to the priority buffer first if
+// priority is set, applying the OverflowPolicy if the buffer is full. It
+// reports whether item was added.
+func (t *HTTPTransport) enqueue(ctx context.Context, item batchItem, priority bool) bool {
+	var timeout <-chan time.Time
+	if t.OverflowPolicy == BlockWithTimeout {
+		timer := time.NewTimer(t.BlockTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		// <-t.buffer is equivalent to acquiring a lock to access the current
+		// batch. t.buffer <- b releases the lock.
+		//
+		// The lock must be held while sending to b.items to guarantee that
+		// b.items is not closed while trying to send to it. Remember that
+		// sending on a closed channel panics.
+		b := <-t.buffer
+
+		if priority {
+			select {
+			case b.priority <- item:
+				t.buffer <- b
+				return true
+			default:
+			}
+		}
+
+		select {
+		case b.items <- item:
+			t.buffer <- b
+			return true
+		default:
+		}
+
+		switch t.OverflowPolicy {
+		case DropOldest:
+			select {
+			case oldest := <-b.items:
+				atomic.StoreInt32(&t.saturated, 1)
+				Logger.Printf("Oldest event %s dropped due to transport buffer being full.", oldest.eventID)
+				t.drops.add(DropQueueOverflow)
+			default:
+			}
+			select {
+			case b.items <- item:
+				t.buffer <- b
+				return true
+			default:
+			}
+		case BlockWithTimeout:
+			// Once the worker started processing the batch, it consumes
+			// b.items without the lock, so that blocking while holding it
+			// only delays Flush. Until then, the worker may need the lock to
+			// start the batch and the lock must be released while waiting.
+			select {
+			case <-b.started:
+				var ok bool
+				select {
+				case b.items <- item:
+					ok = true
+				case <-timeout:
+				case <-ctx.Done():
+				case <-t.done:
+				}
+				t.buffer <- b
+				return ok
+			default:
+			}
+			t.buffer <- b
+			select {
+			case <-b.started:
+				continue
+			case <-timeout:
+			case <-ctx.Done():
+			case <-t.done:
+			}
+			return false
+		}
 
-	t.buffer <- b
+		t.buffer <- b
+		return false
+	}
 }
 
 // Flush waits until any buffered events are sent to the Sentry server, blocking
@@ -326,7 +974,22 @@ func (t *HTTPTransport) SendEvent(event *Event) {
 // have the SDK send events over the network synchronously, configure it to use
 // the HTTPSyncTransport in the call to Init.
 func (t *HTTPTransport) Flush(timeout time.Duration) bool {
-	toolate := time.After(timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return t.FlushWithContext(ctx)
+}
+
+// FlushWithContext is like Flush, but blocks until ctx is done rather than
+// for a timeout. It returns false if ctx is done before buffered events are
+// sent, and immediately once the transport is closed, as its buffered events
+// are never sent then.
+func (t *HTTPTransport) FlushWithContext(ctx context.Context) bool {
+	select {
+	case <-t.done:
+		Logger.Println("Buffer not flushed: the transport is closed.")
+		return false
+	default:
+	}
 
 	// Wait until processing the current batch has started or the timeout.
 	//
@@ -344,7 +1007,9 @@ func (t *HTTPTransport) Flush(timeout time.Duration) bool {
 			default:
 				t.buffer <- b
 			}
-		case <-toolate:
+		case <-t.done:
+			return false
+		case <-ctx.Done():
 			goto fail
 		}
 	}
@@ -355,9 +1020,10 @@ started:
 	close(b.items)
 	// Start a new batch for subsequent events.
 	t.buffer <- batch{
-		items:   make(chan batchItem, t.BufferSize),
-		started: make(chan struct{}),
-		done:    make(chan struct{}),
+		items:    make(chan batchItem, t.BufferSize),
+		priority: make(chan batchItem, t.PriorityBufferSize),
+		started:  make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 
 	// Wait until the current batch is done or the timeout.
@@ -365,7 +1031,9 @@ started:
 	case <-b.done:
 		Logger.Println("Buffer flushed successfully.")
 		return true
-	case <-toolate:
+	case <-t.done:
+		return false
+	case <-ctx.Done():
 		goto fail
 	}
 
@@ -374,8 +1042,29 @@ fail:
 	return false
 }
 
+// Close stops the worker goroutine of the transport and aborts the requests
+// in flight. Events sent after Close are dropped, and so are buffered events
+// that were not sent yet: call Flush before Close to deliver them. It is safe
+// to call Close multiple times.
+func (t *HTTPTransport) Close() {
+	if t.done == nil {
+		return
+	}
+	t.closeOnce.Do(func() {
+		close(t.done)
+		t.cancel()
+	})
+}
+
 func (t *HTTPTransport) worker() {
-	for b := range t.buffer {
+	for {
+		var b batch
+		select {
+		case <-t.done:
+			return
+		case b = <-t.buffer:
+		}
+
 		// Signal that processing of the current batch has started.
 		close(b.started)
 
@@ -383,24 +1072,27 @@ func (t *HTTPTransport) worker() {
 		// Equivalent to releasing a lock.
 		t.buffer <- b
 
-		// Process all batch items.
-		for item := range b.items {
-			if t.disabled(item.category) {
-				continue
+		// Process all batch items, priority items first.
+		for {
+			item, ok := t.next(b)
+			if !ok {
+				break
 			}
-
-			response, err := t.client.Do(item.request)
-			if err != nil {
-				Logger.Printf("There was an issue with sending an event: %v", err)
+			if t.BatchInterval <= 0 {
+				t.send(item)
 				continue
 			}
-			t.mu.Lock()
-			t.limits.Merge(ratelimit.FromResponse(response))
-			t.mu.Unlock()
-			// Drain body up to a limit and close it, allowing the
-			// transport to reuse TCP connections.
-			_, _ = io.CopyN(ioutil.Discard, response.Body, maxDrainResponseBytes)
-			response.Body.Close()
+			items, ok := t.collect(b, item)
+			if !ok {
+				return
+			}
+			t.sendAll(items)
+		}
+
+		select {
+		case <-t.done:
+			return
+		default:
 		}
 
 		// Signal that processing of the batch is done.
@@ -408,6 +1100,140 @@ func (t *HTTPTransport) worker() {
 	}
 }
 
+// next returns the next item of b, priority items first. It returns false
+// once b is closed and drained, or the transport is closed.
+func (t *HTTPTransport) next(b batch) (batchItem, bool) {
+	select {
+	case item := <-b.priority:
+		return item, true
+	default:
+	}
+	select {
+	case <-t.done:
+		return batchItem{}, false
+	case item := <-b.priority:
+		return item, true
+	case item, ok := <-b.items:
+		if ok {
+			return item, true
+		}
+		// No more items can be added to the batch once items is closed:
+		// drain the priority items left.
+		select {
+		case item := <-b.priority:
+			return item, true
+		default:
+			return batchItem{}, false
+		}
+	}
+}
+
+// collect returns item and the items of b received within BatchInterval, or
+// until b is closed. It returns false if the transport is closed.
+func (t *HTTPTransport) collect(b batch, item batchItem) ([]batchItem, bool) {
+	items := []batchItem{item}
+	timer := time.NewTimer(t.BatchInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-t.done:
+			return nil, false
+		case item := <-b.priority:
+			items = append(items, item)
+		case item, ok := <-b.items:
+			if !ok {
+				return items, true
+			}
+			items = append(items, item)
+		case <-timer.C:
+			return items, true
+		}
+	}
+}
+
+// sendAll sends items concurrently, allowing HTTP/2 connections to multiplex
+// the requests, and waits for the responses.
+func (t *HTTPTransport) sendAll(items []batchItem) {
+	var wg sync.WaitGroup
+	for _, item := range items {
+		wg.Add(1)
+		go func(item batchItem) {
+			defer wg.Done()
+			t.send(item)
+		}(item)
+	}
+	wg.Wait()
+}
+
+// send sends the request of item, unless its category is rate limited.
+func (t *HTTPTransport) send(item batchItem) {
+	if t.disabled(item.category) {
+		Logger.Printf("Event %s dropped due to rate limiting.", item.eventID)
+		t.drops.add(DropRateLimit)
+		return
+	}
+
+	response, err := doRequest(t.client, item.request.WithContext(t.ctx))
+	if err == nil && item.fallback != nil && t.endpoint.fallBack(response) {
+		drainAndClose(response)
+		var request *http.Request
+		request, err = newEventRequest(item.fallback, t.dsn, nil, t.version, true)
+		if err != nil {
+			return
+		}
+		response, err = doRequest(t.client, request.WithContext(t.ctx))
+	}
+	if err != nil {
+		Logger.Printf("There was an issue with sending event %s: %v", item.eventID, err)
+		t.drops.add(DropNetworkError)
+		return
+	}
+	logResponse(item.eventID, response)
+	reason, rejected := rejection(response)
+	if rejected {
+		t.drops.add(reason)
+	}
+	t.mu.Lock()
+	t.limits.Merge(ratelimit.FromResponse(response))
+	if !rejected {
+		t.sent++
+	}
+	t.mu.Unlock()
+	drainAndClose(response)
+}
+
+// logResponse logs the status of the response to the request sending the
+// event with the given ID.
+func logResponse(id EventID, response *http.Response) {
+	if _, rejected := rejection(response); rejected {
+		Logger.Printf("Event %s rejected with status %s", id, response.Status)
+		return
+	}
+	Logger.Printf("Event %s sent with status %s", id, response.Status)
+}
+
+// diagnose reports the buffered events, the dropped events and the rate
+// limits of the transport.
+func (t *HTTPTransport) diagnose(report *DiagnosticReport) {
+	if t.buffer != nil {
+		b := <-t.buffer
+		report.QueueDepth += len(b.items) + len(b.priority)
+		t.buffer <- b
+	}
+	t.drops.addTo(report.Dropped)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	report.Sent += t.sent
+	report.addRateLimits(t.limits)
+}
+
+func (t *HTTPTransport) isHealthy() bool {
+	saturated := atomic.SwapInt32(&t.saturated, 0) == 1
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return !saturated && !t.limits.IsAnyRateLimited()
+}
+
 func (t *HTTPTransport) disabled(c ratelimit.Category) bool {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -435,12 +1261,25 @@ func (t *HTTPTransport) disabled(c ratelimit.Category) bool {
 // For most cases, prefer HTTPTransport.
 type HTTPSyncTransport struct {
 	dsn       *Dsn
+	relay     *url.URL
+	version   string
+	endpoint  eventEndpoint
 	client    *http.Client
 	transport http.RoundTripper
 
 	mu     sync.Mutex
 	limits ratelimit.Map
 
+	// sent counts the events accepted by Sentry, protected by mu, and drops
+	// those dropped by the transport. See Diagnose.
+	sent  uint64
+	drops dropCounts
+
+	// inflight holds a channel for each event still being sent after its
+	// caller stopped waiting, closed once it is sent, protected by mu. See
+	// SendEventWithContext.
+	inflight map[chan struct{}]struct{}
+
 	// HTTP Client request timeout. Defaults to 30 seconds.
 	Timeout time.Duration
 }
@@ -463,6 +1302,9 @@ func (t *HTTPSyncTransport) Configure(options ClientOptions) {
 		return
 	}
 	t.dsn = dsn
+	t.relay = relayURL(options)
+	t.version = protocolVersion(options)
+	t.endpoint = eventEndpoint{mode: options.EventEndpoint}
 
 	if options.HTTPTransport != nil {
 		t.transport = options.HTTPTransport
@@ -470,6 +1312,7 @@ func (t *HTTPSyncTransport) Configure(options ClientOptions) {
 		t.transport = &http.Transport{
 			Proxy:           getProxyConfig(options),
 			TLSClientConfig: getTLSConfig(options),
+			DialContext:     options.HTTPDialContext,
 		}
 	}
 
@@ -485,57 +1328,137 @@ func (t *HTTPSyncTransport) Configure(options ClientOptions) {
 
 // SendEvent assembles a new packet out of Event and sends it to remote server.
 func (t *HTTPSyncTransport) SendEvent(event *Event) {
+	t.SendEventWithContext(context.Background(), event)
+}
+
+// SendEventWithContext is like SendEvent, but stops waiting for the event to
+// be sent once ctx is done, such that capturing events does not block on an
+// unresponsive network after the caller gave up. The request is not canceled
+// with ctx: an event captured for a request whose client disconnected is
+// still sent, in the background, and Flush waits for it.
+func (t *HTTPSyncTransport) SendEventWithContext(ctx context.Context, event *Event) {
 	if t.dsn == nil {
 		return
 	}
 
 	if t.disabled(categoryFor(event.Type)) {
+		Logger.Printf("Event %s dropped due to rate limiting.", event.EventID)
+		t.drops.add(DropRateLimit)
 		return
 	}
 
-	request, err := getRequestFromEvent(event, t.dsn)
+	store := t.endpoint.store()
+	request, err := newEventRequest(event, t.dsn, t.relay, t.version, store)
 	if err != nil {
 		return
 	}
 
-	for headerKey, headerValue := range t.dsn.RequestHeaders() {
-		request.Header.Set(headerKey, headerValue)
-	}
-
-	var eventType string
-	if event.Type == transactionType {
-		eventType = "transaction"
-	} else {
-		eventType = fmt.Sprintf("%s event", event.Level)
-	}
+	eventType := describeEvent(event)
 	Logger.Printf(
 		"Sending %s [%s] to %s project: %d",
 		eventType,
 		event.EventID,
-		t.dsn.host,
+		request.URL.Host,
 		t.dsn.projectID,
 	)
 
-	response, err := t.client.Do(request)
+	done := make(chan struct{})
+	t.mu.Lock()
+	if t.inflight == nil {
+		t.inflight = make(map[chan struct{}]struct{})
+	}
+	t.inflight[done] = struct{}{}
+	t.mu.Unlock()
+	go func() {
+		defer func() {
+			t.mu.Lock()
+			delete(t.inflight, done)
+			t.mu.Unlock()
+			close(done)
+		}()
+		t.send(request.WithContext(detachedContext{ctx}), event, store)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		Logger.Printf("Stopped waiting for event %s: %v", event.EventID, ctx.Err())
+	}
+}
+
+// send sends the request of event, falling back to the store endpoint if
+// needed.
+func (t *HTTPSyncTransport) send(request *http.Request, event *Event, store bool) {
+	ctx := request.Context()
+	response, err := doRequest(t.client, request)
+	if err == nil && t.relay == nil && t.endpoint.canFallBack(event, store) && t.endpoint.fallBack(response) {
+		drainAndClose(response)
+		request, err = newEventRequest(event, t.dsn, nil, t.version, true)
+		if err != nil {
+			return
+		}
+		response, err = doRequest(t.client, request.WithContext(ctx))
+	}
 	if err != nil {
-		Logger.Printf("There was an issue with sending an event: %v", err)
+		Logger.Printf("There was an issue with sending event %s: %v", event.EventID, err)
+		t.drops.add(DropNetworkError)
 		return
 	}
+	logResponse(event.EventID, response)
+	reason, rejected := rejection(response)
+	if rejected {
+		t.drops.add(reason)
+	}
 	t.mu.Lock()
 	t.limits.Merge(ratelimit.FromResponse(response))
+	if !rejected {
+		t.sent++
+	}
 	t.mu.Unlock()
+	drainAndClose(response)
+}
 
-	// Drain body up to a limit and close it, allowing the
-	// transport to reuse TCP connections.
-	_, _ = io.CopyN(ioutil.Discard, response.Body, maxDrainResponseBytes)
-	response.Body.Close()
+// Flush waits until the events still being sent after their caller stopped
+// waiting, if any, are sent, or the timeout is reached. It returns false if
+// the timeout was reached.
+func (t *HTTPSyncTransport) Flush(timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return t.FlushWithContext(ctx)
 }
 
-// Flush is a no-op for HTTPSyncTransport. It always returns true immediately.
-func (t *HTTPSyncTransport) Flush(_ time.Duration) bool {
+// FlushWithContext is like Flush, but waits until ctx is done.
+func (t *HTTPSyncTransport) FlushWithContext(ctx context.Context) bool {
+	t.mu.Lock()
+	inflight := make([]chan struct{}, 0, len(t.inflight))
+	for done := range t.inflight {
+		inflight = append(inflight, done)
+	}
+	t.mu.Unlock()
+	for _, done := range inflight {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return false
+		}
+	}
 	return true
 }
 
+// diagnose reports the dropped events and the rate limits of the transport.
+func (t *HTTPSyncTransport) diagnose(report *DiagnosticReport) {
+	t.drops.addTo(report.Dropped)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	report.Sent += t.sent
+	report.addRateLimits(t.limits)
+}
+
+func (t *HTTPSyncTransport) isHealthy() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.limits.IsAnyRateLimited()
+}
+
 func (t *HTTPSyncTransport) disabled(c ratelimit.Category) bool {
 	t.mu.Lock()
 	defer t.mu.Unlock()
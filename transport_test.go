@@ -2,12 +2,18 @@ package sentry
 
 import (
 	"bytes"
+	"context"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httptrace"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -22,9 +28,9 @@ type unserializableType struct {
 }
 
 const (
-	basicEvent                         = `{"message":"mkey","sdk":{},"user":{}}`
-	enhancedEventInvalidBreadcrumb     = `{"extra":{"info":"Could not encode original event as JSON. Succeeded by removing Breadcrumbs, Contexts and Extra. Please verify the data you attach to the scope. Error: json: error calling MarshalJSON for type *sentry.Event: json: error calling MarshalJSON for type *sentry.Breadcrumb: json: unsupported type: func()"},"message":"mkey","sdk":{},"user":{}}`
-	enhancedEventInvalidContextOrExtra = `{"extra":{"info":"Could not encode original event as JSON. Succeeded by removing Breadcrumbs, Contexts and Extra. Please verify the data you attach to the scope. Error: json: error calling MarshalJSON for type *sentry.Event: json: unsupported type: func()"},"message":"mkey","sdk":{},"user":{}}`
+	basicEvent                         = `{"message":"mkey"}`
+	enhancedEventInvalidBreadcrumb     = `{"extra":{"info":"Could not encode original event as JSON. Succeeded by removing Breadcrumbs, Contexts and Extra. Please verify the data you attach to the scope. Error: json: error calling MarshalJSON for type *sentry.Event: json: error calling MarshalJSON for type *sentry.Breadcrumb: json: unsupported type: func()"},"message":"mkey"}`
+	enhancedEventInvalidContextOrExtra = `{"extra":{"info":"Could not encode original event as JSON. Succeeded by removing Breadcrumbs, Contexts and Extra. Please verify the data you attach to the scope. Error: json: error calling MarshalJSON for type *sentry.Event: json: unsupported type: func()"},"message":"mkey"}`
 )
 
 func TestGetRequestBodyFromEventValid(t *testing.T) {
@@ -132,11 +138,60 @@ func TestGetRequestBodyFromEventCompletelyInvalid(t *testing.T) {
 	}
 }
 
+// panickingValue panics when it is encoded or formatted.
+type panickingValue struct{}
+
+func (panickingValue) MarshalJSON() ([]byte, error) { panic("MarshalJSON") }
+func (panickingValue) String() string               { panic("String") }
+
+func TestGetRequestBodyFromEventPanic(t *testing.T) {
+	trace := &TraceContext{TraceID: TraceIDFromHex("d49d9bf66f13450b81f65bc51cf49c03"), SpanID: SpanIDFromHex("a9f442f9330b4e09")}
+	event := &Event{
+		Message: "mkey",
+		Extra: map[string]interface{}{
+			"ok":  1,
+			"wat": panickingValue{},
+		},
+		Contexts: map[string]interface{}{
+			"trace": trace,
+			"app":   map[string]interface{}{"name": "app", "wat": []interface{}{panickingValue{}}},
+		},
+		Breadcrumbs: []*Breadcrumb{{
+			Message: "crumb",
+			Data:    map[string]interface{}{"wat": panickingValue{}},
+		}},
+	}
+	breadcrumb := event.Breadcrumbs[0]
+	body := getRequestBodyFromEvent(event)
+	if body == nil {
+		t.Fatal("got no body")
+	}
+
+	var got struct {
+		Extra       map[string]interface{} `json:"extra"`
+		Contexts    map[string]interface{} `json:"contexts"`
+		Breadcrumbs []*Breadcrumb          `json:"breadcrumbs"`
+		Tags        map[string]string      `json:"tags"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	const wat = "%!v(PANIC=String method: String)"
+	assertEqual(t, got.Extra, map[string]interface{}{"ok": 1.0, "wat": wat})
+	assertEqual(t, got.Contexts["app"], map[string]interface{}{"name": "app", "wat": []interface{}{wat}})
+	assertEqual(t, got.Contexts["trace"].(map[string]interface{})["trace_id"], "d49d9bf66f13450b81f65bc51cf49c03")
+	assertEqual(t, got.Breadcrumbs[0].Message, "crumb")
+	assertEqual(t, got.Breadcrumbs[0].Data, map[string]interface{}{"wat": wat})
+	assertEqual(t, got.Tags[serializationErrorTag], "panic while encoding the event: MarshalJSON")
+	// Breadcrumbs shared with scopes are not modified.
+	assertEqual(t, breadcrumb.Data["wat"], panickingValue{})
+}
+
 func TestTransactionEnvelopeFromBody(t *testing.T) {
 	const eventID = "b81c5be4d31e48959103a1f878a1efcb"
 	sentAt := time.Unix(0, 0).UTC()
 	body := json.RawMessage(`{"type":"transaction","fields":"omitted"}`)
-	b, err := transactionEnvelopeFromBody(eventID, sentAt, body)
+	b, err := envelopeFromBody(eventID, transactionType, sentAt, body)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -173,6 +228,14 @@ func TestGetRequestFromEvent(t *testing.T) {
 			}(),
 			apiURL: "https://host/path/api/42/envelope/",
 		},
+		{
+			testName: "Check-in",
+			event: &Event{
+				Type:    checkInType,
+				CheckIn: &CheckIn{MonitorSlug: "job", Status: CheckInStatusOK},
+			},
+			apiURL: "https://host/path/api/42/envelope/",
+		},
 	}
 
 	for _, test := range testCases {
@@ -514,3 +577,566 @@ func testRateLimiting(t *testing.T, tr Transport) {
 		t.Errorf("got transactionEvent = %d, want %d", n, 1)
 	}
 }
+
+func TestHTTPDialContextUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sentry-socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "relay.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("cannot listen on a Unix socket: %v", err)
+	}
+
+	var requests int32
+	server := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+		})},
+	}
+	server.Start()
+	defer server.Close()
+
+	for _, transport := range []Transport{NewHTTPTransport(), NewHTTPSyncTransport()} {
+		transport.Configure(ClientOptions{
+			// The host of the DSN is not resolved: the socket is dialed.
+			Dsn:             "http://public@relay.invalid/1",
+			HTTPDialContext: UnixSocketDialer(path),
+		})
+		transport.SendEvent(NewEvent())
+		if !transport.Flush(time.Second) {
+			t.Fatalf("%T: Flush timed out", transport)
+		}
+	}
+	assertEqual(t, atomic.LoadInt32(&requests), int32(2))
+}
+
+func TestRelayURL(t *testing.T) {
+	type request struct {
+		path, query, auth, contentType string
+		itemType                       string
+	}
+	var mu sync.Mutex
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lines := bytes.Split(body, []byte("\n"))
+		var item struct {
+			Type string `json:"type"`
+		}
+		if len(lines) > 1 {
+			_ = json.Unmarshal(lines[1], &item)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, request{
+			path:        r.URL.Path,
+			query:       r.URL.RawQuery,
+			auth:        r.Header.Get("X-Sentry-Auth"),
+			contentType: r.Header.Get("Content-Type"),
+			itemType:    item.Type,
+		})
+	}))
+	defer server.Close()
+
+	for _, transport := range []Transport{NewHTTPTransport(), NewHTTPSyncTransport()} {
+		transport.Configure(ClientOptions{
+			Dsn:      "https://public@o1.ingest.sentry.io/42",
+			RelayURL: server.URL + "/relay/",
+		})
+		transport.SendEvent(NewEvent())
+		transport.SendEvent(&Event{Type: transactionType, StartTime: time.Now(), Timestamp: time.Now()})
+		if !transport.Flush(time.Second) {
+			t.Fatalf("%T: Flush timed out", transport)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 4 {
+		t.Fatalf("got %d requests, want 4", len(requests))
+	}
+	for i, r := range requests {
+		assertEqual(t, r.path, "/relay/api/42/envelope/")
+		assertEqual(t, r.query, "sentry_client=sentry.go%2F"+Version+"&sentry_key=public&sentry_version=7")
+		assertEqual(t, r.auth, "")
+		assertEqual(t, r.contentType, "application/x-sentry-envelope")
+		assertEqual(t, r.itemType, []string{"event", "transaction"}[i%2])
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	var mu sync.Mutex
+	var auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auths = append(auths, r.Header.Get("X-Sentry-Auth"))
+	}))
+	defer server.Close()
+
+	for _, version := range []string{"", "6", "invalid"} {
+		for _, transport := range []Transport{NewHTTPTransport(), NewHTTPSyncTransport()} {
+			transport.Configure(ClientOptions{
+				Dsn:             strings.Replace(server.URL, "://", "://public@", 1) + "/1",
+				ProtocolVersion: version,
+			})
+			transport.SendEvent(NewEvent())
+			if !transport.Flush(time.Second) {
+				t.Fatalf("%T: Flush timed out", transport)
+			}
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(auths) != 6 {
+		t.Fatalf("got %d requests, want 6", len(auths))
+	}
+	for i, auth := range auths {
+		want := []string{"7", "6", "7"}[i/2]
+		if !strings.HasPrefix(auth, "Sentry sentry_version="+want+",") {
+			t.Errorf("got X-Sentry-Auth %q, want sentry_version=%s", auth, want)
+		}
+	}
+}
+
+func TestEventEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint EventEndpoint
+		legacy   bool
+		want     []string
+	}{
+		{
+			name: "AutoEnvelope",
+			want: []string{"envelope", "envelope", "envelope"},
+		},
+		{
+			name:   "AutoFallback",
+			legacy: true,
+			want:   []string{"envelope", "store", "store", "envelope"},
+		},
+		{
+			name:     "Envelope",
+			endpoint: EventEndpointEnvelope,
+			legacy:   true,
+			want:     []string{"envelope", "envelope", "envelope"},
+		},
+		{
+			name:     "Store",
+			endpoint: EventEndpointStore,
+			want:     []string{"store", "store", "envelope"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for _, transport := range []Transport{NewHTTPTransport(), NewHTTPSyncTransport()} {
+				var mu sync.Mutex
+				var got []string
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					endpoint := filepath.Base(r.URL.Path)
+					mu.Lock()
+					got = append(got, endpoint)
+					mu.Unlock()
+					if tt.legacy && endpoint == "envelope" {
+						w.WriteHeader(http.StatusNotFound)
+					}
+				}))
+
+				transport.Configure(ClientOptions{
+					Dsn:           strings.Replace(server.URL, "://", "://public@", 1) + "/1",
+					EventEndpoint: tt.endpoint,
+				})
+				for _, event := range []*Event{
+					NewEvent(),
+					NewEvent(),
+					{Type: transactionType, StartTime: time.Now(), Timestamp: time.Now()},
+				} {
+					transport.SendEvent(event)
+					if !transport.Flush(time.Second) {
+						t.Fatalf("%T: Flush timed out", transport)
+					}
+				}
+				server.Close()
+
+				mu.Lock()
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Errorf("%T: endpoints mismatch (-want +got):\n%s", transport, diff)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+}
+
+func TestOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy OverflowPolicy
+		// released is whether the server is released while the third event
+		// is being sent.
+		released bool
+		want     []string
+	}{
+		{name: "DropNewest", policy: DropNewest, want: []string{"1", "2"}},
+		{name: "DropOldest", policy: DropOldest, want: []string{"1", "3"}},
+		{name: "BlockWithTimeout", policy: BlockWithTimeout, want: []string{"1", "2"}},
+		{name: "BlockWithTimeoutReleased", policy: BlockWithTimeout, released: true, want: []string{"1", "2", "3"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var got []string
+			received := make(chan struct{}, 3)
+			release := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var event Event
+				_ = json.NewDecoder(r.Body).Decode(&event)
+				mu.Lock()
+				got = append(got, event.Message)
+				mu.Unlock()
+				received <- struct{}{}
+				<-release
+			}))
+			defer server.Close()
+
+			transport := NewHTTPTransport()
+			transport.BufferSize = 1
+			transport.OverflowPolicy = tt.policy
+			transport.BlockTimeout = 50 * time.Millisecond
+			if tt.released {
+				transport.BlockTimeout = 5 * time.Second
+			}
+			transport.Configure(ClientOptions{
+				Dsn:           strings.Replace(server.URL, "://", "://public@", 1) + "/1",
+				EventEndpoint: EventEndpointStore,
+			})
+			defer transport.Close()
+
+			// The worker blocks on the first event, the second one fills the
+			// buffer.
+			transport.SendEvent(&Event{Message: "1"})
+			<-received
+			transport.SendEvent(&Event{Message: "2"})
+			if tt.released {
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					close(release)
+				}()
+			}
+			transport.SendEvent(&Event{Message: "3"})
+			if !tt.released {
+				close(release)
+			}
+			if !transport.Flush(time.Second) {
+				t.Fatal("Flush timed out")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("events mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPriorityBuffer(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		got = append(got, event.Message)
+		mu.Unlock()
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer server.Close()
+
+	transport := NewHTTPTransport()
+	transport.BufferSize = 2
+	transport.PriorityBufferSize = 1
+	transport.Configure(ClientOptions{
+		Dsn:           strings.Replace(server.URL, "://", "://public@", 1) + "/1",
+		EventEndpoint: EventEndpointStore,
+	})
+	defer transport.Close()
+
+	// The worker blocks on the first event while the others are buffered.
+	transport.SendEvent(&Event{Message: "1", Level: LevelInfo})
+	<-received
+	transport.SendEvent(&Event{Message: "2", Level: LevelInfo})
+	transport.SendEvent(&Event{Message: "3", Level: LevelFatal})
+	// The priority buffer is full.
+	transport.SendEvent(&Event{Message: "4", Level: LevelFatal})
+	// Both buffers are full.
+	transport.SendEvent(&Event{Message: "5", Level: LevelFatal})
+	close(release)
+	if !transport.Flush(time.Second) {
+		t.Fatal("Flush timed out")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if diff := cmp.Diff([]string{"1", "3", "2", "4"}, got); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	// newWedgedServer returns a server that never responds. received
+	// receives when a request arrives, and aborted when the client aborts
+	// it.
+	newWedgedServer := func() (server *httptest.Server, received, aborted chan struct{}) {
+		received = make(chan struct{}, 1)
+		aborted = make(chan struct{}, 1)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The server notices aborted requests once the body is read.
+			_, _ = io.Copy(ioutil.Discard, r.Body)
+			received <- struct{}{}
+			<-r.Context().Done()
+			aborted <- struct{}{}
+		}))
+		return server, received, aborted
+	}
+	waitAborted := func(t *testing.T, aborted chan struct{}) {
+		t.Helper()
+		select {
+		case <-aborted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("request not aborted")
+		}
+	}
+
+	t.Run("HTTPTransport", func(t *testing.T) {
+		server, received, aborted := newWedgedServer()
+		defer server.Close()
+
+		transport := NewHTTPTransport()
+		transport.Configure(ClientOptions{Dsn: strings.Replace(server.URL, "://", "://public@", 1) + "/1"})
+		defer transport.Close()
+
+		transport.SendEventWithContext(context.Background(), &Event{Message: "wedged"})
+		<-received
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if transport.FlushWithContext(ctx) {
+			t.Error("FlushWithContext = true, want false")
+		}
+		transport.Close()
+		waitAborted(t, aborted)
+	})
+
+	t.Run("HTTPSyncTransport", func(t *testing.T) {
+		// The server responds once released: the request is not canceled
+		// with the context, which only bounds waiting.
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(ioutil.Discard, r.Body)
+			<-release
+		}))
+		defer server.Close()
+
+		transport := NewHTTPSyncTransport()
+		transport.Configure(ClientOptions{Dsn: strings.Replace(server.URL, "://", "://public@", 1) + "/1"})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		transport.SendEventWithContext(ctx, &Event{Message: "wedged"})
+		if transport.Flush(50 * time.Millisecond) {
+			t.Error("Flush = true before the response, want false")
+		}
+		close(release)
+		if !transport.Flush(time.Second) {
+			t.Fatal("Flush timed out")
+		}
+		report := DiagnosticReport{Dropped: make(map[DropReason]uint64)}
+		transport.diagnose(&report)
+		assertEqual(t, report.Sent, uint64(1))
+	})
+}
+
+func TestBatchInterval(t *testing.T) {
+	var mu sync.Mutex
+	var protos []string
+	var active, maxActive int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		mu.Lock()
+		protos = append(protos, r.Proto)
+		if n > maxActive {
+			maxActive = n
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	transport := NewHTTPTransport()
+	transport.BatchInterval = 50 * time.Millisecond
+	caCerts := x509.NewCertPool()
+	caCerts.AddCert(server.Certificate())
+	transport.Configure(ClientOptions{
+		Dsn:     strings.Replace(server.URL, "://", "://public@", 1) + "/1",
+		CaCerts: caCerts,
+	})
+	defer transport.Close()
+
+	for i := 0; i < 5; i++ {
+		transport.SendEvent(NewEvent())
+	}
+	if !transport.Flush(time.Second) {
+		t.Fatal("Flush timed out")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(protos) != 5 {
+		t.Fatalf("got %d requests, want 5", len(protos))
+	}
+	for _, proto := range protos {
+		assertEqual(t, proto, "HTTP/2.0")
+	}
+	if maxActive < 2 {
+		t.Errorf("got at most %d concurrent requests, want batched requests", maxActive)
+	}
+}
+
+func TestPooledRequest(t *testing.T) {
+	b := getBuffer()
+	b.WriteString("envelope")
+	request, err := newPooledRequest("https://host/api/1/envelope/", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, request.ContentLength, int64(len("envelope")))
+
+	retry, err := request.GetBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(request.Body)
+	assertEqual(t, string(body), "envelope")
+	body, _ = ioutil.ReadAll(retry)
+	assertEqual(t, string(body), "envelope")
+
+	// net/http closes the body before calling GetBody to send the request
+	// again.
+	request.Body.Close()
+	request.Body.Close()
+	retry, err = request.GetBody()
+	if err != nil {
+		t.Fatalf("GetBody failed after the body was closed: %v", err)
+	}
+	body, _ = ioutil.ReadAll(retry)
+	assertEqual(t, string(body), "envelope")
+
+	request.Body.(*pooledBody).finish()
+	if _, err := request.GetBody(); err == nil {
+		t.Error("GetBody succeeded after the request was sent")
+	}
+}
+
+func TestTransportResendsPooledRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(received *int32) http.Handler
+		setup   func(server *httptest.Server)
+	}{
+		{
+			// The server drops the first connection once idle, so that the
+			// next request may be written to a stale connection.
+			name: "StaleConnection",
+			handler: func(received *int32) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					io.Copy(ioutil.Discard, r.Body)
+					atomic.AddInt32(received, 1)
+				})
+			},
+			setup: func(server *httptest.Server) {
+				var dropped int32
+				server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+					if state == http.StateIdle && atomic.CompareAndSwapInt32(&dropped, 0, 1) {
+						conn.Close()
+					}
+				}
+			},
+		},
+		{
+			name: "Redirect",
+			handler: func(received *int32) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					if !strings.Contains(string(body), `"type":"event"`) {
+						t.Errorf("got body %q, want an event envelope", body)
+					}
+					if !strings.HasSuffix(r.URL.Path, "/redirected") {
+						http.Redirect(w, r, r.URL.Path+"/redirected", http.StatusTemporaryRedirect)
+						return
+					}
+					atomic.AddInt32(received, 1)
+				})
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var received int32
+			server := httptest.NewUnstartedServer(tt.handler(&received))
+			if tt.setup != nil {
+				tt.setup(server)
+			}
+			server.Start()
+			defer server.Close()
+
+			transport := NewHTTPSyncTransport()
+			transport.Configure(ClientOptions{
+				Dsn: strings.Replace(server.URL, "://", "://public@", 1) + "/1",
+			})
+			for i := 0; i < 3; i++ {
+				transport.SendEvent(NewEvent())
+			}
+			assertEqual(t, atomic.LoadInt32(&received), int32(3))
+		})
+	}
+}
+
+func BenchmarkNewEventRequest(b *testing.B) {
+	dsn, err := NewDsn("https://key@host/42")
+	if err != nil {
+		b.Fatal(err)
+	}
+	event := &Event{
+		EventID:   "0123456789abcdef0123456789abcdef",
+		Type:      transactionType,
+		StartTime: time.Unix(0, 0),
+		Timestamp: time.Unix(1, 0),
+		Spans:     []*Span{{Op: "db.query", Description: "SELECT 1"}},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		request, err := newEventRequest(event, dsn, nil, apiVersion, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+		request.Body.Close()
+	}
+}
@@ -2,11 +2,15 @@ package sentry
 
 import (
 	"crypto/rand"
+	"crypto/sha1" //nolint: gosec // used to identify builds, not for security
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	exec "golang.org/x/sys/execabs"
@@ -23,16 +27,77 @@ func uuid() string {
 	return hex.EncodeToString(id)
 }
 
+// randomIDGenerator is the default IDGenerator, backed by crypto/rand.
+type randomIDGenerator struct{}
+
+func (randomIDGenerator) EventID() EventID { return EventID(uuid()) }
+
+func (randomIDGenerator) TraceID() TraceID {
+	// Implementation note:
+	//
+	// While math/rand is ~2x faster than crypto/rand (exact
+	// difference depends on hardware / OS), crypto/rand is probably
+	// fast enough and a safer choice.
+	//
+	// For reference, OpenTelemetry [1] uses crypto/rand to seed
+	// math/rand. AFAICT this approach does not preserve the
+	// properties from crypto/rand that make it suitable for
+	// cryptography. While it might be debatable whether those
+	// properties are important for us here, again, we're taking the
+	// safer path.
+	//
+	// See [2a] & [2b] for a discussion of some of the properties we
+	// obtain by using crypto/rand and [3a] & [3b] for why we avoid
+	// math/rand.
+	//
+	// Because the math/rand seed has only 64 bits (int64), if the
+	// first thing we do after seeding an RNG is to read in a random
+	// TraceID, there are only 2^64 possible values. Compared to
+	// UUID v4 that have 122 random bits, there is a much greater
+	// chance of collision [4a] & [4b].
+	//
+	// [1]:  https://github.com/open-telemetry/opentelemetry-go/blob/958041ddf619a128/sdk/trace/trace.go#L25-L31
+	// [2a]: https://security.stackexchange.com/q/120352/246345
+	// [2b]: https://security.stackexchange.com/a/120365/246345
+	// [3a]: https://github.com/golang/go/issues/11871#issuecomment-126333686
+	// [3b]: https://github.com/golang/go/issues/11871#issuecomment-126357889
+	// [4a]: https://en.wikipedia.org/wiki/Universally_unique_identifier#Collisions
+	// [4b]: https://www.wolframalpha.com/input/?i=sqrt%282*2%5E64*ln%281%2F%281-0.5%29%29%29
+	var id TraceID
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func (randomIDGenerator) SpanID() SpanID {
+	var id SpanID
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// idGeneratorFor returns the IDGenerator configured in the options of client.
+// It returns the random generator if client is nil or no IDGenerator is
+// configured.
+func idGeneratorFor(client *Client) IDGenerator {
+	if client == nil || client.options.IDGenerator == nil {
+		return randomIDGenerator{}
+	}
+	return client.options.IDGenerator
+}
+
 func fileExists(fileName string) bool {
 	_, err := os.Stat(fileName)
 	return err == nil
 }
 
-// monotonicTimeSince replaces uses of time.Now() to take into account the
+// monotonicTimeSince replaces uses of clock.Now() to take into account the
 // monotonic clock reading stored in start, such that duration = end - start is
 // unaffected by changes in the system wall clock.
-func monotonicTimeSince(start time.Time) (end time.Time) {
-	return start.Add(time.Since(start))
+func monotonicTimeSince(start time.Time, clock Clock) (end time.Time) {
+	return start.Add(clock.Now().Sub(start))
 }
 
 //nolint: deadcode, unused
@@ -41,9 +106,24 @@ func prettyPrint(data interface{}) {
 	fmt.Println(string(dbg))
 }
 
+// Sources a release can be detected from. See defaultRelease.
+const (
+	releaseSourceOptions    = "options"
+	releaseSourceEnv        = "env"
+	releaseSourceBuildInfo  = "buildinfo"
+	releaseSourceGit        = "git"
+	releaseSourceExecutable = "executable"
+)
+
 // defaultRelease attempts to guess a default release for the currently running
-// program.
-func defaultRelease() (release string) {
+// program. It returns the release and the source it was detected from, or two
+// empty strings if detection failed.
+//
+// Release detection sources are tried in order: known environment variables,
+// starting with SENTRY_RELEASE, the version control revision recorded in the
+// build info, the output of "git rev-parse HEAD", and finally a checksum of the
+// running executable.
+func defaultRelease() (release, source string) {
 	// Return first non-empty environment variable known to hold release info, if any.
 	envs := []string{
 		"SENTRY_RELEASE",
@@ -62,27 +142,77 @@ func defaultRelease() (release string) {
 	for _, e := range envs {
 		if release = os.Getenv(e); release != "" {
 			Logger.Printf("Using release from environment variable %s: %s", e, release)
-			return release
+			return release, releaseSourceEnv
+		}
+	}
+
+	// Use the revision recorded by the go command at build time, if any.
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if release = vcsRevision(info); release != "" {
+			Logger.Printf("Using release from build info: %s", release)
+			return release, releaseSourceBuildInfo
 		}
 	}
 
 	// Derive a version string from Git.
 	cmd := exec.Command("git", "rev-parse", "HEAD")
 	b, err := cmd.Output()
-	if err != nil {
-		// Either Git is not available or the current directory is not a
-		// Git repository.
-		var s strings.Builder
-		fmt.Fprintf(&s, "Release detection failed: %v", err)
-		if err, ok := err.(*exec.ExitError); ok && len(err.Stderr) > 0 {
-			fmt.Fprintf(&s, ": %s", err.Stderr)
+	if err == nil {
+		release = strings.TrimSpace(string(b))
+		Logger.Printf("Using release from Git: %s", release)
+		return release, releaseSourceGit
+	}
+	// Either Git is not available or the current directory is not a Git
+	// repository.
+	var s strings.Builder
+	fmt.Fprintf(&s, "Release detection from Git failed: %v", err)
+	if err, ok := err.(*exec.ExitError); ok && len(err.Stderr) > 0 {
+		fmt.Fprintf(&s, ": %s", strings.TrimSpace(string(err.Stderr)))
+	}
+	Logger.Print(s.String())
+
+	// As a last resort, identify the build by the contents of the executable.
+	if release, err = executableChecksum(); err == nil {
+		Logger.Printf("Using release from executable checksum: %s", release)
+		return release, releaseSourceExecutable
+	}
+	Logger.Printf("Release detection failed: %v", err)
+	Logger.Print("Some Sentry features will not be available. See https://docs.sentry.io/product/releases/.")
+	Logger.Print("To stop seeing this message, pass a Release to sentry.Init or set the SENTRY_RELEASE environment variable.")
+	return "", ""
+}
+
+// The checksum of the executable is computed once, as it does not change
+// while the process runs. See executableChecksum.
+var (
+	executableChecksumOnce sync.Once
+	executableChecksumHex  string
+	executableChecksumErr  error
+)
+
+// executableChecksum returns the hex-encoded SHA-1 checksum of the executable
+// of the current process.
+func executableChecksum() (string, error) {
+	executableChecksumOnce.Do(func() {
+		var path string
+		path, executableChecksumErr = os.Executable()
+		if executableChecksumErr == nil {
+			executableChecksumHex, executableChecksumErr = fileChecksum(path)
 		}
-		Logger.Print(s.String())
-		Logger.Print("Some Sentry features will not be available. See https://docs.sentry.io/product/releases/.")
-		Logger.Print("To stop seeing this message, pass a Release to sentry.Init or set the SENTRY_RELEASE environment variable.")
-		return ""
+	})
+	return executableChecksumHex, executableChecksumErr
+}
+
+// fileChecksum returns the hex-encoded SHA-1 checksum of the named file.
+func fileChecksum(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
 	}
-	release = strings.TrimSpace(string(b))
-	Logger.Printf("Using release from Git: %s", release)
-	return release
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
@@ -1,6 +1,8 @@
 package sentry
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -27,3 +29,47 @@ func TestFileExistsReturnsFalseForNonExistingFiles(t *testing.T) {
 	assertEqual(t, fileExists(("util_nope.go")), false)
 	assertEqual(t, fileExists(("util_nope_test.go")), false)
 }
+
+func TestDefaultReleaseFromEnvironment(t *testing.T) {
+	defer os.Setenv("SENTRY_RELEASE", os.Getenv("SENTRY_RELEASE"))
+	os.Setenv("SENTRY_RELEASE", "my-release")
+
+	release, source := defaultRelease()
+	assertEqual(t, release, "my-release")
+	assertEqual(t, source, releaseSourceEnv)
+
+	client, err := NewClient(ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, client.Options().Release, "my-release")
+	assertEqual(t, client.Diagnose().ReleaseSource, releaseSourceEnv)
+
+	client, err = NewClient(ClientOptions{Release: "explicit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, client.Diagnose().ReleaseSource, releaseSourceOptions)
+}
+
+func TestFileChecksum(t *testing.T) {
+	f, err := ioutil.TempFile("", "sentry-checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("hello"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	got, err := fileChecksum(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, got, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d")
+
+	if _, err := fileChecksum("util_nope.go"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
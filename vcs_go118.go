@@ -0,0 +1,27 @@
+//go:build go1.18
+// +build go1.18
+
+package sentry
+
+import "runtime/debug"
+
+// vcsRevision returns the version control revision the program was built from,
+// as stamped by the go command in the build info. It returns an empty string if
+// the information is not available, for example when building with
+// -buildvcs=false or outside of a repository.
+func vcsRevision(info *debug.BuildInfo) string {
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
@@ -0,0 +1,46 @@
+//go:build go1.18
+// +build go1.18
+
+package sentry
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestVCSRevision(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []debug.BuildSetting
+		want     string
+	}{
+		{
+			name: "no vcs information",
+			want: "",
+		},
+		{
+			name: "clean",
+			settings: []debug.BuildSetting{
+				{Key: "vcs", Value: "git"},
+				{Key: "vcs.revision", Value: "d3039528d8ac"},
+				{Key: "vcs.modified", Value: "false"},
+			},
+			want: "d3039528d8ac",
+		},
+		{
+			name: "modified",
+			settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "d3039528d8ac"},
+				{Key: "vcs.modified", Value: "true"},
+			},
+			want: "d3039528d8ac-dirty",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := vcsRevision(&debug.BuildInfo{Settings: tt.settings})
+			assertEqual(t, got, tt.want)
+		})
+	}
+}
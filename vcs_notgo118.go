@@ -0,0 +1,12 @@
+//go:build !go1.18
+// +build !go1.18
+
+package sentry
+
+import "runtime/debug"
+
+// vcsRevision returns the version control revision the program was built from.
+// Go versions before 1.18 do not record it in the build info.
+func vcsRevision(info *debug.BuildInfo) string {
+	return ""
+}
@@ -0,0 +1,167 @@
+package sentry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultWatchdogThreshold is the default time without heartbeats after which
+// a Watchdog considers the monitored goroutine blocked.
+const defaultWatchdogThreshold = 5 * time.Second
+
+// WatchdogOptions configure a Watchdog.
+type WatchdogOptions struct {
+	// Name identifies the monitored goroutine in reported events. Defaults
+	// to "main".
+	Name string
+	// Threshold is the time without heartbeats after which the monitored
+	// goroutine is considered blocked. Defaults to 5 seconds.
+	Threshold time.Duration
+}
+
+// A Watchdog is an integration that detects when a monitored goroutine, like
+// the main event loop of a program, stops making progress. It is similar to
+// the "Application Not Responding" detection in mobile SDKs.
+//
+// The monitored goroutine must call Heartbeat periodically, more often than
+// the configured threshold. When no heartbeat is received for longer than the
+// threshold, the Watchdog reports an event with the stack traces of all
+// goroutines, marking the monitored goroutine as crashed. At most one event is
+// reported per stall; reporting resumes after the next heartbeat.
+//
+// A Watchdog is installed like any other integration:
+//
+//	watchdog := sentry.NewWatchdog(sentry.WatchdogOptions{Threshold: 10 * time.Second})
+//	sentry.Init(sentry.ClientOptions{
+//		Integrations: func(integrations []sentry.Integration) []sentry.Integration {
+//			return append(integrations, watchdog)
+//		},
+//	})
+//	for {
+//		watchdog.Heartbeat()
+//		// ...
+//	}
+type Watchdog struct {
+	name      string
+	threshold time.Duration
+
+	mu          sync.Mutex
+	lastBeat    time.Time
+	goroutineID string
+	reported    bool
+
+	start sync.Once
+	stop  chan struct{}
+	close sync.Once
+}
+
+// NewWatchdog returns a new Watchdog. It starts monitoring when installed as
+// an integration of a Client.
+func NewWatchdog(options WatchdogOptions) *Watchdog {
+	name := options.Name
+	if name == "" {
+		name = "main"
+	}
+	threshold := options.Threshold
+	if threshold <= 0 {
+		threshold = defaultWatchdogThreshold
+	}
+	return &Watchdog{
+		name:      name,
+		threshold: threshold,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Name implements Integration.
+func (w *Watchdog) Name() string {
+	return "Watchdog"
+}
+
+// SetupOnce implements Integration. It starts a background goroutine that
+// checks for heartbeats until Stop is called.
+func (w *Watchdog) SetupOnce(client *Client) {
+	w.start.Do(func() {
+		w.mu.Lock()
+		w.lastBeat = time.Now()
+		w.mu.Unlock()
+		go w.run(client)
+	})
+}
+
+// Heartbeat signals that the monitored goroutine is making progress. It must
+// be called from the monitored goroutine, which is identified in reported
+// events.
+func (w *Watchdog) Heartbeat() {
+	id := currentGoroutineID()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastBeat = time.Now()
+	w.goroutineID = id
+	w.reported = false
+}
+
+// Stop stops monitoring. It is safe to call Stop multiple times.
+func (w *Watchdog) Stop() {
+	w.close.Do(func() {
+		close(w.stop)
+	})
+}
+
+func (w *Watchdog) run(client *Client) {
+	ticker := time.NewTicker(w.threshold / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.check(client)
+		}
+	}
+}
+
+// check reports an event if the threshold has been exceeded since the last
+// heartbeat and no event was reported for the current stall.
+func (w *Watchdog) check(client *Client) {
+	w.mu.Lock()
+	blocked := time.Since(w.lastBeat)
+	if w.reported || blocked < w.threshold {
+		w.mu.Unlock()
+		return
+	}
+	w.reported = true
+	id := w.goroutineID
+	w.mu.Unlock()
+
+	Logger.Printf("Watchdog: goroutine %q blocked for %s", w.name, blocked)
+	// The Watchdog runs in its own goroutine, therefore there is no
+	// request-specific hub. Events are enriched with the scope of the
+	// current hub instead.
+	client.CaptureEvent(w.eventFor(id, blocked), nil, CurrentHub().Scope())
+}
+
+func (w *Watchdog) eventFor(goroutineID string, blocked time.Duration) *Event {
+	event := NewEvent()
+	event.Level = LevelError
+	event.Threads = parseGoroutineStacks(allGoroutinesStack())
+
+	exception := Exception{
+		Type: "ApplicationNotResponding",
+		Value: fmt.Sprintf("Goroutine %q blocked for at least %s",
+			w.name, blocked.Round(time.Millisecond)),
+		ThreadID:  goroutineID,
+		Mechanism: &Mechanism{Type: "ANR"},
+	}
+	exception.Mechanism.SetHandled(false)
+	for i := range event.Threads {
+		if goroutineID != "" && event.Threads[i].ID == goroutineID {
+			event.Threads[i].Crashed = true
+			exception.Stacktrace = event.Threads[i].Stacktrace
+		}
+	}
+	event.Exception = []Exception{exception}
+	event.Extra["blocked_for"] = blocked.Seconds()
+	return event
+}
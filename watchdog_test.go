@@ -0,0 +1,84 @@
+package sentry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdogReportsBlockedGoroutine(t *testing.T) {
+	transport := &TransportMock{}
+	watchdog := NewWatchdog(WatchdogOptions{
+		Name:      "loop",
+		Threshold: 20 * time.Millisecond,
+	})
+	defer watchdog.Stop()
+	_, err := NewClient(ClientOptions{
+		Transport: transport,
+		Integrations: func(integrations []Integration) []Integration {
+			return append(integrations, watchdog)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unblock := make(chan struct{})
+	ids := make(chan string)
+	go func() {
+		watchdog.Heartbeat()
+		ids <- currentGoroutineID()
+		<-unblock
+	}()
+	id := <-ids
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(transport.Events()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Wait some more to ensure a single stall is reported only once.
+	time.Sleep(50 * time.Millisecond)
+	close(unblock)
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	event := events[0]
+	if len(event.Exception) != 1 {
+		t.Fatalf("got %d exceptions, want 1", len(event.Exception))
+	}
+	exception := event.Exception[0]
+	assertEqual(t, exception.Type, "ApplicationNotResponding")
+	assertEqual(t, exception.ThreadID, id)
+	var crashed int
+	for _, thread := range event.Threads {
+		if thread.Crashed {
+			crashed++
+			assertEqual(t, thread.ID, id)
+		}
+	}
+	assertEqual(t, crashed, 1)
+}
+
+func TestWatchdogHeartbeatResetsStall(t *testing.T) {
+	transport := &TransportMock{}
+	watchdog := NewWatchdog(WatchdogOptions{Threshold: 40 * time.Millisecond})
+	defer watchdog.Stop()
+	_, err := NewClient(ClientOptions{
+		Transport: transport,
+		Integrations: func(integrations []Integration) []Integration {
+			return append(integrations, watchdog)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		watchdog.Heartbeat()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(transport.Events()); n != 0 {
+		t.Fatalf("got %d events, want 0", n)
+	}
+}
@@ -0,0 +1,94 @@
+package sentrywebsocket
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// A GorillaConn is a github.com/gorilla/websocket connection instrumented with
+// Sentry.
+type GorillaConn struct {
+	*websocket.Conn
+	*Connection
+}
+
+// Upgrade upgrades the HTTP server connection to the WebSocket protocol with
+// upgrader, like upgrader.Upgrade, and attaches a hub to the connection.
+//
+//	var upgrader = websocket.Upgrader{}
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		conn, err := sentrywebsocket.Upgrade(&upgrader, w, r, nil, sentrywebsocket.Options{})
+//		if err != nil {
+//			return
+//		}
+//		defer conn.Close()
+//		conn.Go(func() { writePump(conn) })
+//		readPump(conn)
+//	}
+func Upgrade(upgrader *websocket.Upgrader, w http.ResponseWriter, r *http.Request, responseHeader http.Header, options Options) (*GorillaConn, error) {
+	conn, err := upgrader.Upgrade(w, r, responseHeader)
+	if err != nil {
+		return nil, err
+	}
+	return &GorillaConn{
+		Conn:       conn,
+		Connection: newConnection(r, conn.Subprotocol(), options),
+	}, nil
+}
+
+// ReadMessage reads a message like the ReadMessage method of websocket.Conn,
+// recording errors and disconnections in breadcrumbs.
+func (c *GorillaConn) ReadMessage() (messageType int, p []byte, err error) {
+	messageType, p, err = c.Conn.ReadMessage()
+	if err != nil {
+		c.gorillaReadError(err)
+	}
+	return messageType, p, err
+}
+
+// WriteMessage writes a message like the WriteMessage method of
+// websocket.Conn, recording errors in breadcrumbs.
+func (c *GorillaConn) WriteMessage(messageType int, data []byte) error {
+	err := c.Conn.WriteMessage(messageType, data)
+	if err != nil {
+		c.writeError(err)
+	}
+	return err
+}
+
+// ReadJSON reads a JSON message like the ReadJSON method of websocket.Conn,
+// recording errors and disconnections in breadcrumbs.
+func (c *GorillaConn) ReadJSON(v interface{}) error {
+	err := c.Conn.ReadJSON(v)
+	if err != nil {
+		c.gorillaReadError(err)
+	}
+	return err
+}
+
+// WriteJSON writes a JSON message like the WriteJSON method of
+// websocket.Conn, recording errors in breadcrumbs.
+func (c *GorillaConn) WriteJSON(v interface{}) error {
+	err := c.Conn.WriteJSON(v)
+	if err != nil {
+		c.writeError(err)
+	}
+	return err
+}
+
+// Close closes the underlying network connection, like the Close method of
+// websocket.Conn, recording the disconnection in a breadcrumb.
+func (c *GorillaConn) Close() error {
+	c.closed()
+	return c.Conn.Close()
+}
+
+func (c *GorillaConn) gorillaReadError(err error) {
+	if closeErr, ok := err.(*websocket.CloseError); ok {
+		c.readError(err, closeErr.Code, true)
+		return
+	}
+	c.readError(err, 0, false)
+}
@@ -0,0 +1,67 @@
+package sentrywebsocket
+
+import (
+	"context"
+	"net/http"
+
+	"nhooyr.io/websocket"
+)
+
+// A NhooyrConn is a nhooyr.io/websocket connection instrumented with Sentry.
+type NhooyrConn struct {
+	*websocket.Conn
+	*Connection
+}
+
+// Accept accepts a WebSocket handshake from a client and upgrades the
+// connection to the WebSocket protocol, like websocket.Accept, and attaches a
+// hub to the connection.
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		conn, err := sentrywebsocket.Accept(w, r, nil, sentrywebsocket.Options{})
+//		if err != nil {
+//			return
+//		}
+//		defer conn.Close(websocket.StatusInternalError, "")
+//		ctx := conn.Context(r.Context())
+//		conn.Go(func() { writePump(ctx, conn) })
+//		readPump(ctx, conn)
+//	}
+func Accept(w http.ResponseWriter, r *http.Request, opts *websocket.AcceptOptions, options Options) (*NhooyrConn, error) {
+	conn, err := websocket.Accept(w, r, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &NhooyrConn{
+		Conn:       conn,
+		Connection: newConnection(r, conn.Subprotocol(), options),
+	}, nil
+}
+
+// Read reads a message like the Read method of websocket.Conn, recording
+// errors and disconnections in breadcrumbs.
+func (c *NhooyrConn) Read(ctx context.Context) (websocket.MessageType, []byte, error) {
+	typ, p, err := c.Conn.Read(ctx)
+	if err != nil {
+		code := websocket.CloseStatus(err)
+		c.readError(err, int(code), code != -1)
+	}
+	return typ, p, err
+}
+
+// Write writes a message like the Write method of websocket.Conn, recording
+// errors in breadcrumbs.
+func (c *NhooyrConn) Write(ctx context.Context, typ websocket.MessageType, p []byte) error {
+	err := c.Conn.Write(ctx, typ, p)
+	if err != nil {
+		c.writeError(err)
+	}
+	return err
+}
+
+// Close closes the connection with the given status code and reason, like the
+// Close method of websocket.Conn, recording the disconnection in a breadcrumb.
+func (c *NhooyrConn) Close(code websocket.StatusCode, reason string) error {
+	c.closed()
+	return c.Conn.Close(code, reason)
+}
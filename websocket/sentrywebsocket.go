@@ -0,0 +1,146 @@
+// Package sentrywebsocket provides Sentry integration for WebSocket servers
+// based on the github.com/gorilla/websocket and nhooyr.io/websocket packages.
+package sentrywebsocket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Options configure the instrumentation of WebSocket connections.
+type Options struct {
+	// Repanic configures whether to panic again after recovering from a panic
+	// in a goroutine started with Go.
+	Repanic bool
+	// WaitForDelivery indicates, in case of a panic, whether to block the
+	// current goroutine and wait until the panic event has been reported to
+	// Sentry before repanicking or resuming normal execution.
+	WaitForDelivery bool
+	// Timeout for the delivery of panic events. Defaults to 2s. Only relevant
+	// when WaitForDelivery is true.
+	Timeout time.Duration
+}
+
+// A Connection attaches a hub to a WebSocket connection. The scope of the hub
+// holds the upgrade request and a "websocket" context with the metadata of the
+// connection, so that the events captured while the connection is open can be
+// told apart. Breadcrumbs record the connection, its disconnection and the
+// errors reading and writing messages.
+//
+// Connection is embedded in GorillaConn and NhooyrConn.
+type Connection struct {
+	hub             *sentry.Hub
+	repanic         bool
+	waitForDelivery bool
+	timeout         time.Duration
+}
+
+// newConnection returns the Connection of a WebSocket connection upgraded from
+// r, with the given subprotocol. The hub is the one of the context of r, or a
+// clone of the current hub.
+func newConnection(r *http.Request, subprotocol string, options Options) *Connection {
+	hub := sentry.GetHubFromContext(r.Context())
+	if hub == nil {
+		hub = sentry.CurrentHub().Clone()
+	}
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	c := &Connection{
+		hub:             hub,
+		repanic:         options.Repanic,
+		waitForDelivery: options.WaitForDelivery,
+		timeout:         timeout,
+	}
+
+	// The query string often carries the credentials of the connection.
+	data := map[string]interface{}{
+		"url": r.URL.Path,
+	}
+	if client := hub.Client(); client != nil && client.Options().SendDefaultPII {
+		data["remote_addr"] = r.RemoteAddr
+	}
+	if subprotocol != "" {
+		data["subprotocol"] = subprotocol
+	}
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetRequest(r)
+		scope.SetContext("websocket", data)
+	})
+	c.breadcrumb(sentry.LevelInfo, "connected", nil)
+	return c
+}
+
+// Hub returns the hub of the connection.
+func (c *Connection) Hub() *sentry.Hub {
+	return c.hub
+}
+
+// Context returns a copy of ctx carrying the hub of the connection, to pass to
+// the functions that handle its messages.
+func (c *Connection) Context(ctx context.Context) context.Context {
+	return sentry.SetHubOnContext(ctx, c.hub)
+}
+
+// Go calls f in a new goroutine, recovering and reporting panics to Sentry
+// with the metadata of the connection. Use it to start the read and write
+// pumps of the connection:
+//
+//	conn.Go(func() { writePump(conn) })
+func (c *Connection) Go(f func()) {
+	go func() {
+		defer c.recover()
+		f()
+	}()
+}
+
+func (c *Connection) recover() {
+	if err := recover(); err != nil {
+		eventID := c.hub.Recover(err)
+		if eventID != nil && c.waitForDelivery {
+			c.hub.Flush(c.timeout)
+		}
+		if c.repanic {
+			panic(err)
+		}
+	}
+}
+
+// breadcrumb records an event in the life of the connection.
+func (c *Connection) breadcrumb(level sentry.Level, message string, err error) {
+	b := &sentry.Breadcrumb{
+		Type:     "default",
+		Category: "websocket",
+		Message:  message,
+		Level:    level,
+	}
+	if err != nil {
+		b.Data = map[string]interface{}{"error": err.Error()}
+	}
+	c.hub.AddBreadcrumb(b, nil)
+}
+
+// readError records an error reading a message. Close errors record the
+// disconnection of the peer instead.
+func (c *Connection) readError(err error, code int, closed bool) {
+	if closed {
+		c.breadcrumb(sentry.LevelInfo, fmt.Sprintf("disconnected with status %d", code), nil)
+		return
+	}
+	c.breadcrumb(sentry.LevelError, "read error", err)
+}
+
+// closed records the closing of the connection by the server.
+func (c *Connection) closed() {
+	c.breadcrumb(sentry.LevelInfo, "closed", nil)
+}
+
+// writeError records an error writing a message.
+func (c *Connection) writeError(err error) {
+	c.breadcrumb(sentry.LevelError, "write error", err)
+}
@@ -0,0 +1,141 @@
+package sentrywebsocket_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/getsentry/sentry-go/sentrytest"
+	sentrywebsocket "github.com/getsentry/sentry-go/websocket"
+	gorilla "github.com/gorilla/websocket"
+	"nhooyr.io/websocket"
+)
+
+// waitForEvent waits until transport recorded an event, for at most 5
+// seconds.
+func waitForEvent(t *testing.T, transport *sentrytest.RecordingTransport) {
+	deadline := time.Now().Add(5 * time.Second)
+	for len(transport.Events()) == 0 {
+		if time.Now().After(deadline) {
+			t.Error("no event reported")
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// withHub returns a handler that runs h with a new hub on the request context,
+// sending events to transport.
+func withHub(t *testing.T, transport *sentrytest.RecordingTransport, options sentry.ClientOptions, h http.HandlerFunc) http.HandlerFunc {
+	t.Helper()
+	options.Transport = transport
+	client, err := sentry.NewClient(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		hub := sentry.NewHub(client, sentry.NewScope())
+		h(w, r.WithContext(sentry.SetHubOnContext(r.Context(), hub)))
+	}
+}
+
+// checkPanicEvent checks the event reported for a panic in a pump.
+func checkPanicEvent(t *testing.T, transport *sentrytest.RecordingTransport, wantRemoteAddr bool, wantBreadcrumbs []string) {
+	t.Helper()
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.Message != "pump" {
+		t.Errorf("got message %q, want %q", event.Message, "pump")
+	}
+	data, ok := event.Contexts["websocket"].(map[string]interface{})
+	if !ok || data["url"] != "/ws" || data["subprotocol"] != "chat" {
+		t.Errorf("got websocket context %v", event.Contexts["websocket"])
+	}
+	if _, ok := data["remote_addr"]; ok != wantRemoteAddr {
+		t.Errorf("got remote address %v, want one: %v", data["remote_addr"], wantRemoteAddr)
+	}
+	var breadcrumbs []string
+	for _, b := range event.Breadcrumbs {
+		breadcrumbs = append(breadcrumbs, b.Message)
+	}
+	if strings.Join(breadcrumbs, ",") != strings.Join(wantBreadcrumbs, ",") {
+		t.Errorf("got breadcrumbs %v, want %v", breadcrumbs, wantBreadcrumbs)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	upgrader := gorilla.Upgrader{Subprotocols: []string{"chat"}}
+	done := make(chan struct{})
+	transport := sentrytest.NewRecordingTransport()
+	handler := withHub(t, transport, sentry.ClientOptions{}, func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		conn, err := sentrywebsocket.Upgrade(&upgrader, w, r, nil, sentrywebsocket.Options{WaitForDelivery: true})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		if _, _, err := conn.ReadMessage(); err == nil {
+			t.Error("got no error, want a close error")
+		}
+		conn.Go(func() { panic("pump") })
+		waitForEvent(t, transport)
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	dialer := gorilla.Dialer{Subprotocols: []string{"chat"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws?token=secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := gorilla.FormatCloseMessage(gorilla.CloseNormalClosure, "bye")
+	if err := conn.WriteMessage(gorilla.CloseMessage, msg); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	conn.Close()
+
+	checkPanicEvent(t, transport, false, []string{"connected", "disconnected with status 1000"})
+}
+
+func TestAccept(t *testing.T) {
+	done := make(chan struct{})
+	transport := sentrytest.NewRecordingTransport()
+	handler := withHub(t, transport, sentry.ClientOptions{SendDefaultPII: true}, func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		conn, err := sentrywebsocket.Accept(w, r, &websocket.AcceptOptions{Subprotocols: []string{"chat"}}, sentrywebsocket.Options{})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close(websocket.StatusInternalError, "")
+		if _, _, err := conn.Read(r.Context()); err == nil {
+			t.Error("got no error, want a close error")
+		}
+		conn.Go(func() { panic("pump") })
+		waitForEvent(t, transport)
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, srv.URL+"/ws", &websocket.DialOptions{Subprotocols: []string{"chat"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(websocket.StatusNormalClosure, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	checkPanicEvent(t, transport, true, []string{"connected", "disconnected with status 1000"})
+}